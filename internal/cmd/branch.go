@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jabafett/quill/internal/factories"
+	"github.com/jabafett/quill/internal/providers"
+	"github.com/jabafett/quill/internal/ui"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/jabafett/quill/internal/utils/helpers"
+	"github.com/spf13/cobra"
+)
+
+var branchCmd = &cobra.Command{
+	Use:   "branch [task description...]",
+	Short: "Propose a branch name for the current work and switch to it",
+	Long: `Propose branch names for the uncommitted changes, a task description, or both,
+then create the chosen branch at HEAD and switch to it. Uncommitted changes
+come along to the new branch.
+
+Names follow the pattern under [branch] in the config, "<type>/<ticket>-<slug>"
+by default. The ticket is taken from --ticket or from the first issue key in the
+description, and left out with its separator when there is none. Names that are
+not valid git branch names are dropped, and a name already used by a local or
+remote-tracking branch gets a number appended.
+
+Examples:
+  # Name a branch after the uncommitted changes
+  quill branch
+
+  # Name a branch for work not started yet
+  quill branch "PROJ-123 let users reset their password"
+
+  # Set the ticket explicitly and ask for five names
+  quill branch --ticket PROJ-123 --candidates 5 "password reset"`,
+	RunE: runBranch,
+}
+
+func init() {
+	branchCmd.Flags().StringP("provider", "p", "", "Override default AI provider (gemini, anthropic, openai, ollama)")
+	branchCmd.Flags().IntP("candidates", "c", 3, "Number of branch names to propose")
+	branchCmd.Flags().String("ticket", "", "Issue key to put in the name, e.g. PROJ-123")
+
+	branchCmd.RegisterFlagCompletionFunc("provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"gemini", "anthropic", "openai", "ollama"}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+func runBranch(cmd *cobra.Command, args []string) error {
+	debug.Log("Starting branch command")
+
+	provider, err := cmd.Flags().GetString("provider")
+	if err != nil {
+		return fmt.Errorf("failed to get provider flag: %w", err)
+	}
+	candidates, err := cmd.Flags().GetInt("candidates")
+	if err != nil {
+		return fmt.Errorf("failed to get candidates flag: %w", err)
+	}
+	if candidates < 1 {
+		return fmt.Errorf("invalid --candidates %d: propose at least one name", candidates)
+	}
+	ticket, err := cmd.Flags().GetString("ticket")
+	if err != nil {
+		return fmt.Errorf("failed to get ticket flag: %w", err)
+	}
+	if ticket != "" {
+		if err := git.ValidateBranchName(ticket); err != nil || strings.Contains(ticket, "/") {
+			return fmt.Errorf("invalid --ticket %q: it must fit in a branch name segment", ticket)
+		}
+	}
+
+	brancher, err := providers.NewBranchFactory(factories.ProviderOptions{
+		Provider:   provider,
+		Candidates: candidates,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no git repository found") {
+			return fmt.Errorf("no git repository found")
+		}
+		return fmt.Errorf("failed to create branch factory: %w", err)
+	}
+
+	proposals, err := brancher.Propose(context.Background(), strings.Join(args, " "), ticket)
+	if err != nil {
+		if _, ok := err.(helpers.ErrNoChanges); ok {
+			return fmt.Errorf("no changes to name a branch for; describe the task instead, e.g. quill branch \"add login form\"")
+		}
+		return fmt.Errorf("failed to propose branch names: %w", withProviderHint(err))
+	}
+
+	names := make([]string, len(proposals))
+	notes := make([]string, len(proposals))
+	for i, p := range proposals {
+		names[i], notes[i] = p.Name, p.Note
+	}
+
+	finalModel, err := tea.NewProgram(ui.NewBranchModel(names, notes)).Run()
+	if err != nil {
+		return fmt.Errorf("failed to run interactive UI: %w", err)
+	}
+	selectedModel := finalModel.(ui.BranchModel)
+	if selectedModel.Quitting() {
+		return fmt.Errorf("operation cancelled")
+	}
+	name := strings.TrimSpace(selectedModel.Selected())
+	if name == "" {
+		return fmt.Errorf("no branch name selected")
+	}
+
+	// An edited name has not been checked yet
+	repo := brancher.Repository()
+	if err := git.ValidateBranchName(name); err != nil {
+		return err
+	}
+	collision, err := repo.BranchCollision(name)
+	if err != nil {
+		return err
+	}
+	if collision != "" {
+		return fmt.Errorf("branch %s collides with the existing branch %s", name, collision)
+	}
+
+	if err := repo.CreateBranch(name); err != nil {
+		return err
+	}
+	cmd.Printf("Switched to a new branch '%s'\n", name)
+	return nil
+}
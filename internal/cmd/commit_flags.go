@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/spf13/cobra"
+)
+
+// addCommitFlags registers the signing, hook and author flags shared by generate and suggest
+func addCommitFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("gpg-sign", "S", false, "Sign the commit, commit.gpgsign is honoured without it")
+	cmd.Flags().String("signing-key", "", "Key to sign with, implies --gpg-sign (defaults to user.signingkey)")
+	cmd.Flags().String("sign-format", "", "Signature format: openpgp, x509 or ssh (defaults to gpg.format)")
+	cmd.Flags().BoolP("no-verify", "n", false, "Skip the pre-commit and commit-msg hooks")
+	cmd.Flags().String("author", "", "Override the commit author, \"Name <email>\"")
+
+	cmd.RegisterFlagCompletionFunc("sign-format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"openpgp", "x509", "ssh"}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// commitFlags reads the flags registered by addCommitFlags
+func commitFlags(cmd *cobra.Command) (git.CommitOptions, error) {
+	var opts git.CommitOptions
+	var err error
+
+	if opts.Sign, err = cmd.Flags().GetBool("gpg-sign"); err != nil {
+		return opts, fmt.Errorf("failed to get gpg-sign flag: %w", err)
+	}
+	if opts.SigningKey, err = cmd.Flags().GetString("signing-key"); err != nil {
+		return opts, fmt.Errorf("failed to get signing-key flag: %w", err)
+	}
+	if opts.SignFormat, err = cmd.Flags().GetString("sign-format"); err != nil {
+		return opts, fmt.Errorf("failed to get sign-format flag: %w", err)
+	}
+	if opts.NoVerify, err = cmd.Flags().GetBool("no-verify"); err != nil {
+		return opts, fmt.Errorf("failed to get no-verify flag: %w", err)
+	}
+	if opts.Author, err = cmd.Flags().GetString("author"); err != nil {
+		return opts, fmt.Errorf("failed to get author flag: %w", err)
+	}
+	return opts, nil
+}
+
+// trailerFlags reads the --signoff and --co-author flags shared by generate and suggest
+func trailerFlags(cmd *cobra.Command) (bool, []string, error) {
+	signoff, err := cmd.Flags().GetBool("signoff")
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get signoff flag: %w", err)
+	}
+	coAuthors, err := cmd.Flags().GetStringArray("co-author")
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get co-author flag: %w", err)
+	}
+	return signoff, coAuthors, nil
+}
+
+// printCommitOutput shows what hooks printed during a commit
+func printCommitOutput(cmd *cobra.Command, output string) {
+	if output != "" {
+		cmd.Print(output)
+	}
+}
@@ -62,6 +62,7 @@ Supported providers:
 - gemini   (Google's Gemini API)
 - anthropic (Claude API)
 - openai    (OpenAI API)
+- ollama    (only needed when Ollama sits behind an authenticating proxy)
 
 The API key is stored securely in your system's keyring/keychain and is never
 written to disk in plaintext.`,
@@ -78,6 +79,7 @@ Supported providers:
 - gemini
 - anthropic
 - openai
+- ollama
 
 The key will be displayed in plaintext - use with caution.`,
 	Args: cobra.ExactArgs(1),
@@ -149,6 +151,8 @@ func runSetKey(cmd *cobra.Command, args []string) error {
 		kp = keyring.Anthropic
 	case "openai":
 		kp = keyring.OpenAI
+	case "ollama":
+		kp = keyring.Ollama
 	default:
 		return fmt.Errorf("unknown provider: %s", provider)
 	}
@@ -172,6 +176,8 @@ func runGetKey(cmd *cobra.Command, args []string) error {
 		kp = keyring.Anthropic
 	case "openai":
 		kp = keyring.OpenAI
+	case "ollama":
+		kp = keyring.Ollama
 	default:
 		return fmt.Errorf("unknown provider: %s", provider)
 	}
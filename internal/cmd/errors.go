@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jabafett/quill/internal/utils/ai"
+)
+
+// withProviderHint appends an actionable hint to classified provider errors
+func withProviderHint(err error) error {
+	var provErr *ai.ProviderError
+	if !errors.As(err, &provErr) {
+		return err
+	}
+
+	hint := providerHint(provErr)
+	if hint == "" {
+		return err
+	}
+	return fmt.Errorf("%w\nhint: %s", err, hint)
+}
+
+// providerHint describes what the user can do about a provider failure
+func providerHint(e *ai.ProviderError) string {
+	switch e.Kind {
+	case ai.KindAuth:
+		if e.Provider == "ollama" {
+			return "the Ollama server rejected the request; check OLLAMA_API_KEY or your proxy settings"
+		}
+		return fmt.Sprintf("check your API key with 'quill config set-key %s <api-key>'", e.Provider)
+	case ai.KindRateLimited:
+		if e.RetryAfter > 0 {
+			return fmt.Sprintf("%s is rate limiting requests; try again in %s or lower --candidates", e.Provider, e.RetryAfter.Round(1e9))
+		}
+		return fmt.Sprintf("%s is rate limiting requests; wait a moment or lower --candidates", e.Provider)
+	case ai.KindOverloaded:
+		return fmt.Sprintf("%s is overloaded; try again shortly or switch with --provider", e.Provider)
+	case ai.KindContextTooLong:
+		return "the changes are too large for the model's context window; stage fewer files or configure a model with a larger context"
+	case ai.KindContentFiltered:
+		return "the provider's safety filter blocked the request; try another provider with --provider"
+	case ai.KindNetwork:
+		if e.Provider == "ollama" {
+			return "could not reach Ollama; make sure 'ollama serve' is running and OLLAMA_HOST is correct"
+		}
+		return fmt.Sprintf("could not reach %s; check your network connection or raise core.request_timeout", e.Provider)
+	case ai.KindInvalidRequest:
+		if e.Provider == "ollama" {
+			return "check providers.ollama.model, or download it with 'quill models pull'"
+		}
+		return fmt.Sprintf("check the model name under providers.%s in ~/.config/quill.toml", e.Provider)
+	case ai.KindQuotaExceeded:
+		return fmt.Sprintf("your %s account has run out of quota or credit; check billing or switch with --provider", e.Provider)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jabafett/quill/internal/factories"
+	"github.com/jabafett/quill/internal/providers"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/helpers"
+	"github.com/spf13/cobra"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [<rev> | <rev>..<rev>]",
+	Short: "Explain a commit, a range or the staged changes for a reviewer",
+	Long: `Explain a change in plain language: what changed, why it probably changed,
+the risky areas to review and what to test.
+
+A single commit is compared against its first parent. A range "a..b" covers
+what b added since it forked from a, with the commit messages of those commits.
+The reasons come from the commit messages and the issues and pull requests they
+reference. When 'quill index' has been run, the repository summary is used too.
+
+Examples:
+  # Explain the last commit
+  quill explain
+
+  # Explain an older commit
+  quill explain 3f2a9c1
+
+  # Explain everything a feature branch adds
+  quill explain main..feature/login
+
+  # Explain the staged changes before committing them
+  quill explain --staged
+
+  # Machine-readable output for tooling
+  quill explain HEAD~3..HEAD --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runExplain,
+}
+
+func init() {
+	explainCmd.Flags().StringP("provider", "p", "", "Override default AI provider (gemini, anthropic, openai, ollama)")
+	explainCmd.Flags().Bool("staged", false, "Explain the staged changes instead of a commit")
+	explainCmd.Flags().Bool("json", false, "Print the explanation as JSON")
+
+	explainCmd.RegisterFlagCompletionFunc("provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"gemini", "anthropic", "openai", "ollama"}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	debug.Log("Starting explain command")
+
+	provider, err := cmd.Flags().GetString("provider")
+	if err != nil {
+		return fmt.Errorf("failed to get provider flag: %w", err)
+	}
+	staged, err := cmd.Flags().GetBool("staged")
+	if err != nil {
+		return fmt.Errorf("failed to get staged flag: %w", err)
+	}
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("failed to get json flag: %w", err)
+	}
+
+	var spec string
+	if len(args) > 0 {
+		spec = args[0]
+	}
+	if staged && spec != "" {
+		return fmt.Errorf("--staged explains the staged changes and takes no revision")
+	}
+
+	explainer, err := providers.NewExplainFactory(factories.ProviderOptions{Provider: provider})
+	if err != nil {
+		if strings.Contains(err.Error(), "no git repository found") {
+			return fmt.Errorf("no git repository found")
+		}
+		return fmt.Errorf("failed to create explain factory: %w", err)
+	}
+
+	explanation, err := explainer.Explain(context.Background(), spec, staged)
+	if err != nil {
+		if _, ok := err.(helpers.ErrNoStagedChanges); ok {
+			return fmt.Errorf("no staged changes found")
+		}
+		return fmt.Errorf("failed to explain changes: %w", withProviderHint(err))
+	}
+	if notice := explainer.Notice(); notice != "" {
+		cmd.PrintErrln("Warning: " + notice)
+	}
+
+	// cmd.Print* writes to stderr unless an output is set
+	if asJSON {
+		out, err := json.MarshalIndent(explanation, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode explanation: %w", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(out))
+		return nil
+	}
+	fmt.Fprint(cmd.OutOrStdout(), explanation.Markdown())
+	return nil
+}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os/exec"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -11,6 +10,7 @@ import (
 	"github.com/jabafett/quill/internal/providers"
 	"github.com/jabafett/quill/internal/ui"
 	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
 	"github.com/jabafett/quill/internal/utils/helpers"
 	"github.com/spf13/cobra"
 )
@@ -32,7 +32,19 @@ Examples:
   quill generate --candidates 3
 
   # Adjust generation temperature
-  quill generate --temperature 0.7`,
+  quill generate --temperature 0.7
+
+  # Sign off and credit a pairing partner
+  quill generate --signoff --co-author "Jane Doe <jane@example.com>"
+
+  # Rewrite the last commit's message from its full diff, signing it with SSH
+  quill generate --amend -S --sign-format ssh
+
+  # Commit staged changes as a fixup for an earlier commit
+  quill generate --fixup HEAD~2
+
+  # Have a judge model rank the candidates, best first
+  quill generate --candidates 3 --judge model`,
 	RunE: runGenerate,
 }
 
@@ -40,10 +52,19 @@ func init() {
 	generateCmd.Flags().StringP("provider", "p", "", "Override default AI provider (gemini, anthropic, openai, ollama)")
 	generateCmd.Flags().IntP("candidates", "c", 2, "Number of commit message variations to generate (1-3)")
 	generateCmd.Flags().Float32P("temperature", "t", 0, "Generation temperature (0.0-1.0, 0 for default)")
+	generateCmd.Flags().Bool("signoff", false, "Add a Signed-off-by trailer")
+	generateCmd.Flags().StringArray("co-author", nil, "Add a Co-authored-by trailer, \"Name <email>\" (repeatable)")
+	generateCmd.Flags().Bool("amend", false, "Regenerate the message for HEAD from its full diff and amend it")
+	generateCmd.Flags().String("fixup", "", "Commit staged changes as a fixup! commit for this revision, without generating")
+	generateCmd.Flags().String("judge", "", "Rank candidates: off, rules or model (default from the [judge] config)")
+	addCommitFlags(generateCmd)
 
 	generateCmd.RegisterFlagCompletionFunc("provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return []string{"gemini", "anthropic", "openai", "ollama"}, cobra.ShellCompDirectiveNoFileComp
 	})
+	generateCmd.RegisterFlagCompletionFunc("judge", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"off", "rules", "model"}, cobra.ShellCompDirectiveNoFileComp
+	})
 }
 
 func runGenerate(cmd *cobra.Command, args []string) error {
@@ -60,11 +81,50 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get flags: %w", err)
 	}
 
+	signoff, coAuthors, err := trailerFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	commitOpts, err := commitFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if commitOpts.Amend, err = cmd.Flags().GetBool("amend"); err != nil {
+		return fmt.Errorf("failed to get amend flag: %w", err)
+	}
+	if commitOpts.Fixup, err = cmd.Flags().GetString("fixup"); err != nil {
+		return fmt.Errorf("failed to get fixup flag: %w", err)
+	}
+	judge, err := cmd.Flags().GetString("judge")
+	if err != nil {
+		return fmt.Errorf("failed to get judge flag: %w", err)
+	}
+	switch judge {
+	case "", "off", "rules", "model":
+	default:
+		return fmt.Errorf("invalid --judge %q: use off, rules or model", judge)
+	}
+
+	// Catch bad options before spending a request on generation
+	if err := commitOpts.Validate(); err != nil {
+		return err
+	}
+
+	// A fixup! commit takes its message from the target, there is nothing to generate
+	if commitOpts.Fixup != "" {
+		return runFixup(cmd, commitOpts)
+	}
+
 	// Create generate factory with options
 	generator, err := providers.NewGenerateFactory(factories.ProviderOptions{
 		Provider:    providerVal,
 		Candidates:  candidatesVal,
 		Temperature: temperatureVal,
+		Signoff:     signoff,
+		CoAuthors:   coAuthors,
+		Amend:       commitOpts.Amend,
+		Judge:       judge,
 	})
 	if err != nil {
 		if strings.Contains(err.Error(), "no git repository found") {
@@ -73,26 +133,46 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create generate factory: %w", err)
 	}
 
-	// Generate messages
-	msgs, err := generator.Generate(context.Background())
+	repo, err := git.NewRepository(".")
 	if err != nil {
-		if _, ok := err.(helpers.ErrNoStagedChanges); ok {
-			return fmt.Errorf("no staged changes found")
-		}
-		return fmt.Errorf("failed to generate commit messages: %w", err)
+		return fmt.Errorf("no git repository found")
 	}
 
-	// Create an interactive model for message selection
-	model := ui.NewCommitMessageModel(msgs)
-	p := tea.NewProgram(model, tea.WithFPS(120))
+	// Generate and select a message, regenerating whenever the user stages the
+	// remaining edits of partially staged files
+	var selectedModel ui.CommitMessageModel
+	for {
+		msgs, err := generator.Generate(context.Background())
+		if err != nil {
+			if _, ok := err.(helpers.ErrNoStagedChanges); ok {
+				return fmt.Errorf("no staged changes found")
+			}
+			return fmt.Errorf("failed to generate commit messages: %w", withProviderHint(err))
+		}
+		if notice := generator.Notice(); notice != "" {
+			cmd.PrintErrln("Warning: " + notice)
+		}
 
-	finalModel, err := p.Run()
-	if err != nil {
-		return fmt.Errorf("failed to run interactive UI: %w", err)
+		// Create an interactive model for message selection
+		model := ui.NewCommitMessageModel(msgs).
+			WithRationales(generator.Rationales()).
+			WithPartiallyStaged(generator.PartiallyStaged())
+		p := tea.NewProgram(model, tea.WithFPS(120))
+
+		finalModel, err := p.Run()
+		if err != nil {
+			return fmt.Errorf("failed to run interactive UI: %w", err)
+		}
+		selectedModel = finalModel.(ui.CommitMessageModel)
+		if !selectedModel.StageRemaining() {
+			break
+		}
+		if err := repo.StageFiles(generator.PartiallyStaged()); err != nil {
+			return err
+		}
 	}
 
 	// Get selected message
-	selectedModel := finalModel.(ui.CommitMessageModel)
 	if selectedModel.Selected() == "" {
 		return fmt.Errorf("no commit message selected")
 	}
@@ -102,11 +182,40 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	}
 
 	// Commit selected message
-	err = exec.Command("git", "commit", "-m", selectedModel.Selected()).Run()
+	result, err := repo.CommitWith(selectedModel.Selected(), commitOpts)
+	if err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	printCommitOutput(cmd, result.Output)
+
+	if commitOpts.Amend {
+		cmd.Printf("Successfully amended commit: %s\n", selectedModel.Selected())
+	} else {
+		cmd.Printf("Successfully created commit: %s\n", selectedModel.Selected())
+	}
+	return nil
+}
+
+// runFixup commits the staged changes as a fixup! commit for the given revision
+func runFixup(cmd *cobra.Command, opts git.CommitOptions) error {
+	repo, err := git.NewRepository(".")
+	if err != nil {
+		return fmt.Errorf("no git repository found")
+	}
+
+	if _, err := repo.HasStagedChanges(); err != nil {
+		if _, ok := err.(helpers.ErrNoStagedChanges); ok {
+			return fmt.Errorf("no staged changes found")
+		}
+		return err
+	}
+
+	result, err := repo.CommitWith("", opts)
 	if err != nil {
 		return fmt.Errorf("failed to commit: %w", err)
 	}
+	printCommitOutput(cmd, result.Output)
 
-	cmd.Printf("Successfully created commit: %s\n", selectedModel.Selected())
+	cmd.Printf("Successfully created fixup commit for %s\n", opts.Fixup)
 	return nil
 }
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/jabafett/quill/internal/utils/context"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/spf13/cobra"
+)
+
+var impactCmd = &cobra.Command{
+	Use:   "impact [files...]",
+	Short: "List the code that depends on the staged changes",
+	Long: `List the packages and files that import the staged changes.
+
+Imports are resolved within the repository for Go, JavaScript/TypeScript,
+Python, Rust and Java. Dependencies added, removed or updated in go.mod,
+package.json, Cargo.toml and requirements.txt are listed with the files
+that import them. When 'quill index' has been run, the imports it recorded
+are reused instead of parsing every file again.
+
+Examples:
+  # Direct importers of the staged changes
+  quill impact
+
+  # Follow importers of importers, all the way up
+  quill impact --depth 0
+
+  # Analyze specific files instead of the staged changes
+  quill impact internal/utils/git/diff.go`,
+	RunE: runImpact,
+}
+
+func init() {
+	impactCmd.Flags().Int("depth", 1, "Levels of importers to follow (0 follows all)")
+}
+
+func runImpact(cmd *cobra.Command, args []string) error {
+	debug.Log("Starting impact command")
+
+	depth, err := cmd.Flags().GetInt("depth")
+	if err != nil {
+		return fmt.Errorf("failed to get depth flag: %w", err)
+	}
+
+	repo, err := git.NewRepository(".")
+	if err != nil {
+		return fmt.Errorf("no git repository found")
+	}
+	root, err := repo.GetRepoRootPath()
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	var dependencies []context.DependencyChange
+	if len(args) > 0 {
+		for _, arg := range args {
+			abs, err := filepath.Abs(arg)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return err
+			}
+			changed = append(changed, filepath.ToSlash(rel))
+		}
+	} else {
+		diff, err := repo.StagedDiff()
+		if err != nil {
+			return fmt.Errorf("failed to get staged diff: %w", err)
+		}
+		if diff.IsEmpty() {
+			return fmt.Errorf("no staged changes to analyze; stage changes or name files")
+		}
+		changed = diff.Paths()
+
+		versions, err := repo.StagedVersions()
+		if err != nil {
+			return fmt.Errorf("failed to read staged files: %w", err)
+		}
+		dependencies = context.ManifestChanges(diff, versions)
+	}
+
+	var index *context.FileIndex
+	if context.FileIndexExists(root) {
+		if index, err = context.OpenFileIndex(root); err != nil {
+			debug.Log("Warning: Failed to open file index: %v", err)
+		} else {
+			defer index.Close()
+		}
+	}
+
+	graph, err := context.BuildImportGraph(repo, index)
+	if err != nil {
+		return fmt.Errorf("failed to build import graph: %w", err)
+	}
+	impact := graph.Impact(changed, dependencies, depth)
+
+	// cmd.Print* writes to stderr unless an output is set
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Changed files (%d):\n", len(impact.Changed))
+	for _, file := range impact.Changed {
+		fmt.Fprintf(out, "  %s\n", file)
+	}
+
+	if len(impact.Dependents) == 0 && len(impact.Dependencies) == 0 {
+		fmt.Fprintln(out, "\nNothing in the repository imports the changed files.")
+		return nil
+	}
+
+	if len(impact.Packages) > 0 {
+		fmt.Fprintf(out, "\nAffected packages (%d):\n", len(impact.Packages))
+		for _, pkg := range impact.Packages {
+			fmt.Fprintf(out, "  %s\n", pkg)
+		}
+	}
+
+	if len(impact.Dependents) > 0 {
+		fmt.Fprintf(out, "\nDependent files (%d):\n", len(impact.Dependents))
+		for _, dep := range impact.Dependents {
+			if dep.Depth == 1 {
+				fmt.Fprintf(out, "  %s (imports %s)\n", dep.Path, dep.Via)
+			} else {
+				fmt.Fprintf(out, "  %s (via %s, depth %d)\n", dep.Path, dep.Via, dep.Depth)
+			}
+		}
+	}
+
+	if len(impact.Dependencies) > 0 {
+		fmt.Fprintln(out, "\nDependency changes:")
+		for _, usage := range impact.Dependencies {
+			fmt.Fprintf(out, "  %s\n", usage.Change)
+			for _, user := range usage.Users {
+				fmt.Fprintf(out, "    %s\n", user)
+			}
+		}
+	}
+	return nil
+}
@@ -1,30 +1,47 @@
 package cmd
 
 import (
-        "context"
+        c "context"
         "fmt"
+        "os"
+        "strings"
+        "time"
 
         "github.com/jabafett/quill/internal/providers"
+        "github.com/jabafett/quill/internal/utils/context"
         "github.com/jabafett/quill/internal/utils/debug"
         "github.com/spf13/cobra"
 )
 
 var indexCmd = &cobra.Command{
         Use:   "index",
-        Short: "Generate repository summary",
+        Short: "Generate repository summary and file index",
         Long: `Analyzes the repository and generates an AI-powered summary.
 
 This command examines your git repository structure, files, and languages
 to create a concise summary of the repository. This summary is used by
 the 'generate' command to provide more context-aware commit messages.
 
-By default, the summary is generated once and cached. Use the --force
-flag to regenerate the summary.`,
+It also maintains a per-file index of summaries, exported symbols and
+imports. Only files whose content changed since the last run are
+re-indexed, so running it again is cheap. With embeddings = true in the
+[index] section, the files are also split into chunks and embedded, so
+'generate' and 'suggest' can add the code most related to a change.
+
+The summary records the commit it was written at. It is regenerated
+once the repository has moved past the thresholds in the [index] section
+of the configuration (commits, changed files or age), and 'generate' and
+'suggest' warn about an out-of-date summary or, with on_stale = "reindex",
+refresh it in the background. Use --status to see how far it is behind,
+and --force to regenerate the summary and re-index every file.`,
         RunE: runIndex,
 }
 
 func init() {
-        indexCmd.Flags().Bool("force", false, "Force regeneration of repository summary")
+        indexCmd.Flags().Bool("force", false, "Force regeneration of repository summary and file index")
+        indexCmd.Flags().Bool("status", false, "Show how out of date the summary and file index are, without indexing")
+        indexCmd.Flags().Bool("background", false, "Run as the background re-index started by generate and suggest")
+        indexCmd.Flags().MarkHidden("background")
         rootCmd.AddCommand(indexCmd)
 }
 
@@ -34,6 +51,19 @@ func runIndex(cmd *cobra.Command, args []string) error {
         forceReindex, _ := cmd.Flags().GetBool("force")
         debug.Log("Force regeneration: %v", forceReindex)
 
+        if status, _ := cmd.Flags().GetBool("status"); status {
+                return runIndexStatus(cmd)
+        }
+
+        if background, _ := cmd.Flags().GetBool("background"); background {
+                // Release the lock taken by the command that started this run
+                defer func() {
+                        if root, err := os.Getwd(); err == nil {
+                                os.Remove(context.IndexLockPath(root))
+                        }
+                }()
+        }
+
         fmt.Println("Initializing index provider...")
 
         // Instantiate IndexProvider
@@ -42,18 +72,46 @@ func runIndex(cmd *cobra.Command, args []string) error {
                 return fmt.Errorf("failed to create index provider: %w", err)
         }
 
-        // Check if summary already exists
+        fmt.Println("Updating file index...")
+
+        // Index files whose content changed since the last run
+        stats, err := indexProvider.IndexFiles(c.Background(), forceReindex)
+        if err != nil {
+                return fmt.Errorf("failed to index files: %w", withProviderHint(err))
+        }
+        fmt.Printf("Indexed %d files (%d unchanged, %d skipped, %d removed).\n",
+                stats.Indexed, stats.Unchanged, stats.Skipped, stats.Removed)
+
+        if indexProvider.EmbeddingsEnabled() {
+                fmt.Println("Updating embeddings...")
+                stats, err := indexProvider.EmbedFiles(c.Background())
+                if err != nil {
+                        return fmt.Errorf("failed to embed files: %w", withProviderHint(err))
+                }
+                fmt.Printf("Embedded %d files (%d unchanged, %d removed).\n",
+                        stats.Indexed, stats.Unchanged, stats.Removed)
+        }
+
+        // Keep an existing summary unless it is out of date
         if !forceReindex && indexProvider.HasSummary() {
-                fmt.Println("Repository summary already exists. Use --force to regenerate.")
-                return nil
+                staleness, err := indexProvider.SummaryStaleness()
+                if err != nil {
+                        return fmt.Errorf("failed to check repository summary: %w", err)
+                }
+                if !staleness.Stale() {
+                        fmt.Println("Repository summary is up to date. Use --force to regenerate.")
+                        return nil
+                }
+                fmt.Printf("Repository summary is out of date (%s).\n", strings.Join(staleness.Reasons, ", "))
+                forceReindex = true
         }
 
         fmt.Println("Analyzing repository and generating summary...")
 
         // Generate repository summary
-        err = indexProvider.IndexRepository(context.Background(), forceReindex)
+        err = indexProvider.IndexRepository(c.Background(), forceReindex)
         if err != nil {
-                return fmt.Errorf("failed to generate repository summary: %w", err)
+                return fmt.Errorf("failed to generate repository summary: %w", withProviderHint(err))
         }
 
         fmt.Println("Repository summary generated successfully.")
@@ -67,3 +125,66 @@ func runIndex(cmd *cobra.Command, args []string) error {
 
         return nil
 }
+
+func runIndexStatus(cmd *cobra.Command) error {
+        status, err := providers.GetIndexStatus()
+        if err != nil {
+                return err
+        }
+
+        // cmd.Print* writes to stderr unless an output is set
+        out := cmd.OutOrStdout()
+        if status.Summary == nil {
+                fmt.Fprintln(out, "Repository summary: none, run 'quill index' to generate one")
+        } else {
+                state := "up to date"
+                if status.Staleness.Stale() {
+                        state = "out of date (" + strings.Join(status.Staleness.Reasons, ", ") + ")"
+                }
+                fmt.Fprintf(out, "Repository summary: %s\n", state)
+                for _, line := range status.Staleness.Lines() {
+                        fmt.Fprintf(out, "  %s\n", line)
+                }
+                fmt.Fprintf(out, "  Thresholds: %s\n", formatThresholds(status.Thresholds))
+        }
+
+        switch {
+        case status.FileIndex != nil:
+                fmt.Fprintf(out, "File index: %d files, %d skipped, updated %s ago\n",
+                        len(status.FileIndex.Files), len(status.FileIndex.Skipped),
+                        context.FormatAge(time.Since(status.FileIndex.UpdatedAt)))
+        case status.Running:
+                fmt.Fprintln(out, "File index: in use by the running re-index")
+        default:
+                fmt.Fprintln(out, "File index: none, run 'quill index' to build one")
+        }
+
+        if status.Embeddings != nil {
+                fmt.Fprintf(out, "Embeddings: %d files with %s, updated %s ago\n",
+                        len(status.Embeddings.Files), status.Embeddings.Model,
+                        context.FormatAge(time.Since(status.Embeddings.UpdatedAt)))
+        }
+
+        if status.Running {
+                fmt.Fprintln(out, "A background re-index is running.")
+        }
+        return nil
+}
+
+// formatThresholds lists the staleness thresholds in use, e.g. "50 commits, 30 days"
+func formatThresholds(t context.StalenessThresholds) string {
+        var parts []string
+        if t.Commits > 0 {
+                parts = append(parts, fmt.Sprintf("%d commits", t.Commits))
+        }
+        if t.ChangedFiles > 0 {
+                parts = append(parts, fmt.Sprintf("%d changed files", t.ChangedFiles))
+        }
+        if t.Age > 0 {
+                parts = append(parts, context.FormatAge(t.Age))
+        }
+        if len(parts) == 0 {
+                return "none"
+        }
+        return strings.Join(parts, ", ")
+}
@@ -89,7 +89,11 @@ func GetProviderConfig(provider string) string {
 		return `    model = "qwen2.5-8b-instruct"
     max_tokens = 8192
     temperature = 0.3
-    enable_retries = true`
+    enable_retries = true
+    # Ollama server, overridden by OLLAMA_HOST
+    host = "http://localhost:11434"
+    # Context window passed as num_ctx
+    num_ctx = 16384`
 	default:
 		return ""
 	}
@@ -99,18 +103,73 @@ func GenerateConfig(selectedProvider string) string {
 	return fmt.Sprintf(`[core]
 # Cache TTL duration
 cache_ttl = "168h"
-# Number of retry attempts for API calls
+# Number of attempts for API calls, only transient failures are retried
 retry_attempts = 3
+# Deadline for a single API call
+request_timeout = "120s"
 # Default number of candidates to generate (0-3)
 default_candidates = 1
 # Maximum diff size for processing
 max_diff_size = "500MB"
+# Ask providers for JSON matching a schema instead of parsing free text
+structured_output = true
 # Default provider
 default_provider = "%s"
 
 [providers]
   [providers.%s]
 %s
+    # Rate limits for this provider and model, 0 or unset keeps the default
+    # (60 requests per minute for hosted APIs, unlimited for Ollama)
+    # requests_per_minute = 60
+    # tokens_per_minute = 0
+    # max_concurrency = 0
+
+[trailers]
+# Add Signed-off-by to every commit (same as --signoff)
+signoff = false
+# Add issue references found in the branch name, e.g. feat/PROJ-123-login
+issue_refs = true
+issue_key = "Refs"
+# Current pairing partners, one "Name <email>" per line
+pairing_file = "~/.config/quill-pair"
+
+[index]
+# The repository summary is out of date once any of these is reached, 0 ignores one
+stale_commits = 50
+stale_files = 100
+stale_age = "720h"
+# What generate and suggest do with an out-of-date summary: "warn", "reindex"
+# (run 'quill index' in the background) or "ignore"
+on_stale = "warn"
+# Embed chunks of the tracked files during 'quill index' and add the code most
+# related to each change to the prompt. Anthropic has no embeddings API, so
+# embeddings_provider must name another configured provider when it is the default.
+embeddings = false
+# embeddings_provider = "ollama"
+# Chunks retrieved per changed hunk, and the token budget for all of them
+related_chunks = 3
+related_tokens = 2000
+
+[judge]
+# How generate ranks its candidates: "off", "rules" (format and specificity checks)
+# or "model" (a judge prompt, falling back to the rules)
+mode = "off"
+# Provider of the judge model, empty for the default provider
+# provider = "openai"
+# More providers to draw candidates from when judging
+# providers = ["openai", "ollama"]
+
+[branch]
+# Names proposed by 'quill branch': <type> is the change type, <ticket> the issue
+# key (dropped with its separator when there is none) and <slug> the summary
+pattern = "<type>/<ticket>-<slug>"
+max_slug_length = 40
+
+[review]
+# Lowest severity that makes 'quill review' exit with an error: info, low,
+# medium, high or critical, or "none" to only report
+fail_on = "high"
 `, selectedProvider, selectedProvider, GetProviderConfig(selectedProvider))
 }
 
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jabafett/quill/internal/utils/ai"
+	"github.com/jabafett/quill/internal/utils/config"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/spf13/cobra"
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "Manage local Ollama models",
+	Long: `List and pull models on the Ollama server used by Quill.
+
+The server is taken from OLLAMA_HOST, then providers.ollama.host in
+~/.config/quill.toml, and defaults to http://localhost:11434.
+
+Available Commands:
+  list  - Show the models installed on the Ollama server
+  pull  - Download a model to the Ollama server`,
+}
+
+var modelsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed Ollama models",
+	Args:  cobra.NoArgs,
+	RunE:  runModelsList,
+}
+
+var modelsPullCmd = &cobra.Command{
+	Use:   "pull [model]",
+	Short: "Pull an Ollama model",
+	Long: `Download a model to the Ollama server.
+
+Examples:
+  quill models pull qwen2.5:7b-instruct
+
+  # Pull the model configured in providers.ollama.model
+  quill models pull`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runModelsPull,
+}
+
+func init() {
+	modelsCmd.AddCommand(modelsListCmd)
+	modelsCmd.AddCommand(modelsPullCmd)
+}
+
+// newOllamaProvider builds an Ollama client from the config, falling back to defaults
+// when Quill has not been configured for Ollama yet
+func newOllamaProvider() (*ai.OllamaProvider, ai.Options, error) {
+	options := ai.Options{}
+	if cfg, err := config.LoadConfig(); err == nil {
+		if _, ok := cfg.Providers["ollama"]; ok {
+			options, err = config.ConfigToOptions(cfg, "ollama")
+			if err != nil {
+				return nil, options, err
+			}
+		}
+	} else {
+		debug.Log("Using default Ollama settings: %v", err)
+	}
+
+	provider, err := ai.NewOllamaProvider(options)
+	if err != nil {
+		return nil, options, err
+	}
+	return provider, options, nil
+}
+
+func runModelsList(cmd *cobra.Command, args []string) error {
+	provider, options, err := newOllamaProvider()
+	if err != nil {
+		return err
+	}
+
+	models, err := provider.ListModels(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list models: %w", err)
+	}
+
+	if len(models) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No models installed. Use 'quill models pull <model>' to download one.")
+		return nil
+	}
+
+	for _, m := range models {
+		marker := " "
+		if m.Name == options.Model {
+			marker = "*"
+		}
+		details := strings.TrimSpace(strings.Join([]string{m.Details.ParameterSize, m.Details.QuantizationLevel}, " "))
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %-40s %8s  %s\n", marker, m.Name, formatBytes(m.Size), details)
+	}
+	return nil
+}
+
+func runModelsPull(cmd *cobra.Command, args []string) error {
+	provider, options, err := newOllamaProvider()
+	if err != nil {
+		return err
+	}
+
+	name := options.Model
+	if len(args) == 1 {
+		name = args[0]
+	}
+	if name == "" {
+		return fmt.Errorf("no model given and providers.ollama.model is not set")
+	}
+
+	lastStatus := ""
+	err = provider.PullModel(context.Background(), name, func(p ai.OllamaPullProgress) {
+		if p.Total > 0 {
+			cmd.PrintErrf("\r%s: %s / %s", p.Status, formatBytes(p.Completed), formatBytes(p.Total))
+			lastStatus = p.Status
+			return
+		}
+		if p.Status != lastStatus {
+			if lastStatus != "" {
+				cmd.PrintErrln()
+			}
+			cmd.PrintErr(p.Status)
+			lastStatus = p.Status
+		}
+	})
+	cmd.PrintErrln()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Model %s is ready\n", name)
+	return nil
+}
+
+// formatBytes renders a byte count using binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jabafett/quill/internal/factories"
+	"github.com/jabafett/quill/internal/providers"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/helpers"
+	"github.com/spf13/cobra"
+)
+
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Review the staged changes for bugs, leftovers and secrets before committing",
+	Long: `Review the staged changes before they are committed and report findings with a
+severity and a file:line location.
+
+Local checks look at every added line for secrets, leftover debug statements,
+breakpoints, conflict markers and TODOs. The AI provider then reviews the diff,
+with any secrets redacted, for likely bugs and changed functions without tests.
+Its findings are mapped back onto the lines the diff adds.
+
+The command exits with an error when a finding reaches the fail threshold, set
+by --fail-on or fail_on under [review] in the config ("high" by default), so it
+can run as a pre-commit hook or a CI step.
+
+Examples:
+  # Review the staged changes
+  quill review
+
+  # Fail on anything from medium severity up
+  quill review --fail-on medium
+
+  # Only report, never fail
+  quill review --fail-on none
+
+  # Write a SARIF log for an editor or code scanning
+  quill review --format sarif --output quill.sarif`,
+	Args: cobra.NoArgs,
+	RunE: runReview,
+}
+
+func init() {
+	reviewCmd.Flags().StringP("provider", "p", "", "Override default AI provider (gemini, anthropic, openai, ollama)")
+	reviewCmd.Flags().String("fail-on", "", "Lowest severity that fails the review: info, low, medium, high, critical or none")
+	reviewCmd.Flags().StringP("format", "f", "text", "Output format: text, json or sarif")
+	reviewCmd.Flags().StringP("output", "o", "", "Write the report to a file instead of stdout")
+
+	reviewCmd.RegisterFlagCompletionFunc("provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"gemini", "anthropic", "openai", "ollama"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	reviewCmd.RegisterFlagCompletionFunc("fail-on", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"info", "low", "medium", "high", "critical", "none"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	reviewCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"text", "json", "sarif"}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+func runReview(cmd *cobra.Command, args []string) error {
+	debug.Log("Starting review command")
+
+	provider, err := cmd.Flags().GetString("provider")
+	if err != nil {
+		return fmt.Errorf("failed to get provider flag: %w", err)
+	}
+	failOn, err := cmd.Flags().GetString("fail-on")
+	if err != nil {
+		return fmt.Errorf("failed to get fail-on flag: %w", err)
+	}
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return fmt.Errorf("failed to get format flag: %w", err)
+	}
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return fmt.Errorf("failed to get output flag: %w", err)
+	}
+
+	switch format {
+	case "text", "json", "sarif":
+	default:
+		return fmt.Errorf("invalid --format %q: use text, json or sarif", format)
+	}
+	if failOn != "" {
+		if _, err := helpers.ParseFailThreshold(failOn); err != nil {
+			return fmt.Errorf("invalid --fail-on: %w", err)
+		}
+	}
+
+	reviewer, err := providers.NewReviewFactory(factories.ProviderOptions{Provider: provider})
+	if err != nil {
+		if strings.Contains(err.Error(), "no git repository found") {
+			return fmt.Errorf("no git repository found")
+		}
+		return fmt.Errorf("failed to create review factory: %w", err)
+	}
+	if failOn == "" {
+		failOn = reviewer.FailOn()
+	}
+	threshold, err := helpers.ParseFailThreshold(failOn)
+	if err != nil {
+		return fmt.Errorf("invalid review.fail_on: %w", err)
+	}
+
+	review, err := reviewer.Review(context.Background())
+	if err != nil {
+		if _, ok := err.(helpers.ErrNoStagedChanges); ok {
+			return fmt.Errorf("no staged changes found")
+		}
+		return fmt.Errorf("failed to review changes: %w", withProviderHint(err))
+	}
+	if notice := reviewer.Notice(); notice != "" {
+		cmd.PrintErrln("Warning: " + notice)
+	}
+	if review.ModelError != "" {
+		cmd.PrintErrln("Warning: the AI review failed and only the local checks ran: " + review.ModelError)
+	}
+	if len(review.Omitted) > 0 {
+		cmd.PrintErrln(fmt.Sprintf("Warning: %d files were too large for the AI review and only got the local checks", len(review.Omitted)))
+	}
+
+	var report []byte
+	switch format {
+	case "json":
+		report, err = json.MarshalIndent(review, "", "  ")
+	case "sarif":
+		report, err = review.SARIF()
+	default:
+		report = []byte(review.Text())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode review: %w", err)
+	}
+	if output != "" {
+		if err := os.WriteFile(output, append(report, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write review: %w", err)
+		}
+		cmd.PrintErrln(fmt.Sprintf("Wrote %d findings to %s", len(review.Findings), output))
+	} else {
+		// cmd.Print* writes to stderr unless an output is set
+		fmt.Fprintln(cmd.OutOrStdout(), strings.TrimSuffix(string(report), "\n"))
+	}
+
+	if failing := review.AtOrAbove(threshold); len(failing) > 0 {
+		// Findings are not a usage error
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d findings at or above %s severity", len(failing), threshold)
+	}
+	return nil
+}
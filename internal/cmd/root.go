@@ -27,6 +27,12 @@ func init() {
         rootCmd.AddCommand(initCmd)
         rootCmd.AddCommand(configCmd)
         rootCmd.AddCommand(suggestCmd)
+        rootCmd.AddCommand(undoCmd)
+        rootCmd.AddCommand(modelsCmd)
+        rootCmd.AddCommand(impactCmd)
+        rootCmd.AddCommand(branchCmd)
+        rootCmd.AddCommand(explainCmd)
+        rootCmd.AddCommand(reviewCmd)
 }
 
 // GetRootCmd exposes the root command for testing
@@ -2,14 +2,15 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os/exec"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/jabafett/quill/internal/factories"
 	"github.com/jabafett/quill/internal/providers"
 	"github.com/jabafett/quill/internal/ui"
 	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
 	"github.com/jabafett/quill/internal/utils/helpers"
 	"github.com/spf13/cobra"
 )
@@ -37,7 +38,10 @@ Examples:
   quill suggest --candidates 3
 
   # Adjust generation temperature
-  quill suggest --temperature 0.7`,
+  quill suggest --temperature 0.7
+
+  # Sign off and GPG sign every suggested commit
+  quill suggest --signoff -S`,
 	RunE: runSuggest,
 }
 
@@ -47,6 +51,9 @@ func init() {
 	suggestCmd.Flags().Float32P("temperature", "t", 0, "Generation temperature (0.0-1.0, 0 for default)")
 	suggestCmd.Flags().BoolP("staged-only", "s", false, "Only consider staged changes")
 	suggestCmd.Flags().BoolP("unstaged-only", "u", false, "Only consider unstaged changes")
+	suggestCmd.Flags().Bool("signoff", false, "Add a Signed-off-by trailer")
+	suggestCmd.Flags().StringArray("co-author", nil, "Add a Co-authored-by trailer, \"Name <email>\" (repeatable)")
+	addCommitFlags(suggestCmd)
 	suggestCmd.Flags().BoolP("debug", "d", false, "Enable debug output")
 
 	suggestCmd.RegisterFlagCompletionFunc("provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -91,6 +98,19 @@ func runSuggest(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("cannot use both --staged-only and --unstaged-only flags")
 	}
 
+	signoff, coAuthors, err := trailerFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	commitOpts, err := commitFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if err := commitOpts.Validate(); err != nil {
+		return err
+	}
+
 	// Create suggest factory with options
 	suggester, err := providers.NewSuggestFactory(factories.ProviderOptions{
 		Provider:     providerVal,
@@ -98,6 +118,8 @@ func runSuggest(cmd *cobra.Command, args []string) error {
 		Temperature:  temperatureVal,
 		StagedOnly:   stagedOnly,
 		UnstagedOnly: unstagedOnly,
+		Signoff:      signoff,
+		CoAuthors:    coAuthors,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create suggest factory: %w", err)
@@ -109,7 +131,10 @@ func runSuggest(cmd *cobra.Command, args []string) error {
 		if _, ok := err.(helpers.ErrNoChanges); ok {
 			return fmt.Errorf("no changes found to suggest groupings for")
 		}
-		return fmt.Errorf("failed to generate suggestions: %w", err)
+		return fmt.Errorf("failed to generate suggestions: %w", withProviderHint(err))
+	}
+	if notice := suggester.Notice(); notice != "" {
+		cmd.PrintErrln("Warning: " + notice)
 	}
 
 	// Create an interactive model for suggestion selection
@@ -139,28 +164,38 @@ func runSuggest(cmd *cobra.Command, args []string) error {
 		// Get all suggestions marked for staging
 		groupsToCommit := selectedModel.GetStagedSuggestions()
 
-		// Process each group marked for staging
+		groups := make([]git.CommitGroup, 0, len(groupsToCommit))
 		for _, group := range groupsToCommit {
-			debug.Log("Processing group: %s\n", group.Description)
-			// Stage the files
-			for _, file := range group.Files {
-				debug.Log("Staging file: %s\n", file)
-				stageCmd := exec.Command("git", "add", file)
-				if err := stageCmd.Run(); err != nil {
-					return fmt.Errorf("failed to stage file %s: %v", file, err)
-				}
-			}
-			debug.Log("Files staged successfully.")
-
-			// Commit the changes
-			if group.Message != "" {
-				debug.Log("Committing changes with message: %s\n", group.Message)
-				commitCmd := exec.Command("git", "commit", "-m", group.Message)
-				if err := commitCmd.Run(); err != nil {
-					return fmt.Errorf("failed to commit changes: %v", err)
-				}
-				debug.Log("Changes committed successfully.")
+			groups = append(groups, git.CommitGroup{
+				Description: group.Description,
+				Files:       group.Files,
+				Message:     group.Message,
+			})
+		}
+
+		repo, err := git.NewRepository(".")
+		if err != nil {
+			return fmt.Errorf("no git repository found")
+		}
+
+		// Apply all groups as one transaction so a failure leaves nothing half-applied
+		commits, err := repo.ApplyGroupsWith(groups, commitOpts)
+		if err != nil {
+			var batchErr git.ErrBatchFailed
+			if errors.As(err, &batchErr) {
+				printBatchFailure(cmd, batchErr, len(groups))
+				return fmt.Errorf("failed to apply commit groups")
 			}
+			return fmt.Errorf("failed to apply commit groups: %w", err)
+		}
+
+		for _, commit := range commits {
+			printCommitOutput(cmd, commit.Output)
+		}
+
+		debug.Log("Applied %d commits", len(commits))
+		if len(commits) > 0 {
+			cmd.Printf("Created %d commit(s). Run 'quill undo' to revert them.\n", len(commits))
 		}
 	} else if selected := selectedModel.Selected(); selected != nil {
 		// Just show the suggested message for the selected group
@@ -174,3 +209,18 @@ func runSuggest(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// printBatchFailure reports which group failed and whether the repository was restored
+func printBatchFailure(cmd *cobra.Command, batchErr git.ErrBatchFailed, total int) {
+	cmd.PrintErrf("Group %d of %d (%s) could not be applied:\n", batchErr.Group+1, total, batchErr.Description)
+	cmd.PrintErrf("  %v\n", batchErr.Err)
+	if batchErr.RollbackErr != nil {
+		cmd.PrintErrf("Rollback failed, the repository may be partially applied:\n  %v\n", batchErr.RollbackErr)
+		return
+	}
+	if batchErr.Applied > 0 {
+		cmd.PrintErrf("Rolled back %d commit(s); HEAD and the index are back to their original state.\n", batchErr.Applied)
+	} else {
+		cmd.PrintErrf("No commits were kept; the index is back to its original state.\n")
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert the last batch of commits applied by quill",
+	Long: `Revert the last batch of commits created by 'quill suggest'.
+
+HEAD and the index are reset to where they were before the batch was applied.
+The working tree is left untouched, so none of your changes are lost; they
+simply become uncommitted again.
+
+Examples:
+  # Undo the last batch
+  quill undo
+
+  # Undo even if new commits were made on top of the batch
+  quill undo --force`,
+	Args: cobra.NoArgs,
+	RunE: runUndo,
+}
+
+func init() {
+	undoCmd.Flags().BoolP("force", "f", false, "Undo even if HEAD has moved since the batch was applied")
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	debug.Log("Starting undo command")
+
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return fmt.Errorf("failed to get force flag: %w", err)
+	}
+
+	repo, err := git.NewRepository(".")
+	if err != nil {
+		return fmt.Errorf("no git repository found")
+	}
+
+	record, err := repo.LastBatch()
+	if err != nil {
+		if errors.Is(err, git.ErrNoBatch) {
+			return fmt.Errorf("nothing to undo: no batch applied by quill was found")
+		}
+		return err
+	}
+
+	commits, err := repo.BatchCommits(record)
+	if err != nil {
+		debug.Log("Warning: failed to list batch commits: %v", err)
+	}
+
+	if _, err := repo.UndoLastBatch(force); err != nil {
+		return fmt.Errorf("failed to undo batch: %w", err)
+	}
+
+	// cmd.Print* writes to stderr unless an output is set
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Reverted %d commit(s):\n", len(commits))
+	for _, c := range commits {
+		fmt.Fprintf(out, "  %s\n", c)
+	}
+	return nil
+}
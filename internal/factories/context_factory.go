@@ -2,14 +2,20 @@
 package factories
 
 import (
+	c "context"
 	"fmt"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/jabafett/quill/internal/utils/context"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
 )
 
+// Lines of file context added to a prompt
+const maxFileContextLines = 25
+
 // ContextOptions contains configuration for the context provider
 type ContextOptions struct {
 	RepoRootPath string // Absolute path to the repository root
@@ -83,6 +89,145 @@ func (p *ContextProvider) LoadSummary() (*context.RepoSummary, error) {
 	return p.simpleContext.LoadSummary()
 }
 
+// Staleness measures how out of date the repository summary is. It returns nil when
+// there is no summary.
+func (p *ContextProvider) Staleness(repo *git.Repository, thresholds context.StalenessThresholds) (*context.Staleness, error) {
+	if !p.HasSummary() {
+		return nil, nil
+	}
+	summary, err := p.LoadSummary()
+	if err != nil {
+		return nil, err
+	}
+	return context.CheckStaleness(repo, summary, thresholds)
+}
+
+// OpenFileIndex opens the repository's per-file index, creating it if needed
+func (p *ContextProvider) OpenFileIndex() (*context.FileIndex, error) {
+	return context.OpenFileIndex(p.options.RepoRootPath)
+}
+
+// FileContext returns index summaries of the given files and of the files that
+// import them. It returns nothing when the index has not been built or is in use.
+func (p *ContextProvider) FileContext(paths []string) []string {
+	index := p.existingFileIndex()
+	if index == nil {
+		return nil
+	}
+	defer index.Close()
+
+	lines, err := index.Context(paths, maxFileContextLines)
+	if err != nil {
+		debug.Log("Warning: Failed to read file context: %v", err)
+		return nil
+	}
+	debug.Log("Added %d lines of file context", len(lines))
+	return lines
+}
+
+// Impact summarizes which packages import the changed files and which files use the
+// changed dependencies. It relies on the imports recorded in the file index and
+// returns nothing when the index has not been built.
+func (p *ContextProvider) Impact(repo *git.Repository, files []string, dependencies []context.DependencyChange) []string {
+	index := p.existingFileIndex()
+	if index == nil {
+		return nil
+	}
+	defer index.Close()
+
+	graph, err := context.BuildImportGraph(repo, index)
+	if err != nil {
+		debug.Log("Warning: Failed to build import graph: %v", err)
+		return nil
+	}
+	lines := graph.Impact(files, dependencies, 1).Summarize()
+	debug.Log("Added %d lines of impact analysis", len(lines))
+	return lines
+}
+
+// Packages describes the workspace packages the files belong to, with the scope to
+// use for each and its summary, and the imports coupling them. It returns nothing
+// outside monorepos.
+func (p *ContextProvider) Packages(repo *git.Repository, files []string) []string {
+	workspace, err := context.RepoWorkspace(repo)
+	if err != nil {
+		debug.Log("Warning: Failed to detect workspace: %v", err)
+		return nil
+	}
+	if workspace == nil {
+		return nil
+	}
+	touched := workspace.Touched(files)
+
+	var summary *context.RepoSummary
+	if p.HasSummary() {
+		if summary, err = p.LoadSummary(); err != nil {
+			debug.Log("Warning: Failed to load repository summary: %v", err)
+		}
+	}
+
+	var lines []string
+	for _, pkg := range touched {
+		line := fmt.Sprintf("%s (%s, scope: %s)", pkg.Name, pkg.Dir, pkg.Scope)
+		if summary != nil {
+			if described := summary.Package(pkg.Dir); described != nil && described.Description != "" {
+				line += ": " + strings.Join(strings.Fields(described.Description), " ")
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	// Imports between the touched packages, as recorded by the file index
+	if len(touched) > 1 {
+		if index := p.existingFileIndex(); index != nil {
+			defer index.Close()
+			manifest, err := index.Manifest()
+			if err != nil {
+				debug.Log("Warning: Failed to read file index: %v", err)
+			} else if manifest != nil {
+				for _, coupling := range workspace.Couplings(files, manifest.Deps) {
+					lines = append(lines, coupling.String())
+				}
+			}
+		}
+	}
+	debug.Log("Added %d lines of package context", len(lines))
+	return lines
+}
+
+// RelatedCode returns the code most similar to each changed hunk, taken from the
+// embeddings index and excluding the changed files themselves. It returns nothing
+// when 'quill index' has not built embeddings with the embedder's model.
+func (p *ContextProvider) RelatedCode(ctx c.Context, embedder Embedder, diff *git.Diff, perHunk, budget int) []string {
+	index := p.existingFileIndex()
+	if index == nil {
+		return nil
+	}
+	defer index.Close()
+
+	blocks, err := index.Related(ctx, embedder.Model(), embedder.Embed, context.HunkQueries(diff), diff.Paths(), perHunk, budget)
+	if err != nil {
+		debug.Log("Warning: Failed to retrieve related code: %v", err)
+		return nil
+	}
+	return blocks
+}
+
+// existingFileIndex opens the file index if 'quill index' has built one
+func (p *ContextProvider) existingFileIndex() *context.FileIndex {
+	if !context.FileIndexExists(p.options.RepoRootPath) {
+		debug.Log("No file index available. Run 'quill index' to add file context.")
+		return nil
+	}
+
+	index, err := p.OpenFileIndex()
+	if err != nil {
+		debug.Log("Warning: Failed to open file index: %v", err)
+		return nil
+	}
+	return index
+}
+
 // GetRepoDescription generates a description of the repository
 func (p *ContextProvider) GetRepoDescription() (string, error) {
 	// Get repository name
@@ -2,12 +2,14 @@ package factories
 
 import (
         "context"
+        "errors"
         "fmt"
+        "math/rand/v2"
         "time"
 
         "github.com/jabafett/quill/internal/utils/ai"
         "github.com/jabafett/quill/internal/utils/config"
-        "golang.org/x/time/rate"
+        "github.com/jabafett/quill/internal/utils/debug"
 )
 
 // Provider defines the interface for AI providers
@@ -19,6 +21,25 @@ type Provider interface {
 type rateLimitedProvider struct {
         base          Provider
         enableRetries bool
+        retry         RetryPolicy
+        limiter       *providerLimiter
+}
+
+// RetryPolicy controls how failed provider requests are retried
+type RetryPolicy struct {
+        MaxAttempts    int           // Total attempts, including the first one
+        BaseDelay      time.Duration // Delay before the first retry, doubled on each attempt
+        MaxDelay       time.Duration // Upper bound for a single backoff delay
+        AttemptTimeout time.Duration // Deadline for a single attempt, 0 for none
+}
+
+// DefaultRetryPolicy returns the policy used when the configuration does not override it
+func DefaultRetryPolicy() RetryPolicy {
+        return RetryPolicy{
+                MaxAttempts: 3,
+                BaseDelay:   time.Second,
+                MaxDelay:    30 * time.Second,
+        }
 }
 
 // ProviderOptions contains options for provider factories
@@ -28,20 +49,34 @@ type ProviderOptions struct {
         Temperature float32
         StagedOnly  bool // Only consider staged changes (for suggest command)
         UnstagedOnly bool // Only consider unstaged changes (for suggest command)
+        Signoff     bool     // Append a Signed-off-by trailer
+        CoAuthors   []string // Append Co-authored-by trailers, "Name <email>"
+        Amend       bool     // Describe HEAD plus staged changes (for generate --amend)
+        Judge       string   // Override the judge mode: "off", "rules" or "model" (for generate)
 }
 
 func (p *rateLimitedProvider) Generate(ctx context.Context, prompt string, opts ai.GenerateOptions) ([]string, error) {
         if p.enableRetries {
                 var result []string
-                err := retryWithBackoff(ctx, func() error {
+                err := retryWithBackoff(ctx, p.retry, func(ctx context.Context) error {
                         var genErr error
-                        result, genErr = generateSingleInstance(ctx, p.base, prompt, opts)
+                        result, genErr = p.attempt(ctx, prompt, opts)
                         return genErr
                 })
                 return result, err
         }
 
-        return generateSingleInstance(ctx, p.base, prompt, opts)
+        return p.attempt(ctx, prompt, opts)
+}
+
+// attempt makes a single rate limited request, bounded by the per-attempt timeout
+func (p *rateLimitedProvider) attempt(ctx context.Context, prompt string, opts ai.GenerateOptions) ([]string, error) {
+        if p.retry.AttemptTimeout > 0 {
+                var cancel context.CancelFunc
+                ctx, cancel = context.WithTimeout(ctx, p.retry.AttemptTimeout)
+                defer cancel()
+        }
+        return generateLimited(ctx, p.limiter, p.base, prompt, opts)
 }
 
 // CreateProvider creates a new AI provider instance
@@ -51,6 +86,9 @@ func NewProvider(cfg *config.Config, opts ProviderOptions) (Provider, error) {
         }
 
         name := cfg.Core.DefaultProvider
+        if opts.Provider != "" {
+                name = opts.Provider
+        }
 
         options, err := config.ConfigToOptions(cfg, name)
         if err != nil {
@@ -63,9 +101,6 @@ func NewProvider(cfg *config.Config, opts ProviderOptions) (Provider, error) {
         if opts.Temperature > 0 {
                 options.Temperature = opts.Temperature
         }
-        if opts.Provider != "" {
-                name = opts.Provider
-        }
 
         var baseProvider Provider
         switch name {
@@ -85,32 +120,174 @@ func NewProvider(cfg *config.Config, opts ProviderOptions) (Provider, error) {
                 return nil, err
         }
 
+        retry := DefaultRetryPolicy()
+        if cfg.Core.RetryAttempts > 0 {
+                retry.MaxAttempts = cfg.Core.RetryAttempts
+        }
+        if cfg.Core.RequestTimeout > 0 {
+                retry.AttemptTimeout = cfg.Core.RequestTimeout
+        }
+
         return &rateLimitedProvider{
                 base:          baseProvider,
                 enableRetries: options.EnableRetries,
+                retry:         retry,
+                limiter:       limiterFor(name, options.Model, providerRateLimits(name, cfg.Providers[name])),
         }, nil
 }
 
-const (
-        maxRetries = 3
-        rateLimit  = time.Second // 1 request per second
-)
+// Embedder defines the interface for providers that compute embeddings
+type Embedder interface {
+        Embed(ctx context.Context, texts []string) ([][]float32, error)
+        Model() string // Provider and model, e.g. "ollama/nomic-embed-text"
+}
 
-var (
-        // Global rate limiter shared across all providers
-        limiter = rate.NewLimiter(rate.Every(rateLimit), 1)
-)
+// embeddingProvider is implemented by the AI providers that offer embeddings
+type embeddingProvider interface {
+        Embed(ctx context.Context, texts []string) ([][]float32, error)
+        EmbeddingModel() string
+}
+
+// rateLimitedEmbedder wraps a base embedder with rate limiting and retry logic
+type rateLimitedEmbedder struct {
+        base          embeddingProvider
+        model         string
+        enableRetries bool
+        retry         RetryPolicy
+        limiter       *providerLimiter
+}
+
+func (e *rateLimitedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+        embed := func(ctx context.Context) ([][]float32, error) {
+                if e.retry.AttemptTimeout > 0 {
+                        var cancel context.CancelFunc
+                        ctx, cancel = context.WithTimeout(ctx, e.retry.AttemptTimeout)
+                        defer cancel()
+                }
+                tokens := 0
+                for _, text := range texts {
+                        tokens += (len(text) + 3) / 4
+                }
+                release, err := e.limiter.acquire(ctx, 1, tokens)
+                if err != nil {
+                        return nil, err
+                }
+                defer release()
+                return e.base.Embed(ctx, texts)
+        }
+
+        if !e.enableRetries {
+                return embed(ctx)
+        }
+        var vectors [][]float32
+        err := retryWithBackoff(ctx, e.retry, func(ctx context.Context) error {
+                var embedErr error
+                vectors, embedErr = embed(ctx)
+                return embedErr
+        })
+        return vectors, err
+}
+
+// Model names the embedding model, so stored vectors can be checked against it
+func (e *rateLimitedEmbedder) Model() string {
+        return e.model
+}
+
+// NewEmbedder creates the embedder configured for the embeddings index: the
+// index.embeddings_provider, or else the default provider
+func NewEmbedder(cfg *config.Config) (Embedder, error) {
+        if cfg == nil {
+                return nil, fmt.Errorf("config cannot be nil")
+        }
+
+        name := cfg.Index.EmbeddingsProvider
+        if name == "" {
+                name = cfg.Core.DefaultProvider
+        }
+
+        options, err := config.ConfigToOptions(cfg, name)
+        if err != nil {
+                return nil, err
+        }
+
+        var base embeddingProvider
+        switch name {
+        case "gemini":
+                base, err = ai.NewGeminiProvider(options)
+        case "openai":
+                base, err = ai.NewOpenAIProvider(options)
+        case "ollama":
+                base, err = ai.NewOllamaProvider(options)
+        case "anthropic":
+                return nil, fmt.Errorf("anthropic does not offer embeddings, set index.embeddings_provider to another provider")
+        default:
+                return nil, fmt.Errorf("unknown provider: %s", name)
+        }
+
+        if err != nil {
+                return nil, err
+        }
+
+        retry := DefaultRetryPolicy()
+        if cfg.Core.RetryAttempts > 0 {
+                retry.MaxAttempts = cfg.Core.RetryAttempts
+        }
+        if cfg.Core.RequestTimeout > 0 {
+                retry.AttemptTimeout = cfg.Core.RequestTimeout
+        }
+
+        model := base.EmbeddingModel()
+        return &rateLimitedEmbedder{
+                base:          base,
+                model:         name + "/" + model,
+                enableRetries: options.EnableRetries,
+                retry:         retry,
+                limiter:       limiterFor(name, model, providerRateLimits(name, cfg.Providers[name])),
+        }, nil
+}
+
+// providerRateLimits merges a provider's configured limits over its defaults
+func providerRateLimits(name string, provider config.AIProvider) RateLimits {
+        limits := DefaultRateLimits(name)
+        if provider.RequestsPerMinute > 0 {
+                limits.RequestsPerMinute = provider.RequestsPerMinute
+        }
+        if provider.TokensPerMinute > 0 {
+                limits.TokensPerMinute = provider.TokensPerMinute
+        }
+        if provider.MaxConcurrency > 0 {
+                limits.MaxConcurrency = provider.MaxConcurrency
+        }
+        return limits
+}
+
+// retryWithBackoff retries transient failures with jittered exponential backoff.
+// Errors that cannot succeed on retry (bad keys, oversized prompts, ...) are returned immediately.
+func retryWithBackoff(ctx context.Context, policy RetryPolicy, fn func(context.Context) error) error {
+        attempts := policy.MaxAttempts
+        if attempts < 1 {
+                attempts = 1
+        }
 
-// retryWithBackoff implements exponential backoff retry logic
-func retryWithBackoff(ctx context.Context, fn func() error) error {
         var err error
-        for attempt := 0; attempt < maxRetries; attempt++ {
-                if err = fn(); err == nil {
+        for attempt := 0; attempt < attempts; attempt++ {
+                if err = fn(ctx); err == nil {
                         return nil
                 }
 
-                // Calculate backoff duration: 2^attempt * base duration
-                backoff := time.Duration(1<<uint(attempt)) * time.Second
+                if ctx.Err() != nil {
+                        return err
+                }
+                if !ai.IsRetryable(err) {
+                        debug.Log("Not retrying %s error: %v", ai.Classify(err), err)
+                        return err
+                }
+                if attempt == attempts-1 {
+                        break
+                }
+
+                backoff := policy.backoff(attempt, err)
+                debug.Log("Attempt %d/%d failed (%s), retrying in %s: %v", attempt+1, attempts, ai.Classify(err), backoff, err)
 
                 select {
                 case <-ctx.Done():
@@ -122,11 +299,32 @@ func retryWithBackoff(ctx context.Context, fn func() error) error {
         return fmt.Errorf("max retries exceeded: %w", err)
 }
 
-// generateSingleInstance ensures only one request is made at a time
-func generateSingleInstance(ctx context.Context, provider Provider, prompt string, opts ai.GenerateOptions) ([]string, error) {
-        if err := limiter.Wait(ctx); err != nil {
-                return nil, fmt.Errorf("rate limit wait failed: %w", err)
+// backoff returns the delay before the next attempt. A server-requested delay wins,
+// otherwise the delay doubles each attempt with up to half of it randomized.
+func (p RetryPolicy) backoff(attempt int, err error) time.Duration {
+        var delayer ai.RetryDelayer
+        if errors.As(err, &delayer) && delayer.RetryDelay() > 0 {
+                return delayer.RetryDelay()
+        }
+
+        delay := p.BaseDelay << uint(attempt)
+        if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+                delay = p.MaxDelay
+        }
+        if delay <= 1 {
+                return delay
+        }
+        half := delay / 2
+        return half + time.Duration(rand.Int64N(int64(half)))
+}
+
+// generateLimited waits for the provider's rate limits, counting every request the call sends
+func generateLimited(ctx context.Context, limiter *providerLimiter, provider Provider, prompt string, opts ai.GenerateOptions) ([]string, error) {
+        release, err := limiter.acquire(ctx, RequestsPerCall(provider, opts), EstimateTokens(prompt, opts))
+        if err != nil {
+                return nil, err
         }
+        defer release()
         return provider.Generate(ctx, prompt, opts)
 }
 
@@ -136,5 +334,7 @@ func GetRateLimitedProvider(base Provider, enableRetries bool) *rateLimitedProvi
         return &rateLimitedProvider{
                 base:          base,
                 enableRetries: enableRetries,
+                retry:         DefaultRetryPolicy(),
+                limiter:       newProviderLimiter(RateLimits{RequestsPerMinute: defaultRequestsPerMinute}),
         }
 }
@@ -0,0 +1,145 @@
+package factories
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jabafett/quill/internal/utils/ai"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"golang.org/x/time/rate"
+)
+
+// defaultRequestsPerMinute applies to hosted providers that do not configure a limit
+const defaultRequestsPerMinute = 60
+
+// RateLimits describes the throughput allowed for one provider and model. Zero values
+// leave the corresponding dimension unlimited.
+type RateLimits struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+	MaxConcurrency    int
+}
+
+// DefaultRateLimits returns the limits used for a provider without explicit settings.
+// Local Ollama servers are not throttled, hosted APIs get one request per second.
+func DefaultRateLimits(provider string) RateLimits {
+	if provider == "ollama" {
+		return RateLimits{}
+	}
+	return RateLimits{RequestsPerMinute: defaultRequestsPerMinute}
+}
+
+// providerLimiter enforces RateLimits for a single provider and model
+type providerLimiter struct {
+	requests *rate.Limiter
+	tokens   *rate.Limiter
+	slots    chan struct{}
+}
+
+var (
+	limitersMu sync.Mutex
+	// Limiters keyed by provider and model, shared by every provider instance
+	limiters = map[string]*providerLimiter{}
+)
+
+// limiterFor returns the shared limiter for a provider and model, creating it on first use
+func limiterFor(provider, model string, limits RateLimits) *providerLimiter {
+	key := provider + "/" + model
+
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	if l, ok := limiters[key]; ok {
+		return l
+	}
+
+	l := newProviderLimiter(limits)
+	limiters[key] = l
+	debug.Log("Rate limits for %s: rpm=%d tpm=%d concurrency=%d",
+		key, limits.RequestsPerMinute, limits.TokensPerMinute, limits.MaxConcurrency)
+	return l
+}
+
+func newProviderLimiter(limits RateLimits) *providerLimiter {
+	l := &providerLimiter{}
+
+	if limits.RequestsPerMinute > 0 {
+		// Allow as many requests in a burst as may run concurrently
+		burst := max(limits.MaxConcurrency, 1)
+		l.requests = rate.NewLimiter(rate.Limit(float64(limits.RequestsPerMinute)/60), burst)
+	}
+	if limits.TokensPerMinute > 0 {
+		l.tokens = rate.NewLimiter(rate.Limit(float64(limits.TokensPerMinute)/60), limits.TokensPerMinute)
+	}
+	if limits.MaxConcurrency > 0 {
+		l.slots = make(chan struct{}, limits.MaxConcurrency)
+	}
+
+	return l
+}
+
+// acquire blocks until a call sending the given number of requests, each costing
+// about the given number of tokens, may start. The returned function must be called
+// once the call has finished.
+func (l *providerLimiter) acquire(ctx context.Context, requests, tokens int) (func(), error) {
+	requests = max(requests, 1)
+
+	// Requests a provider sends in parallel each take a slot, up to all of them
+	held := 0
+	release := func() {
+		for ; held > 0; held-- {
+			<-l.slots
+		}
+	}
+	if l.slots != nil {
+		for held < min(requests, cap(l.slots)) {
+			select {
+			case l.slots <- struct{}{}:
+				held++
+			case <-ctx.Done():
+				release()
+				return nil, fmt.Errorf("rate limit wait failed: %w", ctx.Err())
+			}
+		}
+	}
+
+	if l.requests != nil {
+		// One at a time, as the burst may be smaller than the number of requests
+		for i := 0; i < requests; i++ {
+			if err := l.requests.Wait(ctx); err != nil {
+				release()
+				return nil, fmt.Errorf("rate limit wait failed: %w", err)
+			}
+		}
+	}
+
+	if total := tokens * requests; l.tokens != nil && total > 0 {
+		// A call larger than a minute's budget waits for the full budget instead of failing
+		if total > l.tokens.Burst() {
+			total = l.tokens.Burst()
+		}
+		if err := l.tokens.WaitN(ctx, total); err != nil {
+			release()
+			return nil, fmt.Errorf("token limit wait failed: %w", err)
+		}
+	}
+
+	return release, nil
+}
+
+// EstimateTokens roughly estimates the prompt tokens of a single request, assuming
+// about four characters per token
+func EstimateTokens(prompt string, opts ai.GenerateOptions) int {
+	return (len(prompt) + len(opts.System) + 3) / 4
+}
+
+// RequestsPerCall returns how many requests a Generate call sends. Providers that fan
+// out one request per candidate report it and pay the prompt once per candidate;
+// the others send every candidate in one request.
+func RequestsPerCall(provider Provider, opts ai.GenerateOptions) int {
+	if counter, ok := provider.(ai.RequestCounter); ok {
+		return max(counter.Requests(opts), 1)
+	}
+	return 1
+}
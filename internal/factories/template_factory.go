@@ -3,6 +3,7 @@ package factories
 import (
         "bytes"
         "fmt"
+        "strings"
         "text/template"
 
         "github.com/jabafett/quill/internal/utils/templates"
@@ -14,17 +15,48 @@ type TemplateType string
 const (
         CommitMessageType TemplateType = "CommitMessage"
         SuggestionType    TemplateType = "Suggestion"
+        FileSummaryType   TemplateType = "FileSummary"
+        ContextExtractionType TemplateType = "ContextExtraction"
+        JudgeType         TemplateType = "Judge"
+        RepairType        TemplateType = "Repair"
+        BranchType        TemplateType = "Branch"
+        ExplainType       TemplateType = "Explain"
+        ReviewType        TemplateType = "Review"
 )
 
+// templateFuncs are the helpers available to every template
+var templateFuncs = template.FuncMap{
+        "join": strings.Join,
+        "inc":  func(i int) int { return i + 1 },
+}
+
 // TemplateFactory manages template creation and rendering
 type TemplateFactory struct {
-        templates map[TemplateType]*template.Template
+        templates  map[TemplateType]*template.Template
+        system     map[TemplateType]string
+        structured map[TemplateType]string // System prompts for JSON responses
 }
 
 // NewTemplateFactory creates a new template factory instance
 func NewTemplateFactory() (*TemplateFactory, error) {
         factory := &TemplateFactory{
                 templates: make(map[TemplateType]*template.Template),
+                system: map[TemplateType]string{
+                        CommitMessageType: templates.CommitMessageSystemPrompt,
+                        SuggestionType:    templates.SuggestSystemPrompt,
+                        FileSummaryType:   templates.FileSummarySystemPrompt,
+                        ContextExtractionType: templates.ContextExtractionSystemPrompt,
+                        JudgeType:         templates.JudgeSystemPrompt,
+                        RepairType:        templates.RepairSystemPrompt,
+                        BranchType:        templates.BranchSystemPrompt,
+                        ExplainType:       templates.ExplainSystemPrompt,
+                        ReviewType:        templates.ReviewSystemPrompt,
+                },
+                structured: map[TemplateType]string{
+                        CommitMessageType: templates.CommitMessageJSONSystemPrompt,
+                        SuggestionType:    templates.SuggestJSONSystemPrompt,
+                        BranchType:        templates.BranchJSONSystemPrompt,
+                },
         }
 
         // Initialize templates
@@ -40,10 +72,17 @@ func (f *TemplateFactory) initializeTemplates() error {
         templateMap := map[TemplateType]string{
                 CommitMessageType: templates.CommitMessageTemplate,
                 SuggestionType:    templates.SuggestTemplate,
+                FileSummaryType:   templates.FileSummaryTemplate,
+                ContextExtractionType: templates.ContextExtractionTemplate,
+                JudgeType:         templates.JudgeTemplate,
+                RepairType:        templates.RepairTemplate,
+                BranchType:        templates.BranchTemplate,
+                ExplainType:       templates.ExplainTemplate,
+                ReviewType:        templates.ReviewTemplate,
         }
 
         for typ, content := range templateMap {
-                tmpl, err := template.New(string(typ)).Option("missingkey=error").Parse(content)
+                tmpl, err := template.New(string(typ)).Option("missingkey=error").Funcs(templateFuncs).Parse(content)
                 if err != nil {
                         return fmt.Errorf("failed to parse template %s: %w", typ, err)
                 }
@@ -57,10 +96,16 @@ func ValidateTemplates() error {
         templates := map[string]string{
                 "CommitMessage": templates.CommitMessageTemplate,
                 "Suggest":       templates.SuggestTemplate,
+                "FileSummary":   templates.FileSummaryTemplate,
+                "ContextExtraction": templates.ContextExtractionTemplate,
+                "Judge":         templates.JudgeTemplate,
+                "Repair":        templates.RepairTemplate,
+                "Branch":        templates.BranchTemplate,
+                "Explain":       templates.ExplainTemplate,
         }
 
         for name, content := range templates {
-                if _, err := template.New(name).Parse(content); err != nil {
+                if _, err := template.New(name).Funcs(templateFuncs).Parse(content); err != nil {
                         return err
                 }
         }
@@ -82,3 +127,17 @@ func (f *TemplateFactory) Generate(typ TemplateType, data interface{}) (string,
 
         return buf.String(), nil
 }
+
+// System returns the static instructions for the specified template type
+func (f *TemplateFactory) System(typ TemplateType) string {
+        return f.system[typ]
+}
+
+// StructuredSystem returns the static instructions for the specified template type
+// when the response is a JSON object, or System's when the type has no JSON variant
+func (f *TemplateFactory) StructuredSystem(typ TemplateType) string {
+        if system, ok := f.structured[typ]; ok {
+                return system
+        }
+        return f.system[typ]
+}
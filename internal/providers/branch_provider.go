@@ -0,0 +1,317 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jabafett/quill/internal/factories"
+	"github.com/jabafett/quill/internal/utils/ai"
+	"github.com/jabafett/quill/internal/utils/config"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/jabafett/quill/internal/utils/helpers"
+)
+
+// branchDiffBudget caps the diff sent for branch naming, in bytes. A name needs
+// the gist of the work, so files past the budget are only listed.
+const branchDiffBudget = 16000
+
+// maxCollisionSuffix is the highest number tried after a name that is taken
+const maxCollisionSuffix = 9
+
+// BranchFactory handles proposing branch names for uncommitted work
+type BranchFactory struct {
+	config    *config.Config
+	repo      *git.Repository
+	templates *factories.TemplateFactory
+	provider  factories.Provider
+	count     int
+}
+
+// BranchCandidate is a proposed branch name that is valid and not taken
+type BranchCandidate struct {
+	Name string
+	Note string // How the name was changed from the proposal, e.g. to avoid a collision
+}
+
+// NewBranchFactory creates a new factory specifically for the branch command
+func NewBranchFactory(opts factories.ProviderOptions) (*BranchFactory, error) {
+	var (
+		cfg       *config.Config
+		repo      *git.Repository
+		templates *factories.TemplateFactory
+		errChan   = make(chan error, 3)
+		wg        sync.WaitGroup
+	)
+
+	debug.Log("Starting branch factory")
+
+	// Load components concurrently
+	wg.Add(3)
+
+	// Load configuration
+	go func() {
+		defer wg.Done()
+		var err error
+		cfg, err = config.LoadConfig()
+		if err != nil {
+			errChan <- fmt.Errorf("failed to load config: %w", err)
+		}
+		debug.Log("Finished loading configuration")
+	}()
+
+	// Initialize git object
+	go func() {
+		defer wg.Done()
+		var err error
+		repo, err = git.NewRepository(".")
+		if err != nil {
+			errChan <- err
+		}
+		debug.Log("Finished initializing git repository")
+	}()
+
+	// Initialize template factory
+	go func() {
+		defer wg.Done()
+		var err error
+		templates, err = factories.NewTemplateFactory()
+		if err != nil {
+			errChan <- fmt.Errorf("failed to create template factory: %w", err)
+		}
+		debug.Log("Finished initializing template factory")
+	}()
+
+	wg.Wait()
+	close(errChan)
+
+	// Check for any errors
+	for err := range errChan {
+		return nil, err
+	}
+
+	provider, err := factories.NewProvider(cfg, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+
+	debug.Log("Finished creating provider")
+
+	count := opts.Candidates
+	if count <= 0 {
+		count = 3
+	}
+
+	return &BranchFactory{
+		config:    cfg,
+		repo:      repo,
+		templates: templates,
+		provider:  provider,
+		count:     count,
+	}, nil
+}
+
+// Repository returns the repository the names are proposed for
+func (f *BranchFactory) Repository() *git.Repository {
+	return f.repo
+}
+
+// Propose asks the model for branch names for a task description, the uncommitted
+// changes, or both, and formats them with the configured pattern. The ticket
+// defaults to an issue reference leading the description. Names that are not
+// valid branch names are dropped, and a name that is taken gets a number appended.
+func (f *BranchFactory) Propose(ctx context.Context, description, ticket string) ([]BranchCandidate, error) {
+	description = strings.TrimSpace(description)
+	if ticket == "" && description != "" {
+		ref, err := git.IssueRefFromDescription(description, f.config.Trailers.IssuePattern)
+		if err != nil {
+			return nil, err
+		}
+		ticket = strings.TrimPrefix(ref, "#")
+	}
+
+	files, diff, err := f.changes()
+	if err != nil {
+		return nil, err
+	}
+	if description == "" && len(files) == 0 {
+		return nil, helpers.ErrNoChanges{}
+	}
+
+	// An unborn HEAD has no branch to start from
+	var branch string
+	if head, err := f.repo.HeadCommit(); err == nil && head != "" {
+		if branch, err = f.repo.GetCurrentBranch(); err != nil {
+			debug.Log("Warning: Failed to get current branch: %v", err)
+		}
+	}
+
+	structured := f.config.Core.StructuredOutput
+	prompt, err := f.templates.Generate(factories.BranchType, map[string]any{
+		"Description": description,
+		"Branch":      branch,
+		"Files":       files,
+		"Diff":        diff,
+		"Count":       f.count,
+		"Structured":  structured,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate branch prompt: %w", err)
+	}
+
+	opts := ai.GenerateOptions{
+		MaxCandidates: 1,
+		System:        systemPrompt(f.templates, factories.BranchType, structured),
+	}
+	if structured {
+		opts.Schema = helpers.BranchSchema
+	}
+	if temp := f.config.Providers[f.config.Core.DefaultProvider].Temperature; temp > 0 {
+		opts.Temperature = &temp
+	}
+
+	debug.Dump("Prompt:", prompt)
+	responses, err := f.provider.Generate(ctx, prompt, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate branch names: %w", err)
+	}
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no branch names generated")
+	}
+	debug.Dump("AI Responses:", responses)
+
+	var proposals []helpers.BranchObject
+	if structured {
+		proposals = branchObjects(ctx, f.provider, f.templates, prompt, responses[0])
+	} else {
+		proposals = helpers.ParseBranchResponse(responses[0])
+	}
+
+	candidates, err := f.candidates(proposals, ticket)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no valid branch names were proposed")
+	}
+	return candidates, nil
+}
+
+// changes returns the changed and untracked files with their diff, cut to
+// branchDiffBudget. Noisy files are listed but left out of the diff.
+func (f *BranchFactory) changes() ([]string, string, error) {
+	filter, err := f.repo.NoiseFilter()
+	if err != nil {
+		debug.Log("Warning: Failed to load noise filter: %v", err)
+	}
+
+	combined := &git.Diff{}
+	for _, read := range []func() (*git.Diff, error){f.repo.StagedDiff, f.repo.UnstagedDiff} {
+		diff, err := read()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get changes: %w", err)
+		}
+		combined.Files = append(combined.Files, diff.Files...)
+	}
+	files := combined.Paths()
+
+	untracked, err := f.repo.GetUntrackedFiles()
+	if err != nil {
+		debug.Log("Warning: Failed to get untracked files: %v", err)
+	}
+	files = append(files, untracked...)
+	slices.Sort(files)
+	files = slices.Compact(files)
+
+	kept, _ := splitNoise(filter, combined)
+	diff, _ := diffWithin(kept, branchDiffBudget)
+	return files, diff, nil
+}
+
+// candidates formats proposals into valid, unique and untaken branch names, at
+// most f.count of them
+func (f *BranchFactory) candidates(proposals []helpers.BranchObject, ticket string) ([]BranchCandidate, error) {
+	seen := make(map[string]bool)
+	var candidates []BranchCandidate
+	for _, p := range proposals {
+		if len(candidates) == f.count {
+			break
+		}
+		name, err := git.FormatBranchName(f.config.Branch.Pattern, git.BranchFields{
+			Type:   git.Slugify(p.Type, 0),
+			Ticket: ticket,
+			Slug:   git.Slugify(p.Slug, f.config.Branch.MaxSlugLength),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := git.ValidateBranchName(name); err != nil {
+			debug.Log("Warning: Dropping proposed branch: %v", err)
+			continue
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		candidate, err := f.untaken(name)
+		if err != nil {
+			return nil, err
+		}
+		if candidate.Name == "" || (candidate.Name != name && seen[candidate.Name]) {
+			continue
+		}
+		seen[candidate.Name] = true
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// untaken returns name, or name with a number appended when an existing branch
+// collides with it. The name is empty when every number up to
+// maxCollisionSuffix is taken too.
+func (f *BranchFactory) untaken(name string) (BranchCandidate, error) {
+	collision, err := f.repo.BranchCollision(name)
+	if err != nil || collision == "" {
+		return BranchCandidate{Name: name}, err
+	}
+	for i := 2; i <= maxCollisionSuffix; i++ {
+		numbered := fmt.Sprintf("%s-%d", name, i)
+		taken, err := f.repo.BranchCollision(numbered)
+		if err != nil {
+			return BranchCandidate{}, err
+		}
+		if taken == "" {
+			return BranchCandidate{Name: numbered, Note: fmt.Sprintf("%s collides with %s", name, collision)}, nil
+		}
+	}
+	debug.Log("Warning: Dropping proposed branch %s, every numbered variant is taken", name)
+	return BranchCandidate{}, nil
+}
+
+// branchObjects reads a structured branch name response, asking once for a repair
+// when it fails validation. A response that is not JSON at all goes through
+// ParseBranchResponse.
+func branchObjects(ctx context.Context, provider factories.Provider, templates *factories.TemplateFactory, prompt, response string) []helpers.BranchObject {
+	branches, err := helpers.ParseBranchObject(response)
+	if err == nil {
+		return branches
+	}
+	if !strings.HasPrefix(strings.TrimSpace(response), "{") {
+		return helpers.ParseBranchResponse(response)
+	}
+
+	repaired, err := repairResponse(ctx, provider, templates, prompt, response, err, helpers.BranchSchema)
+	if err != nil {
+		debug.Log("Warning: Repair request failed: %v", err)
+		return nil
+	}
+	branches, err = helpers.ParseBranchObject(repaired)
+	if err != nil {
+		debug.Log("Warning: Repaired branch names still failed validation: %v", err)
+	}
+	return branches
+}
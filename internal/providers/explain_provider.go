@@ -0,0 +1,294 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jabafett/quill/internal/factories"
+	"github.com/jabafett/quill/internal/utils/ai"
+	"github.com/jabafett/quill/internal/utils/config"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/jabafett/quill/internal/utils/helpers"
+)
+
+const (
+	// explainDiffBudget caps the diff sent for an explanation, in bytes; the files
+	// past it are still listed
+	explainDiffBudget = 60000
+	// maxExplainCommits is how many commit messages of a range are sent, newest first
+	maxExplainCommits = 30
+)
+
+var (
+	// Trailer keys, lowercased, that link a commit to an issue or pull request
+	referenceTrailers = map[string]bool{"refs": true, "fixes": true, "closes": true, "resolves": true, "see-also": true}
+	// Pull request numbers GitHub appends to squash-merged subjects, e.g. "(#123)"
+	pullRequestRef = regexp.MustCompile(`\(#([0-9]+)\)`)
+)
+
+// ExplainFactory handles explaining commits, ranges and staged changes
+type ExplainFactory struct {
+	config          *config.Config
+	repo            *git.Repository
+	templates       *factories.TemplateFactory
+	provider        factories.Provider
+	contextProvider *factories.ContextProvider
+	notice          string // Set when the repository summary is out of date
+}
+
+// NewExplainFactory creates a new factory specifically for the explain command
+func NewExplainFactory(opts factories.ProviderOptions) (*ExplainFactory, error) {
+	var (
+		cfg       *config.Config
+		repo      *git.Repository
+		templates *factories.TemplateFactory
+		errChan   = make(chan error, 3)
+		wg        sync.WaitGroup
+	)
+
+	debug.Log("Starting explain factory")
+
+	// Load components concurrently
+	wg.Add(3)
+
+	// Load configuration
+	go func() {
+		defer wg.Done()
+		var err error
+		cfg, err = config.LoadConfig()
+		if err != nil {
+			errChan <- fmt.Errorf("failed to load config: %w", err)
+		}
+		debug.Log("Finished loading configuration")
+	}()
+
+	// Initialize git object
+	go func() {
+		defer wg.Done()
+		var err error
+		repo, err = git.NewRepository(".")
+		if err != nil {
+			errChan <- err
+		}
+		debug.Log("Finished initializing git repository")
+	}()
+
+	// Initialize template factory
+	go func() {
+		defer wg.Done()
+		var err error
+		templates, err = factories.NewTemplateFactory()
+		if err != nil {
+			errChan <- fmt.Errorf("failed to create template factory: %w", err)
+		}
+		debug.Log("Finished initializing template factory")
+	}()
+
+	wg.Wait()
+	close(errChan)
+
+	// Check for any errors
+	for err := range errChan {
+		return nil, err
+	}
+
+	provider, err := factories.NewProvider(cfg, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+
+	debug.Log("Finished creating provider")
+
+	// Initialize context provider
+	repoRootPath, err := repo.GetRepoRootPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repo root path: %w", err)
+	}
+
+	contextProvider, err := factories.NewContextProvider(
+		factories.WithRepoRootPath(repoRootPath),
+	)
+	if err != nil {
+		debug.Log("Warning: Failed to create context provider: %v. Proceeding without repository context.", err)
+	}
+
+	return &ExplainFactory{
+		config:          cfg,
+		repo:            repo,
+		templates:       templates,
+		provider:        provider,
+		contextProvider: contextProvider,
+	}, nil
+}
+
+// Explain explains a commit or range, or the staged changes when staged is set.
+// An empty spec explains HEAD.
+func (f *ExplainFactory) Explain(ctx context.Context, spec string, staged bool) (*helpers.Explanation, error) {
+	explanation := &helpers.Explanation{Revision: spec}
+	var (
+		diff     *git.Diff
+		versions func() (*git.FileVersions, error)
+		commits  []git.CommitInfo
+		err      error
+	)
+
+	if staged {
+		explanation.Revision = "Staged changes"
+		if diff, err = f.repo.StagedDiff(); err != nil {
+			return nil, fmt.Errorf("failed to get staged diff: %w", err)
+		}
+		if diff.IsEmpty() {
+			return nil, helpers.ErrNoStagedChanges{}
+		}
+		versions = f.repo.StagedVersions
+	} else {
+		if spec == "" {
+			spec = "HEAD"
+			explanation.Revision = spec
+		}
+		rr, err := f.repo.ResolveRange(spec)
+		if err != nil {
+			return nil, err
+		}
+		if diff, err = rr.Diff(); err != nil {
+			return nil, fmt.Errorf("failed to get the changes of %s: %w", spec, err)
+		}
+		if diff.IsEmpty() {
+			return nil, fmt.Errorf("%s changes no files", spec)
+		}
+		versions = rr.Versions
+		if commits, explanation.MoreCommits, err = rr.Commits(maxExplainCommits); err != nil {
+			return nil, err
+		}
+	}
+
+	filter, err := f.repo.NoiseFilter()
+	if err != nil {
+		debug.Log("Warning: Failed to load noise filter: %v", err)
+	}
+	explanation.Files = diff.Paths()
+	kept, noise := splitNoise(filter, diff)
+	diffText, omitted := diffWithin(kept, explainDiffBudget)
+
+	messages := make([]string, len(commits))
+	for i, c := range commits {
+		explanation.Commits = append(explanation.Commits, helpers.ExplainedCommit{
+			Hash:    c.Hash,
+			Subject: c.Subject(),
+			Author:  c.Author,
+			Date:    c.Date.Format("2006-01-02"),
+		})
+		messages[i] = fmt.Sprintf("%.7s by %s on %s\n%s", c.Hash, c.Author, c.Date.Format("2006-01-02"), c.Message)
+	}
+	explanation.References = f.references(commits)
+
+	data := map[string]any{
+		"Revision":    explanation.Revision,
+		"Context":     "",
+		"Commits":     messages,
+		"MoreCommits": explanation.MoreCommits,
+		"References":  explanation.References,
+		"Files":       explanation.Files,
+		"FileContext": []string(nil),
+		"Symbols":     changedSymbols(kept, versions),
+		"Noise":       noise,
+		"Omitted":     omitted,
+		"Diff":        diffText,
+	}
+	if f.contextProvider != nil {
+		if f.contextProvider.HasSummary() {
+			data["Context"] = f.contextProvider.GetRepoSummary()
+			f.notice = checkSummary(f.config, f.contextProvider, f.repo)
+		}
+		data["FileContext"] = f.contextProvider.FileContext(explanation.Files)
+	}
+
+	prompt, err := f.templates.Generate(factories.ExplainType, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate explain prompt: %w", err)
+	}
+
+	opts := ai.GenerateOptions{
+		MaxCandidates: 1,
+		System:        f.templates.System(factories.ExplainType),
+	}
+	if f.config.Core.StructuredOutput {
+		opts.Schema = helpers.ExplanationSchema
+	}
+	if temp := f.config.Providers[f.config.Core.DefaultProvider].Temperature; temp > 0 {
+		opts.Temperature = &temp
+	}
+
+	debug.Dump("Prompt:", prompt)
+	responses, err := f.provider.Generate(ctx, prompt, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate explanation: %w", err)
+	}
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no explanation generated")
+	}
+	debug.Dump("AI Responses:", responses)
+
+	if explanation.ExplanationObject, err = f.parse(ctx, prompt, responses[0]); err != nil {
+		return nil, err
+	}
+	return explanation, nil
+}
+
+// parse reads the explanation from a response, asking once for a repair when it
+// fails validation. A response that is not JSON at all is kept as the summary.
+func (f *ExplainFactory) parse(ctx context.Context, prompt, response string) (helpers.ExplanationObject, error) {
+	object, err := helpers.ParseExplanationObject(response)
+	if err == nil {
+		return object, nil
+	}
+	if !strings.HasPrefix(strings.TrimSpace(response), "{") {
+		return helpers.ExplanationObject{Summary: strings.TrimSpace(response)}, nil
+	}
+
+	repaired, rerr := repairResponse(ctx, f.provider, f.templates, prompt, response, err, helpers.ExplanationSchema)
+	if rerr != nil {
+		return helpers.ExplanationObject{}, fmt.Errorf("failed to read the explanation: %w", err)
+	}
+	if object, err = helpers.ParseExplanationObject(repaired); err != nil {
+		return helpers.ExplanationObject{}, fmt.Errorf("failed to read the explanation: %w", err)
+	}
+	return object, nil
+}
+
+// references collects the issues and pull requests the commits link to: reference
+// trailers such as "Fixes: #12" and squash-merge suffixes. Subjects are not searched
+// for issue keys, which would also match the likes of UTF-8 or SHA-256.
+func (f *ExplainFactory) references(commits []git.CommitInfo) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	add := func(ref string) {
+		if ref != "" && !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	issueKey := strings.ToLower(f.config.Trailers.IssueKey)
+	for _, c := range commits {
+		for _, t := range git.ParseTrailers(c.Message) {
+			if key := strings.ToLower(t.Key); referenceTrailers[key] || key == issueKey {
+				add(t.Value)
+			}
+		}
+		for _, m := range pullRequestRef.FindAllStringSubmatch(c.Subject(), -1) {
+			add("#" + m[1])
+		}
+	}
+	return refs
+}
+
+// Notice returns a message for the user about the last explanation, e.g. that the
+// repository summary it used is out of date
+func (f *ExplainFactory) Notice() string {
+	return f.notice
+}
@@ -19,7 +19,14 @@ type GenerateFactory struct {
 	repo            *git.Repository
 	templates       *factories.TemplateFactory
 	provider        factories.Provider
+	providerName    string
+	judging         *judging
 	contextProvider *factories.ContextProvider
+	trailers        git.TrailerOptions
+	amend           bool
+	notice          string   // Set when the repository summary is out of date
+	rationales      []string // Why each message of the last generation ranks where it does
+	partial         []string // Staged files of the last generation with further unstaged edits
 }
 
 // NewGenerateFactory creates a new factory specifically for the generate command
@@ -88,6 +95,15 @@ func NewGenerateFactory(opts factories.ProviderOptions) (*GenerateFactory, error
 
 	debug.Log("Finished creating provider")
 
+	providerName := cfg.Core.DefaultProvider
+	if opts.Provider != "" {
+		providerName = opts.Provider
+	}
+	judging, err := newJudging(cfg, opts, providerName)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize context provider
 	repoRootPath, err := repo.GetRepoRootPath()
 	if err != nil {
@@ -106,7 +122,11 @@ func NewGenerateFactory(opts factories.ProviderOptions) (*GenerateFactory, error
 		repo:            repo,
 		templates:       templates,
 		provider:        provider,
+		providerName:    providerName,
+		judging:         judging,
 		contextProvider: contextProvider,
+		trailers:        trailerOptions(cfg, opts),
+		amend:           opts.Amend,
 	}
 
 	return factory, nil
@@ -114,33 +134,57 @@ func NewGenerateFactory(opts factories.ProviderOptions) (*GenerateFactory, error
 
 // Generate generates commit messages based on staged changes
 func (f *GenerateFactory) Generate(ctx context.Context) ([]string, error) {
-	// Check for staged changes
-	_, err := f.repo.HasStagedChanges()
+	diff, err := f.changes()
 	if err != nil {
-		if _, ok := err.(helpers.ErrNoStagedChanges); ok {
-			return nil, err
-		}
-		return nil, fmt.Errorf("failed to check staged changes: %w", err)
+		return nil, err
 	}
 
-	// Get diff and stats
-	diff, err := f.repo.GetStagedDiff()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get staged diff: %w", err)
+	added, deleted := diff.Stats()
+	files := diff.Paths()
+
+	debug.Log("Diff stats - Added: %d, Deleted: %d, Files: %d", added, deleted, len(files))
+
+	// Manifests are read from the full diff since impact analysis needs them all
+	changed := diff
+
+	// Lockfiles, generated and vendored files are summarized instead of sent in full
+	var noise []string
+	if filter, err := f.repo.NoiseFilter(); err != nil {
+		debug.Log("Warning: Failed to load noise filter: %v", err)
+	} else {
+		var noisy []git.NoisyFile
+		diff, noisy = filter.Split(diff)
+		noise = git.SummarizeNoise(noisy)
+		debug.Log("Summarized %d noisy files", len(noisy))
 	}
 
-	added, deleted, files, err := f.repo.GetStagedDiffStats()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get diff stats: %w", err)
+	versions := f.repo.StagedVersions
+	if f.amend {
+		versions = f.repo.AmendVersions
 	}
+	symbols := changedSymbols(diff, versions)
 
-	debug.Log("Diff stats - Added: %d, Deleted: %d, Files: %d", added, deleted, len(files))
+	// Unstaged edits to staged files are not committed, so the model must not describe them
+	f.partial = nil
+	if !f.amend {
+		if f.partial, err = f.repo.PartiallyStagedFiles(); err != nil {
+			debug.Log("Warning: Failed to check for partially staged files: %v", err)
+		}
+	}
 
 	// Prepare template data
 	data := map[string]any{
-		"Diff":            diff,
+		"Diff":            diff.String(),
+		"Noise":           noise,
+		"Symbols":         symbols,
 		"Files":           files,
 		"RepoDescription": "", // Default to empty string
+		"FileContext":     []string(nil),
+		"Impact":          []string(nil),
+		"Packages":        []string(nil),
+		"RelatedCode":     []string(nil),
+		"Structured":      f.config.Core.StructuredOutput,
+		"PartiallyStaged": f.partial,
 	}
 
 	// Add repository summary if available
@@ -148,9 +192,16 @@ func (f *GenerateFactory) Generate(ctx context.Context) ([]string, error) {
 		repoSummary := f.contextProvider.GetRepoSummary()
 		data["RepoDescription"] = repoSummary
 		debug.Log("Added repository summary to prompt data")
+		f.notice = checkSummary(f.config, f.contextProvider, f.repo)
 	} else {
 		debug.Log("No repository summary available. Run 'quill index' first for context-aware generation.")
 	}
+	if f.contextProvider != nil {
+		data["FileContext"] = f.contextProvider.FileContext(files)
+		data["Impact"] = f.contextProvider.Impact(f.repo, files, manifestChanges(changed, versions))
+		data["Packages"] = f.contextProvider.Packages(f.repo, files)
+		data["RelatedCode"] = relatedCode(ctx, f.config, f.contextProvider, diff)
+	}
 
 	// Generate prompt from template
 	prompt, err := f.templates.Generate(factories.CommitMessageType, data)
@@ -161,10 +212,79 @@ func (f *GenerateFactory) Generate(ctx context.Context) ([]string, error) {
 	// Generate messages using the AI provider
 	opts := ai.GenerateOptions{
 		MaxCandidates: f.config.Core.DefaultCandidates,
+		System:        systemPrompt(f.templates, factories.CommitMessageType, f.config.Core.StructuredOutput),
+	}
+	if f.config.Core.StructuredOutput {
+		opts.Schema = helpers.CommitMessageSchema
 	}
 	if temp := f.config.Providers[f.config.Core.DefaultProvider].Temperature; temp > 0 {
 		opts.Temperature = &temp
 	}
 	debug.Log("Sending prompt to AI provider: %s", prompt)
-	return f.provider.Generate(ctx, prompt, opts)
+	msgs, err := f.provider.Generate(ctx, prompt, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Schema != nil {
+		if msgs = commitMessages(ctx, f.provider, f.templates, prompt, msgs); len(msgs) == 0 {
+			return nil, fmt.Errorf("no valid commit message in the response")
+		}
+	}
+
+	f.rationales = nil
+	if f.judging.mode != "off" {
+		msgs, f.rationales = f.rank(ctx, prompt, opts, msgs, data)
+	}
+
+	// Trailers are added here so the picker shows exactly what will be committed
+	msgs, err = appendTrailers(f.repo, f.trailers, msgs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add commit trailers: %w", err)
+	}
+	return msgs, nil
+}
+
+// Notice returns a message for the user about the last generation, e.g. that the
+// repository summary it used is out of date
+func (f *GenerateFactory) Notice() string {
+	return f.notice
+}
+
+// PartiallyStaged returns the staged files of the last generation that have further
+// unstaged edits, which the generated messages do not cover
+func (f *GenerateFactory) PartiallyStaged() []string {
+	return f.partial
+}
+
+// Rationales returns why each message of the last generation ranks where it does,
+// in the order Generate returned them, or nil when judging is off
+func (f *GenerateFactory) Rationales() []string {
+	return f.rationales
+}
+
+// changes returns the diff to describe: the staged changes, or HEAD's full diff
+// including anything staged since when amending
+func (f *GenerateFactory) changes() (*git.Diff, error) {
+	if f.amend {
+		diff, err := f.repo.AmendDiff()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get diff of HEAD: %w", err)
+		}
+		return diff, nil
+	}
+
+	// Check for staged changes
+	_, err := f.repo.HasStagedChanges()
+	if err != nil {
+		if _, ok := err.(helpers.ErrNoStagedChanges); ok {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to check staged changes: %w", err)
+	}
+
+	diff, err := f.repo.StagedDiff()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staged diff: %w", err)
+	}
+	return diff, nil
 }
@@ -3,7 +3,6 @@ package providers
 import (
 	c "context"
 	"fmt"
-	"os/exec"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +15,15 @@ import (
 	"github.com/jabafett/quill/internal/utils/git"
 )
 
+const (
+	// Repositories with more tracked files are shown to the AI as a directory tree
+	maxListedFiles = 300
+	// Commit subjects included in the repository prompt
+	maxHistoryCommits = 20
+	// Workspace packages described by AI; the rest only get a profile
+	maxDescribedPackages = 50
+)
+
 // IndexProvider handles the repository indexing process
 type IndexProvider struct {
 	config          *config.Config
@@ -23,6 +31,7 @@ type IndexProvider struct {
 	contextProvider *factories.ContextProvider
 	repoRootPath    string
 	aiProvider      factories.Provider
+	templates       *factories.TemplateFactory
 }
 
 type IndexProviderOptions struct {
@@ -111,16 +120,85 @@ func NewIndexProvider(opts ...func(*IndexProviderOptions)) (*IndexProvider, erro
 		return nil, fmt.Errorf("failed to create AI provider: %w", err)
 	}
 
+	templates, err := factories.NewTemplateFactory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create template factory: %w", err)
+	}
+
 	return &IndexProvider{
 		config:          cfg,
 		repo:            repo,
 		contextProvider: contextProvider,
 		repoRootPath:    repoRootPath,
 		aiProvider:      aiProvider,
+		templates:       templates,
 	}, nil
 }
 
-// IndexRepository generates a summary of the repository using AI
+// IndexFiles updates the per-file index. Only files whose content changed since the
+// last run are summarized, unless forceReindex is set.
+func (p *IndexProvider) IndexFiles(ctx c.Context, forceReindex bool) (*context.IndexStats, error) {
+	index, err := p.contextProvider.OpenFileIndex()
+	if err != nil {
+		return nil, err
+	}
+	defer index.Close()
+
+	stats, err := index.Update(ctx, p.repo, p.summarizeFiles, forceReindex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update file index: %w", err)
+	}
+	return stats, nil
+}
+
+// EmbeddingsEnabled reports whether the configuration asks for an embeddings index
+func (p *IndexProvider) EmbeddingsEnabled() bool {
+	return p.config.Index.Embeddings
+}
+
+// EmbedFiles embeds the chunks of the files in the file index that have no
+// embedding yet, with the configured embeddings provider
+func (p *IndexProvider) EmbedFiles(ctx c.Context) (*context.IndexStats, error) {
+	embedder, err := factories.NewEmbedder(p.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings provider: %w", err)
+	}
+
+	index, err := p.contextProvider.OpenFileIndex()
+	if err != nil {
+		return nil, err
+	}
+	defer index.Close()
+
+	stats, err := index.UpdateEmbeddings(ctx, p.repo, embedder.Model(), embedder.Embed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update embeddings: %w", err)
+	}
+	return stats, nil
+}
+
+// summarizeFiles asks the AI provider for a one-line summary of each file in a batch
+func (p *IndexProvider) summarizeFiles(ctx c.Context, files []context.SummaryRequest) (map[string]string, error) {
+	prompt, err := p.templates.Generate(factories.FileSummaryType, map[string]any{"Files": files})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate file summary prompt: %w", err)
+	}
+
+	debug.Log("Summarizing %d files", len(files))
+	responses, err := p.aiProvider.Generate(ctx, prompt, ai.GenerateOptions{
+		MaxCandidates: 1,
+		System:        p.templates.System(factories.FileSummaryType),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(responses) == 0 {
+		return nil, nil
+	}
+	return context.ParseFileSummaries(responses[0], files), nil
+}
+
+// IndexRepository profiles the repository and asks the AI to describe it
 func (p *IndexProvider) IndexRepository(ctx c.Context, forceReindex bool) error {
 	debug.Log("Starting repository indexing for: %s", p.repoRootPath)
 	startTime := time.Now()
@@ -137,104 +215,60 @@ func (p *IndexProvider) IndexRepository(ctx c.Context, forceReindex bool) error
 		return fmt.Errorf("failed to get repository name: %w", err)
 	}
 
-	// Get directory tree
-	dirTree, err := p.contextProvider.GetDirectoryTree(3) // Limit to 3 levels deep
+	profile, err := context.ProfileRepository(p.repo)
 	if err != nil {
-		return fmt.Errorf("failed to get directory tree: %w", err)
+		return fmt.Errorf("failed to profile repository: %w", err)
 	}
 
-	// Get file info
-	fileInfo, err := p.contextProvider.GetFileInfo()
+	description, err := p.describe(ctx, ".", "", profile)
 	if err != nil {
-		return fmt.Errorf("failed to get file info: %w", err)
-	}
-
-	// Count files
-	fileCount := 0
-	for _, count := range fileInfo {
-		fileCount += count
+		return fmt.Errorf("failed to generate repository summary: %w", err)
 	}
 
-	// Get language info
-	languages, err := p.contextProvider.GetLanguageInfo()
+	head, err := p.repo.HeadCommit()
 	if err != nil {
-		return fmt.Errorf("failed to get language info: %w", err)
-	}
-
-	// Get README content if available, searching subfolders case-insensitively for the first match
-	// find <path> -type f \( -iname README.md -o -iname README \) -print -quit
-	// Explanation:
-	// p.repoRootPath:  Start searching here
-	// -type f:         Find only files
-	// \( ... \):       Group conditions
-	// -iname README.md: Find README.md (case-insensitive)
-	// -o:              OR
-	// -iname README:   Find README (case-insensitive)
-	// -print:          Print the path
-	// -quit:           Exit immediately after the first match is found
-	readmeCmd := exec.Command("find", p.repoRootPath, "-type", "f", `(`, "-iname", "README.md", "-o", "-iname", "README", `)`, "-print", "-quit")
-	readmePathBytes, err := readmeCmd.Output()
-	var readmeContent string
-	if err == nil && len(readmePathBytes) > 0 {
-		readmePath := strings.TrimSpace(string(readmePathBytes))
-		if readmePath != "" {
-			debug.Log("Attempting to read README content from: %s", readmePath)
-			catCmd := exec.Command("cat", readmePath)
-			readmeBytes, catErr := catCmd.Output()
-			if catErr == nil {
-				readmeContent = string(readmeBytes)
-				debug.Log("Successfully read README content.")
-			} else {
-				debug.Log("Failed to 'cat' README file '%s': %v", readmePath, catErr)
-			}
-		}
-	} else {
-		if err != nil {
-			debug.Log("Error executing find command for README: %v", err)
-		} else {
-			debug.Log("No README file found in the repository.")
-		}
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
 	}
-
-	prompt := fmt.Sprintf(`Generate a concise summary of this repository:
-
-Repository Name: %s
-File Count: %d
-Languages: %v
-
-Directory Structure:
-%s
-
-Readme Content:
-%s
-
-The summary should describe what this repository is for, its main components, and its purpose.
-Focus on the technical aspects and be specific about what the code does based on the directory structure and languages used.
-`, repoName, fileCount, languages, dirTree, readmeContent)
-
-	// Generate summary using AI
-	debug.Log("Generating repository summary using AI...\nPrompt: %s", prompt)
-	summary, err := p.aiProvider.Generate(ctx, prompt, ai.GenerateOptions{
-		MaxCandidates: 1,
-	})
+	tree, err := p.repo.HeadTree()
 	if err != nil {
-		return fmt.Errorf("failed to generate repository summary: %w", err)
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
 	}
 
-	if len(summary) == 0 {
-		return fmt.Errorf("AI provider returned empty summary")
+	languages := make([]string, len(profile.Languages))
+	for i, lang := range profile.Languages {
+		languages[i] = lang.Name
 	}
 
 	// Create repository summary
 	repoSummary := &context.RepoSummary{
 		Name:        repoName,
-		Description: summary[0],
-		Files:       fileCount,
-		Directories: len(dirTree),
+		Description: description,
+		Files:       profile.Files,
+		Directories: profile.Directories,
 		Languages:   languages,
+		Profile:     profile,
+		Head:        head,
+		TreeHash:    tree,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
+	existing, err := p.contextProvider.LoadSummary()
+	if err != nil {
+		existing = nil
+	}
+	if existing != nil && !existing.CreatedAt.IsZero() {
+		repoSummary.CreatedAt = existing.CreatedAt
+	}
+
+	if profile.Workspace != nil {
+		if existing == nil || forceReindex {
+			existing = &context.RepoSummary{}
+		}
+		repoSummary.Packages, err = p.describePackages(ctx, profile.Workspace, tree, existing)
+		if err != nil {
+			return err
+		}
+	}
 
 	// Save summary
 	err = p.contextProvider.SaveSummary(repoSummary)
@@ -246,6 +280,106 @@ Focus on the technical aspects and be specific about what the code does based on
 	return nil
 }
 
+// describePackages summarizes each package of a monorepo. Packages whose directory
+// is unchanged since the previous summary keep their description.
+func (p *IndexProvider) describePackages(ctx c.Context, workspace *context.Workspace, tree string, previous *context.RepoSummary) ([]context.PackageSummary, error) {
+	var summaries []context.PackageSummary
+	for i, pkg := range workspace.Packages {
+		subtree := ""
+		if tree != "" {
+			var err error
+			if subtree, err = p.repo.SubtreeHash(tree, pkg.Dir); err != nil {
+				return nil, err
+			}
+		}
+		if old := previous.Package(pkg.Dir); old != nil && subtree != "" && old.TreeHash == subtree {
+			debug.Log("Package %s unchanged, keeping its summary", pkg.Dir)
+			old.WorkspacePackage = pkg
+			summaries = append(summaries, *old)
+			continue
+		}
+
+		profile, err := context.ProfilePackage(p.repo, pkg.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to profile package %s: %w", pkg.Dir, err)
+		}
+		summary := context.PackageSummary{WorkspacePackage: pkg, Profile: profile, TreeHash: subtree}
+		if i < maxDescribedPackages {
+			debug.Log("Summarizing package %s", pkg.Name)
+			if summary.Description, err = p.describe(ctx, pkg.Dir, pkg.Name, profile); err != nil {
+				return nil, fmt.Errorf("failed to summarize package %s: %w", pkg.Dir, err)
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// describe asks the AI to describe the repository, or the package in dir, from its
+// profile, key files, file listing and history
+func (p *IndexProvider) describe(ctx c.Context, dir, pkg string, profile *context.RepoProfile) (string, error) {
+	contents, err := context.SampleKeyFiles(p.repo, dir, profile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read key files: %w", err)
+	}
+
+	var files []string
+	tracked, err := p.repo.ListTrackedFiles()
+	if err != nil {
+		return "", fmt.Errorf("failed to list files: %w", err)
+	}
+	for _, file := range tracked {
+		if dir == "." || strings.HasPrefix(file, dir+"/") {
+			files = append(files, file)
+		}
+	}
+	filesClarification := fmt.Sprintf("These are all %d tracked files.", len(files))
+	if len(files) > maxListedFiles {
+		depth := 3
+		if dir != "." {
+			depth += strings.Count(dir, "/") + 1
+		}
+		files = strings.Split(strings.TrimSpace(context.DirectoryTree(files, depth)), "\n")
+		filesClarification = fmt.Sprintf("There are %d files; only their directory tree is shown.", profile.Files)
+	}
+
+	history, err := p.repo.RecentCommitsIn(dir, maxHistoryCommits)
+	if err != nil {
+		debug.Log("Warning: Failed to read history: %v", err)
+	}
+	historyClarification := fmt.Sprintf("These are the subjects of the last %d commits, newest first.", len(history))
+	if len(history) == 0 {
+		historyClarification = "There are no commits yet."
+	}
+
+	prompt, err := p.templates.Generate(factories.ContextExtractionType, map[string]any{
+		"Package":                  pkg,
+		"Profile":                  profile.Lines(),
+		"Files":                    files,
+		"FilesContent":             contents,
+		"AllTheFilesClarification": filesClarification,
+		"GitHistory":               history,
+		"GitHistoryClarification":  historyClarification,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate repository prompt: %w", err)
+	}
+
+	// Generate summary using AI
+	debug.Log("Generating summary of %s using AI...\nPrompt: %s", dir, prompt)
+	summary, err := p.aiProvider.Generate(ctx, prompt, ai.GenerateOptions{
+		MaxCandidates: 1,
+		System:        p.templates.System(factories.ContextExtractionType),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(summary) == 0 {
+		return "", fmt.Errorf("AI provider returned empty summary")
+	}
+	return strings.TrimSpace(summary[0]), nil
+}
+
 // GetRepoSummary returns the repository summary
 func (p *IndexProvider) GetRepoSummary() string {
 	return p.contextProvider.GetRepoSummary()
@@ -256,6 +390,12 @@ func (p *IndexProvider) HasSummary() bool {
 	return p.contextProvider.HasSummary()
 }
 
+// SummaryStaleness measures how out of date the repository summary is against the
+// configured thresholds. It returns nil when there is no summary.
+func (p *IndexProvider) SummaryStaleness() (*context.Staleness, error) {
+	return p.contextProvider.Staleness(p.repo, stalenessThresholds(p.config))
+}
+
 // WithRepoRootPath sets the repository root path
 func WithRepoRootPath(path string) func(*IndexProviderOptions) {
 	return func(opts *IndexProviderOptions) {
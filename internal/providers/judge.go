@@ -0,0 +1,150 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jabafett/quill/internal/factories"
+	"github.com/jabafett/quill/internal/utils/ai"
+	"github.com/jabafett/quill/internal/utils/config"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/helpers"
+)
+
+// candidateSource is a provider commit message candidates are drawn from
+type candidateSource struct {
+	name     string
+	provider factories.Provider
+}
+
+// judging holds what the ranking stage of generate needs
+type judging struct {
+	mode    string            // "off", "rules" or "model"
+	sources []candidateSource // Providers besides the main one
+	judge   factories.Provider
+}
+
+// newJudging creates the providers of the ranking stage. The mode comes from
+// the --judge flag, or else the [judge] section of the configuration.
+func newJudging(cfg *config.Config, opts factories.ProviderOptions, main string) (*judging, error) {
+	j := &judging{mode: cfg.Judge.Mode}
+	if opts.Judge != "" {
+		j.mode = opts.Judge
+	}
+	if j.mode == "off" {
+		return j, nil
+	}
+
+	for _, name := range cfg.Judge.Providers {
+		if name == main {
+			continue
+		}
+		provider, err := factories.NewProvider(cfg, factories.ProviderOptions{
+			Provider:    name,
+			Candidates:  opts.Candidates,
+			Temperature: opts.Temperature,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create candidate provider %s: %w", name, err)
+		}
+		j.sources = append(j.sources, candidateSource{name: name, provider: provider})
+	}
+
+	if j.mode == "model" {
+		judge, err := factories.NewProvider(cfg, factories.ProviderOptions{Provider: cfg.Judge.Provider})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create judge provider: %w", err)
+		}
+		j.judge = judge
+	}
+	return j, nil
+}
+
+// rank adds the candidates of the other providers to msgs, scores them all and
+// returns them best first with a rationale for each. Failing providers and a failing
+// judge only cost their candidates or the judge's scores.
+func (f *GenerateFactory) rank(ctx context.Context, prompt string, opts ai.GenerateOptions, msgs []string, data map[string]any) ([]string, []string) {
+	candidates := make([]helpers.RankedMessage, 0, len(msgs))
+	seen := make(map[string]bool)
+	add := func(source string, messages []string) {
+		for _, msg := range messages {
+			msg = strings.TrimSpace(msg)
+			if msg == "" || seen[msg] {
+				continue
+			}
+			seen[msg] = true
+			candidates = append(candidates, helpers.RankedMessage{Message: msg, Source: source})
+		}
+	}
+	add(f.providerName, msgs)
+
+	results := make([][]string, len(f.judging.sources))
+	var wg sync.WaitGroup
+	for i, source := range f.judging.sources {
+		wg.Add(1)
+		go func(i int, source candidateSource) {
+			defer wg.Done()
+			var err error
+			if results[i], err = source.provider.Generate(ctx, prompt, opts); err != nil {
+				debug.Log("Warning: Candidate provider %s failed: %v", source.name, err)
+			} else if opts.Schema != nil {
+				results[i] = commitMessages(ctx, source.provider, f.templates, prompt, results[i])
+			}
+		}(i, source)
+	}
+	wg.Wait()
+	for i, source := range f.judging.sources {
+		add(source.name, results[i])
+	}
+
+	files, _ := data["Files"].([]string)
+	symbols, _ := data["Symbols"].([]string)
+	var judgements map[int]helpers.Judgement
+	if f.judging.judge != nil && len(candidates) > 1 {
+		judgements = f.judge(ctx, candidates, data)
+	}
+	ranked := helpers.RankMessages(candidates, judgements, files, symbols)
+
+	messages := make([]string, len(ranked))
+	rationales := make([]string, len(ranked))
+	for i, candidate := range ranked {
+		messages[i] = candidate.Message
+		rationales[i] = fmt.Sprintf("%.1f/10 from %s: %s", candidate.Score, candidate.Source, candidate.Rationale)
+		debug.Log("Candidate %d: %s", i+1, rationales[i])
+	}
+	return messages, rationales
+}
+
+// judge asks the judge model to score the candidates against the change
+func (f *GenerateFactory) judge(ctx context.Context, candidates []helpers.RankedMessage, data map[string]any) map[int]helpers.Judgement {
+	messages := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		messages[i] = candidate.Message
+	}
+	prompt, err := f.templates.Generate(factories.JudgeType, map[string]any{
+		"Files":      data["Files"],
+		"Diff":       data["Diff"],
+		"Symbols":    data["Symbols"],
+		"Candidates": messages,
+	})
+	if err != nil {
+		debug.Log("Warning: Failed to generate judge prompt: %v", err)
+		return nil
+	}
+
+	temperature := float32(0)
+	responses, err := f.judging.judge.Generate(ctx, prompt, ai.GenerateOptions{
+		MaxCandidates: 1,
+		Temperature:   &temperature,
+		System:        f.templates.System(factories.JudgeType),
+	})
+	if err != nil || len(responses) == 0 {
+		debug.Log("Warning: Judge failed, ranking by rules only: %v", err)
+		return nil
+	}
+	judgements := helpers.ParseJudgeResponse(responses[0], len(candidates))
+	debug.Log("Judge scored %d of %d candidates", len(judgements), len(candidates))
+	return judgements
+}
@@ -0,0 +1,24 @@
+package providers
+
+import (
+	c "context"
+
+	"github.com/jabafett/quill/internal/factories"
+	"github.com/jabafett/quill/internal/utils/config"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+// relatedCode retrieves code similar to the changes from the embeddings index,
+// when the configuration enables it
+func relatedCode(ctx c.Context, cfg *config.Config, contextProvider *factories.ContextProvider, diff *git.Diff) []string {
+	if !cfg.Index.Embeddings || contextProvider == nil || diff.IsEmpty() {
+		return nil
+	}
+	embedder, err := factories.NewEmbedder(cfg)
+	if err != nil {
+		debug.Log("Warning: Failed to create embeddings provider: %v", err)
+		return nil
+	}
+	return contextProvider.RelatedCode(ctx, embedder, diff, cfg.Index.RelatedChunks, cfg.Index.RelatedTokens)
+}
@@ -0,0 +1,298 @@
+package providers
+
+import (
+	c "context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jabafett/quill/internal/factories"
+	"github.com/jabafett/quill/internal/utils/ai"
+	"github.com/jabafett/quill/internal/utils/config"
+	"github.com/jabafett/quill/internal/utils/context"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/jabafett/quill/internal/utils/helpers"
+)
+
+// reviewDiffBudget caps the numbered diff sent for a review, in bytes; the files
+// past it are still checked by the local rules
+const reviewDiffBudget = 60000
+
+// ReviewFactory handles reviewing the staged changes before they are committed
+type ReviewFactory struct {
+	config          *config.Config
+	repo            *git.Repository
+	templates       *factories.TemplateFactory
+	provider        factories.Provider
+	contextProvider *factories.ContextProvider
+	notice          string // Set when the repository summary is out of date
+}
+
+// NewReviewFactory creates a new factory specifically for the review command
+func NewReviewFactory(opts factories.ProviderOptions) (*ReviewFactory, error) {
+	var (
+		cfg       *config.Config
+		repo      *git.Repository
+		templates *factories.TemplateFactory
+		errChan   = make(chan error, 3)
+		wg        sync.WaitGroup
+	)
+
+	debug.Log("Starting review factory")
+
+	// Load components concurrently
+	wg.Add(3)
+
+	// Load configuration
+	go func() {
+		defer wg.Done()
+		var err error
+		cfg, err = config.LoadConfig()
+		if err != nil {
+			errChan <- fmt.Errorf("failed to load config: %w", err)
+		}
+		debug.Log("Finished loading configuration")
+	}()
+
+	// Initialize git object
+	go func() {
+		defer wg.Done()
+		var err error
+		repo, err = git.NewRepository(".")
+		if err != nil {
+			errChan <- err
+		}
+		debug.Log("Finished initializing git repository")
+	}()
+
+	// Initialize template factory
+	go func() {
+		defer wg.Done()
+		var err error
+		templates, err = factories.NewTemplateFactory()
+		if err != nil {
+			errChan <- fmt.Errorf("failed to create template factory: %w", err)
+		}
+		debug.Log("Finished initializing template factory")
+	}()
+
+	wg.Wait()
+	close(errChan)
+
+	// Check for any errors
+	for err := range errChan {
+		return nil, err
+	}
+
+	provider, err := factories.NewProvider(cfg, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+
+	debug.Log("Finished creating provider")
+
+	// Initialize context provider
+	repoRootPath, err := repo.GetRepoRootPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repo root path: %w", err)
+	}
+
+	contextProvider, err := factories.NewContextProvider(
+		factories.WithRepoRootPath(repoRootPath),
+	)
+	if err != nil {
+		debug.Log("Warning: Failed to create context provider: %v. Proceeding without repository context.", err)
+	}
+
+	return &ReviewFactory{
+		config:          cfg,
+		repo:            repo,
+		templates:       templates,
+		provider:        provider,
+		contextProvider: contextProvider,
+	}, nil
+}
+
+// NewReviewFactoryWithProvider creates a review factory around an existing
+// configuration, repository and provider, without repository context
+func NewReviewFactoryWithProvider(cfg *config.Config, repo *git.Repository, provider factories.Provider) (*ReviewFactory, error) {
+	templates, err := factories.NewTemplateFactory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create template factory: %w", err)
+	}
+	return &ReviewFactory{
+		config:    cfg,
+		repo:      repo,
+		templates: templates,
+		provider:  provider,
+	}, nil
+}
+
+// FailOn returns the configured lowest severity that fails a review
+func (f *ReviewFactory) FailOn() string {
+	return f.config.Review.FailOn
+}
+
+// Review reviews the staged changes. The local rules check every added line for
+// secrets, debug statements and TODOs; the model looks for bugs and missing tests
+// in the diff, with secrets redacted, and its findings are mapped back onto it.
+// When the model fails, the review keeps the local findings and records why in
+// ModelError.
+func (f *ReviewFactory) Review(ctx c.Context) (*helpers.Review, error) {
+	diff, err := f.repo.StagedDiff()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staged diff: %w", err)
+	}
+	if diff.IsEmpty() {
+		return nil, helpers.ErrNoStagedChanges{}
+	}
+
+	review := &helpers.Review{Files: diff.Paths()}
+	local := context.ScanDiff(diff)
+	debug.Log("Local rules found %d problems", len(local))
+
+	filter, err := f.repo.NoiseFilter()
+	if err != nil {
+		debug.Log("Warning: Failed to load noise filter: %v", err)
+	}
+	kept, noise := splitNoise(filter, diff)
+	diffText, omitted := renderWithin(context.RedactSecrets(kept), reviewDiffBudget, (*git.FileDiff).NumberedString)
+	review.Omitted = omitted
+
+	var tests []string
+	for _, path := range review.Files {
+		if context.IsTestFile(path) {
+			tests = append(tests, path)
+		}
+	}
+	reported := make([]string, len(local))
+	for i, finding := range local {
+		reported[i] = fmt.Sprintf("%s: [%s] %s", finding.Location(), finding.Category, finding.Message)
+	}
+
+	data := map[string]any{
+		"Context":     "",
+		"Files":       review.Files,
+		"Tests":       tests,
+		"FileContext": []string(nil),
+		"Symbols":     changedSymbols(kept, f.repo.StagedVersions),
+		"Findings":    reported,
+		"Noise":       noise,
+		"Omitted":     omitted,
+		"Diff":        diffText,
+	}
+	if f.contextProvider != nil {
+		if f.contextProvider.HasSummary() {
+			data["Context"] = f.contextProvider.GetRepoSummary()
+			f.notice = checkSummary(f.config, f.contextProvider, f.repo)
+		}
+		data["FileContext"] = f.contextProvider.FileContext(review.Files)
+	}
+
+	prompt, err := f.templates.Generate(factories.ReviewType, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate review prompt: %w", err)
+	}
+
+	opts := ai.GenerateOptions{
+		MaxCandidates: 1,
+		System:        f.templates.System(factories.ReviewType),
+	}
+	if f.config.Core.StructuredOutput {
+		opts.Schema = helpers.ReviewSchema
+	}
+	if temp := f.config.Providers[f.config.Core.DefaultProvider].Temperature; temp > 0 {
+		opts.Temperature = &temp
+	}
+
+	debug.Dump("Prompt:", prompt)
+	found, err := f.generate(ctx, prompt, opts)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		// The local findings still stand, and still count against the threshold
+		debug.Log("Warning: AI review failed, keeping the local findings: %v", err)
+		review.ModelError = err.Error()
+	}
+
+	review.Findings = append(local, locateFindings(kept, found, local)...)
+	review.Sort()
+	return review, nil
+}
+
+// generate asks the provider for a review and reads its findings
+func (f *ReviewFactory) generate(ctx c.Context, prompt string, opts ai.GenerateOptions) ([]helpers.Finding, error) {
+	responses, err := f.provider.Generate(ctx, prompt, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate review: %w", err)
+	}
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no review generated")
+	}
+	debug.Dump("AI Responses:", responses)
+
+	return f.parse(ctx, prompt, responses[0])
+}
+
+// parse reads the findings from a response, asking once for a repair when it
+// fails validation
+func (f *ReviewFactory) parse(ctx c.Context, prompt, response string) ([]helpers.Finding, error) {
+	found, err := helpers.ParseReviewObject(response)
+	if err == nil {
+		return found, nil
+	}
+
+	repaired, rerr := repairResponse(ctx, f.provider, f.templates, prompt, response, err, helpers.ReviewSchema)
+	if rerr != nil {
+		return nil, fmt.Errorf("failed to read the review: %w", err)
+	}
+	if found, err = helpers.ParseReviewObject(repaired); err != nil {
+		return nil, fmt.Errorf("failed to read the review: %w", err)
+	}
+	return found, nil
+}
+
+// locateFindings maps the model's findings onto the diff it reviewed. Findings
+// about files outside the diff are dropped, lines outside its hunks move to the
+// nearest added line, and findings the local rules already made are left out.
+func locateFindings(diff *git.Diff, found, local []helpers.Finding) []helpers.Finding {
+	seen := make(map[string]bool)
+	key := func(f helpers.Finding) string {
+		return fmt.Sprintf("%s:%d:%s", f.File, f.Line, f.Category)
+	}
+	for _, f := range local {
+		seen[key(f)] = true
+	}
+
+	var located []helpers.Finding
+	for _, f := range found {
+		file := diff.File(f.File)
+		if file == nil {
+			file = diff.File(strings.TrimPrefix(f.File, "b/"))
+		}
+		if file == nil {
+			debug.Log("Dropping finding about %s, which the diff does not change", f.File)
+			continue
+		}
+
+		f.File = file.Path
+		if f.Line > 0 {
+			f.Line = file.LocateLine(f.Line)
+		}
+		f.Source = "model"
+		if seen[key(f)] {
+			continue
+		}
+		seen[key(f)] = true
+		located = append(located, f)
+	}
+	return located
+}
+
+// Notice returns a message for the user about the last review, e.g. that the
+// repository summary it used is out of date
+func (f *ReviewFactory) Notice() string {
+	return f.notice
+}
@@ -0,0 +1,172 @@
+package providers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jabafett/quill/internal/factories"
+	"github.com/jabafett/quill/internal/utils/config"
+	"github.com/jabafett/quill/internal/utils/context"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+// A background re-index holding the lock longer than this is assumed to have died
+const indexLockTimeout = 30 * time.Minute
+
+// IndexStatus describes the repository summary and file index of a repository
+type IndexStatus struct {
+	Summary    *context.RepoSummary // Nil when 'quill index' has not run
+	Staleness  *context.Staleness
+	Thresholds context.StalenessThresholds
+	FileIndex  *context.Manifest           // Nil when there is no file index or it is in use
+	Embeddings *context.EmbeddingsManifest // Nil when no embeddings have been built
+	Running    bool                        // A background re-index holds the lock
+}
+
+// GetIndexStatus reads the index state of the repository in the working directory.
+// Unlike NewIndexProvider it needs no AI provider.
+func GetIndexStatus() (*IndexStatus, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	repo, err := git.NewRepository("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+	root, err := repo.GetRepoRootPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repo root path: %w", err)
+	}
+	contextProvider, err := factories.NewContextProvider(factories.WithRepoRootPath(root))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create context provider: %w", err)
+	}
+
+	status := &IndexStatus{Thresholds: stalenessThresholds(cfg)}
+	if info, err := os.Stat(context.IndexLockPath(root)); err == nil {
+		status.Running = time.Since(info.ModTime()) < indexLockTimeout
+	}
+
+	if contextProvider.HasSummary() {
+		if status.Summary, err = contextProvider.LoadSummary(); err != nil {
+			return nil, err
+		}
+		if status.Staleness, err = context.CheckStaleness(repo, status.Summary, status.Thresholds); err != nil {
+			return nil, fmt.Errorf("failed to check repository summary: %w", err)
+		}
+	}
+
+	if context.FileIndexExists(root) {
+		index, err := contextProvider.OpenFileIndex()
+		if err != nil {
+			debug.Log("Warning: Failed to open file index: %v", err)
+			return status, nil
+		}
+		defer index.Close()
+		if status.FileIndex, err = index.Manifest(); err != nil {
+			return nil, fmt.Errorf("failed to read file index: %w", err)
+		}
+		if status.Embeddings, err = index.EmbeddingsManifest(); err != nil {
+			return nil, fmt.Errorf("failed to read embeddings: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// stalenessThresholds reads the staleness thresholds from the configuration
+func stalenessThresholds(cfg *config.Config) context.StalenessThresholds {
+	return context.StalenessThresholds{
+		Commits:      cfg.Index.StaleCommits,
+		ChangedFiles: cfg.Index.StaleFiles,
+		Age:          cfg.Index.StaleAge,
+	}
+}
+
+// checkSummary compares the repository summary with the repository and returns a
+// notice for the user when it is out of date. With on_stale = "reindex" it also
+// starts 'quill index' in the background.
+func checkSummary(cfg *config.Config, contextProvider *factories.ContextProvider, repo *git.Repository) string {
+	if cfg.Index.OnStale == "ignore" {
+		return ""
+	}
+
+	staleness, err := contextProvider.Staleness(repo, stalenessThresholds(cfg))
+	if err != nil {
+		debug.Log("Warning: Failed to check repository summary: %v", err)
+		return ""
+	}
+	if staleness == nil || !staleness.Stale() {
+		return ""
+	}
+
+	reasons := strings.Join(staleness.Reasons, ", ")
+	debug.Log("Repository summary is out of date: %s", reasons)
+	if cfg.Index.OnStale == "reindex" {
+		root, err := repo.GetRepoRootPath()
+		if err == nil {
+			err = reindexInBackground(root)
+		}
+		if err == nil {
+			return fmt.Sprintf("Repository summary is out of date (%s); re-indexing in the background.", reasons)
+		}
+		debug.Log("Warning: Failed to start background re-index: %v", err)
+	}
+	return fmt.Sprintf("Repository summary is out of date (%s). Run 'quill index' to refresh it.", reasons)
+}
+
+// reindexInBackground starts 'quill index' as a process that outlives this one, with
+// its output in .git/quill-index.log. The lock file it removes when done keeps later
+// commands from starting another run meanwhile.
+func reindexInBackground(repoRoot string) error {
+	lock := context.IndexLockPath(repoRoot)
+	if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) >= indexLockTimeout {
+		// A lock this old was left behind by a run that died
+		os.Remove(lock)
+	}
+	// Creating the lock exclusively keeps two commands from both starting a re-index
+	lockFile, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if os.IsExist(err) {
+		debug.Log("Background re-index already running")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create index lock: %w", err)
+	}
+	_, err = lockFile.WriteString(strconv.Itoa(os.Getpid()))
+	if closeErr := lockFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(lock)
+		return fmt.Errorf("failed to write index lock: %w", err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		os.Remove(lock)
+		return fmt.Errorf("failed to locate quill executable: %w", err)
+	}
+	logFile, err := os.Create(context.IndexLogPath(repoRoot))
+	if err != nil {
+		os.Remove(lock)
+		return fmt.Errorf("failed to create index log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, "index", "--background")
+	cmd.Dir = repoRoot
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		os.Remove(lock)
+		return fmt.Errorf("failed to start quill index: %w", err)
+	}
+	debug.Log("Started background re-index, pid %d", cmd.Process.Pid)
+	return cmd.Process.Release()
+}
@@ -0,0 +1,109 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jabafett/quill/internal/factories"
+	"github.com/jabafett/quill/internal/utils/ai"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/helpers"
+)
+
+// systemPrompt returns the instructions for a template type in the response
+// format in use
+func systemPrompt(templates *factories.TemplateFactory, typ factories.TemplateType, structured bool) string {
+	if structured {
+		return templates.StructuredSystem(typ)
+	}
+	return templates.System(typ)
+}
+
+// repairResponse asks the provider once to correct a structured response that
+// failed validation, listing the problems found
+func repairResponse(ctx context.Context, provider factories.Provider, templates *factories.TemplateFactory, prompt, response string, invalid error, schema *ai.Schema) (string, error) {
+	problems := []string{invalid.Error()}
+	var validation *helpers.ValidationError
+	if errors.As(invalid, &validation) {
+		problems = validation.Problems
+	}
+	debug.Log("Structured response failed validation, asking for a repair: %v", problems)
+
+	repairPrompt, err := templates.Generate(factories.RepairType, map[string]any{
+		"Prompt":   prompt,
+		"Response": response,
+		"Problems": problems,
+	})
+	if err != nil {
+		return "", err
+	}
+	temperature := float32(0)
+	responses, err := provider.Generate(ctx, repairPrompt, ai.GenerateOptions{
+		MaxCandidates: 1,
+		Temperature:   &temperature,
+		System:        templates.System(factories.RepairType),
+		Schema:        schema,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(responses) == 0 {
+		return "", errors.New("no repaired response")
+	}
+	return responses[0], nil
+}
+
+// commitMessages reads structured commit message responses, repairing those that
+// fail validation. A response that is not JSON at all, from a provider that ignored
+// the schema, is kept as the message text; one that stays invalid is dropped.
+func commitMessages(ctx context.Context, provider factories.Provider, templates *factories.TemplateFactory, prompt string, responses []string) []string {
+	messages := make([]string, 0, len(responses))
+	for _, response := range responses {
+		message, err := helpers.ParseCommitObject(response)
+		if err != nil {
+			if !strings.HasPrefix(strings.TrimSpace(response), "{") {
+				messages = append(messages, response)
+				continue
+			}
+			repaired, rerr := repairResponse(ctx, provider, templates, prompt, response, err, helpers.CommitMessageSchema)
+			if rerr == nil {
+				message, err = helpers.ParseCommitObject(repaired)
+			}
+			if rerr != nil || err != nil {
+				debug.Log("Warning: Dropping a commit message that failed validation: %v", errors.Join(rerr, err))
+				continue
+			}
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// suggestionGroups reads a structured suggestion response and checks it against the
+// changed files, asking once for a repair when it fails. The repaired groups are
+// used unless the repair returned none, and a response that is not JSON at all goes
+// through ParseSuggestionResponse. Either way the groups still need ReconcileGroups.
+func suggestionGroups(ctx context.Context, provider factories.Provider, templates *factories.TemplateFactory, prompt, response string, stagedFiles, unstagedFiles []string) []helpers.SuggestionGroup {
+	groups, err := helpers.ParseSuggestionObject(response, stagedFiles, unstagedFiles)
+	if err == nil {
+		return groups
+	}
+	if !strings.HasPrefix(strings.TrimSpace(response), "{") {
+		return helpers.ParseSuggestionResponse(response, stagedFiles, unstagedFiles)
+	}
+
+	repaired, rerr := repairResponse(ctx, provider, templates, prompt, response, err, helpers.SuggestionSchema)
+	if rerr != nil {
+		debug.Log("Warning: Repair request failed: %v", rerr)
+		return groups
+	}
+	repairedGroups, err := helpers.ParseSuggestionObject(repaired, stagedFiles, unstagedFiles)
+	if err != nil {
+		debug.Log("Warning: Repaired suggestions still failed validation: %v", err)
+	}
+	if len(repairedGroups) == 0 {
+		return groups
+	}
+	return repairedGroups
+}
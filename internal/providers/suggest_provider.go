@@ -24,6 +24,8 @@ type SuggestFactory struct {
 	contextProvider *factories.ContextProvider
 	stagedOnly      bool
 	unstagedOnly    bool
+	trailers        git.TrailerOptions
+	notice          string // Set when the repository summary is out of date
 }
 
 // NewSuggestFactory creates a new factory specifically for the suggest command
@@ -113,6 +115,7 @@ func NewSuggestFactory(opts factories.ProviderOptions) (*SuggestFactory, error)
 		contextProvider: contextProvider,
 		stagedOnly:      opts.StagedOnly,
 		unstagedOnly:    opts.UnstagedOnly,
+		trailers:        trailerOptions(cfg, opts),
 	}
 
 	return factory, nil
@@ -123,47 +126,59 @@ func (f *SuggestFactory) Suggest(ctx context.Context) ([]helpers.SuggestionGroup
 	// Check for changes
 	hasStagedChanges, _ := f.repo.HasStagedChangesOptimized()
 
+	// Lockfiles, generated and vendored files are summarized instead of sent in full
+	filter, err := f.repo.NoiseFilter()
+	if err != nil {
+		debug.Log("Warning: Failed to load noise filter: %v", err)
+	}
+
+	// A file moved in the worktree shows up as a deletion and an untracked file, so
+	// statuses are read to treat it as one rename
+	statuses, err := f.repo.FileStatuses()
+	if err != nil {
+		debug.Log("Warning: Failed to get file status: %v", err)
+	}
+	renames := worktreeRenames(statuses)
+
 	// Get staged diff if needed
 	var stagedDiff string
+	var stagedNoise []string
 	var stagedFiles []string
-	var err error
+	var stagedSymbols []string
+	// Staged and unstaged changes, without noise, to find related code for
+	changes := &git.Diff{}
 
 	if !f.unstagedOnly {
 		if hasStagedChanges {
-			stagedDiff, err = f.repo.GetStagedDiffOptimized()
+			diff, err := f.repo.StagedDiff()
 			if err != nil {
 				return nil, fmt.Errorf("failed to get staged diff: %w", err)
 			}
-
-			stagedFiles, err = f.repo.GetStagedFilesOptimized()
-			if err != nil {
-				return nil, fmt.Errorf("failed to get staged files: %w", err)
-			}
+			stagedFiles = diff.Paths()
+			diff, stagedNoise = splitNoise(filter, diff)
+			stagedSymbols = changedSymbols(diff, f.repo.StagedVersions)
+			stagedDiff = diff.String()
+			changes.Files = append(changes.Files, diff.Files...)
 		}
 	}
 
 	// Get unstaged diff if needed
 	var unstagedDiff string
 	var unstagedFiles []string
+	var unstagedNoise []string
+	var unstagedSymbols []string
 
 	if !f.stagedOnly {
-		// Use git diff to get unstaged changes
-		cmd := "git diff --no-color"
-		output, err := helpers.ExecuteCommand(cmd)
+		diff, err := f.repo.UnstagedDiff()
 		if err != nil {
 			return nil, fmt.Errorf("failed to get unstaged diff: %w", err)
 		}
-		unstagedDiff = output
-
-		// Get unstaged files
-		cmd = "git diff --name-only"
-		output, err = helpers.ExecuteCommand(cmd)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get unstaged files: %w", err)
-		}
-		if output != "" {
-			unstagedFiles = helpers.SplitLines(output)
-		}
+		diff = withoutRenamedFrom(diff, renames)
+		unstagedFiles = diff.Paths()
+		diff, unstagedNoise = splitNoise(filter, diff)
+		unstagedSymbols = changedSymbols(diff, f.repo.UnstagedVersions)
+		unstagedDiff = diff.String()
+		changes.Files = append(changes.Files, diff.Files...)
 	}
 
 	// Get untracked files that are not gitignored
@@ -175,6 +190,7 @@ func (f *SuggestFactory) Suggest(ctx context.Context) ([]helpers.SuggestionGroup
 
 	// Get content of untracked files
 	untrackedContent := ""
+	var untrackedNoisy []git.NoisyFile
 	if len(untrackedFiles) > 0 {
 		debug.Log("Found %d untracked files to include in context", len(untrackedFiles))
 		var untrackedContentBuilder strings.Builder
@@ -186,6 +202,17 @@ func (f *SuggestFactory) Suggest(ctx context.Context) ([]helpers.SuggestionGroup
 				continue
 			}
 
+			if filter != nil {
+				if kind, ok := filter.ClassifyFile(file, []byte(content)); ok {
+					untrackedNoisy = append(untrackedNoisy, git.NoisyFile{
+						Path:    file,
+						Kind:    kind,
+						Summary: fmt.Sprintf("%s: new %s file", file, kind),
+					})
+					continue
+				}
+			}
+
 			untrackedContentBuilder.WriteString(fmt.Sprintf("File: %s\n", file))
 			untrackedContentBuilder.WriteString(content)
 			untrackedContentBuilder.WriteString("\n\n")
@@ -209,17 +236,35 @@ func (f *SuggestFactory) Suggest(ctx context.Context) ([]helpers.SuggestionGroup
 	if f.contextProvider != nil && f.contextProvider.HasSummary() {
 		repoContext = f.contextProvider.GetRepoSummary()
 		debug.Log("Added repository summary to prompt data")
+		f.notice = checkSummary(f.config, f.contextProvider, f.repo)
 	} else {
 		debug.Log("No repository summary available. Run 'quill index' first for context-aware suggestions.")
 	}
+	var fileContext, packages, related []string
+	if f.contextProvider != nil {
+		changedFiles := slices.Concat(stagedFiles, unstagedFiles)
+		fileContext = f.contextProvider.FileContext(changedFiles)
+		packages = f.contextProvider.Packages(f.repo, changedFiles)
+		related = relatedCode(ctx, f.config, f.contextProvider, changes)
+	}
 
 	// Prepare template data
 	data := map[string]interface{}{
-		"Context":        repoContext,
-		"Staged":         stagedDiff,
-		"Unstaged":       unstagedDiff,
-		"Untracked":      untrackedContent,
-		"UntrackedFiles": untrackedFiles,
+		"Context":         repoContext,
+		"FileContext":     fileContext,
+		"Packages":        packages,
+		"RelatedCode":     related,
+		"Staged":          stagedDiff,
+		"Unstaged":        unstagedDiff,
+		"Untracked":       untrackedContent,
+		"StagedNoise":     stagedNoise,
+		"UnstagedNoise":   unstagedNoise,
+		"UntrackedNoise":  git.SummarizeNoise(untrackedNoisy),
+		"StagedSymbols":   stagedSymbols,
+		"UnstagedSymbols": unstagedSymbols,
+		"UntrackedFiles":  untrackedFiles,
+		"Renames":         renameLines(renames),
+		"Structured":      f.config.Core.StructuredOutput,
 	}
 
 	// Generate prompt from template
@@ -231,6 +276,10 @@ func (f *SuggestFactory) Suggest(ctx context.Context) ([]helpers.SuggestionGroup
 	// Generate suggestions using the AI provider
 	opts := ai.GenerateOptions{
 		MaxCandidates: f.config.Core.DefaultCandidates,
+		System:        systemPrompt(f.templates, factories.SuggestionType, f.config.Core.StructuredOutput),
+	}
+	if f.config.Core.StructuredOutput {
+		opts.Schema = helpers.SuggestionSchema
 	}
 	if temp := f.config.Providers[f.config.Core.DefaultProvider].Temperature; temp > 0 {
 		opts.Temperature = &temp
@@ -244,20 +293,144 @@ func (f *SuggestFactory) Suggest(ctx context.Context) ([]helpers.SuggestionGroup
 	}
 
 	debug.Dump("AI Responses:", responses)
+	trailers, err := f.repo.BuildTrailers(f.trailers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add commit trailers: %w", err)
+	}
+
 	// Parse the responses into suggestion groups
 	suggestions := make([]helpers.SuggestionGroup, 0, len(responses))
 
 	for i, response := range responses {
 		// Parse the AI response into structured suggestions
 		allFiles := slices.Concat(stagedFiles, unstagedFiles)
-		groups := helpers.ParseSuggestionResponse(response, stagedFiles, allFiles)
+		var groups []helpers.SuggestionGroup
+		if opts.Schema != nil {
+			groups = suggestionGroups(ctx, f.provider, f.templates, prompt, response, stagedFiles, allFiles)
+		} else {
+			groups = helpers.ParseSuggestionResponse(response, stagedFiles, allFiles)
+		}
+
+		// Fit the groups to the files that really changed before anything is staged
+		groups, reconciliation := helpers.ReconcileGroups(renameFiles(groups, renames), stagedFiles, allFiles)
+		for _, line := range reconciliation.Lines() {
+			debug.Log("Suggestion %d: %s", i+1, line)
+		}
+		labelFiles(groups, statuses)
 
 		// Add each group to our suggestions
 		for j, group := range groups {
 			group.ID = fmt.Sprintf("suggestion-%d-%d", i+1, j+1)
+			group.Message = git.AppendTrailers(group.Message, trailers)
 			suggestions = append(suggestions, group)
 		}
 	}
 
 	return suggestions, nil
 }
+
+// worktreeRenames returns the files renamed in the worktree by their old path
+func worktreeRenames(statuses []git.FileStatus) map[string]git.FileStatus {
+	renames := make(map[string]git.FileStatus)
+	for _, s := range statuses {
+		if s.Worktree == 'R' {
+			renames[s.OrigPath] = s
+		}
+	}
+	return renames
+}
+
+// withoutRenamedFrom drops the deletions of files renamed in the worktree from a
+// diff, since the new file is listed with the untracked files
+func withoutRenamedFrom(diff *git.Diff, renames map[string]git.FileStatus) *git.Diff {
+	if len(renames) == 0 {
+		return diff
+	}
+	kept := &git.Diff{}
+	for _, file := range diff.Files {
+		if _, ok := renames[file.Path]; ok && file.Status == git.StatusDeleted {
+			continue
+		}
+		kept.Files = append(kept.Files, file)
+	}
+	return kept
+}
+
+// renameLines describes worktree renames for the prompt, e.g. "old.go -> new.go"
+func renameLines(renames map[string]git.FileStatus) []string {
+	lines := make([]string, 0, len(renames))
+	for old, s := range renames {
+		lines = append(lines, old+" -> "+s.Path)
+	}
+	slices.Sort(lines)
+	return lines
+}
+
+// renameFiles replaces the old paths of worktree renames in groups with the new
+// ones, so a group listing either side stages the whole rename
+func renameFiles(groups []helpers.SuggestionGroup, renames map[string]git.FileStatus) []helpers.SuggestionGroup {
+	for i := range groups {
+		for j, file := range groups[i].Files {
+			if s, ok := renames[file]; ok {
+				groups[i].Files[j] = s.Path
+			}
+		}
+	}
+	return groups
+}
+
+// labelFiles notes which group files are deleted, renamed, new or partially staged
+func labelFiles(groups []helpers.SuggestionGroup, statuses []git.FileStatus) {
+	byPath := git.StatusByPath(statuses)
+	for i := range groups {
+		for _, file := range groups[i].Files {
+			status, ok := byPath[file]
+			if !ok || status.Label() == "" {
+				continue
+			}
+			if groups[i].Labels == nil {
+				groups[i].Labels = make(map[string]string)
+			}
+			groups[i].Labels[file] = status.Label()
+		}
+	}
+}
+
+// splitNoise removes noisy files from a diff and returns their one-line summaries
+func splitNoise(filter *git.NoiseFilter, diff *git.Diff) (*git.Diff, []string) {
+	if filter == nil {
+		return diff, nil
+	}
+	kept, noisy := filter.Split(diff)
+	return kept, git.SummarizeNoise(noisy)
+}
+
+// diffWithin renders whole files of a diff until budget bytes are used, returning
+// the paths of the files left out
+func diffWithin(diff *git.Diff, budget int) (string, []string) {
+	return renderWithin(diff, budget, (*git.FileDiff).String)
+}
+
+// renderWithin is diffWithin with a custom rendering of each file
+func renderWithin(diff *git.Diff, budget int, render func(*git.FileDiff) string) (string, []string) {
+	var b strings.Builder
+	var omitted []string
+	for _, file := range diff.Files {
+		text := render(file)
+		if len(omitted) > 0 || b.Len()+len(text) > budget {
+			omitted = append(omitted, file.Path)
+			continue
+		}
+		b.WriteString(text)
+	}
+	if len(omitted) > 0 {
+		debug.Log("Diff budget of %d bytes reached, leaving out %d files", budget, len(omitted))
+	}
+	return b.String(), omitted
+}
+
+// Notice returns a message for the user about the last suggestion, e.g. that the
+// repository summary it used is out of date
+func (f *SuggestFactory) Notice() string {
+	return f.notice
+}
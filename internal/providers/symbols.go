@@ -0,0 +1,40 @@
+package providers
+
+import (
+	"github.com/jabafett/quill/internal/utils/context"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+// changedSymbols lists the functions, methods and types touched by a diff, one
+// line per file. Symbols are a prompt aid only, so failures just leave them out.
+func changedSymbols(diff *git.Diff, versions func() (*git.FileVersions, error)) []string {
+	if diff.IsEmpty() {
+		return nil
+	}
+
+	v, err := versions()
+	if err != nil {
+		debug.Log("Warning: Failed to read file versions for symbols: %v", err)
+		return nil
+	}
+
+	files := context.ChangedSymbols(diff, v)
+	debug.Log("Found changed symbols in %d files", len(files))
+	return context.SummarizeSymbols(files)
+}
+
+// manifestChanges lists the dependencies added, removed or updated in the package
+// manifests of a diff. Like symbols, failures just leave them out.
+func manifestChanges(diff *git.Diff, versions func() (*git.FileVersions, error)) []context.DependencyChange {
+	if diff.IsEmpty() {
+		return nil
+	}
+
+	v, err := versions()
+	if err != nil {
+		debug.Log("Warning: Failed to read file versions for manifests: %v", err)
+		return nil
+	}
+	return context.ManifestChanges(diff, v)
+}
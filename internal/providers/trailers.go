@@ -0,0 +1,36 @@
+package providers
+
+import (
+	"github.com/jabafett/quill/internal/factories"
+	"github.com/jabafett/quill/internal/utils/config"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+// trailerOptions merges the [trailers] config with command line flags
+func trailerOptions(cfg *config.Config, opts factories.ProviderOptions) git.TrailerOptions {
+	return git.TrailerOptions{
+		Signoff:      cfg.Trailers.Signoff || opts.Signoff,
+		CoAuthors:    append(append([]string(nil), cfg.Trailers.CoAuthors...), opts.CoAuthors...),
+		PairingFile:  cfg.Trailers.PairingFile,
+		IssueRefs:    cfg.Trailers.IssueRefs,
+		IssueKey:     cfg.Trailers.IssueKey,
+		IssuePattern: cfg.Trailers.IssuePattern,
+	}
+}
+
+// appendTrailers resolves the configured trailers and appends them to each message
+func appendTrailers(repo *git.Repository, opts git.TrailerOptions, messages []string) ([]string, error) {
+	trailers, err := repo.BuildTrailers(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(trailers) == 0 {
+		return messages, nil
+	}
+
+	result := make([]string, len(messages))
+	for i, msg := range messages {
+		result[i] = git.AppendTrailers(msg, trailers)
+	}
+	return result, nil
+}
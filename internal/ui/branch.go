@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// BranchModel lets the user pick or edit one of the proposed branch names
+type BranchModel struct {
+	names    []string
+	notes    []string // How each name was changed from the proposal, shown under it
+	cursor   int
+	input    textinput.Model
+	keys     keyMap
+	selected string
+	quitting bool
+	editing  bool
+}
+
+func NewBranchModel(names, notes []string) BranchModel {
+	ti := textinput.New()
+	ti.Placeholder = "Branch name"
+	ti.Prompt = "┃ "
+	ti.CharLimit = 200
+
+	return BranchModel{
+		names: names,
+		notes: notes,
+		input: ti,
+		keys:  keys,
+	}
+}
+
+func (m BranchModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m BranchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if m.editing {
+		switch keyMsg.String() {
+		case "esc":
+			m.editing = false
+			m.input.Blur()
+			return m, nil
+		case "enter":
+			m.selected = m.input.Value()
+			return m, tea.Quit
+		default:
+			var cmd tea.Cmd
+			m.input, cmd = m.input.Update(msg)
+			return m, cmd
+		}
+	}
+
+	switch {
+	case key.Matches(keyMsg, m.keys.Quit):
+		m.quitting = true
+		return m, tea.Quit
+	case key.Matches(keyMsg, m.keys.Up):
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case key.Matches(keyMsg, m.keys.Down):
+		if m.cursor < len(m.names)-1 {
+			m.cursor++
+		}
+	case key.Matches(keyMsg, m.keys.Enter):
+		m.selected = m.names[m.cursor]
+		return m, tea.Quit
+	case key.Matches(keyMsg, m.keys.Edit):
+		m.editing = true
+		m.input.SetValue(m.names[m.cursor])
+		m.input.CursorEnd()
+		return m, m.input.Focus()
+	}
+	return m, nil
+}
+
+func (m BranchModel) View() string {
+	if m.quitting {
+		return styleError.Render("Operation cancelled")
+	}
+
+	mainStyle := lipgloss.NewStyle().Padding(1, 2)
+
+	if m.editing {
+		return mainStyle.Render(
+			lipgloss.JoinVertical(lipgloss.Left,
+				styleHeading.Render("✎ Edit Branch Name"),
+				styleInput.Render(m.input.View()),
+				styleHelp.Render("enter: create • esc: cancel"),
+			),
+		)
+	}
+
+	if m.selected != "" {
+		return mainStyle.Render(styleSuccess.Render("✓ " + m.selected))
+	}
+
+	var items []string
+	for i, name := range m.names {
+		if i == m.cursor {
+			items = append(items, styleSelectedItem.Render(name))
+		} else {
+			items = append(items, styleListItem.Render(name))
+		}
+		if i < len(m.notes) && m.notes[i] != "" {
+			items = append(items, styleFileItem.Render(m.notes[i]))
+		}
+	}
+
+	help := lipgloss.JoinHorizontal(lipgloss.Center,
+		"↑/↓: navigate",
+		" • ",
+		"enter: create and switch",
+		" • ",
+		"e: edit",
+		" • ",
+		"q: quit",
+	)
+
+	return mainStyle.Render(
+		lipgloss.JoinVertical(lipgloss.Left,
+			styleHeading.Render("⎇ Select Branch Name"),
+			lipgloss.JoinVertical(lipgloss.Left, items...),
+			styleHelp.Render(help),
+		),
+	)
+}
+
+// Selected returns the chosen or edited name, empty when none was chosen
+func (m BranchModel) Selected() string {
+	return m.selected
+}
+
+func (m BranchModel) Quitting() bool {
+	return m.quitting
+}
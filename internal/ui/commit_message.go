@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"fmt"
+
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
@@ -14,6 +16,7 @@ type keyMap struct {
 	Quit   key.Binding
 	Edit   key.Binding
 	Reload key.Binding
+	Stage  key.Binding
 }
 
 var keys = keyMap{
@@ -37,6 +40,10 @@ var keys = keyMap{
 		key.WithKeys("r"),
 		key.WithHelp("r", "reload"),
 	),
+	Stage: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "include unstaged edits"),
+	),
 	Quit: key.NewBinding(
 		key.WithKeys("q", "esc", "ctrl+c"),
 		key.WithHelp("q", "quit"),
@@ -44,15 +51,18 @@ var keys = keyMap{
 }
 
 type CommitMessageModel struct {
-	messages []string
-	cursor   int
-	input    textarea.Model
-	keys     keyMap
-	selected string
-	quitting bool
-	editing  bool
-	width    int
-	height   int
+	messages   []string
+	rationales []string // Ranking rationale per message, shown under it
+	partial    []string // Staged files with unstaged edits the commit leaves out
+	cursor     int
+	input      textarea.Model
+	keys       keyMap
+	selected   string
+	quitting   bool
+	editing    bool
+	stage      bool // Stage the partial files' remaining edits and regenerate
+	width      int
+	height     int
 }
 
 func NewCommitMessageModel(messages []string) CommitMessageModel {
@@ -71,6 +81,19 @@ func NewCommitMessageModel(messages []string) CommitMessageModel {
 	}
 }
 
+// WithRationales shows why each message ranks where it does, under the message
+func (m CommitMessageModel) WithRationales(rationales []string) CommitMessageModel {
+	m.rationales = rationales
+	return m
+}
+
+// WithPartiallyStaged warns that these staged files have unstaged edits the commit
+// leaves out, and offers to stage them
+func (m CommitMessageModel) WithPartiallyStaged(files []string) CommitMessageModel {
+	m.partial = files
+	return m
+}
+
 func (m CommitMessageModel) Init() tea.Cmd {
 	return textarea.Blink
 }
@@ -115,6 +138,9 @@ func (m CommitMessageModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.Enter):
 			m.selected = m.messages[m.cursor]
 			return m, tea.Quit
+		case key.Matches(msg, m.keys.Stage) && len(m.partial) > 0:
+			m.stage = true
+			return m, tea.Quit
 		case key.Matches(msg, m.keys.Edit):
 			m.editing = true
 			m.input.SetValue(m.messages[m.cursor])
@@ -143,6 +169,10 @@ func (m CommitMessageModel) View() string {
 		)
 	}
 
+	if m.stage {
+		return mainStyle.Render(styleHeading.Render("Staging remaining edits and regenerating..."))
+	}
+
 	if m.selected != "" {
 		return mainStyle.Render(
 			lipgloss.JoinVertical(lipgloss.Left,
@@ -161,25 +191,32 @@ func (m CommitMessageModel) View() string {
 		} else {
 			items = append(items, styleListItem.Render(msg))
 		}
+		if i < len(m.rationales) && m.rationales[i] != "" {
+			items = append(items, styleFileItem.Render(fmt.Sprintf("#%d %s", i+1, m.rationales[i])))
+		}
+	}
+
+	helpItems := []string{"↑/↓: navigate", " • ", "enter: select", " • ", "e: edit"}
+	if len(m.partial) > 0 {
+		helpItems = append(helpItems, " • ", "a: include unstaged edits")
 	}
+	helpItems = append(helpItems, " • ", "q: quit")
+	help := lipgloss.JoinHorizontal(lipgloss.Center, helpItems...)
 
-	help := lipgloss.JoinHorizontal(lipgloss.Center,
-		"↑/↓: navigate",
-		" • ",
-		"enter: select",
-		" • ",
-		"e: edit",
-		" • ",
-		"q: quit",
-	)
-
-	return mainStyle.Render(
-		lipgloss.JoinVertical(lipgloss.Left,
-			styleHeading.Render("✨ Select Commit Message"),
-			lipgloss.JoinVertical(lipgloss.Left, items...),
-			styleHelp.Render(help),
-		),
-	)
+	sections := []string{
+		styleHeading.Render("✨ Select Commit Message"),
+		lipgloss.JoinVertical(lipgloss.Left, items...),
+	}
+	if len(m.partial) > 0 {
+		warning := []string{styleError.Render("⚠ Unstaged edits not in this commit:")}
+		for _, file := range m.partial {
+			warning = append(warning, styleFileItem.Render("• "+file))
+		}
+		sections = append(sections, lipgloss.JoinVertical(lipgloss.Left, warning...))
+	}
+	sections = append(sections, styleHelp.Render(help))
+
+	return mainStyle.Render(lipgloss.JoinVertical(lipgloss.Left, sections...))
 }
 
 func (m CommitMessageModel) IsEditing() bool {
@@ -190,6 +227,12 @@ func (m CommitMessageModel) Selected() string {
 	return m.selected
 }
 
+// StageRemaining reports whether the user chose to stage the remaining edits of the
+// partially staged files instead of selecting a message
+func (m CommitMessageModel) StageRemaining() bool {
+	return m.stage
+}
+
 func (m CommitMessageModel) Quitting() bool {
 	return m.quitting
 }
\ No newline at end of file
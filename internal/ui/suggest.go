@@ -81,10 +81,14 @@ func (i SuggestionItem) Description() string {
 	if count == 0 {
 		return "No files"
 	}
+	var fixed string
+	if len(i.suggestion.Notes) > 0 {
+		fixed = fmt.Sprintf(" (%d path fixes)", len(i.suggestion.Notes))
+	}
 	if count == 1 {
-		return fmt.Sprintf("1 file: %s", i.suggestion.Files[0])
+		return fmt.Sprintf("1 file: %s%s", i.suggestion.Files[0], fixed)
 	}
-	return fmt.Sprintf("%d files: %s, ...", count, i.suggestion.Files[0])
+	return fmt.Sprintf("%d files: %s, ...%s", count, i.suggestion.Files[0], fixed)
 }
 func (i SuggestionItem) FilterValue() string { return i.suggestion.Description }
 
@@ -352,10 +356,21 @@ func (m SuggestModel) renderDetailPanel() string {
 				} else {
 					prefix = "✗"
 				}
+				if label := s.Labels[f]; label != "" {
+					f += " (" + label + ")"
+				}
 				content = append(content, styleFileItem.Render(prefix+" "+f))
 			}
 		}
 
+		// How the suggested files were fitted to the real changes
+		if len(s.Notes) > 0 {
+			content = append(content, styleListTitle.Render("Path Fixes"))
+			for _, note := range s.Notes {
+				content = append(content, styleFileItem.Copy().Foreground(warningColor).Render("! "+note))
+			}
+		}
+
 		content = append(content, styleListTitle.Render("Commit Message"))
 		msgParts := strings.Split(s.Message, "\n\n")
 		if len(msgParts) > 1 {
@@ -5,20 +5,64 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/jabafett/quill/internal/utils/debug"
+)
+
+// Anthropic error types, as reported in the error.type field of a failed response
+const (
+	AnthropicOverloaded     = "overloaded_error"
+	AnthropicRateLimit      = "rate_limit_error"
+	AnthropicInvalidRequest = "invalid_request_error"
+	AnthropicAuthentication = "authentication_error"
+	AnthropicPermission     = "permission_error"
+	AnthropicNotFound       = "not_found_error"
+	AnthropicRequestTooLong = "request_too_large"
+	AnthropicAPIError       = "api_error"
 )
 
 type AnthropicProvider struct {
 	options Options
 	baseURL string
+	client  *http.Client
 }
 
 type anthropicRequest struct {
-	Model       string             `json:"model"`
-	MaxTokens   int                `json:"max_tokens"`
-	Messages    []anthropicMessage `json:"messages"`
-	Temperature float32            `json:"temperature"`
+	Model       string                 `json:"model"`
+	MaxTokens   int                    `json:"max_tokens"`
+	System      []anthropicSystemBlock `json:"system,omitempty"`
+	Messages    []anthropicMessage     `json:"messages"`
+	Temperature float32                `json:"temperature"`
+	Tools       []anthropicTool        `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice   `json:"tool_choice,omitempty"`
+}
+
+// anthropicTool declares a tool; a forced call to it makes the input a structured response
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicSystemBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+type anthropicCacheControl struct {
+	Type string `json:"type"`
 }
 
 type anthropicMessage struct {
@@ -28,27 +72,78 @@ type anthropicMessage struct {
 
 type anthropicResponse struct {
 	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Input json.RawMessage `json:"input"` // Arguments of a tool_use block
 	} `json:"content"`
+	Usage struct {
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	} `json:"usage"`
+}
+
+type anthropicErrorResponse struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// AnthropicError is a failed response from the Anthropic API
+type AnthropicError struct {
+	StatusCode int
+	Type       string        // One of the Anthropic* error type constants
+	Message    string        // Message reported by the API
+	RetryAfter time.Duration // Delay requested by the retry-after header, if any
+}
+
+func (e *AnthropicError) Error() string {
+	return fmt.Sprintf("anthropic %s (status %d): %s", e.Type, e.StatusCode, e.Message)
+}
+
+// RetryDelay returns the delay requested by the server before retrying
+func (e *AnthropicError) RetryDelay() time.Duration {
+	return e.RetryAfter
+}
+
+// IsOverloaded reports whether the API was temporarily overloaded
+func (e *AnthropicError) IsOverloaded() bool {
+	return e.Type == AnthropicOverloaded || e.StatusCode == 529
+}
+
+// IsRateLimit reports whether the request was rejected by rate limiting
+func (e *AnthropicError) IsRateLimit() bool {
+	return e.Type == AnthropicRateLimit || e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsInvalidRequest reports whether the request itself was rejected and should not be retried
+func (e *AnthropicError) IsInvalidRequest() bool {
+	return e.Type == AnthropicInvalidRequest || e.Type == AnthropicRequestTooLong
 }
 
 func NewAnthropicProvider(options Options) (*AnthropicProvider, error) {
+	baseURL := "https://api.anthropic.com/v1/messages"
+	if options.Host != "" {
+		baseURL = strings.TrimRight(options.Host, "/") + "/v1/messages"
+	}
+
 	return &AnthropicProvider{
 		options: options,
-		baseURL: "https://api.anthropic.com/v1/messages",
+		baseURL: baseURL,
+		client:  &http.Client{},
 	}, nil
 }
 
+// Requests returns the number of HTTP requests Generate sends, one per candidate
+func (p *AnthropicProvider) Requests(opts GenerateOptions) int {
+	return candidateCount(opts, p.options)
+}
+
 func (p *AnthropicProvider) Generate(ctx context.Context, prompt string, opts GenerateOptions) ([]string, error) {
-	// Use provider defaults if not specified in opts
-	maxCandidates := opts.MaxCandidates
-	if maxCandidates <= 0 {
-		maxCandidates = p.options.CandidateCount
-	}
-	if maxCandidates > 3 {
-		maxCandidates = 3
-	}
+	maxCandidates := candidateCount(opts, p.options)
 
 	temperature := p.options.Temperature
 	if opts.Temperature != nil {
@@ -72,14 +167,44 @@ func (p *AnthropicProvider) Generate(ctx context.Context, prompt string, opts Ge
 		},
 	}
 
-	// Make multiple requests if multiple candidates are requested
-	var responses []string
+	// Anthropic has no JSON mode, so the schema becomes a tool the model must call
+	if opts.Schema != nil {
+		reqBody.Tools = []anthropicTool{{
+			Name:        opts.Schema.Name,
+			Description: opts.Schema.Description,
+			InputSchema: opts.Schema.Definition,
+		}}
+		reqBody.ToolChoice = &anthropicToolChoice{Type: "tool", Name: opts.Schema.Name}
+	}
+
+	// The instructions are identical across requests, so mark them for prompt caching
+	if opts.System != "" {
+		reqBody.System = []anthropicSystemBlock{
+			{
+				Type:         "text",
+				Text:         opts.System,
+				CacheControl: &anthropicCacheControl{Type: "ephemeral"},
+			},
+		}
+	}
+
+	// The API has no n parameter, so candidates are requested concurrently
+	responses := make([]string, maxCandidates)
+	errs := make([]error, maxCandidates)
+	var wg sync.WaitGroup
 	for i := 0; i < maxCandidates; i++ {
-		resp, err := p.makeRequest(ctx, reqBody)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = p.makeRequest(ctx, reqBody)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
 		if err != nil {
-			return responses, fmt.Errorf("failed to generate candidate %d: %w", i+1, err)
+			return nil, fmt.Errorf("failed to generate candidate %d: %w", i+1, err)
 		}
-		responses = append(responses, resp)
 	}
 
 	return responses, nil
@@ -101,15 +226,14 @@ func (p *AnthropicProvider) makeRequest(ctx context.Context, reqBody anthropicRe
 	req.Header.Set("x-api-key", p.options.APIKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return "", fmt.Errorf("failed to send request: %w", newNetworkError("anthropic", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return "", parseAnthropicError(resp)
 	}
 
 	var result anthropicResponse
@@ -117,16 +241,89 @@ func (p *AnthropicProvider) makeRequest(ctx context.Context, reqBody anthropicRe
 		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	debug.Log("Anthropic usage: input=%d output=%d cache_write=%d cache_read=%d",
+		result.Usage.InputTokens, result.Usage.OutputTokens,
+		result.Usage.CacheCreationInputTokens, result.Usage.CacheReadInputTokens)
+
 	// Extract text from the first content block
 	if len(result.Content) == 0 {
 		return "", fmt.Errorf("no content in response")
 	}
 
 	for _, content := range result.Content {
-		if content.Type == "text" {
-			return content.Text, nil
+		switch content.Type {
+		case "tool_use":
+			return string(content.Input), nil
+		case "text":
+			if len(reqBody.Tools) == 0 {
+				return content.Text, nil
+			}
 		}
 	}
 
 	return "", fmt.Errorf("no text content in response")
 }
+
+// parseAnthropicError turns a non-200 response into a *ProviderError wrapping an *AnthropicError
+func parseAnthropicError(resp *http.Response) error {
+	apiErr := &AnthropicError{
+		StatusCode: resp.StatusCode,
+		Type:       AnthropicAPIError,
+		RetryAfter: parseRetryAfter(resp.Header.Get("retry-after")),
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var parsed anthropicErrorResponse
+	if err := json.Unmarshal(body, &parsed); err == nil && parsed.Error.Type != "" {
+		apiErr.Type = parsed.Error.Type
+		apiErr.Message = parsed.Error.Message
+	} else {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return &ProviderError{
+		Kind:       anthropicErrorKind(apiErr),
+		Provider:   "anthropic",
+		StatusCode: apiErr.StatusCode,
+		Message:    apiErr.Message,
+		RetryAfter: apiErr.RetryAfter,
+		Err:        apiErr,
+	}
+}
+
+// anthropicErrorKind maps an Anthropic error type onto the shared error taxonomy
+func anthropicErrorKind(e *AnthropicError) ErrorKind {
+	msg := strings.ToLower(e.Message)
+	switch {
+	case e.IsOverloaded(), e.Type == AnthropicAPIError && e.StatusCode >= 500:
+		return KindOverloaded
+	case e.IsRateLimit():
+		return KindRateLimited
+	case e.Type == AnthropicAuthentication, e.Type == AnthropicPermission:
+		return KindAuth
+	case e.Type == AnthropicRequestTooLong, strings.Contains(msg, "prompt is too long"):
+		return KindContextTooLong
+	case strings.Contains(msg, "credit balance"):
+		return KindQuotaExceeded
+	case e.Type == AnthropicInvalidRequest, e.Type == AnthropicNotFound:
+		return KindInvalidRequest
+	default:
+		return kindFromStatus(e.StatusCode)
+	}
+}
+
+// parseRetryAfter reads a retry-after header given either in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
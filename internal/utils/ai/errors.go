@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// ErrorKind classifies provider failures so callers can decide whether to retry
+// and what to tell the user
+type ErrorKind int
+
+const (
+	KindUnknown         ErrorKind = iota // Unclassified
+	KindAuth                             // Missing, invalid or unauthorized API key
+	KindRateLimited                      // Too many requests, retry after a delay
+	KindOverloaded                       // Provider overloaded or failing server side
+	KindContextTooLong                   // Prompt exceeds the model's context window
+	KindContentFiltered                  // Prompt or response blocked by a safety filter
+	KindNetwork                          // Provider could not be reached or timed out
+	KindInvalidRequest                   // Request rejected, e.g. an unknown model
+	KindQuotaExceeded                    // Account quota or credit exhausted
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindAuth:
+		return "authentication"
+	case KindRateLimited:
+		return "rate limited"
+	case KindOverloaded:
+		return "overloaded"
+	case KindContextTooLong:
+		return "context too long"
+	case KindContentFiltered:
+		return "content filtered"
+	case KindNetwork:
+		return "network"
+	case KindInvalidRequest:
+		return "invalid request"
+	case KindQuotaExceeded:
+		return "quota exceeded"
+	default:
+		return "unknown"
+	}
+}
+
+// Retryable reports whether an error of this kind may succeed if the request is repeated.
+// Unclassified errors are not retried, as they are as likely to fail again.
+func (k ErrorKind) Retryable() bool {
+	switch k {
+	case KindRateLimited, KindOverloaded, KindNetwork:
+		return true
+	default:
+		return false
+	}
+}
+
+// ProviderError is the common error type every provider maps its failures into
+type ProviderError struct {
+	Kind       ErrorKind
+	Provider   string        // Provider name, e.g. "anthropic"
+	StatusCode int           // HTTP status code, 0 when not applicable
+	Message    string        // Message reported by the provider
+	RetryAfter time.Duration // Server-requested delay before retrying, if any
+	Err        error         // Underlying provider-specific error
+}
+
+func (e *ProviderError) Error() string {
+	msg := e.Message
+	if msg == "" && e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: %s error (status %d): %s", e.Provider, e.Kind, e.StatusCode, msg)
+	}
+	return fmt.Sprintf("%s: %s error: %s", e.Provider, e.Kind, msg)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// RetryDelay returns the delay requested by the server before retrying
+func (e *ProviderError) RetryDelay() time.Duration {
+	return e.RetryAfter
+}
+
+// Classify returns the kind of a provider error. Errors that were not mapped by a
+// provider are checked for network failures and otherwise reported as KindUnknown.
+func Classify(err error) ErrorKind {
+	if err == nil {
+		return KindUnknown
+	}
+
+	var provErr *ProviderError
+	if errors.As(err, &provErr) {
+		return provErr.Kind
+	}
+
+	if isNetworkError(err) {
+		return KindNetwork
+	}
+	return KindUnknown
+}
+
+// IsRetryable reports whether a failed request is worth repeating
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	return Classify(err).Retryable()
+}
+
+// newNetworkError wraps a transport failure for the given provider
+func newNetworkError(provider string, err error) error {
+	if !isNetworkError(err) {
+		return err
+	}
+	return &ProviderError{Kind: KindNetwork, Provider: provider, Err: err}
+}
+
+// isNetworkError reports whether err is a transport level failure or timeout
+func isNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// kindFromStatus maps an HTTP status code to an error kind
+func kindFromStatus(status int) ErrorKind {
+	switch {
+	case status == 401 || status == 403:
+		return KindAuth
+	case status == 429:
+		return KindRateLimited
+	case status == 413:
+		return KindContextTooLong
+	case status == 400 || status == 404 || status == 422:
+		return KindInvalidRequest
+	case status >= 500:
+		return KindOverloaded
+	default:
+		return KindUnknown
+	}
+}
@@ -2,11 +2,15 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type GeminiProvider struct {
@@ -48,11 +52,18 @@ func (p *GeminiProvider) Generate(ctx context.Context, prompt string, opts Gener
 	model := p.client.GenerativeModel(p.options.Model)
 	model.SetTemperature(temperature)
 	model.SetCandidateCount(int32(maxCandidates))
+	if opts.Schema != nil {
+		model.ResponseMIMEType = "application/json"
+		model.ResponseSchema = geminiSchema(opts.Schema.Definition)
+	}
+
+	if opts.System != "" {
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(opts.System)}}
+	}
 
-	// Use the prompt directly since it's already formatted
 	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate content: %w", err)
+		return nil, fmt.Errorf("failed to generate content: %w", mapGeminiError(err))
 	}
 
 	if resp == nil || len(resp.Candidates) == 0 {
@@ -75,3 +86,134 @@ func (p *GeminiProvider) Generate(ctx context.Context, prompt string, opts Gener
 
 	return responses, nil
 }
+
+// geminiSchema converts a JSON Schema definition to Gemini's subset of OpenAPI schemas.
+// Keywords Gemini does not know, such as additionalProperties, are dropped.
+func geminiSchema(def map[string]any) *genai.Schema {
+	schema := &genai.Schema{}
+	switch def["type"] {
+	case "object":
+		schema.Type = genai.TypeObject
+	case "array":
+		schema.Type = genai.TypeArray
+	case "string":
+		schema.Type = genai.TypeString
+	case "integer":
+		schema.Type = genai.TypeInteger
+	case "number":
+		schema.Type = genai.TypeNumber
+	case "boolean":
+		schema.Type = genai.TypeBoolean
+	}
+	schema.Description, _ = def["description"].(string)
+	if enum, ok := def["enum"].([]string); ok {
+		schema.Enum = enum
+	}
+	if required, ok := def["required"].([]string); ok {
+		schema.Required = required
+	}
+	if items, ok := def["items"].(map[string]any); ok {
+		schema.Items = geminiSchema(items)
+	}
+	if properties, ok := def["properties"].(map[string]any); ok {
+		schema.Properties = make(map[string]*genai.Schema, len(properties))
+		for name, property := range properties {
+			if property, ok := property.(map[string]any); ok {
+				schema.Properties[name] = geminiSchema(property)
+			}
+		}
+	}
+	return schema
+}
+
+// mapGeminiError maps Gemini API errors onto the shared error taxonomy. The client
+// talks REST, so failures carry an HTTP status; gax reports every one of them with
+// the gRPC code Unknown, which makes the status code the only reliable signal.
+func mapGeminiError(err error) error {
+	var blocked *genai.BlockedError
+	if errors.As(err, &blocked) {
+		return &ProviderError{Kind: KindContentFiltered, Provider: "gemini", Err: err}
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		kind := kindFromStatus(apiErr.Code)
+		if apiErr.Code == 400 {
+			kind = geminiBadRequestKind(apiErr.Message)
+		}
+		return &ProviderError{Kind: kind, Provider: "gemini", StatusCode: apiErr.Code, Message: apiErr.Message, Err: err}
+	}
+
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.Unknown {
+		return newNetworkError("gemini", err)
+	}
+
+	kind := KindUnknown
+	switch st.Code() {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		kind = KindAuth
+	case codes.InvalidArgument:
+		kind = geminiBadRequestKind(st.Message())
+	case codes.NotFound, codes.FailedPrecondition:
+		kind = KindInvalidRequest
+	case codes.ResourceExhausted:
+		kind = KindRateLimited
+	case codes.Unavailable, codes.Internal:
+		kind = KindOverloaded
+	case codes.DeadlineExceeded:
+		kind = KindNetwork
+	}
+
+	return &ProviderError{Kind: kind, Provider: "gemini", Message: st.Message(), Err: err}
+}
+
+// geminiBadRequestKind tells invalid API keys and oversized prompts apart from other
+// rejected requests, which Gemini all reports as bad requests
+func geminiBadRequestKind(message string) ErrorKind {
+	msg := strings.ToLower(message)
+	switch {
+	case strings.Contains(msg, "api key"):
+		return KindAuth
+	case strings.Contains(msg, "token") && (strings.Contains(msg, "exceed") || strings.Contains(msg, "limit")):
+		return KindContextTooLong
+	default:
+		return KindInvalidRequest
+	}
+}
+
+// defaultGeminiEmbeddingModel is used when no embedding model is configured
+const defaultGeminiEmbeddingModel = "text-embedding-004"
+
+// EmbeddingModel returns the model Embed uses
+func (p *GeminiProvider) EmbeddingModel() string {
+	if p.options.EmbeddingModel != "" {
+		return p.options.EmbeddingModel
+	}
+	return defaultGeminiEmbeddingModel
+}
+
+// Embed returns one embedding vector per text, in order
+func (p *GeminiProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	model := p.client.EmbeddingModel(p.EmbeddingModel())
+	batch := model.NewBatch()
+	for _, text := range texts {
+		batch.AddContent(genai.Text(text))
+	}
+	resp, err := model.BatchEmbedContents(ctx, batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed: %w", mapGeminiError(err))
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Embeddings), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, embedding := range resp.Embeddings {
+		if embedding == nil {
+			return nil, fmt.Errorf("no embedding for text %d", i)
+		}
+		vectors[i] = embedding.Values
+	}
+	return vectors, nil
+}
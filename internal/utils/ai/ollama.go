@@ -1,95 +1,345 @@
 package ai
 
 import (
-    "context"
-    "fmt"
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOllamaHost = "http://localhost:11434"
+	// defaultOllamaEmbeddingModel is used when no embedding model is configured
+	defaultOllamaEmbeddingModel = "nomic-embed-text"
 )
 
 type OllamaProvider struct {
 	options Options
 	baseURL string
+	client  *http.Client
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	NumCtx      int     `json:"num_ctx,omitempty"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+	Temperature float32 `json:"temperature"`
+	Seed        int     `json:"seed,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   map[string]any  `json:"format,omitempty"` // JSON Schema the response must match
+	Options  ollamaOptions   `json:"options"`
+}
+
+type ollamaChatResponse struct {
+	Message ollamaMessage `json:"message"`
+	Done    bool          `json:"done"`
 }
 
-type ollamaRequest struct {
-    Model       string  `json:"model"`
-    Prompt      string  `json:"prompt"`
-    Temperature float32 `json:"temperature"`
+type ollamaErrorResponse struct {
+	Error string `json:"error"`
 }
 
-type ollamaResponse struct {
-    Response string `json:"response"`
+// OllamaModel describes a model available on the Ollama server
+type OllamaModel struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	Digest     string    `json:"digest"`
+	ModifiedAt time.Time `json:"modified_at"`
+	Details    struct {
+		Family            string `json:"family"`
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+}
+
+// OllamaPullProgress is a single progress update while pulling a model
+type OllamaPullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+	Error     string `json:"error"`
 }
 
 func NewOllamaProvider(options Options) (*OllamaProvider, error) {
+	baseURL, err := ResolveOllamaHost(options.Host)
+	if err != nil {
+		return nil, err
+	}
+
 	return &OllamaProvider{
 		options: options,
-        baseURL: "http://localhost:11434/api/generate",
-    }, nil
+		baseURL: baseURL,
+		client:  &http.Client{},
+	}, nil
+}
+
+// ResolveOllamaHost returns the Ollama base URL. OLLAMA_HOST takes precedence over the
+// configured host, and both accept the same forms as the ollama CLI ("host", "host:port"
+// or a full URL).
+func ResolveOllamaHost(configHost string) (string, error) {
+	host := strings.TrimSpace(os.Getenv("OLLAMA_HOST"))
+	if host == "" {
+		host = strings.TrimSpace(configHost)
+	}
+	if host == "" {
+		return defaultOllamaHost, nil
+	}
+
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid Ollama host %q: %w", host, err)
+	}
+	// Only plain HTTP defaults to Ollama's port; https keeps its own default
+	port := u.Port()
+	if port == "" && u.Scheme == "http" {
+		port = "11434"
+	}
+	hostname := u.Hostname()
+	// 0.0.0.0 and :: are bind addresses, connect to loopback instead
+	switch hostname {
+	case "0.0.0.0":
+		hostname = "127.0.0.1"
+	case "::":
+		hostname = "::1"
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(hostname, port)
+	} else if strings.Contains(hostname, ":") {
+		u.Host = "[" + hostname + "]"
+	} else {
+		u.Host = hostname
+	}
+
+	return strings.TrimRight(u.String(), "/"), nil
+}
+
+// Requests returns the number of HTTP requests Generate sends, one per candidate
+func (p *OllamaProvider) Requests(opts GenerateOptions) int {
+	return candidateCount(opts, p.options)
 }
 
 func (p *OllamaProvider) Generate(ctx context.Context, prompt string, opts GenerateOptions) ([]string, error) {
-	maxCandidates := opts.MaxCandidates
-	if maxCandidates <= 0 {
-        maxCandidates = p.options.CandidateCount
-    }
-    if maxCandidates > 3 {
-        maxCandidates = 3
-    }
-
-    temperature := p.options.Temperature
-    if opts.Temperature != nil {
-        temperature = *opts.Temperature
-    }
-
-    reqBody := ollamaRequest{
-        Model:       p.options.Model,
-        Prompt:      prompt,
-        Temperature: temperature,
-    }
-
-    var responses []string
-    for i := 0; i < maxCandidates; i++ {
-        resp, err := p.makeRequest(ctx, reqBody)
-        if err != nil {
-            return responses, fmt.Errorf("failed to generate candidate %d: %w", i+1, err)
-        }
-        responses = append(responses, resp)
-    }
-
-    return responses, nil
-}
-
-func (p *OllamaProvider) makeRequest(ctx context.Context, reqBody ollamaRequest) (string, error) {
-    jsonData, err := json.Marshal(reqBody)
-    if err != nil {
-        return "", fmt.Errorf("failed to marshal request: %w", err)
-    }
-
-    req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL, bytes.NewBuffer(jsonData))
-    if err != nil {
-        return "", fmt.Errorf("failed to create request: %w", err)
-    }
-
-    req.Header.Set("Content-Type", "application/json")
-
-    client := &http.Client{}
-    resp, err := client.Do(req)
-    if err != nil {
-        return "", fmt.Errorf("failed to send request: %w", err)
-    }
-    defer resp.Body.Close()
-
-    if resp.StatusCode != http.StatusOK {
-        return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-    }
-
-    var result ollamaResponse
-    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-        return "", fmt.Errorf("failed to decode response: %w", err)
-    }
-
-    return result.Response, nil
-} 
\ No newline at end of file
+	maxCandidates := candidateCount(opts, p.options)
+
+	temperature := p.options.Temperature
+	if opts.Temperature != nil {
+		temperature = *opts.Temperature
+	}
+
+	maxTokens := p.options.MaxTokens
+	if opts.MaxTokens > 0 {
+		maxTokens = opts.MaxTokens
+	}
+
+	messages := make([]ollamaMessage, 0, 2)
+	if opts.System != "" {
+		messages = append(messages, ollamaMessage{Role: "system", Content: opts.System})
+	}
+	messages = append(messages, ollamaMessage{Role: "user", Content: prompt})
+
+	// Ollama has no n parameter, so candidates are requested in parallel. Each one gets
+	// its own seed so a fixed seed still yields distinct candidates.
+	responses := make([]string, maxCandidates)
+	errs := make([]error, maxCandidates)
+	var wg sync.WaitGroup
+	for i := 0; i < maxCandidates; i++ {
+		reqBody := ollamaChatRequest{
+			Model:    p.options.Model,
+			Messages: messages,
+			Stream:   false,
+			Options: ollamaOptions{
+				NumCtx:      p.options.NumCtx,
+				NumPredict:  maxTokens,
+				Temperature: temperature,
+			},
+		}
+		if p.options.Seed != 0 {
+			reqBody.Options.Seed = p.options.Seed + i
+		}
+		if opts.Schema != nil {
+			reqBody.Format = opts.Schema.Definition
+		}
+
+		wg.Add(1)
+		go func(i int, reqBody ollamaChatRequest) {
+			defer wg.Done()
+			responses[i], errs[i] = p.makeRequest(ctx, reqBody)
+		}(i, reqBody)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate candidate %d: %w", i+1, err)
+		}
+	}
+
+	return responses, nil
+}
+
+func (p *OllamaProvider) makeRequest(ctx context.Context, reqBody ollamaChatRequest) (string, error) {
+	var result ollamaChatResponse
+	if err := p.do(ctx, http.MethodPost, "/api/chat", reqBody, &result); err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(result.Message.Content) == "" {
+		return "", fmt.Errorf("no content in response")
+	}
+
+	return result.Message.Content, nil
+}
+
+// ListModels returns the models available on the Ollama server
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]OllamaModel, error) {
+	var result struct {
+		Models []OllamaModel `json:"models"`
+	}
+	if err := p.do(ctx, http.MethodGet, "/api/tags", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Models, nil
+}
+
+// EmbeddingModel returns the model Embed uses
+func (p *OllamaProvider) EmbeddingModel() string {
+	if p.options.EmbeddingModel != "" {
+		return p.options.EmbeddingModel
+	}
+	return defaultOllamaEmbeddingModel
+}
+
+// Embed returns one embedding vector per text, in order
+func (p *OllamaProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	body := map[string]any{"model": p.EmbeddingModel(), "input": texts}
+	if err := p.do(ctx, http.MethodPost, "/api/embed", body, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(result.Embeddings), len(texts))
+	}
+	return result.Embeddings, nil
+}
+
+// PullModel downloads a model to the Ollama server, reporting progress as it goes
+func (p *OllamaProvider) PullModel(ctx context.Context, name string, progress func(OllamaPullProgress)) error {
+	jsonData, err := json.Marshal(map[string]any{"model": name, "stream": true})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := p.send(ctx, http.MethodPost, "/api/pull", jsonData)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The pull endpoint streams one JSON object per line
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var update OllamaPullProgress
+		if err := json.Unmarshal(line, &update); err != nil {
+			return fmt.Errorf("failed to decode pull progress: %w", err)
+		}
+		if update.Error != "" {
+			return fmt.Errorf("failed to pull %s: %s", name, update.Error)
+		}
+		if progress != nil {
+			progress(update)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read pull progress: %w", err)
+	}
+	return nil
+}
+
+// do sends a JSON request and decodes the JSON response into out
+func (p *OllamaProvider) do(ctx context.Context, method, path string, body any, out any) error {
+	var jsonData []byte
+	if body != nil {
+		var err error
+		jsonData, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	resp, err := p.send(ctx, method, path, jsonData)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// send issues the request and turns non-200 responses into errors carrying Ollama's message
+func (p *OllamaProvider) send(ctx context.Context, method, path string, jsonData []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if p.options.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.options.APIKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Ollama at %s: %w", p.baseURL, newNetworkError("ollama", err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		provErr := &ProviderError{
+			Kind:       kindFromStatus(resp.StatusCode),
+			Provider:   "ollama",
+			StatusCode: resp.StatusCode,
+			Message:    http.StatusText(resp.StatusCode),
+		}
+		var apiErr ollamaErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err == nil && apiErr.Error != "" {
+			provErr.Message = apiErr.Error
+		}
+		return nil, provErr
+	}
+
+	return resp, nil
+}
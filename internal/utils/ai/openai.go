@@ -2,6 +2,8 @@ package ai
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/sashabaranov/go-openai"
@@ -39,24 +41,41 @@ func (p *OpenAIProvider) Generate(ctx context.Context, prompt string, opts Gener
 		maxTokens = opts.MaxTokens
 	}
 
-	resp, err := p.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: p.options.Model,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
+	messages := make([]openai.ChatCompletionMessage, 0, 2)
+	if opts.System != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: opts.System,
+		})
+	}
+	messages = append(messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: prompt,
+	})
+
+	request := openai.ChatCompletionRequest{
+		Model:       p.options.Model,
+		Messages:    messages,
+		MaxTokens:   maxTokens,
+		Temperature: float32(temperature),
+		N:           maxCandidates,
+	}
+	if opts.Schema != nil {
+		request.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:        opts.Schema.Name,
+				Description: opts.Schema.Description,
+				Schema:      jsonSchema(opts.Schema.Definition),
+				Strict:      true,
 			},
-			MaxTokens:   maxTokens,
-			Temperature: float32(temperature),
-			N:          maxCandidates,
-		},
-	)
+		}
+	}
+
+	resp, err := p.client.CreateChatCompletion(ctx, request)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate: %w", err)
+		return nil, fmt.Errorf("failed to generate: %w", mapOpenAIError(err))
 	}
 
 	if len(resp.Choices) == 0 {
@@ -65,8 +84,101 @@ func (p *OpenAIProvider) Generate(ctx context.Context, prompt string, opts Gener
 
 	responses := make([]string, 0, len(resp.Choices))
 	for _, choice := range resp.Choices {
+		if choice.FinishReason == openai.FinishReasonContentFilter {
+			continue
+		}
 		responses = append(responses, choice.Message.Content)
 	}
 
+	if len(responses) == 0 {
+		return nil, &ProviderError{
+			Kind:     KindContentFiltered,
+			Provider: "openai",
+			Message:  "all candidates were blocked by the content filter",
+		}
+	}
+
 	return responses, nil
 }
+
+// jsonSchema lets a schema definition be passed where go-openai expects a json.Marshaler
+type jsonSchema map[string]any
+
+func (s jsonSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any(s))
+}
+
+// mapOpenAIError maps go-openai errors onto the shared error taxonomy
+func mapOpenAIError(err error) error {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		code, _ := apiErr.Code.(string)
+		kind := kindFromStatus(apiErr.HTTPStatusCode)
+		switch {
+		case code == "context_length_exceeded":
+			kind = KindContextTooLong
+		case code == "insufficient_quota":
+			kind = KindQuotaExceeded
+		case code == "content_filter" || code == "content_policy_violation":
+			kind = KindContentFiltered
+		case code == "invalid_api_key":
+			kind = KindAuth
+		}
+		return &ProviderError{
+			Kind:       kind,
+			Provider:   "openai",
+			StatusCode: apiErr.HTTPStatusCode,
+			Message:    apiErr.Message,
+			Err:        err,
+		}
+	}
+
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		if reqErr.HTTPStatusCode == 0 {
+			return newNetworkError("openai", reqErr.Err)
+		}
+		return &ProviderError{
+			Kind:       kindFromStatus(reqErr.HTTPStatusCode),
+			Provider:   "openai",
+			StatusCode: reqErr.HTTPStatusCode,
+			Err:        err,
+		}
+	}
+
+	return newNetworkError("openai", err)
+}
+
+// defaultOpenAIEmbeddingModel is used when no embedding model is configured
+const defaultOpenAIEmbeddingModel = openai.SmallEmbedding3
+
+// EmbeddingModel returns the model Embed uses
+func (p *OpenAIProvider) EmbeddingModel() string {
+	if p.options.EmbeddingModel != "" {
+		return p.options.EmbeddingModel
+	}
+	return string(defaultOpenAIEmbeddingModel)
+}
+
+// Embed returns one embedding vector per text, in order
+func (p *OpenAIProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	resp, err := p.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: texts,
+		Model: openai.EmbeddingModel(p.EmbeddingModel()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed: %w", mapOpenAIError(err))
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+	return vectors, nil
+}
@@ -1,5 +1,7 @@
 package ai
 
+import "time"
+
 // Options contains common configuration options for AI providers
 type Options struct {
 	Model          string
@@ -8,6 +10,10 @@ type Options struct {
 	APIKey         string
 	EnableRetries  bool
 	CandidateCount int
+	Host           string // Base URL override, e.g. an Ollama server or an API proxy
+	NumCtx         int    // Context window size (Ollama)
+	Seed           int    // Sampling seed, 0 for random (Ollama)
+	EmbeddingModel string // Model for Embed, empty for the provider default
 }
 
 // GenerateOptions contains options for a single generation request
@@ -15,4 +21,37 @@ type GenerateOptions struct {
 	MaxCandidates int      // Number of variations to generate (max 3)
 	MaxTokens     int      // Override default max tokens if needed
 	Temperature   *float32 // Override default temperature if needed
-}
\ No newline at end of file
+	System        string   // Optional system prompt sent ahead of the user prompt
+	Schema        *Schema  // Request a JSON object matching this schema instead of free text
+}
+
+// Schema describes the JSON object a structured response must match. Providers
+// constrain their output to it where the API allows (OpenAI json_schema, Gemini
+// ResponseSchema, Anthropic forced tool use, Ollama format), and every response
+// is then the JSON object as a string.
+type Schema struct {
+	Name        string         // Identifier, e.g. "commit_message"
+	Description string         // What the object holds
+	Definition  map[string]any // JSON Schema of an object, with every property required
+}
+
+// RetryDelayer is implemented by errors that carry a server-requested retry delay
+type RetryDelayer interface {
+	RetryDelay() time.Duration
+}
+
+// RequestCounter is implemented by providers that send several requests for one
+// Generate call, one per candidate, so that rate limits count each of them
+type RequestCounter interface {
+	Requests(opts GenerateOptions) int
+}
+
+// candidateCount returns how many candidates to generate: the requested number, else
+// the configured one, between 1 and 3
+func candidateCount(opts GenerateOptions, options Options) int {
+	n := opts.MaxCandidates
+	if n <= 0 {
+		n = options.CandidateCount
+	}
+	return min(max(n, 1), 3)
+}
@@ -107,13 +107,21 @@ func (c *Cache) Get(key string, value interface{}) error {
 
 // Set adds or updates a cached value with the default TTL
 func (c *Cache) Set(key string, value interface{}) error {
+	return c.SetWithTTL(key, value, defaultTTL)
+}
+
+// SetWithTTL adds or updates a cached value that expires after ttl, or never when ttl is zero
+func (c *Cache) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
 	data, err := json.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal value for key '%s': %w", key, err)
 	}
 
 	err = c.db.Update(func(txn *badger.Txn) error {
-		e := badger.NewEntry([]byte(key), data).WithTTL(defaultTTL)
+		e := badger.NewEntry([]byte(key), data)
+		if ttl > 0 {
+			e = e.WithTTL(ttl)
+		}
 		return txn.SetEntry(e)
 	})
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/jabafett/quill/internal/utils/ai"
@@ -22,6 +23,11 @@ var (
 type Config struct {
 	Core      CoreConfig            `mapstructure:"core"`
 	Providers map[string]AIProvider `mapstructure:"providers"`
+	Trailers  TrailersConfig        `mapstructure:"trailers"`
+	Index     IndexConfig           `mapstructure:"index"`
+	Judge     JudgeConfig           `mapstructure:"judge"`
+	Branch    BranchConfig          `mapstructure:"branch"`
+	Review    ReviewConfig          `mapstructure:"review"`
 }
 
 type CoreConfig struct {
@@ -29,6 +35,9 @@ type CoreConfig struct {
 	CacheTTL          time.Duration `mapstructure:"cache_ttl"`
 	MaxDiffSize       string        `mapstructure:"max_diff_size"`
 	DefaultCandidates int           `mapstructure:"default_candidates"`
+	RetryAttempts     int           `mapstructure:"retry_attempts"`
+	RequestTimeout    time.Duration `mapstructure:"request_timeout"`
+	StructuredOutput  bool          `mapstructure:"structured_output"` // Ask providers for JSON matching a schema
 }
 
 type AIProvider struct {
@@ -37,6 +46,58 @@ type AIProvider struct {
 	Temperature    float32 `mapstructure:"temperature"`
 	EnableRetries  bool    `mapstructure:"enable_retries"`
 	CandidateCount int     `mapstructure:"candidate_count"`
+	Host           string  `mapstructure:"host"`    // Ollama server URL
+	NumCtx         int     `mapstructure:"num_ctx"` // Ollama context window
+	Seed           int     `mapstructure:"seed"`    // Ollama sampling seed
+
+	EmbeddingModel string `mapstructure:"embedding_model"` // Model for the embeddings index, empty for the provider default
+
+	// Rate limits, keyed by provider and model. Zero keeps the provider default.
+	RequestsPerMinute int `mapstructure:"requests_per_minute"`
+	TokensPerMinute   int `mapstructure:"tokens_per_minute"`
+	MaxConcurrency    int `mapstructure:"max_concurrency"`
+}
+
+// TrailersConfig controls the trailers appended to generated commit messages
+type TrailersConfig struct {
+	Signoff      bool     `mapstructure:"signoff"`       // Always add Signed-off-by
+	CoAuthors    []string `mapstructure:"co_authors"`    // Always add these co-authors
+	PairingFile  string   `mapstructure:"pairing_file"`  // Current pairing partners, one per line
+	IssueRefs    bool     `mapstructure:"issue_refs"`    // Add issue references found in the branch name
+	IssueKey     string   `mapstructure:"issue_key"`     // Trailer key for issue references
+	IssuePattern string   `mapstructure:"issue_pattern"` // Custom issue reference regex
+}
+
+// IndexConfig controls when the repository summary counts as out of date
+type IndexConfig struct {
+	StaleCommits int           `mapstructure:"stale_commits"` // Commits since indexing, 0 to ignore
+	StaleFiles   int           `mapstructure:"stale_files"`   // Files changed since indexing, 0 to ignore
+	StaleAge     time.Duration `mapstructure:"stale_age"`     // Age of the summary, 0 to ignore
+	OnStale      string        `mapstructure:"on_stale"`      // "warn", "reindex" in the background, or "ignore"
+
+	// Embeddings index of code chunks, used to add related code to prompts
+	Embeddings         bool   `mapstructure:"embeddings"`          // Build the index and retrieve from it
+	EmbeddingsProvider string `mapstructure:"embeddings_provider"` // Provider computing embeddings, empty for the default provider
+	RelatedChunks      int    `mapstructure:"related_chunks"`      // Chunks retrieved per changed hunk
+	RelatedTokens      int    `mapstructure:"related_tokens"`      // Token budget for related code in a prompt
+}
+
+// JudgeConfig controls how generated commit message candidates are ranked
+type JudgeConfig struct {
+	Mode      string   `mapstructure:"mode"`      // "off", "rules" (deterministic scoring) or "model" (a judge prompt)
+	Provider  string   `mapstructure:"provider"`  // Provider of the judge model, empty for the default provider
+	Providers []string `mapstructure:"providers"` // More providers to draw candidates from
+}
+
+// BranchConfig controls the names quill branch proposes
+type BranchConfig struct {
+	Pattern       string `mapstructure:"pattern"`         // Placeholders <type>, <ticket> and <slug>, e.g. "<type>/<ticket>-<slug>"
+	MaxSlugLength int    `mapstructure:"max_slug_length"` // Longest slug kept, 0 for no limit
+}
+
+// ReviewConfig controls when quill review fails
+type ReviewConfig struct {
+	FailOn string `mapstructure:"fail_on"` // Lowest severity that fails the review, or "none"
 }
 
 // ConfigToOptions converts a provider config to Options
@@ -55,13 +116,19 @@ func ConfigToOptions(cfg *Config, providerName string) (ai.Options, error) {
 		kp = keyring.Anthropic
 	case "openai":
 		kp = keyring.OpenAI
+	case "ollama":
+		kp = keyring.Ollama
 	default:
 		return ai.Options{}, fmt.Errorf("unknown provider: %s", providerName)
 	}
 
 	apiKey, err := keyring.GetAPIKey(kp)
 	if err != nil {
-		return ai.Options{}, fmt.Errorf("failed to get API key: %w", err)
+		// Ollama only needs a key when it sits behind an authenticating proxy
+		if providerName != "ollama" {
+			return ai.Options{}, fmt.Errorf("failed to get API key: %w", err)
+		}
+		apiKey = ""
 	}
 
 	return ai.Options{
@@ -71,6 +138,10 @@ func ConfigToOptions(cfg *Config, providerName string) (ai.Options, error) {
 		APIKey:         apiKey,
 		EnableRetries:  provider.EnableRetries,
 		CandidateCount: provider.CandidateCount,
+		Host:           provider.Host,
+		NumCtx:         provider.NumCtx,
+		Seed:           provider.Seed,
+		EmbeddingModel: provider.EmbeddingModel,
 	}, nil
 }
 
@@ -104,6 +175,22 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("%w: run 'quill init' to create one", ErrNoConfig)
 	}
 
+	// Trailer defaults apply to configs written before the section existed
+	viper.SetDefault("trailers.issue_refs", true)
+	viper.SetDefault("trailers.issue_key", "Refs")
+	viper.SetDefault("trailers.pairing_file", "~/.config/quill-pair")
+	viper.SetDefault("index.stale_commits", 50)
+	viper.SetDefault("index.stale_files", 100)
+	viper.SetDefault("index.stale_age", "720h")
+	viper.SetDefault("index.on_stale", "warn")
+	viper.SetDefault("index.related_chunks", 3)
+	viper.SetDefault("index.related_tokens", 2000)
+	viper.SetDefault("judge.mode", "off")
+	viper.SetDefault("core.structured_output", true)
+	viper.SetDefault("branch.pattern", "<type>/<ticket>-<slug>")
+	viper.SetDefault("branch.max_slug_length", 40)
+	viper.SetDefault("review.fail_on", "high")
+
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
@@ -125,5 +212,38 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("%w: provider '%s' not configured", ErrInvalidProvider, cfg.Core.DefaultProvider)
 	}
 
+	switch cfg.Index.OnStale {
+	case "warn", "reindex", "ignore":
+	default:
+		return fmt.Errorf("%w: index.on_stale must be warn, reindex or ignore, got '%s'", ErrInvalidConfig, cfg.Index.OnStale)
+	}
+
+	switch cfg.Judge.Mode {
+	case "off", "rules", "model":
+	default:
+		return fmt.Errorf("%w: judge.mode must be off, rules or model, got '%s'", ErrInvalidConfig, cfg.Judge.Mode)
+	}
+	for _, name := range append([]string{cfg.Judge.Provider}, cfg.Judge.Providers...) {
+		if _, ok := cfg.Providers[name]; name != "" && !ok {
+			return fmt.Errorf("%w: judge provider '%s' not configured", ErrInvalidProvider, name)
+		}
+	}
+
+	if !strings.Contains(cfg.Branch.Pattern, "<slug>") {
+		return fmt.Errorf("%w: branch.pattern must contain <slug>, got '%s'", ErrInvalidConfig, cfg.Branch.Pattern)
+	}
+
+	switch cfg.Review.FailOn {
+	case "none", "info", "low", "medium", "high", "critical":
+	default:
+		return fmt.Errorf("%w: review.fail_on must be none, info, low, medium, high or critical, got '%s'", ErrInvalidConfig, cfg.Review.FailOn)
+	}
+
+	if name := cfg.Index.EmbeddingsProvider; name != "" {
+		if _, ok := cfg.Providers[name]; !ok {
+			return fmt.Errorf("%w: embeddings provider '%s' not configured", ErrInvalidProvider, name)
+		}
+	}
+
 	return nil
 }
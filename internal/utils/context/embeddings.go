@@ -0,0 +1,431 @@
+package context
+
+import (
+	c "context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+const (
+	embeddingsKey      = "embeddings"
+	embeddingKeyPrefix = "emb:"
+	// Symbols longer than this are split into windows
+	maxChunkLines = 80
+	// Window size for files without symbols
+	chunkWindowLines = 40
+	maxChunkBytes    = 4000
+	maxFileChunks    = 50
+	// Texts embedded per AI request
+	embedBatchSize = 32
+	// Changed hunks used as retrieval queries
+	maxRelatedQueries = 20
+	maxQueryBytes     = 2000
+)
+
+// Chunk is an embedded span of a file
+type Chunk struct {
+	Start  int       `json:"start"` // 1-based, inclusive
+	End    int       `json:"end"`
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector,omitempty"` // Normalized to unit length
+}
+
+// EmbeddingEntry holds the chunks of one file version, keyed by blob hash like FileEntry
+type EmbeddingEntry struct {
+	Blob   string  `json:"blob"`
+	Chunks []Chunk `json:"chunks"`
+}
+
+// EmbeddingsManifest records which files have embeddings and the model that made
+// them. Vectors of different models cannot be compared, so a new model re-embeds all.
+type EmbeddingsManifest struct {
+	Model     string            `json:"model"`
+	Files     map[string]string `json:"files"` // Path to blob hash
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Embed returns one embedding vector per text
+type Embed func(ctx c.Context, texts []string) ([][]float32, error)
+
+// RelatedQuery is a changed hunk to find related code for
+type RelatedQuery struct {
+	Path string
+	Text string
+}
+
+// EmbeddingsManifest returns the embedded files, or nil when nothing has been embedded
+func (ix *FileIndex) EmbeddingsManifest() (*EmbeddingsManifest, error) {
+	var m EmbeddingsManifest
+	if err := ix.cache.Get(embeddingsKey, &m); err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// embedding returns the chunks of a blob, or nil when it has none
+func (ix *FileIndex) embedding(blob string) (*EmbeddingEntry, error) {
+	var entry EmbeddingEntry
+	if err := ix.cache.Get(embeddingKeyPrefix+blob, &entry); err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// UpdateEmbeddings embeds the chunks of the files in the file index whose blob has no
+// embedding yet, so it runs after Update. Everything is embedded again when the
+// model differs from the one the stored vectors came from.
+func (ix *FileIndex) UpdateEmbeddings(ctx c.Context, repo *git.Repository, model string, embed Embed) (*IndexStats, error) {
+	manifest, err := ix.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("file index is empty")
+	}
+	previous, err := ix.EmbeddingsManifest()
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		previous = &EmbeddingsManifest{}
+	}
+	reuse := previous.Model == model
+
+	current := &EmbeddingsManifest{Model: model, Files: make(map[string]string)}
+	stats := &IndexStats{}
+
+	paths := make([]string, 0, len(manifest.Files))
+	for p := range manifest.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var pending []*EmbeddingEntry
+	done := make(map[string]bool) // Blobs embedded in this run
+	for _, p := range paths {
+		blob := manifest.Files[p]
+		current.Files[p] = blob
+		if done[blob] {
+			continue
+		}
+		done[blob] = true
+		if reuse {
+			entry, err := ix.embedding(blob)
+			if err != nil {
+				return nil, err
+			}
+			if entry != nil {
+				stats.Unchanged++
+				continue
+			}
+		}
+
+		content, err := repo.BlobContent(blob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		pending = append(pending, &EmbeddingEntry{Blob: blob, Chunks: ChunkFile(p, content)})
+	}
+
+	// Embed the chunks of several files per request
+	var chunks []*Chunk
+	for _, entry := range pending {
+		for i := range entry.Chunks {
+			chunks = append(chunks, &entry.Chunks[i])
+		}
+	}
+	for start := 0; start < len(chunks); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(chunks))
+		texts := make([]string, 0, end-start)
+		for _, chunk := range chunks[start:end] {
+			texts = append(texts, chunk.Text)
+		}
+		vectors, err := embed(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		if len(vectors) != len(texts) {
+			return nil, fmt.Errorf("got %d embeddings for %d chunks", len(vectors), len(texts))
+		}
+		for i, vector := range vectors {
+			chunks[start+i].Vector = normalize(vector)
+		}
+		debug.Log("Embedded %d/%d chunks", end, len(chunks))
+	}
+
+	for _, entry := range pending {
+		if err := ix.cache.SetWithTTL(embeddingKeyPrefix+entry.Blob, entry, 0); err != nil {
+			return nil, err
+		}
+	}
+	stats.Indexed = len(pending)
+
+	current.UpdatedAt = time.Now()
+	if err := ix.cache.SetWithTTL(embeddingsKey, current, 0); err != nil {
+		return nil, err
+	}
+
+	// Drop embeddings no file refers to anymore, or made by another model
+	live := make(map[string]bool, len(current.Files))
+	for _, blob := range current.Files {
+		live[blob] = true
+	}
+	for _, blob := range previous.Files {
+		if !live[blob] {
+			live[blob] = true
+			if err := ix.cache.Delete(embeddingKeyPrefix + blob); err != nil {
+				return nil, err
+			}
+			stats.Removed++
+		}
+	}
+
+	debug.Log("Embeddings updated: %d files embedded, %d unchanged, %d removed",
+		stats.Indexed, stats.Unchanged, stats.Removed)
+	return stats, nil
+}
+
+// Related returns the chunks most similar to each query, best first, rendered as
+// "path:start-end" followed by the chunk. Chunks of the excluded files (usually the
+// changed files, which the prompt shows already) are skipped, at most k chunks are
+// taken per query and the result fits in about budget tokens. It returns nothing
+// when the stored vectors were made by another model.
+func (ix *FileIndex) Related(ctx c.Context, model string, embed Embed, queries []RelatedQuery, exclude []string, k, budget int) ([]string, error) {
+	if len(queries) == 0 || k <= 0 || budget <= 0 {
+		return nil, nil
+	}
+	manifest, err := ix.EmbeddingsManifest()
+	if err != nil || manifest == nil {
+		return nil, err
+	}
+	if manifest.Model != model {
+		debug.Log("Embeddings were made by %s, not %s. Run 'quill index' to rebuild them.", manifest.Model, model)
+		return nil, nil
+	}
+
+	excluded := make(map[string]bool, len(exclude))
+	for _, p := range exclude {
+		excluded[p] = true
+	}
+
+	type candidate struct {
+		path  string
+		chunk *Chunk
+	}
+	paths := make([]string, 0, len(manifest.Files))
+	for p := range manifest.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var candidates []candidate
+	seen := make(map[string]bool) // Files sharing a blob are searched once
+	for _, p := range paths {
+		blob := manifest.Files[p]
+		if excluded[p] || seen[blob] {
+			continue
+		}
+		seen[blob] = true
+		entry, err := ix.embedding(blob)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		for i := range entry.Chunks {
+			candidates = append(candidates, candidate{path: p, chunk: &entry.Chunks[i]})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	queries = queries[:min(len(queries), maxRelatedQueries)]
+	texts := make([]string, len(queries))
+	for i, q := range queries {
+		texts[i] = q.Text
+	}
+	vectors, err := embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed changes: %w", err)
+	}
+
+	// Best score of each chunk among the queries it is a top-k match for
+	best := make(map[int]float32)
+	scores := make([]float32, len(candidates))
+	order := make([]int, len(candidates))
+	for _, vector := range vectors {
+		vector = normalize(vector)
+		for i, cand := range candidates {
+			scores[i] = dot(vector, cand.chunk.Vector)
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+		for _, i := range order[:min(k, len(order))] {
+			if score, ok := best[i]; !ok || scores[i] > score {
+				best[i] = scores[i]
+			}
+		}
+	}
+
+	picked := make([]int, 0, len(best))
+	for i := range best {
+		picked = append(picked, i)
+	}
+	sort.Slice(picked, func(a, b int) bool {
+		if best[picked[a]] != best[picked[b]] {
+			return best[picked[a]] > best[picked[b]]
+		}
+		return picked[a] < picked[b]
+	})
+
+	var blocks []string
+	used := 0
+	for _, i := range picked {
+		cand := candidates[i]
+		block := fmt.Sprintf("%s:%d-%d\n%s", cand.path, cand.chunk.Start, cand.chunk.End, cand.chunk.Text)
+		tokens := (len(block) + 3) / 4
+		if used+tokens > budget {
+			continue
+		}
+		used += tokens
+		blocks = append(blocks, block)
+	}
+	debug.Log("Retrieved %d related chunks (~%d tokens) for %d changes", len(blocks), used, len(queries))
+	return blocks, nil
+}
+
+// ChunkFile splits a file into the spans that are embedded: one per top-level
+// symbol for languages with symbol support, split into windows when long, and
+// fixed windows of lines otherwise
+func ChunkFile(path string, content []byte) []Chunk {
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+
+	var spans [][2]int
+	if SupportsSymbols(path) {
+		symbols, err := ExtractSymbols(path, content)
+		if err != nil {
+			debug.Log("Chunking %s by lines: %v", path, err)
+		}
+		sort.SliceStable(symbols, func(i, j int) bool { return symbols[i].StartLine < symbols[j].StartLine })
+		covered := 0
+		for _, sym := range symbols {
+			// Nested symbols are part of their parent's chunk
+			if sym.StartLine <= covered {
+				continue
+			}
+			// Doc comments and decorators belong to the symbol they precede
+			start := sym.StartLine
+			for start-1 > covered && isLeadingComment(lines[start-2]) {
+				start--
+			}
+			spans = append(spans, windows(start, min(sym.EndLine, len(lines)), maxChunkLines)...)
+			covered = sym.EndLine
+		}
+	}
+	if len(spans) == 0 {
+		spans = windows(1, len(lines), chunkWindowLines)
+	}
+
+	var chunks []Chunk
+	for _, span := range spans {
+		if len(chunks) >= maxFileChunks {
+			break
+		}
+		text := strings.Join(lines[span[0]-1:span[1]], "\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if len(text) > maxChunkBytes {
+			text = text[:maxChunkBytes]
+		}
+		chunks = append(chunks, Chunk{Start: span[0], End: span[1], Text: text})
+	}
+	return chunks
+}
+
+// HunkQueries turns each hunk of a diff into a retrieval query: the file, the
+// enclosing section and the changed lines
+func HunkQueries(diff *git.Diff) []RelatedQuery {
+	var queries []RelatedQuery
+	for _, file := range diff.Files {
+		for _, hunk := range file.Hunks {
+			var b strings.Builder
+			b.WriteString(file.Path)
+			if hunk.Section != "" {
+				b.WriteString(" " + hunk.Section)
+			}
+			for _, line := range hunk.Lines {
+				if line.Kind == git.LineAdded || line.Kind == git.LineDeleted {
+					b.WriteString("\n" + line.Content)
+				}
+			}
+			text := b.String()
+			if len(text) > maxQueryBytes {
+				text = text[:maxQueryBytes]
+			}
+			queries = append(queries, RelatedQuery{Path: file.Path, Text: text})
+		}
+	}
+	return queries
+}
+
+// isLeadingComment reports whether a line is a comment or decorator line
+func isLeadingComment(line string) bool {
+	line = strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "#", "/*", "*", "@"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// windows splits the lines from start to end into spans of at most size lines
+func windows(start, end, size int) [][2]int {
+	var spans [][2]int
+	for from := start; from <= end; from += size {
+		spans = append(spans, [2]int{from, min(from+size-1, end)})
+	}
+	return spans
+}
+
+// normalize scales a vector to unit length, so a dot product is the cosine similarity
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range min(len(a), len(b)) {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,461 @@
+package context
+
+import (
+	"bytes"
+	c "context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/jabafett/quill/internal/utils/cache"
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+const (
+	manifestKey   = "manifest"
+	fileKeyPrefix = "file:"
+	// Files larger than this are not indexed
+	maxIndexFileSize = 256 * 1024
+	// Files summarized per AI request
+	summaryBatchSize = 20
+	// How much of each file the summarizer sees
+	excerptLines    = 60
+	maxExcerptBytes = 3000
+	// Exported symbols kept per file
+	maxIndexSymbols = 40
+)
+
+// FileEntry is the index record of one file version. Entries are keyed by blob hash,
+// so identical content is indexed once and renames cost nothing.
+type FileEntry struct {
+	Blob      string    `json:"blob"`
+	Summary   string    `json:"summary"`
+	Symbols   []string  `json:"symbols,omitempty"` // Exported symbols, e.g. "func ParseDiff"
+	Imports   []string  `json:"imports,omitempty"` // Import specifiers as written
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+// Manifest maps the indexed tree to its entries
+type Manifest struct {
+	Head      string              `json:"head"`
+	Files     map[string]string   `json:"files"`             // Path to blob hash
+	Skipped   map[string]string   `json:"skipped,omitempty"` // Binary, noisy and oversized files, path to blob hash
+	Deps      map[string][]string `json:"deps,omitempty"`    // Path to the repository files it imports
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// SummaryRequest describes a file for the summarizer
+type SummaryRequest struct {
+	Path    string
+	Symbols []string
+	Imports []string
+	Excerpt string
+}
+
+// Summarizer returns a one-sentence summary for each requested path. Paths missing
+// from the result fall back to a summary derived from the file itself.
+type Summarizer func(ctx c.Context, files []SummaryRequest) (map[string]string, error)
+
+// IndexStats reports what an index update did
+type IndexStats struct {
+	Indexed   int
+	Unchanged int
+	Skipped   int
+	Removed   int
+}
+
+// FileIndex is the per-file index stored in the repository's .git directory
+type FileIndex struct {
+	cache *cache.Cache
+}
+
+// fileIndexPath is where the file index of a repository lives
+func fileIndexPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".git", "quill-index")
+}
+
+// FileIndexExists reports whether 'quill index' has built a file index for the repository
+func FileIndexExists(repoRoot string) bool {
+	_, err := os.Stat(fileIndexPath(repoRoot))
+	return err == nil
+}
+
+// OpenFileIndex opens the file index of the repository at repoRoot, creating it if needed
+func OpenFileIndex(repoRoot string) (*FileIndex, error) {
+	store, err := cache.NewCacheWithPath(fileIndexPath(repoRoot))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file index: %w", err)
+	}
+	return &FileIndex{cache: store}, nil
+}
+
+// Close releases the underlying store
+func (ix *FileIndex) Close() error {
+	return ix.cache.Close()
+}
+
+// Manifest returns the indexed tree, or nil when nothing has been indexed yet
+func (ix *FileIndex) Manifest() (*Manifest, error) {
+	var m Manifest
+	if err := ix.cache.Get(manifestKey, &m); err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Entry returns the record of a blob, or nil when it is not indexed
+func (ix *FileIndex) Entry(blob string) (*FileEntry, error) {
+	var entry FileEntry
+	if err := ix.cache.Get(fileKeyPrefix+blob, &entry); err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Update brings the index in line with the repository's index (the staging area).
+// Only files whose blob has no entry yet are read and summarized; force re-indexes
+// everything. A nil summarizer uses the fallback summaries only.
+func (ix *FileIndex) Update(ctx c.Context, repo *git.Repository, summarize Summarizer, force bool) (*IndexStats, error) {
+	blobs, err := repo.TrackedBlobs()
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	previous, err := ix.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		previous = &Manifest{}
+	}
+	old := previous
+	if force {
+		old = &Manifest{}
+	}
+
+	filter, err := repo.NoiseFilter()
+	if err != nil {
+		debug.Log("Warning: Failed to load noise filter: %v", err)
+	}
+
+	manifest := &Manifest{
+		Head:    head,
+		Files:   make(map[string]string),
+		Skipped: make(map[string]string),
+	}
+	stats := &IndexStats{}
+	entries := make(map[string]*FileEntry) // By path
+	var pending []*FileEntry
+	var requests []SummaryRequest
+
+	paths := make([]string, 0, len(blobs))
+	for p := range blobs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		blob := blobs[p]
+		if old.Skipped[p] == blob {
+			manifest.Skipped[p] = blob
+			stats.Skipped++
+			continue
+		}
+		if !force {
+			entry, err := ix.Entry(blob)
+			if err != nil {
+				return nil, err
+			}
+			if entry != nil {
+				manifest.Files[p] = blob
+				entries[p] = entry
+				stats.Unchanged++
+				continue
+			}
+		}
+
+		content, err := repo.BlobContent(blob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		if !indexable(filter, p, content) {
+			manifest.Skipped[p] = blob
+			stats.Skipped++
+			continue
+		}
+
+		entry := &FileEntry{Blob: blob, IndexedAt: time.Now()}
+		if SupportsSymbols(p) {
+			analysis, err := AnalyzeFile(p, content)
+			if err != nil {
+				debug.Log("Skipping symbols for %s: %v", p, err)
+			} else {
+				entry.Symbols = exportedSymbols(analysis.Symbols)
+				entry.Imports = analysis.Imports
+			}
+		}
+		entry.Summary = FallbackSummary(content, entry.Symbols)
+
+		manifest.Files[p] = blob
+		entries[p] = entry
+		pending = append(pending, entry)
+		requests = append(requests, SummaryRequest{
+			Path:    p,
+			Symbols: entry.Symbols,
+			Imports: entry.Imports,
+			Excerpt: excerpt(content),
+		})
+	}
+
+	if summarize != nil {
+		for start := 0; start < len(requests); start += summaryBatchSize {
+			end := min(start+summaryBatchSize, len(requests))
+			summaries, err := summarize(ctx, requests[start:end])
+			if err != nil {
+				return nil, fmt.Errorf("failed to summarize files: %w", err)
+			}
+			for i := start; i < end; i++ {
+				if summary := strings.TrimSpace(summaries[requests[i].Path]); summary != "" {
+					pending[i].Summary = summary
+				}
+			}
+		}
+	}
+
+	for _, entry := range pending {
+		if err := ix.cache.SetWithTTL(fileKeyPrefix+entry.Blob, entry, 0); err != nil {
+			return nil, err
+		}
+	}
+	stats.Indexed = len(pending)
+
+	manifest.Deps = resolveDeps(repo, blobs, entries)
+	manifest.UpdatedAt = time.Now()
+	if err := ix.cache.SetWithTTL(manifestKey, manifest, 0); err != nil {
+		return nil, err
+	}
+
+	// Drop entries no file refers to anymore
+	live := make(map[string]bool, len(manifest.Files))
+	for _, blob := range manifest.Files {
+		live[blob] = true
+	}
+	for _, blob := range previous.Files {
+		if !live[blob] {
+			live[blob] = true
+			if err := ix.cache.Delete(fileKeyPrefix + blob); err != nil {
+				return nil, err
+			}
+			stats.Removed++
+		}
+	}
+
+	debug.Log("File index updated: %d indexed, %d unchanged, %d skipped, %d removed",
+		stats.Indexed, stats.Unchanged, stats.Skipped, stats.Removed)
+	return stats, nil
+}
+
+// Context returns one prompt line per touched file followed by the files that
+// import them, e.g. "internal/git/diff.go: Parses unified diffs" and
+// "internal/cmd/generate.go (imports internal/git/diff.go): Runs the generate command".
+// Files missing from the index are left out and at most limit lines are returned.
+func (ix *FileIndex) Context(touched []string, limit int) ([]string, error) {
+	manifest, err := ix.Manifest()
+	if err != nil || manifest == nil {
+		return nil, err
+	}
+
+	dependents := make(map[string][]string)
+	for file, deps := range manifest.Deps {
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], file)
+		}
+	}
+
+	isTouched := make(map[string]bool, len(touched))
+	for _, p := range touched {
+		isTouched[p] = true
+	}
+
+	var lines []string
+	add := func(p, label string) error {
+		blob, ok := manifest.Files[p]
+		if !ok {
+			return nil
+		}
+		entry, err := ix.Entry(blob)
+		if err != nil || entry == nil || entry.Summary == "" {
+			return err
+		}
+		lines = append(lines, label+": "+entry.Summary)
+		return nil
+	}
+
+	for _, p := range touched {
+		if len(lines) >= limit {
+			return lines, nil
+		}
+		if err := add(p, p); err != nil {
+			return nil, err
+		}
+	}
+
+	listed := make(map[string]bool)
+	for _, p := range touched {
+		users := dependents[p]
+		sort.Strings(users)
+		for _, user := range users {
+			if len(lines) >= limit {
+				return lines, nil
+			}
+			if isTouched[user] || listed[user] {
+				continue
+			}
+			listed[user] = true
+			if err := add(user, fmt.Sprintf("%s (imports %s)", user, p)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return lines, nil
+}
+
+// resolveDeps maps each indexed file to the repository files it imports
+func resolveDeps(repo *git.Repository, blobs map[string]string, entries map[string]*FileEntry) map[string][]string {
+	files := make([]string, 0, len(blobs))
+	for p := range blobs {
+		files = append(files, p)
+	}
+	resolver := NewResolver(files, func(p string) ([]byte, error) {
+		return repo.BlobContent(blobs[p])
+	})
+
+	imports := make(map[string][]string, len(entries))
+	for p, entry := range entries {
+		imports[p] = entry.Imports
+	}
+	return NewImportGraph(resolver, imports).imports
+}
+
+// indexable reports whether a file is worth indexing: text, not too large, and not
+// a lockfile, generated or vendored file
+func indexable(filter *git.NoiseFilter, p string, content []byte) bool {
+	if len(content) > maxIndexFileSize || bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		return false
+	}
+	if filter != nil {
+		if _, noisy := filter.ClassifyFile(p, content); noisy {
+			return false
+		}
+	}
+	return true
+}
+
+// exportedSymbols renders the exported symbols of a file as "kind name"
+func exportedSymbols(symbols []Symbol) []string {
+	var names []string
+	for _, sym := range symbols {
+		if sym.Exported && len(names) < maxIndexSymbols {
+			names = append(names, string(sym.Kind)+" "+sym.Name)
+		}
+	}
+	return names
+}
+
+// excerpt returns the head of a file for the summarizer
+func excerpt(content []byte) string {
+	lines := strings.SplitN(string(content), "\n", excerptLines+1)
+	text := strings.Join(lines[:min(len(lines), excerptLines)], "\n")
+	if len(text) > maxExcerptBytes {
+		// Cut at a rune boundary so the excerpt stays valid UTF-8
+		cut := maxExcerptBytes
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+	return text
+}
+
+// FallbackSummary describes a file without AI: the first sentence of its leading
+// comment, or else the symbols it exports
+func FallbackSummary(content []byte, symbols []string) string {
+	var comment []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" && len(comment) == 0,
+			strings.HasPrefix(line, "#!"),
+			strings.HasPrefix(line, "package ") && len(comment) == 0:
+			continue
+		case strings.HasPrefix(line, "//"), strings.HasPrefix(line, "#"),
+			strings.HasPrefix(line, "/*"), strings.HasPrefix(line, "*"),
+			strings.HasPrefix(line, `"""`):
+			text := strings.TrimLeft(line, "/#*! ")
+			text = strings.TrimSpace(strings.Trim(text, `"`))
+			if text != "" {
+				comment = append(comment, text)
+			}
+			continue
+		}
+		break
+	}
+
+	if text := strings.Join(comment, " "); text != "" {
+		if i := strings.Index(text, ". "); i >= 0 {
+			text = text[:i]
+		}
+		return truncate(strings.TrimSuffix(text, "."), 160)
+	}
+	if len(symbols) > 0 {
+		return "Defines " + strings.Join(symbols[:min(len(symbols), 5)], ", ")
+	}
+	return ""
+}
+
+// ParseFileSummaries reads "path: summary" lines from a summarizer response,
+// keeping only the requested paths
+func ParseFileSummaries(response string, requested []SummaryRequest) map[string]string {
+	wanted := make(map[string]bool, len(requested))
+	for _, req := range requested {
+		wanted[req.Path] = true
+	}
+
+	summaries := make(map[string]string)
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimLeft(strings.TrimSpace(line), "-*• ")
+		file, summary, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		file = path.Clean(strings.Trim(file, "`*\"' "))
+		if wanted[file] {
+			summaries[file] = strings.TrimSpace(summary)
+		}
+	}
+	return summaries
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.TrimSpace(s[:n]) + "..."
+}
@@ -0,0 +1,287 @@
+package context
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+// Packages named in a prompt before the rest are counted instead
+const maxPromptPackages = 10
+
+// ImportGraph records which repository files import which
+type ImportGraph struct {
+	imports   map[string][]string // File to the repository files it imports
+	importers map[string][]string // File to the repository files importing it
+	external  map[string][]string // File to the imports that resolved outside the repository
+	resolver  *Resolver
+}
+
+// NewImportGraph resolves the imports of each file, given as specifiers keyed by path
+func NewImportGraph(resolver *Resolver, imports map[string][]string) *ImportGraph {
+	g := &ImportGraph{
+		imports:   make(map[string][]string),
+		importers: make(map[string][]string),
+		external:  make(map[string][]string),
+		resolver:  resolver,
+	}
+
+	for file, specs := range imports {
+		seen := make(map[string]bool)
+		for _, spec := range specs {
+			targets := resolver.Resolve(file, spec)
+			if len(targets) == 0 {
+				g.external[file] = append(g.external[file], spec)
+			}
+			for _, target := range targets {
+				if target != file && !seen[target] {
+					seen[target] = true
+					g.imports[file] = append(g.imports[file], target)
+					g.importers[target] = append(g.importers[target], file)
+				}
+			}
+		}
+	}
+
+	for _, edges := range []map[string][]string{g.imports, g.importers} {
+		for _, files := range edges {
+			sort.Strings(files)
+		}
+	}
+	return g
+}
+
+// BuildImportGraph parses the imports of every tracked source file. Imports already
+// recorded in the file index are reused; index may be nil.
+func BuildImportGraph(repo *git.Repository, index *FileIndex) (*ImportGraph, error) {
+	blobs, err := repo.TrackedBlobs()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(blobs))
+	imports := make(map[string][]string)
+	for p, blob := range blobs {
+		files = append(files, p)
+		if !SupportsSymbols(p) {
+			continue
+		}
+
+		if index != nil {
+			entry, err := index.Entry(blob)
+			if err != nil {
+				return nil, err
+			}
+			if entry != nil {
+				imports[p] = entry.Imports
+				continue
+			}
+		}
+
+		content, err := repo.BlobContent(blob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		if len(content) > maxIndexFileSize {
+			continue
+		}
+		analysis, err := AnalyzeFile(p, content)
+		if err != nil {
+			debug.Log("Skipping imports of %s: %v", p, err)
+			continue
+		}
+		imports[p] = analysis.Imports
+	}
+
+	resolver := NewResolver(files, func(p string) ([]byte, error) {
+		return repo.BlobContent(blobs[p])
+	})
+	return NewImportGraph(resolver, imports), nil
+}
+
+// Imports returns the repository files a file imports
+func (g *ImportGraph) Imports(file string) []string {
+	return g.imports[file]
+}
+
+// Importers returns the repository files that import a file
+func (g *ImportGraph) Importers(file string) []string {
+	return g.importers[file]
+}
+
+// Package names the package a file belongs to: the import path of its directory for
+// Go, the name in the nearest package.json or Cargo.toml otherwise, falling back to
+// the directory
+func (g *ImportGraph) Package(file string) string {
+	dir := path.Dir(file)
+	var best *PackageManifest
+	for _, m := range g.resolver.Manifests() {
+		if m.Name == "" || (m.Kind == ManifestGoMod) != strings.HasSuffix(file, ".go") {
+			continue
+		}
+		if m.Dir() != "." && dir != m.Dir() && !strings.HasPrefix(dir, m.Dir()+"/") {
+			continue
+		}
+		if best == nil || len(m.Dir()) > len(best.Dir()) {
+			best = m
+		}
+	}
+
+	switch {
+	case best == nil:
+		return dir
+	case best.Kind == ManifestGoMod:
+		rel := strings.TrimPrefix(strings.TrimPrefix(dir, best.Dir()), "/")
+		if best.Dir() == "." {
+			rel = dir
+		}
+		if rel == "." || rel == "" {
+			return best.Name
+		}
+		return best.Name + "/" + rel
+	}
+	return best.Name
+}
+
+// Dependent is a file that imports a changed file, directly or through other files
+type Dependent struct {
+	Path  string
+	Via   string // The file it imports on the way to the change
+	Depth int    // 1 for direct importers
+}
+
+// DependencyUsage is a changed dependency and the files that import it
+type DependencyUsage struct {
+	Change DependencyChange
+	Users  []string
+}
+
+// Impact is what depends on a set of changes
+type Impact struct {
+	Changed      []string
+	Dependents   []Dependent
+	Packages     []string // Packages of the dependents, other than the changed files' own
+	Dependencies []DependencyUsage
+}
+
+// Impact walks the importers of the changed files up to depth levels, or all the
+// way when depth is zero, and finds the users of changed dependencies
+func (g *ImportGraph) Impact(changed []string, dependencies []DependencyChange, depth int) *Impact {
+	impact := &Impact{Changed: changed}
+
+	visited := make(map[string]bool, len(changed))
+	changedPackages := make(map[string]bool)
+	for _, file := range changed {
+		visited[file] = true
+		changedPackages[g.Package(file)] = true
+	}
+
+	level := append([]string(nil), changed...)
+	for d := 1; len(level) > 0 && (depth <= 0 || d <= depth); d++ {
+		var next []string
+		for _, file := range level {
+			for _, importer := range g.importers[file] {
+				if visited[importer] {
+					continue
+				}
+				visited[importer] = true
+				impact.Dependents = append(impact.Dependents, Dependent{Path: importer, Via: file, Depth: d})
+				next = append(next, importer)
+			}
+		}
+		level = next
+	}
+
+	packages := make(map[string]bool)
+	for _, dep := range impact.Dependents {
+		if pkg := g.Package(dep.Path); !changedPackages[pkg] && !packages[pkg] {
+			packages[pkg] = true
+			impact.Packages = append(impact.Packages, pkg)
+		}
+	}
+	sort.Strings(impact.Packages)
+
+	for _, change := range dependencies {
+		impact.Dependencies = append(impact.Dependencies, DependencyUsage{
+			Change: change,
+			Users:  g.DependencyUsers(change),
+		})
+	}
+	return impact
+}
+
+// DependencyUsers lists the files governed by a manifest that import one of its
+// dependencies
+func (g *ImportGraph) DependencyUsers(change DependencyChange) []string {
+	var manifest *PackageManifest
+	for _, m := range g.resolver.Manifests() {
+		if m.Path == change.Manifest {
+			manifest = m
+		}
+	}
+	if manifest == nil {
+		// A deleted manifest is not in the tree anymore; its format is all we need
+		kind, _ := ManifestKindOf(change.Manifest)
+		manifest = &PackageManifest{Path: change.Manifest, Kind: kind}
+	}
+
+	var users []string
+	for file, specs := range g.external {
+		if manifest.Dir() != "." && !strings.HasPrefix(file, manifest.Dir()+"/") {
+			continue
+		}
+		for _, spec := range specs {
+			if manifest.Imports(change.Name, spec) {
+				users = append(users, file)
+				break
+			}
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// Summarize renders the impact as prompt lines, e.g.
+// "Callers in internal/cmd, internal/providers depend on the changed files"
+func (i *Impact) Summarize() []string {
+	var lines []string
+	switch {
+	case len(i.Packages) > 0:
+		named := strings.Join(i.Packages[:min(len(i.Packages), maxPromptPackages)], ", ")
+		if extra := len(i.Packages) - maxPromptPackages; extra > 0 {
+			named += fmt.Sprintf(" and %d more packages", extra)
+		}
+		lines = append(lines, fmt.Sprintf("Callers in %s depend on the changed files", named))
+	case len(i.Dependents) > 0:
+		lines = append(lines, fmt.Sprintf("%s import the changed files", listFiles(dependentPaths(i.Dependents))))
+	}
+
+	for _, usage := range i.Dependencies {
+		line := usage.Change.String()
+		if len(usage.Users) > 0 {
+			line += ", imported by " + listFiles(usage.Users)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func dependentPaths(dependents []Dependent) []string {
+	paths := make([]string, len(dependents))
+	for i, dep := range dependents {
+		paths[i] = dep.Path
+	}
+	return paths
+}
+
+// listFiles names up to three files and counts the rest
+func listFiles(files []string) string {
+	if len(files) > 3 {
+		return fmt.Sprintf("%s and %d more files", strings.Join(files[:3], ", "), len(files)-3)
+	}
+	return strings.Join(files, ", ")
+}
@@ -0,0 +1,88 @@
+//go:build !cgo
+
+package context
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	goImport      = regexp.MustCompile(`^\s*import\s+(?:[\w.]+\s+)?"([^"]+)"`)
+	goImportBlock = regexp.MustCompile(`^\s*import\s*\(`)
+	goImportSpec  = regexp.MustCompile(`^\s*(?:[\w.]+\s+)?"([^"]+)"`)
+	scriptImports = []*regexp.Regexp{
+		regexp.MustCompile(`(?:^|[^\w$.])(?:import|export)\b[^'"]*?\bfrom\s*['"]([^'"]+)['"]`),
+		regexp.MustCompile(`^\s*import\s*['"]([^'"]+)['"]`),
+		regexp.MustCompile(`(?:^|[^\w$.])(?:require|import)\s*\(\s*['"]([^'"]+)['"]\s*\)`),
+	}
+	pythonImport     = regexp.MustCompile(`^\s*import\s+([^#]+)`)
+	pythonImportFrom = regexp.MustCompile(`^\s*from\s+(\S+)\s+import\b`)
+	rustUse          = regexp.MustCompile(rustVisibility + `use\s+([^;]+);`)
+	rustMod          = regexp.MustCompile(rustVisibility + `mod\s+(\w+)\s*;`)
+	javaImport       = regexp.MustCompile(`^\s*import\s+(?:static\s+)?([\w.*\s]+);`)
+)
+
+// scanImports returns the import specifiers of a file in source order, without
+// duplicates
+func scanImports(lang *language, lines []string) []string {
+	var imports []string
+	seen := make(map[string]bool)
+	add := func(spec string) {
+		spec = strings.TrimSpace(spec)
+		if spec != "" && !seen[spec] {
+			seen[spec] = true
+			imports = append(imports, spec)
+		}
+	}
+
+	block := false // Inside a Go import ( ... ) block
+	for _, line := range lines {
+		switch lang.family {
+		case familyGo:
+			switch {
+			case block && strings.HasPrefix(strings.TrimSpace(line), ")"):
+				block = false
+			case block:
+				if m := goImportSpec.FindStringSubmatch(line); m != nil {
+					add(m[1])
+				}
+			case goImportBlock.MatchString(line):
+				block = true
+			default:
+				if m := goImport.FindStringSubmatch(line); m != nil {
+					add(m[1])
+				}
+			}
+		case familyJavaScript:
+			for _, pattern := range scriptImports {
+				for _, m := range pattern.FindAllStringSubmatch(line, -1) {
+					add(m[1])
+				}
+			}
+		case familyPython:
+			if m := pythonImportFrom.FindStringSubmatch(line); m != nil {
+				add(m[1])
+			} else if m := pythonImport.FindStringSubmatch(line); m != nil {
+				for _, name := range strings.Split(m[1], ",") {
+					// import pkg.util as u
+					if fields := strings.Fields(name); len(fields) > 0 {
+						add(fields[0])
+					}
+				}
+			}
+		case familyRust:
+			if m := rustUse.FindStringSubmatch(line); m != nil {
+				add(m[1])
+			} else if m := rustMod.FindStringSubmatch(line); m != nil {
+				// "mod foo;" pulls in foo.rs or foo/mod.rs
+				add("mod " + m[1])
+			}
+		case familyJava:
+			if m := javaImport.FindStringSubmatch(line); m != nil {
+				add(strings.Join(strings.Fields(m[1]), ""))
+			}
+		}
+	}
+	return imports
+}
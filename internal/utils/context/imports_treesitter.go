@@ -0,0 +1,106 @@
+//go:build cgo
+
+package context
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// extractImports returns the import specifiers of a parsed file in source order,
+// without duplicates
+func extractImports(lang *language, root *sitter.Node, src []byte) []string {
+	var imports []string
+	seen := make(map[string]bool)
+	add := func(spec string) {
+		spec = strings.TrimSpace(spec)
+		if spec != "" && !seen[spec] {
+			seen[spec] = true
+			imports = append(imports, spec)
+		}
+	}
+
+	var visit func(node *sitter.Node)
+	visit = func(node *sitter.Node) {
+		switch lang.family {
+		case familyGo:
+			if node.Type() == "import_spec" {
+				if path := node.ChildByFieldName("path"); path != nil {
+					add(unquote(path.Content(src)))
+				}
+				return
+			}
+		case familyJavaScript:
+			switch node.Type() {
+			case "import_statement", "export_statement":
+				if source := node.ChildByFieldName("source"); source != nil {
+					add(unquote(source.Content(src)))
+				}
+			case "call_expression":
+				// require("x") and dynamic import("x")
+				fn := node.ChildByFieldName("function")
+				args := node.ChildByFieldName("arguments")
+				if fn != nil && args != nil && args.NamedChildCount() > 0 {
+					name := fn.Content(src)
+					if arg := args.NamedChild(0); (name == "require" || name == "import") && arg.Type() == "string" {
+						add(unquote(arg.Content(src)))
+					}
+				}
+			}
+		case familyPython:
+			switch node.Type() {
+			case "import_statement":
+				for i := 0; i < int(node.NamedChildCount()); i++ {
+					child := node.NamedChild(i)
+					if child.Type() == "aliased_import" {
+						child = child.ChildByFieldName("name")
+					}
+					if child != nil {
+						add(child.Content(src))
+					}
+				}
+				return
+			case "import_from_statement":
+				if module := node.ChildByFieldName("module_name"); module != nil {
+					add(module.Content(src))
+				}
+				return
+			}
+		case familyRust:
+			switch node.Type() {
+			case "use_declaration":
+				if arg := node.ChildByFieldName("argument"); arg != nil {
+					add(arg.Content(src))
+				}
+				return
+			case "mod_item":
+				// "mod foo;" pulls in foo.rs or foo/mod.rs
+				if node.ChildByFieldName("body") == nil {
+					if name := node.ChildByFieldName("name"); name != nil {
+						add("mod " + name.Content(src))
+					}
+				}
+			}
+		case familyJava:
+			if node.Type() == "import_declaration" {
+				text := strings.TrimSuffix(strings.TrimSpace(node.Content(src)), ";")
+				text = strings.TrimPrefix(text, "import")
+				text = strings.TrimPrefix(strings.TrimSpace(text), "static")
+				add(strings.Join(strings.Fields(text), ""))
+				return
+			}
+		}
+
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			visit(node.NamedChild(i))
+		}
+	}
+	visit(root)
+	return imports
+}
+
+// unquote strips the quotes around a string literal
+func unquote(s string) string {
+	return strings.Trim(s, "\"'`")
+}
@@ -0,0 +1,137 @@
+package context
+
+import (
+	"path"
+	"strings"
+)
+
+// LanguageType groups languages the way GitHub linguist does. Only programming and
+// markup languages count towards a repository's language breakdown.
+type LanguageType string
+
+const (
+	LanguageProgramming LanguageType = "programming"
+	LanguageMarkup      LanguageType = "markup"
+	LanguageData        LanguageType = "data"
+	LanguageProse       LanguageType = "prose"
+)
+
+type languageInfo struct {
+	name string
+	typ  LanguageType
+}
+
+// languagesByExtension maps lowercase file extensions to languages
+var languagesByExtension = map[string]languageInfo{
+	".go":     {"Go", LanguageProgramming},
+	".js":     {"JavaScript", LanguageProgramming},
+	".mjs":    {"JavaScript", LanguageProgramming},
+	".cjs":    {"JavaScript", LanguageProgramming},
+	".jsx":    {"JavaScript", LanguageProgramming},
+	".ts":     {"TypeScript", LanguageProgramming},
+	".mts":    {"TypeScript", LanguageProgramming},
+	".cts":    {"TypeScript", LanguageProgramming},
+	".tsx":    {"TSX", LanguageProgramming},
+	".py":     {"Python", LanguageProgramming},
+	".pyi":    {"Python", LanguageProgramming},
+	".rb":     {"Ruby", LanguageProgramming},
+	".java":   {"Java", LanguageProgramming},
+	".kt":     {"Kotlin", LanguageProgramming},
+	".kts":    {"Kotlin", LanguageProgramming},
+	".scala":  {"Scala", LanguageProgramming},
+	".groovy": {"Groovy", LanguageProgramming},
+	".c":      {"C", LanguageProgramming},
+	".h":      {"C", LanguageProgramming},
+	".cc":     {"C++", LanguageProgramming},
+	".cpp":    {"C++", LanguageProgramming},
+	".cxx":    {"C++", LanguageProgramming},
+	".hpp":    {"C++", LanguageProgramming},
+	".hh":     {"C++", LanguageProgramming},
+	".cs":     {"C#", LanguageProgramming},
+	".fs":     {"F#", LanguageProgramming},
+	".rs":     {"Rust", LanguageProgramming},
+	".swift":  {"Swift", LanguageProgramming},
+	".m":      {"Objective-C", LanguageProgramming},
+	".mm":     {"Objective-C++", LanguageProgramming},
+	".php":    {"PHP", LanguageProgramming},
+	".pl":     {"Perl", LanguageProgramming},
+	".pm":     {"Perl", LanguageProgramming},
+	".lua":    {"Lua", LanguageProgramming},
+	".r":      {"R", LanguageProgramming},
+	".jl":     {"Julia", LanguageProgramming},
+	".dart":   {"Dart", LanguageProgramming},
+	".ex":     {"Elixir", LanguageProgramming},
+	".exs":    {"Elixir", LanguageProgramming},
+	".erl":    {"Erlang", LanguageProgramming},
+	".hs":     {"Haskell", LanguageProgramming},
+	".ml":     {"OCaml", LanguageProgramming},
+	".clj":    {"Clojure", LanguageProgramming},
+	".zig":    {"Zig", LanguageProgramming},
+	".nim":    {"Nim", LanguageProgramming},
+	".sh":     {"Shell", LanguageProgramming},
+	".bash":   {"Shell", LanguageProgramming},
+	".zsh":    {"Shell", LanguageProgramming},
+	".fish":   {"Shell", LanguageProgramming},
+	".ps1":    {"PowerShell", LanguageProgramming},
+	".sql":    {"SQL", LanguageData},
+	".vue":    {"Vue", LanguageMarkup},
+	".svelte": {"Svelte", LanguageMarkup},
+	".html":   {"HTML", LanguageMarkup},
+	".htm":    {"HTML", LanguageMarkup},
+	".css":    {"CSS", LanguageMarkup},
+	".scss":   {"SCSS", LanguageMarkup},
+	".sass":   {"Sass", LanguageMarkup},
+	".less":   {"Less", LanguageMarkup},
+	".tf":     {"HCL", LanguageProgramming},
+	".hcl":    {"HCL", LanguageProgramming},
+	".proto":  {"Protocol Buffer", LanguageData},
+	".json":   {"JSON", LanguageData},
+	".yml":    {"YAML", LanguageData},
+	".yaml":   {"YAML", LanguageData},
+	".toml":   {"TOML", LanguageData},
+	".xml":    {"XML", LanguageData},
+	".csv":    {"CSV", LanguageData},
+	".md":     {"Markdown", LanguageProse},
+	".mdx":    {"MDX", LanguageProse},
+	".rst":    {"reStructuredText", LanguageProse},
+	".txt":    {"Text", LanguageProse},
+	".adoc":   {"AsciiDoc", LanguageProse},
+}
+
+// languagesByName maps file names without a telling extension to languages
+var languagesByName = map[string]languageInfo{
+	"makefile":       {"Makefile", LanguageProgramming},
+	"gnumakefile":    {"Makefile", LanguageProgramming},
+	"dockerfile":     {"Dockerfile", LanguageProgramming},
+	"cmakelists.txt": {"CMake", LanguageProgramming},
+	"justfile":       {"Just", LanguageProgramming},
+	"rakefile":       {"Ruby", LanguageProgramming},
+	"gemfile":        {"Ruby", LanguageProgramming},
+}
+
+// DetectLanguage names the language of a file from its name
+func DetectLanguage(p string) (string, LanguageType, bool) {
+	base := strings.ToLower(path.Base(p))
+	if info, ok := languagesByName[base]; ok {
+		return info.name, info.typ, true
+	}
+	if strings.HasPrefix(base, "dockerfile.") || strings.HasSuffix(base, ".dockerfile") {
+		return "Dockerfile", LanguageProgramming, true
+	}
+	if info, ok := languagesByExtension[path.Ext(base)]; ok {
+		return info.name, info.typ, true
+	}
+	return "", "", false
+}
+
+// isDocumentation reports whether a path lies in a documentation directory, which
+// linguist leaves out of language statistics
+func isDocumentation(p string) bool {
+	for _, part := range strings.Split(path.Dir(p), "/") {
+		switch strings.ToLower(part) {
+		case "docs", "doc", "documentation", "examples", "example":
+			return true
+		}
+	}
+	return false
+}
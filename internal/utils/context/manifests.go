@@ -0,0 +1,252 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/mod/modfile"
+)
+
+// ManifestKind identifies a package manifest format
+type ManifestKind string
+
+const (
+	ManifestGoMod        ManifestKind = "go.mod"
+	ManifestPackageJSON  ManifestKind = "package.json"
+	ManifestCargo        ManifestKind = "Cargo.toml"
+	ManifestRequirements ManifestKind = "requirements.txt"
+)
+
+// PackageManifest is the name and declared dependencies of a package
+type PackageManifest struct {
+	Path         string
+	Kind         ManifestKind
+	Name         string            // Module, package or crate name; empty for requirements files
+	Dependencies map[string]string // Dependency name to version constraint
+}
+
+// Dir returns the directory the manifest governs
+func (m *PackageManifest) Dir() string {
+	return path.Dir(m.Path)
+}
+
+// ManifestKindOf returns the manifest format of a path, if it is one
+func ManifestKindOf(p string) (ManifestKind, bool) {
+	base := path.Base(p)
+	switch {
+	case base == "go.mod":
+		return ManifestGoMod, true
+	case base == "package.json":
+		return ManifestPackageJSON, true
+	case base == "Cargo.toml":
+		return ManifestCargo, true
+	case strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt"):
+		return ManifestRequirements, true
+	}
+	return "", false
+}
+
+// ParseManifest reads a package manifest
+func ParseManifest(p string, content []byte) (*PackageManifest, error) {
+	kind, ok := ManifestKindOf(p)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a package manifest", p)
+	}
+
+	m := &PackageManifest{Path: p, Kind: kind, Dependencies: make(map[string]string)}
+	switch kind {
+	case ManifestGoMod:
+		file, err := modfile.ParseLax(p, content, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+		}
+		if file.Module != nil {
+			m.Name = file.Module.Mod.Path
+		}
+		for _, req := range file.Require {
+			m.Dependencies[req.Mod.Path] = req.Mod.Version
+		}
+
+	case ManifestPackageJSON:
+		var pkg struct {
+			Name                 string            `json:"name"`
+			Dependencies         map[string]string `json:"dependencies"`
+			DevDependencies      map[string]string `json:"devDependencies"`
+			PeerDependencies     map[string]string `json:"peerDependencies"`
+			OptionalDependencies map[string]string `json:"optionalDependencies"`
+		}
+		if err := json.Unmarshal(content, &pkg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+		}
+		m.Name = pkg.Name
+		for _, deps := range []map[string]string{pkg.PeerDependencies, pkg.OptionalDependencies, pkg.DevDependencies, pkg.Dependencies} {
+			for name, version := range deps {
+				m.Dependencies[name] = version
+			}
+		}
+
+	case ManifestCargo:
+		var cargo struct {
+			Package struct {
+				Name string `toml:"name"`
+			} `toml:"package"`
+			Dependencies      map[string]any `toml:"dependencies"`
+			DevDependencies   map[string]any `toml:"dev-dependencies"`
+			BuildDependencies map[string]any `toml:"build-dependencies"`
+			Workspace         struct {
+				Dependencies map[string]any `toml:"dependencies"`
+			} `toml:"workspace"`
+		}
+		if err := toml.Unmarshal(content, &cargo); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+		}
+		m.Name = cargo.Package.Name
+		for _, deps := range []map[string]any{cargo.Workspace.Dependencies, cargo.BuildDependencies, cargo.DevDependencies, cargo.Dependencies} {
+			for name, spec := range deps {
+				m.Dependencies[name] = cargoVersion(spec)
+			}
+		}
+
+	case ManifestRequirements:
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if i := strings.Index(line, " #"); i >= 0 {
+				line = strings.TrimSpace(line[:i])
+			}
+			// Comments, options such as -r and -e, and direct URLs name no package
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") || strings.Contains(line, "://") {
+				continue
+			}
+			end := strings.IndexAny(line, "<>=!~;[@ ")
+			if end < 0 {
+				end = len(line)
+			}
+			m.Dependencies[line[:end]] = strings.TrimSpace(line[end:])
+		}
+	}
+	return m, nil
+}
+
+// cargoVersion reads the version of a Cargo dependency, written either as a string
+// or as a table
+func cargoVersion(spec any) string {
+	switch v := spec.(type) {
+	case string:
+		return v
+	case map[string]any:
+		if version, ok := v["version"].(string); ok {
+			return version
+		}
+		if p, ok := v["path"].(string); ok {
+			return "path:" + p
+		}
+		if _, ok := v["workspace"]; ok {
+			return "workspace"
+		}
+	}
+	return ""
+}
+
+// Imports reports whether an import specifier refers to the named dependency
+func (m *PackageManifest) Imports(dependency, spec string) bool {
+	switch m.Kind {
+	case ManifestGoMod, ManifestPackageJSON:
+		return spec == dependency || strings.HasPrefix(spec, dependency+"/")
+	case ManifestCargo:
+		crate, _, _ := strings.Cut(spec, "::")
+		return crate == strings.ReplaceAll(dependency, "-", "_")
+	case ManifestRequirements:
+		module, _, _ := strings.Cut(spec, ".")
+		return strings.EqualFold(module, strings.ReplaceAll(dependency, "-", "_"))
+	}
+	return false
+}
+
+// DependencyChange is a dependency added to, removed from or updated in a manifest
+type DependencyChange struct {
+	Manifest string
+	Name     string
+	Change   ChangeKind // SymbolAdded, SymbolRemoved or SymbolModified
+	From, To string
+}
+
+func (d DependencyChange) String() string {
+	switch d.Change {
+	case SymbolAdded:
+		return fmt.Sprintf("%s: %s added %s", d.Manifest, d.Name, d.To)
+	case SymbolRemoved:
+		return fmt.Sprintf("%s: %s removed", d.Manifest, d.Name)
+	}
+	return fmt.Sprintf("%s: %s updated %s -> %s", d.Manifest, d.Name, d.From, d.To)
+}
+
+// DiffManifests compares the dependencies of two versions of a manifest. Either
+// version may be empty when the manifest was added or deleted.
+func DiffManifests(p string, before, after []byte) ([]DependencyChange, error) {
+	deps := func(content []byte) (map[string]string, error) {
+		if len(content) == 0 {
+			return nil, nil
+		}
+		m, err := ParseManifest(p, content)
+		if err != nil {
+			return nil, err
+		}
+		return m.Dependencies, nil
+	}
+
+	old, err := deps(before)
+	if err != nil {
+		return nil, err
+	}
+	current, err := deps(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []DependencyChange
+	for name, version := range current {
+		previous, existed := old[name]
+		switch {
+		case !existed:
+			changes = append(changes, DependencyChange{Manifest: p, Name: name, Change: SymbolAdded, To: version})
+		case previous != version:
+			changes = append(changes, DependencyChange{Manifest: p, Name: name, Change: SymbolModified, From: previous, To: version})
+		}
+	}
+	for name, version := range old {
+		if _, ok := current[name]; !ok {
+			changes = append(changes, DependencyChange{Manifest: p, Name: name, Change: SymbolRemoved, From: version})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes, nil
+}
+
+// ManifestChanges lists the dependency changes of every manifest in a diff.
+// Manifests that fail to load or parse are skipped.
+func ManifestChanges(diff *git.Diff, versions *git.FileVersions) []DependencyChange {
+	var changes []DependencyChange
+	for _, file := range diff.Files {
+		if _, ok := ManifestKindOf(file.Path); !ok || file.Binary {
+			continue
+		}
+		before, after, err := versions.Read(file)
+		if err != nil {
+			debug.Log("Skipping manifest %s: %v", file.Path, err)
+			continue
+		}
+		fileChanges, err := DiffManifests(file.Path, before, after)
+		if err != nil {
+			debug.Log("Skipping manifest %s: %v", file.Path, err)
+			continue
+		}
+		changes = append(changes, fileChanges...)
+	}
+	return changes
+}
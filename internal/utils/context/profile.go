@@ -0,0 +1,775 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+const (
+	// Top-level modules described in a profile
+	maxProfileModules = 15
+	// Test directories named in a profile
+	maxTestDirs = 3
+	// Workspace packages named in a profile
+	maxProfilePackages = 30
+)
+
+// LanguageShare is a language's share of the source bytes
+type LanguageShare struct {
+	Name    string  `json:"name"`
+	Bytes   int64   `json:"bytes"`
+	Percent float64 `json:"percent"`
+}
+
+// TestLayout describes where a repository keeps its tests
+type TestLayout struct {
+	Files     int      `json:"files"`
+	Dirs      []string `json:"dirs,omitempty"` // Directories holding the most test files
+	Colocated bool     `json:"colocated"`      // Tests sit next to the code they test
+}
+
+// ModuleInfo describes a top-level module of the repository
+type ModuleInfo struct {
+	Path        string `json:"path"`
+	Files       int    `json:"files"`
+	Language    string `json:"language,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// RepoProfile is everything about a repository that can be worked out without AI
+type RepoProfile struct {
+	Files        int             `json:"files"`
+	Directories  int             `json:"directories"`
+	Languages    []LanguageShare `json:"language_bytes,omitempty"`
+	BuildSystems []string        `json:"build_systems,omitempty"`
+	Frameworks   []string        `json:"frameworks,omitempty"`
+	Tests        TestLayout      `json:"tests"`
+	EntryPoints  []string        `json:"entry_points,omitempty"`
+	CI           []string        `json:"ci,omitempty"`
+	License      string          `json:"license,omitempty"`
+	Modules      []ModuleInfo    `json:"modules,omitempty"`
+	Workspace    *Workspace      `json:"workspace,omitempty"` // Packages of a monorepo, nil otherwise
+}
+
+// buildSystemFiles maps file names to the build system they indicate
+var buildSystemFiles = map[string]string{
+	"go.mod":             "Go modules",
+	"package.json":       "npm",
+	"yarn.lock":          "Yarn",
+	"pnpm-lock.yaml":     "pnpm",
+	"bun.lockb":          "Bun",
+	"cargo.toml":         "Cargo",
+	"pyproject.toml":     "pyproject",
+	"setup.py":           "setuptools",
+	"requirements.txt":   "pip",
+	"pipfile":            "Pipenv",
+	"makefile":           "Make",
+	"cmakelists.txt":     "CMake",
+	"meson.build":        "Meson",
+	"build.gradle":       "Gradle",
+	"build.gradle.kts":   "Gradle",
+	"pom.xml":            "Maven",
+	"build.sbt":          "sbt",
+	"workspace":          "Bazel",
+	"workspace.bazel":    "Bazel",
+	"module.bazel":       "Bazel",
+	"gemfile":            "Bundler",
+	"composer.json":      "Composer",
+	"mix.exs":            "Mix",
+	"dockerfile":         "Docker",
+	"docker-compose.yml": "Docker Compose",
+	"compose.yaml":       "Docker Compose",
+	"justfile":           "just",
+	"taskfile.yml":       "Task",
+}
+
+// frameworks maps dependency names, or Go module path prefixes, to frameworks worth
+// naming in a summary
+var frameworks = map[string]string{
+	"github.com/spf13/cobra":             "Cobra",
+	"github.com/charmbracelet/bubbletea": "Bubble Tea",
+	"github.com/gin-gonic/gin":           "Gin",
+	"github.com/labstack/echo":           "Echo",
+	"github.com/gofiber/fiber":           "Fiber",
+	"github.com/go-chi/chi":              "chi",
+	"google.golang.org/grpc":             "gRPC",
+	"gorm.io/gorm":                       "GORM",
+	"react":                              "React",
+	"next":                               "Next.js",
+	"vue":                                "Vue",
+	"nuxt":                               "Nuxt",
+	"svelte":                             "Svelte",
+	"@angular/core":                      "Angular",
+	"express":                            "Express",
+	"fastify":                            "Fastify",
+	"@nestjs/core":                       "NestJS",
+	"electron":                           "Electron",
+	"jest":                               "Jest",
+	"vitest":                             "Vitest",
+	"tailwindcss":                        "Tailwind CSS",
+	"tokio":                              "Tokio",
+	"actix-web":                          "Actix Web",
+	"axum":                               "Axum",
+	"rocket":                             "Rocket",
+	"clap":                               "clap",
+	"bevy":                               "Bevy",
+	"django":                             "Django",
+	"flask":                              "Flask",
+	"fastapi":                            "FastAPI",
+	"pytest":                             "pytest",
+	"numpy":                              "NumPy",
+	"pandas":                             "pandas",
+	"torch":                              "PyTorch",
+	"tensorflow":                         "TensorFlow",
+}
+
+// ciFiles maps paths, or directory prefixes ending in a slash, to CI services
+var ciFiles = []struct{ prefix, name string }{
+	{".github/workflows/", "GitHub Actions"},
+	{".gitlab-ci.yml", "GitLab CI"},
+	{".circleci/", "CircleCI"},
+	{"Jenkinsfile", "Jenkins"},
+	{"azure-pipelines.yml", "Azure Pipelines"},
+	{".travis.yml", "Travis CI"},
+	{"bitbucket-pipelines.yml", "Bitbucket Pipelines"},
+	{".drone.yml", "Drone"},
+	{".buildkite/", "Buildkite"},
+}
+
+// Directories whose children are the real modules, e.g. internal/cmd rather than internal
+var containerDirs = map[string]bool{
+	"src": true, "internal": true, "pkg": true, "lib": true, "cmd": true,
+	"packages": true, "apps": true, "crates": true, "services": true, "libs": true,
+}
+
+// ProfileRepository builds a repository profile from the files in the index
+func ProfileRepository(repo *git.Repository) (*RepoProfile, error) {
+	return ProfilePackage(repo, ".")
+}
+
+// ProfilePackage profiles the files under dir as if it were a repository of its own.
+// Paths in the profile stay relative to the repository root.
+func ProfilePackage(repo *git.Repository, dir string) (*RepoProfile, error) {
+	blobs, err := repo.TrackedBlobs()
+	if err != nil {
+		return nil, err
+	}
+	filter, err := repo.NoiseFilter()
+	if err != nil {
+		debug.Log("Warning: Failed to load noise filter: %v", err)
+	}
+
+	// Detection works on paths relative to the package
+	files := make([]string, 0, len(blobs))
+	for p := range blobs {
+		if rel, ok := relativeTo(p, dir); ok {
+			files = append(files, rel)
+		}
+	}
+	sort.Strings(files)
+	full := func(p string) string { return path.Join(dir, p) }
+	read := func(p string) ([]byte, error) {
+		return repo.BlobContent(blobs[full(p)])
+	}
+
+	profile := &RepoProfile{
+		Files:       len(files),
+		Directories: countDirectories(files),
+	}
+
+	profile.Languages = languageBreakdown(files, func(p string) int64 {
+		if filter != nil {
+			if _, noisy := filter.ClassifyFile(full(p), nil); noisy {
+				return 0
+			}
+		}
+		size, err := repo.BlobSize(blobs[full(p)])
+		if err != nil {
+			debug.Log("Failed to size %s: %v", full(p), err)
+		}
+		return size
+	})
+	profile.BuildSystems = detectBuildSystems(files)
+	profile.Frameworks = detectFrameworks(files, read)
+	profile.Tests = detectTests(files)
+	profile.EntryPoints = detectEntryPoints(files, read)
+	profile.CI = detectCI(files)
+	profile.License = detectLicense(files, read)
+	profile.Modules = describeModules(files, read)
+	if dir == "." {
+		profile.Workspace = DetectWorkspace(files, read)
+		return profile, nil
+	}
+
+	for i, p := range profile.EntryPoints {
+		profile.EntryPoints[i] = full(p)
+	}
+	for i, p := range profile.Tests.Dirs {
+		profile.Tests.Dirs[i] = full(p)
+	}
+	for i := range profile.Modules {
+		profile.Modules[i].Path = full(profile.Modules[i].Path)
+	}
+	return profile, nil
+}
+
+// relativeTo returns p relative to dir, if it lies within it
+func relativeTo(p, dir string) (string, bool) {
+	if dir == "." {
+		return p, true
+	}
+	rel, ok := strings.CutPrefix(p, dir+"/")
+	return rel, ok
+}
+
+// countDirectories counts every directory holding a tracked file, directly or below
+func countDirectories(files []string) int {
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		for dir := path.Dir(file); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	return len(dirs)
+}
+
+// DirectoryTree renders the directories holding tracked files as an indented list,
+// down to maxDepth levels
+func DirectoryTree(files []string, maxDepth int) string {
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		for dir := path.Dir(file); dir != "."; dir = path.Dir(dir) {
+			if strings.Count(dir, "/") < maxDepth {
+				dirs[dir] = true
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Strings(sorted)
+
+	var tree strings.Builder
+	for _, dir := range sorted {
+		// Hidden directories such as .github are left out
+		if strings.HasPrefix(dir, ".") || strings.Contains(dir, "/.") {
+			continue
+		}
+		tree.WriteString(fmt.Sprintf("%s- %s\n", strings.Repeat("  ", strings.Count(dir, "/")), path.Base(dir)))
+	}
+	return tree.String()
+}
+
+// languageBreakdown totals the bytes of programming and markup files per language,
+// leaving out documentation directories and files size reports as zero
+func languageBreakdown(files []string, size func(p string) int64) []LanguageShare {
+	bytes := make(map[string]int64)
+	var total int64
+	for _, file := range files {
+		name, typ, ok := DetectLanguage(file)
+		if !ok || (typ != LanguageProgramming && typ != LanguageMarkup) || isDocumentation(file) {
+			continue
+		}
+		n := size(file)
+		bytes[name] += n
+		total += n
+	}
+	if total == 0 {
+		return nil
+	}
+
+	shares := make([]LanguageShare, 0, len(bytes))
+	for name, n := range bytes {
+		if n == 0 {
+			continue
+		}
+		shares = append(shares, LanguageShare{
+			Name:    name,
+			Bytes:   n,
+			Percent: float64(n) * 100 / float64(total),
+		})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Bytes != shares[j].Bytes {
+			return shares[i].Bytes > shares[j].Bytes
+		}
+		return shares[i].Name < shares[j].Name
+	})
+	return shares
+}
+
+// detectBuildSystems names the build tools whose files appear in the repository
+func detectBuildSystems(files []string) []string {
+	var found []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		name, ok := buildSystemFiles[strings.ToLower(path.Base(file))]
+		if ok && !seen[name] && !isDocumentation(file) {
+			seen[name] = true
+			found = append(found, name)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// detectFrameworks matches the dependencies of every manifest against known frameworks
+func detectFrameworks(files []string, read func(string) ([]byte, error)) []string {
+	var found []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if _, ok := ManifestKindOf(file); !ok {
+			continue
+		}
+		content, err := read(file)
+		if err != nil {
+			continue
+		}
+		manifest, err := ParseManifest(file, content)
+		if err != nil {
+			debug.Log("Failed to parse %s: %v", file, err)
+			continue
+		}
+		for dep := range manifest.Dependencies {
+			for key, name := range frameworks {
+				matches := strings.EqualFold(dep, key)
+				if manifest.Kind == ManifestGoMod {
+					matches = dep == key || strings.HasPrefix(dep, key+"/")
+				}
+				if matches && !seen[name] {
+					seen[name] = true
+					found = append(found, name)
+				}
+			}
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// IsTestFile recognizes test files by the naming conventions of common languages
+func IsTestFile(p string) bool {
+	base := path.Base(p)
+	switch {
+	case strings.HasSuffix(base, "_test.go"),
+		strings.Contains(base, ".test."), strings.Contains(base, ".spec."),
+		strings.HasPrefix(base, "test_") && strings.HasSuffix(base, ".py"),
+		strings.HasSuffix(base, "_test.py"),
+		strings.HasSuffix(base, "Test.java"), strings.HasSuffix(base, "Tests.java"),
+		strings.HasSuffix(base, "_spec.rb"):
+		return true
+	}
+	for _, part := range strings.Split(path.Dir(p), "/") {
+		if part == "__tests__" || (part == "tests" && strings.HasSuffix(base, ".rs")) {
+			return true
+		}
+	}
+	return strings.Contains(p, "src/test/")
+}
+
+// detectTests counts test files and finds where most of them live. Tests are
+// colocated when most test directories also hold non-test code.
+func detectTests(files []string) TestLayout {
+	tests := make(map[string]int)
+	code := make(map[string]bool)
+	layout := TestLayout{}
+	for _, file := range files {
+		dir := path.Dir(file)
+		if IsTestFile(file) {
+			layout.Files++
+			tests[dir]++
+		} else if name, typ, ok := DetectLanguage(file); ok && typ == LanguageProgramming && name != "Shell" {
+			code[dir] = true
+		}
+	}
+
+	dirs := make([]string, 0, len(tests))
+	colocated := 0
+	for dir := range tests {
+		dirs = append(dirs, dir)
+		if code[dir] {
+			colocated += tests[dir]
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if tests[dirs[i]] != tests[dirs[j]] {
+			return tests[dirs[i]] > tests[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
+	layout.Dirs = dirs[:min(len(dirs), maxTestDirs)]
+	layout.Colocated = layout.Files > 0 && colocated*2 > layout.Files
+	return layout
+}
+
+// detectEntryPoints finds main packages, binaries and scripts a program starts from
+func detectEntryPoints(files []string, read func(string) ([]byte, error)) []string {
+	var found []string
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			found = append(found, p)
+		}
+	}
+
+	for _, file := range files {
+		base := path.Base(file)
+		dir := path.Dir(file)
+		switch {
+		case base == "main.go":
+			if content, err := read(file); err == nil && strings.Contains(string(content), "package main") {
+				add(file)
+			}
+		case file == "src/main.rs", strings.HasPrefix(file, "src/bin/") && strings.HasSuffix(file, ".rs"):
+			add(file)
+		case base == "__main__.py", base == "manage.py",
+			(base == "main.py" || base == "app.py") && (dir == "." || dir == "src"):
+			add(file)
+		case base == "main.c", base == "main.cpp", base == "Main.java", base == "Program.cs":
+			add(file)
+		case base == "package.json":
+			content, err := read(file)
+			if err != nil {
+				continue
+			}
+			var pkg struct {
+				Main string          `json:"main"`
+				Bin  json.RawMessage `json:"bin"`
+			}
+			if json.Unmarshal(content, &pkg) != nil {
+				continue
+			}
+			if pkg.Main != "" {
+				add(path.Join(dir, pkg.Main))
+			}
+			var bin string
+			var bins map[string]string
+			if json.Unmarshal(pkg.Bin, &bin) == nil && bin != "" {
+				add(path.Join(dir, bin))
+			} else if json.Unmarshal(pkg.Bin, &bins) == nil {
+				for _, b := range bins {
+					add(path.Join(dir, b))
+				}
+			}
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// detectCI names the CI services configured in the repository with their files
+func detectCI(files []string) []string {
+	byService := make(map[string][]string)
+	var services []string
+	for _, file := range files {
+		for _, ci := range ciFiles {
+			matches := file == ci.prefix
+			if strings.HasSuffix(ci.prefix, "/") {
+				matches = strings.HasPrefix(file, ci.prefix)
+			}
+			if matches {
+				if _, ok := byService[ci.name]; !ok {
+					services = append(services, ci.name)
+				}
+				byService[ci.name] = append(byService[ci.name], file)
+			}
+		}
+	}
+
+	var found []string
+	for _, service := range services {
+		found = append(found, fmt.Sprintf("%s (%s)", service, strings.Join(byService[service], ", ")))
+	}
+	return found
+}
+
+// licenseMarkers identify common licenses by phrases from their text, most specific first
+var licenseMarkers = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 2"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+}
+
+// detectLicense identifies the license file at the repository root
+func detectLicense(files []string, read func(string) ([]byte, error)) string {
+	for _, file := range files {
+		upper := strings.ToUpper(file)
+		if strings.Contains(file, "/") || !(strings.HasPrefix(upper, "LICENSE") || strings.HasPrefix(upper, "LICENCE") || strings.HasPrefix(upper, "COPYING")) {
+			continue
+		}
+		content, err := read(file)
+		if err != nil {
+			continue
+		}
+		text := strings.Join(strings.Fields(string(content)), " ")
+		for _, license := range licenseMarkers {
+			matched := true
+			for _, phrase := range license.phrases {
+				if !strings.Contains(text, phrase) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return license.id
+			}
+		}
+		return "Custom (" + file + ")"
+	}
+	return ""
+}
+
+// describeModules describes each top-level directory, or the children of container
+// directories such as internal and src, from its README or leading doc comment
+func describeModules(files []string, read func(string) ([]byte, error)) []ModuleInfo {
+	members := make(map[string][]string)
+	var order []string
+	for _, file := range files {
+		parts := strings.Split(file, "/")
+		if len(parts) < 2 || strings.HasPrefix(parts[0], ".") || isDocumentation(file) {
+			continue
+		}
+		module := parts[0]
+		if containerDirs[parts[0]] && len(parts) > 2 {
+			module = parts[0] + "/" + parts[1]
+		}
+		if _, ok := members[module]; !ok {
+			order = append(order, module)
+		}
+		members[module] = append(members[module], file)
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return len(members[order[i]]) > len(members[order[j]]) })
+	order = order[:min(len(order), maxProfileModules)]
+	sort.Strings(order)
+
+	modules := make([]ModuleInfo, 0, len(order))
+	for _, module := range order {
+		info := ModuleInfo{Path: module, Files: len(members[module])}
+		languages := languageBreakdown(members[module], func(string) int64 { return 1 })
+		if len(languages) > 0 {
+			info.Language = languages[0].Name
+		}
+		info.Description = moduleDescription(module, members[module], read)
+		modules = append(modules, info)
+	}
+	return modules
+}
+
+// moduleDescription reads the first paragraph of a module's README, or else the first
+// leading comment among its source files, shallowest first
+func moduleDescription(module string, files []string, read func(string) ([]byte, error)) string {
+	sorted := append([]string(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return strings.Count(sorted[i], "/") < strings.Count(sorted[j], "/")
+	})
+
+	for _, file := range sorted {
+		if path.Dir(file) == module && strings.EqualFold(strings.TrimSuffix(path.Base(file), path.Ext(file)), "readme") {
+			if content, err := read(file); err == nil {
+				if text := readmeParagraph(content); text != "" {
+					return text
+				}
+			}
+		}
+	}
+
+	for _, file := range sorted {
+		if !SupportsSymbols(file) || IsTestFile(file) {
+			continue
+		}
+		content, err := read(file)
+		if err != nil || len(content) > maxIndexFileSize {
+			continue
+		}
+		if text := FallbackSummary(content, nil); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// readmeParagraph returns the first prose paragraph of a README, skipping headings,
+// badges and HTML
+func readmeParagraph(content []byte) string {
+	var paragraph []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			if len(paragraph) > 0 {
+				return truncate(strings.Join(paragraph, " "), 200)
+			}
+		case strings.HasPrefix(line, "#"), strings.HasPrefix(line, "!["), strings.HasPrefix(line, "[!["),
+			strings.HasPrefix(line, "<"), strings.HasPrefix(line, "```"), strings.HasPrefix(line, "---"):
+			if len(paragraph) > 0 {
+				return truncate(strings.Join(paragraph, " "), 200)
+			}
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	return truncate(strings.Join(paragraph, " "), 200)
+}
+
+// Lines renders the profile for prompts and the index command, one fact per line
+func (p *RepoProfile) Lines() []string {
+	var lines []string
+	if len(p.Languages) > 0 {
+		var parts []string
+		for _, lang := range p.Languages {
+			if lang.Percent < 1 && len(parts) >= 3 {
+				break
+			}
+			parts = append(parts, fmt.Sprintf("%s %.1f%%", lang.Name, lang.Percent))
+		}
+		lines = append(lines, "Languages: "+strings.Join(parts, ", "))
+	}
+	if len(p.BuildSystems) > 0 {
+		lines = append(lines, "Build systems: "+strings.Join(p.BuildSystems, ", "))
+	}
+	if len(p.Frameworks) > 0 {
+		lines = append(lines, "Frameworks: "+strings.Join(p.Frameworks, ", "))
+	}
+	if p.Tests.Files > 0 {
+		placement := "in separate test directories"
+		if p.Tests.Colocated {
+			placement = "next to the code"
+		}
+		lines = append(lines, fmt.Sprintf("Tests: %d files %s, mostly in %s", p.Tests.Files, placement, strings.Join(p.Tests.Dirs, ", ")))
+	}
+	if len(p.EntryPoints) > 0 {
+		lines = append(lines, "Entry points: "+strings.Join(p.EntryPoints, ", "))
+	}
+	if len(p.CI) > 0 {
+		lines = append(lines, "CI: "+strings.Join(p.CI, "; "))
+	}
+	if p.License != "" {
+		lines = append(lines, "License: "+p.License)
+	}
+	if p.Workspace != nil {
+		lines = append(lines, "Workspace: "+p.Workspace.Line())
+		for _, pkg := range p.Workspace.Packages[:min(len(p.Workspace.Packages), maxProfilePackages)] {
+			lines = append(lines, fmt.Sprintf("- %s (%s)", pkg.Name, pkg.Dir))
+		}
+		if extra := len(p.Workspace.Packages) - maxProfilePackages; extra > 0 {
+			lines = append(lines, fmt.Sprintf("- and %d more packages", extra))
+		}
+	}
+	if len(p.Modules) > 0 {
+		lines = append(lines, "Modules:")
+		for _, m := range p.Modules {
+			line := fmt.Sprintf("- %s (%d files", m.Path, m.Files)
+			if m.Language != "" {
+				line += ", " + m.Language
+			}
+			line += ")"
+			if m.Description != "" {
+				line += ": " + m.Description
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+const (
+	// Files sampled for the AI summary
+	maxKeyFiles = 12
+	// Bytes of each sampled file sent to the AI
+	maxKeyFileBytes = 4000
+)
+
+// SampleKeyFiles picks the files under dir that say the most about it, its README,
+// manifests, entry points and module READMEs, and returns their heads by path
+func SampleKeyFiles(repo *git.Repository, dir string, profile *RepoProfile) (map[string]string, error) {
+	all, err := repo.TrackedBlobs()
+	if err != nil {
+		return nil, err
+	}
+	blobs := make(map[string]string)
+	for p, blob := range all {
+		if _, ok := relativeTo(p, dir); ok {
+			blobs[p] = blob
+		}
+	}
+
+	var picked []string
+	seen := make(map[string]bool)
+	pick := func(p string) {
+		if _, ok := blobs[p]; ok && !seen[p] && len(picked) < maxKeyFiles {
+			seen[p] = true
+			picked = append(picked, p)
+		}
+	}
+
+	var readmes, manifests []string
+	for p := range blobs {
+		if strings.EqualFold(strings.TrimSuffix(path.Base(p), path.Ext(p)), "readme") && !isDocumentation(p) {
+			readmes = append(readmes, p)
+		}
+		if _, ok := ManifestKindOf(p); ok || path.Base(p) == "pyproject.toml" {
+			manifests = append(manifests, p)
+		}
+	}
+	// Shallowest first, so the root README and manifests win
+	byDepth := func(paths []string) {
+		sort.Slice(paths, func(i, j int) bool {
+			di, dj := strings.Count(paths[i], "/"), strings.Count(paths[j], "/")
+			if di != dj {
+				return di < dj
+			}
+			return paths[i] < paths[j]
+		})
+	}
+	byDepth(readmes)
+	byDepth(manifests)
+
+	if len(readmes) > 0 {
+		pick(readmes[0])
+	}
+	for _, p := range manifests[:min(len(manifests), 3)] {
+		pick(p)
+	}
+	for _, p := range profile.EntryPoints {
+		pick(p)
+	}
+	for _, p := range readmes {
+		pick(p)
+	}
+
+	contents := make(map[string]string, len(picked))
+	for _, p := range picked {
+		content, err := repo.BlobContent(blobs[p])
+		if err != nil {
+			debug.Log("Failed to read %s: %v", p, err)
+			continue
+		}
+		if len(content) > maxKeyFileBytes {
+			content = append(content[:maxKeyFileBytes:maxKeyFileBytes], "\n..."...)
+		}
+		contents[p] = string(content)
+	}
+	return contents, nil
+}
@@ -0,0 +1,299 @@
+package context
+
+import (
+	"path"
+	"strings"
+
+	"github.com/jabafett/quill/internal/utils/debug"
+)
+
+// Extensions tried, in order, for extensionless JavaScript and TypeScript imports
+var scriptExtensions = []string{".ts", ".tsx", ".d.ts", ".js", ".jsx", ".mjs", ".cjs"}
+
+// Resolver maps import specifiers to files in the repository. Imports of external
+// packages resolve to nothing.
+type Resolver struct {
+	files     map[string]bool
+	dirs      map[string][]string // Directory to the files directly in it
+	goModules map[string]string   // Go module path to the directory holding its go.mod
+	packages  map[string]string   // Local npm package name to its directory
+	crates    map[string]string   // Local crate name, as used in paths, to its directory
+	byBase    map[string][]string // File name to every path with that name
+	manifests []*PackageManifest
+}
+
+// NewResolver indexes the repository's files. readFile is used to read package
+// manifests, which map local module, package and crate names to directories.
+func NewResolver(files []string, readFile func(path string) ([]byte, error)) *Resolver {
+	r := &Resolver{
+		files:     make(map[string]bool, len(files)),
+		dirs:      make(map[string][]string),
+		goModules: make(map[string]string),
+		packages:  make(map[string]string),
+		crates:    make(map[string]string),
+		byBase:    make(map[string][]string),
+	}
+
+	for _, file := range files {
+		r.files[file] = true
+		r.dirs[path.Dir(file)] = append(r.dirs[path.Dir(file)], file)
+		r.byBase[path.Base(file)] = append(r.byBase[path.Base(file)], file)
+
+		if _, ok := ManifestKindOf(file); !ok {
+			continue
+		}
+		content, err := readFile(file)
+		if err != nil {
+			debug.Log("Failed to read %s: %v", file, err)
+			continue
+		}
+		manifest, err := ParseManifest(file, content)
+		if err != nil {
+			debug.Log("Failed to parse %s: %v", file, err)
+			continue
+		}
+		r.manifests = append(r.manifests, manifest)
+		if manifest.Name == "" {
+			continue
+		}
+		switch manifest.Kind {
+		case ManifestGoMod:
+			r.goModules[manifest.Name] = manifest.Dir()
+		case ManifestPackageJSON:
+			r.packages[manifest.Name] = manifest.Dir()
+		case ManifestCargo:
+			r.crates[strings.ReplaceAll(manifest.Name, "-", "_")] = manifest.Dir()
+		}
+	}
+	return r
+}
+
+// Manifests returns the package manifests found in the repository
+func (r *Resolver) Manifests() []*PackageManifest {
+	return r.manifests
+}
+
+// Resolve returns the repository files an import in from refers to
+func (r *Resolver) Resolve(from, spec string) []string {
+	lang, ok := languages[strings.ToLower(path.Ext(from))]
+	if !ok {
+		return nil
+	}
+
+	switch lang.family {
+	case familyGo:
+		return r.resolveGo(spec)
+	case familyJavaScript:
+		return r.resolveScript(from, spec)
+	case familyPython:
+		return r.resolvePython(from, spec)
+	case familyRust:
+		return r.resolveRust(from, spec)
+	case familyJava:
+		return r.resolveJava(spec)
+	}
+	return nil
+}
+
+// resolveGo maps a package import to the non-test Go files of its directory
+func (r *Resolver) resolveGo(spec string) []string {
+	for module, dir := range r.goModules {
+		if spec != module && !strings.HasPrefix(spec, module+"/") {
+			continue
+		}
+		pkgDir := path.Join(dir, strings.TrimPrefix(strings.TrimPrefix(spec, module), "/"))
+		var files []string
+		for _, file := range r.dirs[pkgDir] {
+			if strings.HasSuffix(file, ".go") && !strings.HasSuffix(file, "_test.go") {
+				files = append(files, file)
+			}
+		}
+		return files
+	}
+	return nil
+}
+
+// resolveScript follows relative imports the way bundlers do: the exact file, then
+// known extensions, then an index file in the directory. Imports of workspace
+// packages resolve into the package's directory.
+func (r *Resolver) resolveScript(from, spec string) []string {
+	if !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") {
+		for name, dir := range r.packages {
+			if spec == name {
+				return r.scriptCandidates(path.Join(dir, "index"), path.Join(dir, "src", "index"))
+			}
+			if rest, ok := strings.CutPrefix(spec, name+"/"); ok {
+				return r.scriptCandidates(path.Join(dir, rest), path.Join(dir, "src", rest))
+			}
+		}
+		return nil
+	}
+	return r.scriptCandidates(path.Join(path.Dir(from), spec))
+}
+
+// scriptCandidates returns the first file matching any of the bases
+func (r *Resolver) scriptCandidates(bases ...string) []string {
+	var candidates []string
+	for _, base := range bases {
+		candidates = append(candidates, scriptFiles(base)...)
+	}
+	return r.first(candidates)
+}
+
+// scriptFiles lists the files a bundler tries for an import of base
+func scriptFiles(base string) []string {
+	candidates := []string{base}
+	for _, ext := range scriptExtensions {
+		candidates = append(candidates, base+ext)
+	}
+	for _, ext := range scriptExtensions {
+		candidates = append(candidates, base+"/index"+ext)
+	}
+	return candidates
+}
+
+// resolvePython handles relative imports and absolute ones rooted at the repository
+// or a src directory. Imports of names inside a module resolve to the module.
+func (r *Resolver) resolvePython(from, spec string) []string {
+	var roots []string
+	dots := len(spec) - len(strings.TrimLeft(spec, "."))
+	if dots > 0 {
+		dir := path.Dir(from)
+		for i := 1; i < dots; i++ {
+			dir = path.Dir(dir)
+		}
+		roots = []string{dir}
+		spec = spec[dots:]
+	} else {
+		roots = []string{".", "src"}
+	}
+
+	var parts []string
+	if spec != "" {
+		parts = strings.Split(spec, ".")
+	}
+	for _, root := range roots {
+		for n := len(parts); n >= 0; n-- {
+			module := path.Join(append([]string{root}, parts[:n]...)...)
+			if found := r.first([]string{module + ".py", module + ".pyi", module + "/__init__.py"}); found != nil {
+				return found
+			}
+			if dots == 0 && n == 1 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// resolveRust handles mod declarations and crate, self and super paths
+func (r *Resolver) resolveRust(from, spec string) []string {
+	dir := path.Dir(from)
+	// Modules declared in foo.rs live in foo/, those in mod.rs, lib.rs and main.rs beside it
+	moduleDir := dir
+	switch path.Base(from) {
+	case "mod.rs", "lib.rs", "main.rs":
+	default:
+		moduleDir = path.Join(dir, strings.TrimSuffix(path.Base(from), ".rs"))
+	}
+
+	if name, ok := strings.CutPrefix(spec, "mod "); ok {
+		return r.first([]string{
+			path.Join(moduleDir, name+".rs"),
+			path.Join(moduleDir, name, "mod.rs"),
+		})
+	}
+
+	if i := strings.Index(spec, "::{"); i >= 0 {
+		spec = spec[:i]
+	}
+	parts := strings.Split(spec, "::")
+	var base string
+	switch parts[0] {
+	case "crate":
+		base = r.crateRoot(dir)
+	case "self":
+		base = moduleDir
+	case "super":
+		base = path.Dir(moduleDir)
+	default:
+		// Another crate of the workspace
+		dir, ok := r.crates[parts[0]]
+		if !ok {
+			return nil
+		}
+		base = path.Join(dir, "src")
+	}
+	if base == "" {
+		return nil
+	}
+
+	parts = parts[1:]
+	for n := len(parts); n > 0; n-- {
+		module := path.Join(append([]string{base}, parts[:n]...)...)
+		if found := r.first([]string{module + ".rs", module + "/mod.rs"}); found != nil {
+			return found
+		}
+	}
+	// Items declared in the module or crate root itself
+	return r.first([]string{base + ".rs", path.Join(base, "mod.rs"), path.Join(base, "lib.rs"), path.Join(base, "main.rs")})
+}
+
+// crateRoot finds the directory holding lib.rs or main.rs above dir
+func (r *Resolver) crateRoot(dir string) string {
+	for {
+		if r.files[path.Join(dir, "lib.rs")] || r.files[path.Join(dir, "main.rs")] {
+			return dir
+		}
+		if dir == "." || dir == "/" {
+			return ""
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// resolveJava maps a class or wildcard import to the files whose path ends with the
+// package directories, so any source root works
+func (r *Resolver) resolveJava(spec string) []string {
+	parts := strings.Split(spec, ".")
+	if parts[len(parts)-1] == "*" {
+		suffix := "/" + strings.Join(parts[:len(parts)-1], "/")
+		var files []string
+		for dir, dirFiles := range r.dirs {
+			if dir == suffix[1:] || strings.HasSuffix(dir, suffix) {
+				for _, file := range dirFiles {
+					if strings.HasSuffix(file, ".java") {
+						files = append(files, file)
+					}
+				}
+			}
+		}
+		return files
+	}
+
+	// Static imports name a member, so also try the enclosing class
+	for n := len(parts); n > 1; n-- {
+		suffix := strings.Join(parts[:n], "/") + ".java"
+		var files []string
+		for _, file := range r.byBase[parts[n-1]+".java"] {
+			if file == suffix || strings.HasSuffix(file, "/"+suffix) {
+				files = append(files, file)
+			}
+		}
+		if len(files) > 0 {
+			return files
+		}
+	}
+	return nil
+}
+
+// first returns the first candidate that exists, as a one-element slice
+func (r *Resolver) first(candidates []string) []string {
+	for _, candidate := range candidates {
+		candidate = path.Clean(candidate)
+		if r.files[candidate] {
+			return []string{candidate}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package context
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/jabafett/quill/internal/utils/helpers"
+)
+
+// reviewRule is a local check run on every line a change adds
+type reviewRule struct {
+	category  helpers.Category
+	severity  helpers.Severity
+	pattern   *regexp.Regexp // For secrets, the last group, if any, is the value checked and redacted
+	message   string
+	languages []string // Languages the rule applies to, empty for all files
+	tests     bool     // Also check test files
+	detail    bool     // Append the rest of the line after the match to the message
+}
+
+var (
+	jsLanguages = []string{"JavaScript", "TypeScript", "TSX", "Vue", "Svelte"}
+
+	// Values that only look like secrets: documentation examples, templates,
+	// environment lookups and values already redacted
+	placeholderSecret = regexp.MustCompile(`(?i)example|sample|dummy|placeholder|changeme|your[_-]|xxxx|\*\*\*\*|\$\{|\{\{|^<.*>$|redacted`)
+)
+
+// reviewRules hold the checks that need no model. Secret rules come first, the
+// specific formats before the generic assignment.
+var reviewRules = []reviewRule{
+	{
+		category: helpers.CategorySecret, severity: helpers.SeverityCritical, tests: true,
+		pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY(?: BLOCK)?-----`),
+		message: "Private key committed; remove it and rotate the key",
+	},
+	{
+		category: helpers.CategorySecret, severity: helpers.SeverityCritical, tests: true,
+		pattern: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`),
+		message: "AWS access key ID committed; remove it and rotate the credential",
+	},
+	{
+		category: helpers.CategorySecret, severity: helpers.SeverityCritical, tests: true,
+		pattern: regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,})\b`),
+		message: "GitHub token committed; remove it and revoke the token",
+	},
+	{
+		category: helpers.CategorySecret, severity: helpers.SeverityCritical, tests: true,
+		pattern: regexp.MustCompile(`\b(xox[abprs]-[A-Za-z0-9-]{10,})`),
+		message: "Slack token committed; remove it and revoke the token",
+	},
+	{
+		category: helpers.CategorySecret, severity: helpers.SeverityHigh, tests: true,
+		pattern: regexp.MustCompile(`\b(sk-[A-Za-z0-9_-]{20,}|AIza[0-9A-Za-z_-]{35})`),
+		message: "API key committed; load it from the environment or a secret store",
+	},
+	{
+		category: helpers.CategorySecret, severity: helpers.SeverityHigh, tests: true,
+		pattern: regexp.MustCompile(`(?i)[\w.-]*(?:password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token|private[_-]?key)[\w.-]*["']?\s*(?::=|[:=])\s*["']([^"'\s]{8,})["']`),
+		message: "Hardcoded credential; load it from the environment or a secret store",
+	},
+	{
+		category: helpers.CategoryBug, severity: helpers.SeverityHigh, tests: true,
+		pattern: regexp.MustCompile(`^(<{7}|>{7})(\s|$)`),
+		message: "Merge conflict marker left in",
+	},
+	{
+		category: helpers.CategoryDebug, severity: helpers.SeverityLow, languages: []string{"Go"},
+		pattern: regexp.MustCompile(`^\s*print(ln)?\(|\bspew\.(Dump|Print)`), // fmt.Print is how commands write output
+		message: "Debug print left in; remove it or use a logger",
+	},
+	{
+		category: helpers.CategoryDebug, severity: helpers.SeverityLow, languages: jsLanguages,
+		pattern: regexp.MustCompile(`\bconsole\.(log|debug|trace|dir|table)\(`),
+		message: "console call left in; remove it or use a logger",
+	},
+	{
+		category: helpers.CategoryDebug, severity: helpers.SeverityMedium, languages: jsLanguages,
+		pattern: regexp.MustCompile(`^\s*debugger\b`),
+		message: "debugger statement left in",
+	},
+	{
+		category: helpers.CategoryDebug, severity: helpers.SeverityLow, languages: []string{"Python"},
+		pattern: regexp.MustCompile(`^\s*print\(`),
+		message: "Debug print left in; remove it or use logging",
+	},
+	{
+		category: helpers.CategoryDebug, severity: helpers.SeverityMedium, languages: []string{"Python"},
+		pattern: regexp.MustCompile(`\bi?pdb\.set_trace\(|^\s*breakpoint\(\)`),
+		message: "Breakpoint left in",
+	},
+	{
+		category: helpers.CategoryDebug, severity: helpers.SeverityMedium, languages: []string{"Ruby"},
+		pattern: regexp.MustCompile(`\bbinding\.(pry|irb)\b|^\s*byebug\b`),
+		message: "Breakpoint left in",
+	},
+	{
+		category: helpers.CategoryDebug, severity: helpers.SeverityLow, languages: []string{"Rust"},
+		pattern: regexp.MustCompile(`\bdbg!\(`),
+		message: "dbg! left in",
+	},
+	{
+		category: helpers.CategoryDebug, severity: helpers.SeverityLow, languages: []string{"PHP"},
+		pattern: regexp.MustCompile(`\b(var_dump|print_r|dd)\(`),
+		message: "Debug dump left in",
+	},
+	{
+		category: helpers.CategoryDebug, severity: helpers.SeverityLow, languages: []string{"Java", "Kotlin"},
+		pattern: regexp.MustCompile(`\bSystem\.(out|err)\.print(ln)?\(|\.printStackTrace\(\)`),
+		message: "Debug print left in; remove it or use a logger",
+	},
+	{
+		category: helpers.CategoryTodo, severity: helpers.SeverityInfo, tests: true, detail: true,
+		pattern: regexp.MustCompile(`\b(TODO|FIXME|XXX|HACK)\b:?`),
+		message: "Unresolved",
+	},
+}
+
+// applies reports whether the rule checks a file of the given language
+func (r *reviewRule) applies(language string, test bool) bool {
+	if test && !r.tests {
+		return false
+	}
+	if len(r.languages) == 0 {
+		return true
+	}
+	for _, l := range r.languages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the span of the rule's value in a line, ok false when the rule
+// does not match or only matches placeholders
+func (r *reviewRule) match(line string) (start, end int, ok bool) {
+	for _, m := range r.pattern.FindAllStringSubmatchIndex(line, -1) {
+		start, end = m[0], m[1]
+		if r.category == helpers.CategorySecret && len(m) > 2 {
+			start, end = m[len(m)-2], m[len(m)-1]
+		}
+		if start < 0 {
+			continue
+		}
+		if r.category == helpers.CategorySecret && placeholderSecret.MatchString(line[start:end]) {
+			continue
+		}
+		return start, end, true
+	}
+	return 0, 0, false
+}
+
+// ScanDiff runs the local review rules over the lines a diff adds: secrets,
+// leftover debug statements, breakpoints, conflict markers and TODOs. Debug rules
+// skip test files.
+func ScanDiff(diff *git.Diff) []helpers.Finding {
+	var findings []helpers.Finding
+	for _, file := range diff.Files {
+		if file.Binary || file.Status == git.StatusDeleted {
+			continue
+		}
+		language, _, _ := DetectLanguage(file.Path)
+		test := IsTestFile(file.Path)
+
+		for _, line := range file.AddedLines() {
+			secret := false // One secret finding per line, from the most specific rule
+			for i := range reviewRules {
+				rule := &reviewRules[i]
+				if !rule.applies(language, test) || (secret && rule.category == helpers.CategorySecret) {
+					continue
+				}
+				start, end, ok := rule.match(line.Content)
+				if !ok {
+					continue
+				}
+				secret = secret || rule.category == helpers.CategorySecret
+				message := rule.message
+				if rule.detail {
+					message += " " + strings.TrimSuffix(line.Content[start:end], ":")
+					if rest := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line.Content[end:]), "*/")); rest != "" {
+						message += ": " + rest
+					}
+				}
+				findings = append(findings, helpers.Finding{
+					File:     file.Path,
+					Line:     line.Number,
+					Severity: rule.severity,
+					Category: rule.category,
+					Message:  message,
+					Source:   "rules",
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// RedactSecrets returns a copy of the diff with every value the secret rules
+// match replaced, so that it can be sent to a provider. Context and deleted lines
+// are redacted too: a secret being removed has still been committed before.
+func RedactSecrets(diff *git.Diff) *git.Diff {
+	redacted := &git.Diff{Files: make([]*git.FileDiff, len(diff.Files))}
+	for i, file := range diff.Files {
+		copied := *file
+		copied.Hunks = make([]*git.Hunk, len(file.Hunks))
+		for j, hunk := range file.Hunks {
+			h := *hunk
+			h.Lines = make([]git.Line, len(hunk.Lines))
+			for k, l := range hunk.Lines {
+				l.Content = redactLine(l.Content)
+				h.Lines[k] = l
+			}
+			copied.Hunks[j] = &h
+		}
+		redacted.Files[i] = &copied
+	}
+	return redacted
+}
+
+// redactLine replaces the secrets in a line with a marker
+func redactLine(line string) string {
+	for i := range reviewRules {
+		rule := &reviewRules[i]
+		if rule.category != helpers.CategorySecret {
+			continue
+		}
+		for {
+			start, end, ok := rule.match(line)
+			if !ok {
+				break
+			}
+			line = line[:start] + "[REDACTED]" + line[end:]
+		}
+	}
+	return line
+}
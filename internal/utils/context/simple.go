@@ -14,13 +14,35 @@ import (
 
 // RepoSummary contains a simplified summary of the repository
 type RepoSummary struct {
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Files       int       `json:"files"`
-	Directories int       `json:"directories"`
-	Languages   []string  `json:"languages"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"` // Written by AI from the profile and key files
+	Files       int              `json:"files"`
+	Directories int              `json:"directories"`
+	Languages   []string         `json:"languages"` // Largest first
+	Profile     *RepoProfile     `json:"profile,omitempty"`
+	Head        string           `json:"head,omitempty"`      // Commit the summary was written at
+	TreeHash    string           `json:"tree_hash,omitempty"` // Tree of that commit
+	Packages    []PackageSummary `json:"packages,omitempty"`  // One per workspace package in a monorepo
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// PackageSummary describes one package of a monorepo
+type PackageSummary struct {
+	WorkspacePackage
+	Description string       `json:"description"`
+	Profile     *RepoProfile `json:"profile,omitempty"`
+	TreeHash    string       `json:"tree_hash"` // Tree of the package directory when described
+}
+
+// Package returns the summary of the package in dir, or nil
+func (s *RepoSummary) Package(dir string) *PackageSummary {
+	for i := range s.Packages {
+		if s.Packages[i].Dir == dir {
+			return &s.Packages[i]
+		}
+	}
+	return nil
 }
 
 // SimpleContext provides basic repository context without complex analysis
@@ -45,48 +67,33 @@ func NewSimpleContext(repoRoot string) (*SimpleContext, error) {
 
 // GetDirectoryTree returns a simplified directory tree
 func (sc *SimpleContext) GetDirectoryTree(maxDepth int) (string, error) {
-	cmd := exec.Command("find", sc.RepoRoot, "-type", "d", "-not", "-path", "*/\\.*", "-maxdepth", fmt.Sprintf("%d", maxDepth))
-	output, err := cmd.Output()
+	files, err := sc.trackedFiles()
 	if err != nil {
 		return "", fmt.Errorf("failed to get directory tree: %w", err)
 	}
-
-	// Format the output
-	dirs := strings.Split(strings.TrimSpace(string(output)), "\n")
-	var tree strings.Builder
-
-	for _, dir := range dirs {
-		// Skip the root directory
-		if dir == sc.RepoRoot {
-			continue
-		}
-
-		// Calculate relative path and indentation
-		relPath, err := filepath.Rel(sc.RepoRoot, dir)
-		if err != nil {
-			continue
-		}
-
-		depth := len(strings.Split(relPath, string(filepath.Separator))) - 1
-		indent := strings.Repeat("  ", depth)
-
-		tree.WriteString(fmt.Sprintf("%s- %s\n", indent, filepath.Base(dir)))
-	}
-
-	return tree.String(), nil
+	return DirectoryTree(files, maxDepth), nil
 }
 
-// GetFileInfo returns information about files in the repository
-func (sc *SimpleContext) GetFileInfo() (map[string]int, error) {
-	// Use git ls-files to get all tracked files
+// trackedFiles lists the files git tracks, relative to the repository root
+func (sc *SimpleContext) trackedFiles() ([]string, error) {
 	cmd := exec.Command("git", "ls-files")
 	cmd.Dir = sc.RepoRoot
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get file list: %w", err)
 	}
+	if len(strings.TrimSpace(string(output))) == 0 {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSpace(string(output)), "\n"), nil
+}
 
-	files := strings.Split(strings.TrimSpace(string(output)), "\n")
+// GetFileInfo returns information about files in the repository
+func (sc *SimpleContext) GetFileInfo() (map[string]int, error) {
+	files, err := sc.trackedFiles()
+	if err != nil {
+		return nil, err
+	}
 
 	// Count files by extension
 	extensions := make(map[string]int)
@@ -101,51 +108,18 @@ func (sc *SimpleContext) GetFileInfo() (map[string]int, error) {
 	return extensions, nil
 }
 
-// GetLanguageInfo returns information about programming languages used
+// GetLanguageInfo returns the programming and markup languages used, most files first
 func (sc *SimpleContext) GetLanguageInfo() ([]string, error) {
-	fileInfo, err := sc.GetFileInfo()
+	files, err := sc.trackedFiles()
 	if err != nil {
 		return nil, err
 	}
 
-	// Map extensions to languages (simplified)
-	extToLang := map[string]string{
-		".go":   "Go",
-		".js":   "JavaScript",
-		".ts":   "TypeScript",
-		".jsx":  "React",
-		".tsx":  "React/TypeScript",
-		".py":   "Python",
-		".rb":   "Ruby",
-		".java": "Java",
-		".c":    "C",
-		".cpp":  "C++",
-		".h":    "C/C++ Header",
-		".rs":   "Rust",
-		".php":  "PHP",
-		".html": "HTML",
-		".css":  "CSS",
-		".md":   "Markdown",
-		".json": "JSON",
-		".yml":  "YAML",
-		".yaml": "YAML",
-		".toml": "TOML",
-	}
-
-	// Count languages
-	langCount := make(map[string]int)
-	for ext, count := range fileInfo {
-		if lang, ok := extToLang[ext]; ok {
-			langCount[lang] += count
-		}
-	}
-
-	// Convert to sorted slice
-	var languages []string
-	for lang := range langCount {
-		languages = append(languages, lang)
+	shares := languageBreakdown(files, func(string) int64 { return 1 })
+	languages := make([]string, len(shares))
+	for i, share := range shares {
+		languages[i] = share.Name
 	}
-
 	return languages, nil
 }
 
@@ -199,7 +173,13 @@ func (sc *SimpleContext) GetRepoSummary() string {
 	result.WriteString(fmt.Sprintf("Description: %s\n", summary.Description))
 	result.WriteString(fmt.Sprintf("Files: %d\n", summary.Files))
 	result.WriteString(fmt.Sprintf("Directories: %d\n", summary.Directories))
-	result.WriteString(fmt.Sprintf("Languages: %s\n", strings.Join(summary.Languages, ", ")))
+	if summary.Profile == nil {
+		result.WriteString(fmt.Sprintf("Languages: %s\n", strings.Join(summary.Languages, ", ")))
+		return result.String()
+	}
+	for _, line := range summary.Profile.Lines() {
+		result.WriteString(line + "\n")
+	}
 
 	return result.String()
 }
@@ -0,0 +1,167 @@
+package context
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+// Commits walked when no commit threshold bounds the walk
+const maxStalenessCommits = 10000
+
+// IndexLockPath is the lock file held while a background 'quill index' runs
+func IndexLockPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".git", "quill-index.lock")
+}
+
+// IndexLogPath collects the output of the last background 'quill index'
+func IndexLogPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".git", "quill-index.log")
+}
+
+// StalenessThresholds decide when a repository summary is out of date. A zero
+// threshold is never crossed.
+type StalenessThresholds struct {
+	Commits      int
+	ChangedFiles int
+	Age          time.Duration
+}
+
+// Staleness measures how far the repository has moved on since its summary was written
+type Staleness struct {
+	IndexedHead  string
+	Head         string
+	Commits      int // Commits made since the summary, at least this many when CommitsExact is false
+	CommitsExact bool
+	Rewritten    bool // The indexed commit is no longer in the history of HEAD
+	ChangedFiles int  // Files that differ between the indexed tree and HEAD's
+	IndexedFiles int
+	Files        int
+	Age          time.Duration
+	Untracked    bool     // The summary predates staleness tracking
+	Reasons      []string // Thresholds crossed, empty when the summary is fresh
+}
+
+// CheckStaleness compares a summary with the current state of the repository
+func CheckStaleness(repo *git.Repository, summary *RepoSummary, thresholds StalenessThresholds) (*Staleness, error) {
+	s := &Staleness{
+		IndexedHead:  summary.Head,
+		IndexedFiles: summary.Files,
+		Age:          time.Since(summary.UpdatedAt),
+	}
+
+	head, err := repo.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	s.Head = head
+
+	tree, err := repo.HeadTree()
+	if err != nil {
+		return nil, err
+	}
+	files, err := repo.TrackedBlobs()
+	if err != nil {
+		return nil, err
+	}
+	s.Files = len(files)
+
+	if summary.Head == "" || summary.TreeHash == "" {
+		s.Untracked = true
+		s.Reasons = append(s.Reasons, "the summary does not record the commit it was written at")
+	} else if summary.TreeHash != tree {
+		limit := maxStalenessCommits
+		if thresholds.Commits > 0 {
+			limit = thresholds.Commits
+		}
+		s.Commits, s.CommitsExact, err = repo.CommitsSince(summary.Head, limit)
+		if errors.Is(err, git.ErrNotInHistory) {
+			s.Rewritten = true
+		} else if err != nil {
+			return nil, err
+		}
+
+		if tree != "" {
+			if s.ChangedFiles, err = repo.TreeChanges(summary.TreeHash, tree); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if s.Rewritten {
+		s.Reasons = append(s.Reasons, "the indexed commit is no longer in the history of HEAD")
+	}
+	if thresholds.Commits > 0 && s.Commits >= thresholds.Commits {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%s commits since it was written", s.commitCount()))
+	}
+	if thresholds.ChangedFiles > 0 && s.ChangedFiles >= thresholds.ChangedFiles {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%d files changed since it was written", s.ChangedFiles))
+	}
+	if thresholds.Age > 0 && s.Age >= thresholds.Age {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("written %s ago", FormatAge(s.Age)))
+	}
+	return s, nil
+}
+
+// Stale reports whether any threshold was crossed
+func (s *Staleness) Stale() bool {
+	return len(s.Reasons) > 0
+}
+
+// Lines renders the measurements for 'quill index --status'
+func (s *Staleness) Lines() []string {
+	if s.Untracked {
+		return []string{"Indexed at: unknown, the summary predates staleness tracking"}
+	}
+
+	lines := []string{fmt.Sprintf("Indexed at: %s, %s ago", shortHash(s.IndexedHead), FormatAge(s.Age))}
+	switch {
+	case s.Rewritten:
+		lines = append(lines, "Commits since: unknown, the indexed commit was rewritten")
+	default:
+		lines = append(lines, "Commits since: "+s.commitCount())
+	}
+	lines = append(lines,
+		fmt.Sprintf("Files changed: %d", s.ChangedFiles),
+		fmt.Sprintf("Tracked files: %d (%+d)", s.Files, s.Files-s.IndexedFiles),
+	)
+	return lines
+}
+
+func (s *Staleness) commitCount() string {
+	if s.CommitsExact || s.Commits == 0 {
+		return fmt.Sprint(s.Commits)
+	}
+	return fmt.Sprintf("at least %d", s.Commits)
+}
+
+// FormatAge rounds a duration to the largest whole unit, e.g. "3 days"
+func FormatAge(d time.Duration) string {
+	units := []struct {
+		size time.Duration
+		name string
+	}{
+		{24 * time.Hour, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+	}
+	for _, unit := range units {
+		if n := int(d / unit.size); n > 0 {
+			if n == 1 {
+				return "1 " + unit.name
+			}
+			return fmt.Sprintf("%d %ss", n, unit.name)
+		}
+	}
+	return "less than a minute"
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
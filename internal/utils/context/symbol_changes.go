@@ -0,0 +1,236 @@
+package context
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+// ChangeKind describes what happened to a symbol
+type ChangeKind string
+
+const (
+	SymbolAdded            ChangeKind = "added"
+	SymbolRemoved          ChangeKind = "removed"
+	SymbolSignatureChanged ChangeKind = "signature changed"
+	SymbolModified         ChangeKind = "modified"
+)
+
+const (
+	// Files larger than this are not parsed for symbols
+	maxSymbolFileSize = 512 * 1024
+	// Changed symbols listed in a prompt before the rest are counted instead
+	maxPromptSymbols = 60
+)
+
+// SymbolChange is a symbol touched by a diff
+type SymbolChange struct {
+	Symbol       Symbol
+	Change       ChangeKind
+	OldSignature string // Set when the signature changed
+}
+
+func (c SymbolChange) String() string {
+	return fmt.Sprintf("%s %s (%s)", c.Symbol.Kind, c.Symbol.Name, c.Change)
+}
+
+// FileSymbols lists the changed symbols of one file
+type FileSymbols struct {
+	Path    string
+	Changes []SymbolChange
+}
+
+// ChangedSymbols maps every hunk in a diff to the symbols it touches. Files in
+// unsupported languages, binary files and files that fail to load are skipped.
+func ChangedSymbols(diff *git.Diff, versions *git.FileVersions) []FileSymbols {
+	var result []FileSymbols
+	for _, file := range diff.Files {
+		if file.Binary || !SupportsSymbols(file.Path) {
+			continue
+		}
+
+		before, after, err := versions.Read(file)
+		if err != nil {
+			debug.Log("Skipping symbols for %s: %v", file.Path, err)
+			continue
+		}
+		if len(before) > maxSymbolFileSize || len(after) > maxSymbolFileSize {
+			continue
+		}
+
+		changes, err := DiffSymbols(file, before, after)
+		if err != nil {
+			debug.Log("Skipping symbols for %s: %v", file.Path, err)
+			continue
+		}
+		if len(changes) > 0 {
+			result = append(result, FileSymbols{Path: file.Path, Changes: changes})
+		}
+	}
+	return result
+}
+
+// DiffSymbols compares the symbols of both versions of a file. Symbols present on
+// one side only are added or removed, a differing header is a signature change, and
+// symbols enclosing a changed line are modified. Symbols nested in an added or
+// removed parent are folded into it.
+func DiffSymbols(file *git.FileDiff, before, after []byte) ([]SymbolChange, error) {
+	oldPath := file.Path
+	if file.OldPath != "" {
+		oldPath = file.OldPath
+	}
+
+	oldSymbols, err := ExtractSymbols(oldPath, before)
+	if err != nil {
+		return nil, err
+	}
+	newSymbols, err := ExtractSymbols(file.Path, after)
+	if err != nil {
+		return nil, err
+	}
+
+	deletedLines, addedLines := changedLines(file)
+	oldByKey := indexSymbols(oldSymbols)
+	newByKey := indexSymbols(newSymbols)
+	touched := make(map[string]bool)
+	for key := range innermost(oldSymbols, deletedLines) {
+		touched[key] = true
+	}
+	for key := range innermost(newSymbols, addedLines) {
+		touched[key] = true
+	}
+
+	var changes []SymbolChange
+	added := make(map[string]bool)
+	for _, key := range symbolKeys(newSymbols) {
+		sym := newByKey[key]
+		old, existed := oldByKey[key]
+		switch {
+		case !existed:
+			added[sym.Name] = true
+			if !added[sym.Parent] {
+				changes = append(changes, SymbolChange{Symbol: sym, Change: SymbolAdded})
+			}
+		case old.Signature != sym.Signature:
+			changes = append(changes, SymbolChange{Symbol: sym, Change: SymbolSignatureChanged, OldSignature: old.Signature})
+		case touched[key]:
+			changes = append(changes, SymbolChange{Symbol: sym, Change: SymbolModified})
+		}
+	}
+
+	removed := make(map[string]bool)
+	for _, key := range symbolKeys(oldSymbols) {
+		if _, ok := newByKey[key]; ok {
+			continue
+		}
+		sym := oldByKey[key]
+		removed[sym.Name] = true
+		if !removed[sym.Parent] {
+			changes = append(changes, SymbolChange{Symbol: sym, Change: SymbolRemoved})
+		}
+	}
+	return changes, nil
+}
+
+// changedLines returns the deleted line numbers of the old file and the added line
+// numbers of the new file
+func changedLines(file *git.FileDiff) (deleted, added []int) {
+	for _, hunk := range file.Hunks {
+		oldLine, newLine := hunk.OldStart, hunk.NewStart
+		for _, line := range hunk.Lines {
+			switch line.Kind {
+			case git.LineContext:
+				oldLine++
+				newLine++
+			case git.LineDeleted:
+				deleted = append(deleted, oldLine)
+				oldLine++
+			case git.LineAdded:
+				added = append(added, newLine)
+				newLine++
+			}
+		}
+	}
+	return deleted, added
+}
+
+// symbolKey identifies a symbol across versions. Overloads with the same name are
+// told apart by their position among symbols of that name.
+func symbolKey(sym Symbol, occurrence int) string {
+	key := string(sym.Kind) + " " + sym.Name
+	if occurrence > 0 {
+		key += fmt.Sprintf("#%d", occurrence)
+	}
+	return key
+}
+
+// symbolKeys returns the keys of symbols in source order
+func symbolKeys(symbols []Symbol) []string {
+	seen := make(map[string]int)
+	keys := make([]string, len(symbols))
+	for i, sym := range symbols {
+		base := symbolKey(sym, 0)
+		keys[i] = symbolKey(sym, seen[base])
+		seen[base]++
+	}
+	return keys
+}
+
+func indexSymbols(symbols []Symbol) map[string]Symbol {
+	byKey := make(map[string]Symbol, len(symbols))
+	for i, key := range symbolKeys(symbols) {
+		byKey[key] = symbols[i]
+	}
+	return byKey
+}
+
+// innermost returns the keys of the smallest symbols enclosing each line
+func innermost(symbols []Symbol, lines []int) map[string]bool {
+	keys := symbolKeys(symbols)
+	result := make(map[string]bool)
+	for _, line := range lines {
+		best := -1
+		for i, sym := range symbols {
+			if line < sym.StartLine || line > sym.EndLine {
+				continue
+			}
+			if best < 0 || sym.EndLine-sym.StartLine < symbols[best].EndLine-symbols[best].StartLine {
+				best = i
+			}
+		}
+		if best >= 0 {
+			result[keys[best]] = true
+		}
+	}
+	return result
+}
+
+// SummarizeSymbols renders one compact line per file for prompts, e.g.
+// "internal/git/diff.go: func ParseDiff (signature changed), method Diff.Paths (added)"
+func SummarizeSymbols(files []FileSymbols) []string {
+	var lines []string
+	listed, total := 0, 0
+	for _, file := range files {
+		total += len(file.Changes)
+		if listed >= maxPromptSymbols {
+			continue
+		}
+
+		var parts []string
+		for _, change := range file.Changes {
+			if listed >= maxPromptSymbols {
+				break
+			}
+			parts = append(parts, change.String())
+			listed++
+		}
+		lines = append(lines, file.Path+": "+strings.Join(parts, ", "))
+	}
+
+	if total > listed {
+		lines = append(lines, fmt.Sprintf("... and %d more changed symbols", total-listed))
+	}
+	return lines
+}
@@ -0,0 +1,97 @@
+package context
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// SymbolKind is the kind of declaration a symbol is
+type SymbolKind string
+
+const (
+	KindFunction    SymbolKind = "func"
+	KindMethod      SymbolKind = "method"
+	KindConstructor SymbolKind = "constructor"
+	KindType        SymbolKind = "type"
+	KindStruct      SymbolKind = "struct"
+	KindInterface   SymbolKind = "interface"
+	KindClass       SymbolKind = "class"
+	KindEnum        SymbolKind = "enum"
+	KindTrait       SymbolKind = "trait"
+	KindModule      SymbolKind = "module"
+)
+
+// Signatures longer than this are cut, they only need to tell versions apart
+const maxSignatureLength = 200
+
+// Symbol is a named declaration in a source file
+type Symbol struct {
+	Name      string // Qualified with enclosing types, e.g. Repository.Commit
+	Kind      SymbolKind
+	Signature string // Declaration header with whitespace collapsed
+	Parent    string // Qualified name of the enclosing symbol, if any
+	Exported  bool   // Visible outside its file or package, by the language's rules
+	StartLine int    // 1-based, inclusive
+	EndLine   int
+}
+
+// Language families share export and import rules
+const (
+	familyGo         = "go"
+	familyJavaScript = "javascript"
+	familyPython     = "python"
+	familyRust       = "rust"
+	familyJava       = "java"
+)
+
+// SupportsSymbols reports whether symbols can be extracted from a file
+func SupportsSymbols(path string) bool {
+	_, ok := languages[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// ExtractSymbols parses a file and returns its declarations in source order.
+// Files in unsupported languages have no symbols.
+func ExtractSymbols(path string, content []byte) ([]Symbol, error) {
+	analysis, err := AnalyzeFile(path, content)
+	if err != nil || analysis == nil {
+		return nil, err
+	}
+	return analysis.Symbols, nil
+}
+
+// FileAnalysis is what a single parse of a source file yields
+type FileAnalysis struct {
+	Symbols []Symbol
+	Imports []string // Import specifiers as written, e.g. "./util" or "github.com/x/y"
+}
+
+// AnalyzeFile parses a file once for both its symbols and its imports. Files in
+// unsupported languages return nil. Builds with cgo parse with tree-sitter, the
+// others fall back to matching declarations line by line.
+func AnalyzeFile(path string, content []byte) (*FileAnalysis, error) {
+	lang, ok := languages[strings.ToLower(filepath.Ext(path))]
+	if !ok || len(content) == 0 {
+		return nil, nil
+	}
+	return lang.analyze(path, content)
+}
+
+// collapseSignature turns a declaration header into a one-line signature
+func collapseSignature(text string) string {
+	sig := strings.Join(strings.Fields(text), " ")
+	sig = strings.TrimRight(strings.TrimSuffix(sig, "=>"), ":;")
+	sig = strings.TrimSpace(sig)
+	if len(sig) > maxSignatureLength {
+		sig = sig[:maxSignatureLength]
+	}
+	return sig
+}
+
+// genericBase strips type parameters, e.g. Map<K, V> or Repo[T]
+func genericBase(name string) string {
+	if i := strings.IndexAny(name, "<["); i > 0 {
+		return strings.TrimSpace(name[:i])
+	}
+	return name
+}
@@ -0,0 +1,405 @@
+//go:build !cgo
+
+package context
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Without cgo there are no tree-sitter grammars, so declarations are matched line
+// by line the way they are conventionally written. Nesting follows braces or, for
+// Python, indentation, and function bodies are skipped like the parser skips locals.
+
+// declaration matches a line declaring a symbol, its "name" group is the name
+type declaration struct {
+	pattern   *regexp.Regexp
+	kind      SymbolKind // Empty for blocks that only qualify nested symbols, e.g. Rust impl blocks
+	container bool       // Nested functions become methods qualified with this symbol's name
+	member    bool       // Only declares symbols nested in a container, e.g. class methods
+}
+
+// language is a family of source files with the declarations worth reporting
+type language struct {
+	family       string
+	declarations []declaration
+}
+
+const (
+	rustVisibility = `^\s*(?:pub(?:\([^)]*\))?\s+)?`
+	javaModifiers  = `^\s*(?:(?:public|private|protected|static|final|abstract|sealed|non-sealed|strictfp|synchronized|native|default)\s+|@\w+(?:\([^)]*\))?\s+)*`
+)
+
+var (
+	goLanguage = &language{
+		family: familyGo,
+		declarations: []declaration{
+			{pattern: regexp.MustCompile(`^func\s*\((?P<receiver>[^)]*)\)\s*(?P<name>\w+)`), kind: KindMethod},
+			{pattern: regexp.MustCompile(`^func\s+(?P<name>\w+)`), kind: KindFunction},
+			{pattern: regexp.MustCompile(`^type\s+(?P<name>\w+)(?:\[[^\]]*\])?\s+struct\b`), kind: KindStruct},
+			{pattern: regexp.MustCompile(`^type\s+(?P<name>\w+)(?:\[[^\]]*\])?\s+interface\b`), kind: KindInterface},
+			{pattern: regexp.MustCompile(`^type\s+(?P<name>\w+)`), kind: KindType},
+		},
+	}
+
+	scriptLanguage = &language{
+		family: familyJavaScript,
+		declarations: []declaration{
+			{pattern: regexp.MustCompile(`^\s*(?:export\s+(?:default\s+)?)?(?:declare\s+)?(?:async\s+)?function\b\s*\*?\s*(?P<name>[\w$]+)`), kind: KindFunction},
+			{pattern: regexp.MustCompile(`^\s*(?:export\s+(?:default\s+)?)?(?:declare\s+)?(?:abstract\s+)?class\s+(?P<name>[\w$]+)`), kind: KindClass, container: true},
+			{pattern: regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?interface\s+(?P<name>[\w$]+)`), kind: KindInterface, container: true},
+			{pattern: regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?type\s+(?P<name>[\w$]+)\s*(?:<[^=]*>\s*)?=`), kind: KindType},
+			{pattern: regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?(?:const\s+)?enum\s+(?P<name>[\w$]+)`), kind: KindEnum},
+			{pattern: regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?(?:namespace|module)\s+(?P<name>[\w$.]+)\s*\{`), kind: KindModule, container: true},
+			{pattern: regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+(?P<name>[\w$]+)\s*(?::[^=]*)?=\s*(?:async\s+)?(?:function\b|(?:\([^)]*\)|[\w$]+)\s*(?::[^=]*)?=>)`), kind: KindFunction},
+			{pattern: regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|readonly|abstract|declare|override|async|get|set)\s+)*\*?\s*(?P<name>#?[\w$]+)\s*\??\s*(?:<[^>]*>\s*)?\(`), kind: KindMethod, member: true},
+		},
+	}
+
+	pythonLanguage = &language{
+		family: familyPython,
+		declarations: []declaration{
+			{pattern: regexp.MustCompile(`^\s*(?:async\s+)?def\s+(?P<name>\w+)`), kind: KindFunction},
+			{pattern: regexp.MustCompile(`^\s*class\s+(?P<name>\w+)`), kind: KindClass, container: true},
+		},
+	}
+
+	rustLanguage = &language{
+		family: familyRust,
+		declarations: []declaration{
+			{pattern: regexp.MustCompile(rustVisibility + `(?:(?:const|async|unsafe|extern\s+"[^"]*")\s+)*fn\s+(?P<name>\w+)`), kind: KindFunction},
+			{pattern: regexp.MustCompile(rustVisibility + `struct\s+(?P<name>\w+)`), kind: KindStruct},
+			{pattern: regexp.MustCompile(rustVisibility + `enum\s+(?P<name>\w+)`), kind: KindEnum},
+			{pattern: regexp.MustCompile(rustVisibility + `(?:union|type)\s+(?P<name>\w+)`), kind: KindType},
+			{pattern: regexp.MustCompile(rustVisibility + `(?:unsafe\s+)?trait\s+(?P<name>\w+)`), kind: KindTrait, container: true},
+			{pattern: regexp.MustCompile(rustVisibility + `mod\s+(?P<name>\w+)`), kind: KindModule, container: true},
+			{pattern: regexp.MustCompile(`^\s*(?:unsafe\s+)?impl\b(?:\s*<[^{]*?>)?\s+(?:[^{]*?\s+for\s+)?(?P<name>[\w:]+)`), container: true},
+		},
+	}
+
+	javaLanguage = &language{
+		family: familyJava,
+		declarations: []declaration{
+			{pattern: regexp.MustCompile(javaModifiers + `class\s+(?P<name>\w+)`), kind: KindClass, container: true},
+			{pattern: regexp.MustCompile(javaModifiers + `@?interface\s+(?P<name>\w+)`), kind: KindInterface, container: true},
+			{pattern: regexp.MustCompile(javaModifiers + `enum\s+(?P<name>\w+)`), kind: KindEnum, container: true},
+			{pattern: regexp.MustCompile(javaModifiers + `record\s+(?P<name>\w+)`), kind: KindClass, container: true},
+			{pattern: regexp.MustCompile(javaModifiers + `(?P<name>\w+)\s*\(`), kind: KindConstructor, member: true},
+			{pattern: regexp.MustCompile(javaModifiers + `(?:<[^>]*>\s*)?[\w.]+(?:<[^>]*>)?(?:\[\])*\s+(?P<name>\w+)\s*\(`), kind: KindMethod, member: true},
+		},
+	}
+
+	languages = map[string]*language{
+		".go":   goLanguage,
+		".js":   scriptLanguage,
+		".jsx":  scriptLanguage,
+		".mjs":  scriptLanguage,
+		".cjs":  scriptLanguage,
+		".ts":   scriptLanguage,
+		".mts":  scriptLanguage,
+		".cts":  scriptLanguage,
+		".tsx":  scriptLanguage,
+		".py":   pythonLanguage,
+		".pyi":  pythonLanguage,
+		".rs":   rustLanguage,
+		".java": javaLanguage,
+	}
+
+	// Keywords that look like method declarations in a class body
+	memberKeywords = map[string]bool{
+		"if": true, "for": true, "while": true, "switch": true, "catch": true,
+		"return": true, "function": true, "new": true, "await": true, "super": true,
+	}
+
+	privateMember = regexp.MustCompile(`\b(?:private|protected)\s`)
+	rustPublic    = regexp.MustCompile(`^\s*pub\b`)
+	javaPublic    = regexp.MustCompile(`\bpublic\s`)
+)
+
+// analyze matches the declarations and imports of a file line by line
+func (lang *language) analyze(path string, content []byte) (*FileAnalysis, error) {
+	lines := strings.Split(string(content), "\n")
+	s := &lineScanner{lang: lang}
+	if lang.family == familyPython {
+		s.scanIndented(lines)
+	} else {
+		s.scanBraced(lines)
+	}
+	return &FileAnalysis{Symbols: s.symbols, Imports: scanImports(lang, lines)}, nil
+}
+
+// lineScanner collects the symbols of a file line by line
+type lineScanner struct {
+	lang    *language
+	symbols []Symbol
+}
+
+// scope is the container a declaration is nested in
+type scope struct {
+	name     string // Qualified name, empty at file level
+	kind     SymbolKind
+	exported bool
+}
+
+// opened is a declaration whose body may follow
+type opened struct {
+	symbol    int // Index into symbols, -1 for blocks that only qualify nested symbols
+	inner     scope
+	container bool
+}
+
+// scanBraced follows the braces of C-like languages. A declaration owns the first
+// brace after it, unless a semicolon or another statement comes first.
+func (s *lineScanner) scanBraced(lines []string) {
+	type block struct {
+		depth int // Brace depth outside the block
+		opened
+	}
+	var (
+		stack   []block
+		pending *opened
+		depth   int
+		parens  int  // Open parentheses since the pending declaration
+		loose   bool // The pending declaration's header ended, only a leading brace opens it
+		comment bool
+		last    int
+	)
+	singleQuotes := s.lang.family != familyRust // Rust lifetimes are not strings
+
+	for i, line := range lines {
+		inComment := comment
+		code := stripCode(line, singleQuotes, &comment)
+		trimmed := strings.TrimSpace(code)
+		if trimmed == "" {
+			continue
+		}
+		last = i + 1
+		if loose && !strings.HasPrefix(trimmed, "{") {
+			pending = nil
+		}
+
+		parent, body := scope{exported: true}, false
+		if n := len(stack); n > 0 {
+			parent, body = stack[n-1].inner, !stack[n-1].container
+		}
+		if !body && !inComment {
+			if o, ok := s.declare(line, i+1, parent); ok {
+				pending, parens = &o, 0
+			}
+		}
+
+		for _, ch := range code {
+			switch ch {
+			case '(':
+				parens++
+			case ')':
+				parens--
+			case ';':
+				if parens <= 0 {
+					pending = nil
+				}
+			case '{':
+				if pending != nil {
+					stack = append(stack, block{depth: depth, opened: *pending})
+					pending = nil
+				}
+				depth++
+			case '}':
+				depth--
+				if n := len(stack); n > 0 && stack[n-1].depth == depth {
+					s.end(stack[n-1].symbol, i+1)
+					stack = stack[:n-1]
+				}
+			}
+		}
+		loose = pending != nil && parens <= 0
+	}
+	for _, b := range stack {
+		s.end(b.symbol, last)
+	}
+}
+
+// scanIndented follows the indentation of Python, a block ends at the next line
+// indented no deeper than its declaration
+func (s *lineScanner) scanIndented(lines []string) {
+	type level struct {
+		indent int
+		opened
+	}
+	var stack []level
+	last := 0 // Last line with code
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		for len(stack) > 0 && indent <= stack[len(stack)-1].indent {
+			s.end(stack[len(stack)-1].symbol, last)
+			stack = stack[:len(stack)-1]
+		}
+		last = i + 1
+
+		parent := scope{exported: true}
+		if n := len(stack); n > 0 {
+			if !stack[n-1].container {
+				continue
+			}
+			parent = stack[n-1].inner
+		}
+		if o, ok := s.declare(line, i+1, parent); ok {
+			stack = append(stack, level{indent: indent, opened: o})
+		}
+	}
+	for _, l := range stack {
+		s.end(l.symbol, last)
+	}
+}
+
+// declare records the symbol a line declares, if any
+func (s *lineScanner) declare(line string, number int, parent scope) (opened, bool) {
+	for i := range s.lang.declarations {
+		d := &s.lang.declarations[i]
+		if d.member && parent.name == "" {
+			continue
+		}
+		m := d.pattern.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		group := d.pattern.SubexpIndex("name")
+		start, end := m[2*group], m[2*group+1]
+		name, kind := line[start:end], d.kind
+
+		switch {
+		case d.member && kind == KindMethod && memberKeywords[name]:
+			continue
+		case kind == KindConstructor && name != parent.name[strings.LastIndex(parent.name, ".")+1:]:
+			continue
+		}
+		if group := d.pattern.SubexpIndex("receiver"); group > 0 && m[2*group] >= 0 {
+			// Go methods are qualified with their receiver type
+			if recv := receiverType(line[m[2*group]:m[2*group+1]]); recv != "" {
+				name = recv + "." + name
+			}
+		}
+		if kind == KindFunction && parent.name != "" {
+			kind = KindMethod
+		}
+
+		qualified := name
+		if parent.name != "" {
+			qualified = parent.name + "." + name
+		}
+		o := opened{symbol: -1, container: d.container}
+		exported := parent.exported
+		if kind != "" {
+			// Blocks such as Rust impls only group declarations, they have no visibility
+			exported = s.exported(line[:start], name, parent)
+			o.symbol = len(s.symbols)
+			header := line[m[0]:]
+			if j := strings.Index(header, "{"); j >= 0 {
+				header = header[:j]
+			}
+			s.symbols = append(s.symbols, Symbol{
+				Name:      qualified,
+				Kind:      kind,
+				Signature: collapseSignature(header),
+				Parent:    parent.name,
+				Exported:  exported,
+				StartLine: number,
+				EndLine:   number,
+			})
+		}
+		o.inner = scope{name: qualified, kind: kind, exported: exported}
+		return o, true
+	}
+	return opened{}, false
+}
+
+// end records the last line of a symbol's body
+func (s *lineScanner) end(symbol, line int) {
+	if symbol >= 0 && line > s.symbols[symbol].EndLine {
+		s.symbols[symbol].EndLine = line
+	}
+}
+
+// exported applies the language's visibility rules to a declaration, given the
+// text in front of its name
+func (s *lineScanner) exported(prefix, name string, parent scope) bool {
+	if !parent.exported {
+		return false
+	}
+	// Qualified Go method names start with the receiver type
+	name = name[strings.LastIndex(name, ".")+1:]
+
+	switch s.lang.family {
+	case familyGo:
+		return name != "" && unicode.IsUpper([]rune(name)[0])
+	case familyPython:
+		return !strings.HasPrefix(name, "_")
+	case familyJavaScript:
+		if parent.name != "" {
+			// Class members are public unless marked otherwise
+			return !strings.HasPrefix(name, "#") && !privateMember.MatchString(prefix)
+		}
+		return strings.HasPrefix(strings.TrimSpace(prefix), "export")
+	case familyRust:
+		return parent.kind == KindTrait || rustPublic.MatchString(prefix)
+	case familyJava:
+		// Interface members are implicitly public
+		return parent.kind == KindInterface || javaPublic.MatchString(prefix)
+	}
+	return false
+}
+
+// receiverType returns the type name of a Go method receiver such as r *Repo[T]
+func receiverType(receiver string) string {
+	fields := strings.Fields(receiver)
+	if len(fields) == 0 {
+		return ""
+	}
+	return genericBase(strings.TrimLeft(fields[len(fields)-1], "*"))
+}
+
+// stripCode blanks out comments and string literals so that the brackets inside
+// them do not count. comment carries an unterminated block comment across lines.
+func stripCode(line string, singleQuotes bool, comment *bool) string {
+	b := []byte(line)
+	var quote byte
+	for i := 0; i < len(b); i++ {
+		switch {
+		case *comment:
+			if b[i] == '*' && i+1 < len(b) && b[i+1] == '/' {
+				b[i+1] = ' '
+				*comment = false
+			}
+			b[i] = ' '
+		case quote != 0:
+			if b[i] == quote {
+				quote = 0
+				continue
+			}
+			if b[i] == '\\' && i+1 < len(b) {
+				b[i+1] = ' '
+			}
+			b[i] = ' '
+		case b[i] == '/' && i+1 < len(b) && b[i+1] == '/':
+			for ; i < len(b); i++ {
+				b[i] = ' '
+			}
+		case b[i] == '/' && i+1 < len(b) && b[i+1] == '*':
+			b[i], b[i+1] = ' ', ' '
+			i++
+			*comment = true
+		case b[i] == '"' || b[i] == '`' || (b[i] == '\'' && singleQuotes):
+			quote = b[i]
+		case b[i] == '\'' && i+2 < len(b) && (b[i+2] == '\'' || b[i+1] == '\\'):
+			// A char literal such as '{' or '\n', not a lifetime
+			quote = b[i]
+		}
+	}
+	return string(b)
+}
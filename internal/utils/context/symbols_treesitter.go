@@ -0,0 +1,321 @@
+//go:build cgo
+
+package context
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// nodeSpec describes how a syntax node type maps to a symbol
+type nodeSpec struct {
+	kind      SymbolKind // Empty for nodes that only qualify nested symbols, e.g. Rust impl blocks
+	nameField string
+	container bool // Nested functions become methods qualified with this symbol's name
+}
+
+// language is a tree-sitter grammar with the declarations worth reporting
+type language struct {
+	family  string
+	grammar func() *sitter.Language
+	nodes   map[string]nodeSpec
+}
+
+var (
+	goLanguage = &language{
+		family:  familyGo,
+		grammar: golang.GetLanguage,
+		nodes: map[string]nodeSpec{
+			"function_declaration": {kind: KindFunction, nameField: "name"},
+			"method_declaration":   {kind: KindMethod, nameField: "name"},
+			"type_spec":            {kind: KindType, nameField: "name"},
+		},
+	}
+
+	javascriptNodes = map[string]nodeSpec{
+		"function_declaration":           {kind: KindFunction, nameField: "name"},
+		"generator_function_declaration": {kind: KindFunction, nameField: "name"},
+		"class_declaration":              {kind: KindClass, nameField: "name", container: true},
+		"method_definition":              {kind: KindMethod, nameField: "name"},
+		"variable_declarator":            {kind: KindFunction, nameField: "name"},
+	}
+
+	typescriptNodes = merge(javascriptNodes, map[string]nodeSpec{
+		"abstract_class_declaration": {kind: KindClass, nameField: "name", container: true},
+		"interface_declaration":      {kind: KindInterface, nameField: "name", container: true},
+		"type_alias_declaration":     {kind: KindType, nameField: "name"},
+		"enum_declaration":           {kind: KindEnum, nameField: "name"},
+		"method_signature":           {kind: KindMethod, nameField: "name"},
+		"abstract_method_signature":  {kind: KindMethod, nameField: "name"},
+		"function_signature":         {kind: KindFunction, nameField: "name"},
+		"internal_module":            {kind: KindModule, nameField: "name", container: true},
+	})
+
+	languages = map[string]*language{
+		".go":   goLanguage,
+		".js":   {family: familyJavaScript, grammar: javascript.GetLanguage, nodes: javascriptNodes},
+		".jsx":  {family: familyJavaScript, grammar: javascript.GetLanguage, nodes: javascriptNodes},
+		".mjs":  {family: familyJavaScript, grammar: javascript.GetLanguage, nodes: javascriptNodes},
+		".cjs":  {family: familyJavaScript, grammar: javascript.GetLanguage, nodes: javascriptNodes},
+		".ts":   {family: familyJavaScript, grammar: typescript.GetLanguage, nodes: typescriptNodes},
+		".mts":  {family: familyJavaScript, grammar: typescript.GetLanguage, nodes: typescriptNodes},
+		".cts":  {family: familyJavaScript, grammar: typescript.GetLanguage, nodes: typescriptNodes},
+		".tsx":  {family: familyJavaScript, grammar: tsx.GetLanguage, nodes: typescriptNodes},
+		".py":   {family: familyPython, grammar: python.GetLanguage, nodes: pythonNodes},
+		".pyi":  {family: familyPython, grammar: python.GetLanguage, nodes: pythonNodes},
+		".rs":   {family: familyRust, grammar: rust.GetLanguage, nodes: rustNodes},
+		".java": {family: familyJava, grammar: java.GetLanguage, nodes: javaNodes},
+	}
+
+	pythonNodes = map[string]nodeSpec{
+		"function_definition": {kind: KindFunction, nameField: "name"},
+		"class_definition":    {kind: KindClass, nameField: "name", container: true},
+	}
+
+	rustNodes = map[string]nodeSpec{
+		"function_item":           {kind: KindFunction, nameField: "name"},
+		"function_signature_item": {kind: KindFunction, nameField: "name"},
+		"struct_item":             {kind: KindStruct, nameField: "name"},
+		"enum_item":               {kind: KindEnum, nameField: "name"},
+		"union_item":              {kind: KindType, nameField: "name"},
+		"type_item":               {kind: KindType, nameField: "name"},
+		"trait_item":              {kind: KindTrait, nameField: "name", container: true},
+		"mod_item":                {kind: KindModule, nameField: "name", container: true},
+		"impl_item":               {nameField: "type", container: true},
+	}
+
+	javaNodes = map[string]nodeSpec{
+		"class_declaration":           {kind: KindClass, nameField: "name", container: true},
+		"interface_declaration":       {kind: KindInterface, nameField: "name", container: true},
+		"enum_declaration":            {kind: KindEnum, nameField: "name", container: true},
+		"record_declaration":          {kind: KindClass, nameField: "name", container: true},
+		"annotation_type_declaration": {kind: KindInterface, nameField: "name", container: true},
+		"method_declaration":          {kind: KindMethod, nameField: "name"},
+		"constructor_declaration":     {kind: KindConstructor, nameField: "name"},
+	}
+)
+
+func merge(base, extra map[string]nodeSpec) map[string]nodeSpec {
+	merged := make(map[string]nodeSpec, len(base)+len(extra))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
+// analyze parses a file with the language's grammar
+func (lang *language) analyze(path string, content []byte) (*FileAnalysis, error) {
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(lang.grammar())
+
+	tree := parser.Parse(nil, content)
+	if tree == nil {
+		return nil, fmt.Errorf("failed to parse %s", path)
+	}
+	defer tree.Close()
+
+	root := tree.RootNode()
+	e := &extractor{lang: lang, src: content}
+	e.walk(root, scope{exported: true})
+	return &FileAnalysis{Symbols: e.symbols, Imports: extractImports(lang, root, content)}, nil
+}
+
+// extractor walks a syntax tree collecting symbols
+type extractor struct {
+	lang    *language
+	src     []byte
+	symbols []Symbol
+}
+
+// scope is the container a declaration is nested in
+type scope struct {
+	name     string // Qualified name, empty at file level
+	exported bool
+}
+
+func (e *extractor) walk(node *sitter.Node, parent scope) {
+	inner := parent
+	if spec, ok := e.lang.nodes[node.Type()]; ok {
+		if name, kind := e.describe(node, spec, parent.name); name != "" {
+			qualified := name
+			if parent.name != "" {
+				qualified = parent.name + "." + name
+			}
+			exported := parent.exported
+			if kind != "" {
+				// Blocks such as Rust impls only group declarations, they have no visibility
+				exported = e.exported(node, name, parent)
+			}
+			if kind != "" {
+				e.symbols = append(e.symbols, Symbol{
+					Name:      qualified,
+					Kind:      kind,
+					Signature: e.signature(node),
+					Parent:    parent.name,
+					Exported:  exported,
+					StartLine: int(node.StartPoint().Row) + 1,
+					EndLine:   int(node.EndPoint().Row) + 1,
+				})
+			}
+			if !spec.container {
+				// Locals inside a function body are not interesting on their own
+				return
+			}
+			inner = scope{name: qualified, exported: exported}
+		}
+	}
+
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		e.walk(node.NamedChild(i), inner)
+	}
+}
+
+// exported applies the language's visibility rules to a declaration
+func (e *extractor) exported(node *sitter.Node, name string, parent scope) bool {
+	if !parent.exported {
+		return false
+	}
+	// Qualified Go method names start with the receiver type
+	name = name[strings.LastIndex(name, ".")+1:]
+
+	switch e.lang.family {
+	case familyGo:
+		return name != "" && unicode.IsUpper([]rune(name)[0])
+	case familyPython:
+		return !strings.HasPrefix(name, "_")
+	case familyJavaScript:
+		if parent.name != "" {
+			// Class members are public unless marked otherwise
+			if strings.HasPrefix(name, "#") {
+				return false
+			}
+			if mod := childOfType(node, "accessibility_modifier"); mod != nil {
+				return mod.Content(e.src) == "public"
+			}
+			return true
+		}
+		for n := node.Parent(); n != nil; n = n.Parent() {
+			switch n.Type() {
+			case "export_statement":
+				return true
+			case "lexical_declaration", "variable_declaration":
+				continue
+			}
+			return false
+		}
+		return false
+	case familyRust:
+		if parent.name != "" && node.Parent() != nil && node.Parent().Parent() != nil &&
+			node.Parent().Parent().Type() == "trait_item" {
+			return true
+		}
+		return childOfType(node, "visibility_modifier") != nil
+	case familyJava:
+		if mods := childOfType(node, "modifiers"); mods != nil {
+			return strings.Contains(mods.Content(e.src), "public")
+		}
+		// Interface members are implicitly public
+		return node.Parent() != nil && node.Parent().Type() == "interface_body"
+	}
+	return false
+}
+
+// childOfType returns the first direct child of the given type
+func childOfType(node *sitter.Node, typ string) *sitter.Node {
+	for i := 0; i < int(node.ChildCount()); i++ {
+		if child := node.Child(i); child != nil && child.Type() == typ {
+			return child
+		}
+	}
+	return nil
+}
+
+// describe returns the symbol's unqualified name and kind, or an empty name when
+// the node is not a declaration after all
+func (e *extractor) describe(node *sitter.Node, spec nodeSpec, parent string) (string, SymbolKind) {
+	nameNode := node.ChildByFieldName(spec.nameField)
+	if nameNode == nil {
+		return "", ""
+	}
+	name := nameNode.Content(e.src)
+	kind := spec.kind
+
+	switch node.Type() {
+	case "method_declaration":
+		// Go methods are qualified with their receiver type
+		if receiver := node.ChildByFieldName("receiver"); receiver != nil && e.lang == goLanguage {
+			if recv := receiverType(receiver, e.src); recv != "" {
+				name = recv + "." + name
+			}
+		}
+	case "type_spec":
+		if t := node.ChildByFieldName("type"); t != nil {
+			switch t.Type() {
+			case "struct_type":
+				kind = KindStruct
+			case "interface_type":
+				kind = KindInterface
+			}
+		}
+	case "variable_declarator":
+		// Only const handler = () => {} style declarations are functions
+		value := node.ChildByFieldName("value")
+		if value == nil || nameNode.Type() != "identifier" {
+			return "", ""
+		}
+		switch value.Type() {
+		case "arrow_function", "function", "function_expression", "generator_function":
+		default:
+			return "", ""
+		}
+	case "impl_item":
+		// Methods of trait impls are qualified with the implementing type as well
+		name = genericBase(name)
+	}
+
+	if kind == KindFunction && parent != "" {
+		kind = KindMethod
+	}
+	return name, kind
+}
+
+// signature returns the declaration header: everything before the body
+func (e *extractor) signature(node *sitter.Node) string {
+	text := node.Content(e.src)
+	if body := node.ChildByFieldName("body"); body != nil {
+		text = string(e.src[node.StartByte():body.StartByte()])
+	} else if value := node.ChildByFieldName("value"); value != nil && node.Type() == "variable_declarator" {
+		if body := value.ChildByFieldName("body"); body != nil {
+			text = string(e.src[node.StartByte():body.StartByte()])
+		}
+	} else if i := strings.Index(text, "{"); i >= 0 {
+		text = text[:i]
+	}
+
+	return collapseSignature(text)
+}
+
+// receiverType returns the type name of a Go method receiver such as (r *Repo[T])
+func receiverType(receiver *sitter.Node, src []byte) string {
+	for i := 0; i < int(receiver.NamedChildCount()); i++ {
+		param := receiver.NamedChild(i)
+		if t := param.ChildByFieldName("type"); t != nil {
+			return genericBase(strings.TrimLeft(t.Content(src), "*"))
+		}
+	}
+	return ""
+}
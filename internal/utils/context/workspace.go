@@ -0,0 +1,405 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/mod/modfile"
+	"gopkg.in/yaml.v3"
+)
+
+// WorkspaceKind identifies the tool that declares a monorepo's packages
+type WorkspaceKind string
+
+const (
+	WorkspaceGo    WorkspaceKind = "go.work"
+	WorkspaceNpm   WorkspaceKind = "npm"
+	WorkspacePnpm  WorkspaceKind = "pnpm"
+	WorkspaceCargo WorkspaceKind = "Cargo"
+	WorkspaceNx    WorkspaceKind = "Nx"
+	WorkspaceTurbo WorkspaceKind = "Turborepo"
+	WorkspaceBazel WorkspaceKind = "Bazel"
+)
+
+// WorkspacePackage is one package of a monorepo
+type WorkspacePackage struct {
+	Name  string        `json:"name"`  // Module, package, crate or Bazel package name
+	Dir   string        `json:"dir"`   // Directory relative to the repository root
+	Scope string        `json:"scope"` // Short name used as a commit scope
+	Kind  WorkspaceKind `json:"kind"`
+}
+
+// Workspace is the set of packages a monorepo declares
+type Workspace struct {
+	Kinds    []WorkspaceKind    `json:"kinds"`
+	Packages []WorkspacePackage `json:"packages"` // Sorted by directory
+}
+
+// DetectWorkspace finds the packages declared by go.work, npm, Yarn and pnpm
+// workspaces, Cargo workspaces, Nx projects and Bazel BUILD roots. It returns nil
+// for repositories that are not monorepos.
+func DetectWorkspace(files []string, read func(string) ([]byte, error)) *Workspace {
+	present := make(map[string]bool, len(files))
+	for _, file := range files {
+		present[file] = true
+	}
+
+	w := &Workspace{}
+	seen := make(map[string]bool)
+	add := func(kind WorkspaceKind, packages []WorkspacePackage) {
+		added := false
+		for _, pkg := range packages {
+			if pkg.Dir == "." || seen[pkg.Dir] {
+				continue
+			}
+			seen[pkg.Dir] = true
+			pkg.Kind = kind
+			if pkg.Scope == "" {
+				pkg.Scope = path.Base(pkg.Dir)
+			}
+			w.Packages = append(w.Packages, pkg)
+			added = true
+		}
+		if added {
+			w.Kinds = append(w.Kinds, kind)
+		}
+	}
+	readFile := func(p string) []byte {
+		if !present[p] {
+			return nil
+		}
+		content, err := read(p)
+		if err != nil {
+			debug.Log("Failed to read %s: %v", p, err)
+			return nil
+		}
+		return content
+	}
+
+	if content := readFile("go.work"); content != nil {
+		add(WorkspaceGo, goWorkPackages(content, readFile))
+	}
+	if content := readFile("pnpm-workspace.yaml"); content != nil {
+		var pnpm struct {
+			Packages []string `yaml:"packages"`
+		}
+		if err := yaml.Unmarshal(content, &pnpm); err != nil {
+			debug.Log("Failed to parse pnpm-workspace.yaml: %v", err)
+		}
+		add(WorkspacePnpm, npmPackages(files, pnpm.Packages, readFile))
+	}
+	if content := readFile("package.json"); content != nil {
+		add(WorkspaceNpm, npmPackages(files, npmWorkspaces(content), readFile))
+	}
+	if content := readFile("Cargo.toml"); content != nil {
+		add(WorkspaceCargo, cargoPackages(files, content, readFile))
+	}
+	if present["nx.json"] {
+		add(WorkspaceNx, nxPackages(files, readFile))
+	}
+	if present["turbo.json"] && len(w.Packages) > 0 {
+		w.Kinds = append(w.Kinds, WorkspaceTurbo)
+	}
+	if present["WORKSPACE"] || present["WORKSPACE.bazel"] || present["MODULE.bazel"] {
+		add(WorkspaceBazel, bazelPackages(files))
+	}
+
+	if len(w.Packages) == 0 {
+		return nil
+	}
+	sort.Slice(w.Packages, func(i, j int) bool { return w.Packages[i].Dir < w.Packages[j].Dir })
+	return w
+}
+
+// RepoWorkspace detects the workspace of the files in the index, nil when the
+// repository is not a monorepo
+func RepoWorkspace(repo *git.Repository) (*Workspace, error) {
+	blobs, err := repo.TrackedBlobs()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(blobs))
+	for p := range blobs {
+		files = append(files, p)
+	}
+	sort.Strings(files)
+	return DetectWorkspace(files, func(p string) ([]byte, error) {
+		return repo.BlobContent(blobs[p])
+	}), nil
+}
+
+// goWorkPackages reads the modules a go.work file uses
+func goWorkPackages(content []byte, readFile func(string) []byte) []WorkspacePackage {
+	work, err := modfile.ParseWork("go.work", content, nil)
+	if err != nil {
+		debug.Log("Failed to parse go.work: %v", err)
+		return nil
+	}
+
+	var packages []WorkspacePackage
+	for _, use := range work.Use {
+		dir := path.Clean(strings.TrimPrefix(use.Path, "./"))
+		pkg := WorkspacePackage{Name: dir, Dir: dir}
+		if mod := readFile(path.Join(dir, "go.mod")); mod != nil {
+			if name := modfile.ModulePath(mod); name != "" {
+				pkg.Name = name
+				pkg.Scope = goModuleScope(name)
+			}
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// goModuleScope is the last element of a module path, skipping a major version suffix
+func goModuleScope(module string) string {
+	parts := strings.Split(module, "/")
+	last := parts[len(parts)-1]
+	if len(parts) > 1 && len(last) > 1 && last[0] == 'v' && strings.Trim(last[1:], "0123456789") == "" {
+		last = parts[len(parts)-2]
+	}
+	return last
+}
+
+// npmWorkspaces reads the workspace patterns of a root package.json, written either
+// as a list or, as Yarn allows, under a packages key
+func npmWorkspaces(content []byte) []string {
+	var pkg struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil || len(pkg.Workspaces) == 0 {
+		return nil
+	}
+
+	var patterns []string
+	if json.Unmarshal(pkg.Workspaces, &patterns) == nil {
+		return patterns
+	}
+	var yarn struct {
+		Packages []string `json:"packages"`
+	}
+	if json.Unmarshal(pkg.Workspaces, &yarn) == nil {
+		return yarn.Packages
+	}
+	return nil
+}
+
+// npmPackages finds the package.json directories matching workspace patterns
+func npmPackages(files []string, patterns []string, readFile func(string) []byte) []WorkspacePackage {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	var packages []WorkspacePackage
+	for _, dir := range manifestDirs(files, "package.json") {
+		if !matchesWorkspace(dir, patterns) {
+			continue
+		}
+		pkg := WorkspacePackage{Name: dir, Dir: dir}
+		var manifest struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(readFile(path.Join(dir, "package.json")), &manifest) == nil && manifest.Name != "" {
+			pkg.Name = manifest.Name
+			pkg.Scope = manifest.Name[strings.LastIndex(manifest.Name, "/")+1:]
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// cargoPackages finds the crates matching the members of a Cargo workspace
+func cargoPackages(files []string, content []byte, readFile func(string) []byte) []WorkspacePackage {
+	var root struct {
+		Workspace struct {
+			Members []string `toml:"members"`
+			Exclude []string `toml:"exclude"`
+		} `toml:"workspace"`
+	}
+	if err := toml.Unmarshal(content, &root); err != nil {
+		debug.Log("Failed to parse Cargo.toml: %v", err)
+		return nil
+	}
+	if len(root.Workspace.Members) == 0 {
+		return nil
+	}
+
+	patterns := append([]string(nil), root.Workspace.Members...)
+	for _, exclude := range root.Workspace.Exclude {
+		patterns = append(patterns, "!"+exclude)
+	}
+
+	var packages []WorkspacePackage
+	for _, dir := range manifestDirs(files, "Cargo.toml") {
+		if !matchesWorkspace(dir, patterns) {
+			continue
+		}
+		pkg := WorkspacePackage{Name: dir, Dir: dir}
+		if m, err := ParseManifest(path.Join(dir, "Cargo.toml"), readFile(path.Join(dir, "Cargo.toml"))); err == nil && m.Name != "" {
+			pkg.Name = m.Name
+			pkg.Scope = m.Name
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// nxPackages lists the Nx projects, one per project.json
+func nxPackages(files []string, readFile func(string) []byte) []WorkspacePackage {
+	var packages []WorkspacePackage
+	for _, dir := range manifestDirs(files, "project.json") {
+		pkg := WorkspacePackage{Name: path.Base(dir), Dir: dir}
+		var project struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(readFile(path.Join(dir, "project.json")), &project) == nil && project.Name != "" {
+			pkg.Name = project.Name
+			pkg.Scope = project.Name
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// bazelPackages lists the outermost Bazel packages: directories with a BUILD file
+// that no other BUILD file, besides the root's, encloses
+func bazelPackages(files []string) []WorkspacePackage {
+	dirs := append(manifestDirs(files, "BUILD"), manifestDirs(files, "BUILD.bazel")...)
+	sort.Strings(dirs)
+
+	var packages []WorkspacePackage
+	var last string
+	for _, dir := range dirs {
+		if dir == "." || dir == last || (last != "" && strings.HasPrefix(dir, last+"/")) {
+			continue
+		}
+		last = dir
+		packages = append(packages, WorkspacePackage{Name: "//" + dir, Dir: dir})
+	}
+	return packages
+}
+
+// manifestDirs lists the directories holding a file with the given name
+func manifestDirs(files []string, name string) []string {
+	var dirs []string
+	for _, file := range files {
+		if path.Base(file) == name && !strings.Contains(file, "node_modules/") {
+			dirs = append(dirs, path.Dir(file))
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// matchesWorkspace matches a directory against workspace globs such as "packages/*"
+// or "apps/**". Patterns starting with "!" exclude directories.
+func matchesWorkspace(dir string, patterns []string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		exclude := strings.HasPrefix(pattern, "!")
+		pattern = path.Clean(strings.TrimPrefix(strings.TrimPrefix(pattern, "!"), "./"))
+
+		ok := false
+		if prefix, found := strings.CutSuffix(pattern, "/**"); found {
+			ok = strings.HasPrefix(dir, prefix+"/")
+		} else {
+			ok, _ = path.Match(pattern, dir)
+		}
+		if ok {
+			matched = !exclude
+		}
+	}
+	return matched
+}
+
+// PackageOf returns the package a file belongs to, or nil when it lies outside
+// every package
+func (w *Workspace) PackageOf(file string) *WorkspacePackage {
+	var best *WorkspacePackage
+	for i := range w.Packages {
+		pkg := &w.Packages[i]
+		if strings.HasPrefix(file, pkg.Dir+"/") && (best == nil || len(pkg.Dir) > len(best.Dir)) {
+			best = pkg
+		}
+	}
+	return best
+}
+
+// Touched returns the packages holding any of the files, in directory order
+func (w *Workspace) Touched(files []string) []WorkspacePackage {
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		if pkg := w.PackageOf(file); pkg != nil {
+			dirs[pkg.Dir] = true
+		}
+	}
+
+	var touched []WorkspacePackage
+	for _, pkg := range w.Packages {
+		if dirs[pkg.Dir] {
+			touched = append(touched, pkg)
+		}
+	}
+	return touched
+}
+
+// Coupling is an import from a changed file in one package into another package
+// the change touches
+type Coupling struct {
+	From, To string // Package scopes
+	File     string // Importing file
+	Import   string // Imported file
+}
+
+func (c Coupling) String() string {
+	return fmt.Sprintf("%s and %s are coupled: %s imports %s", c.From, c.To, c.File, c.Import)
+}
+
+// Couplings finds the changed files that import files of other touched packages,
+// using the imports recorded by the file index. One coupling is kept per pair of
+// packages.
+func (w *Workspace) Couplings(files []string, deps map[string][]string) []Coupling {
+	touched := make(map[string]bool)
+	for _, pkg := range w.Touched(files) {
+		touched[pkg.Dir] = true
+	}
+
+	var couplings []Coupling
+	seen := make(map[[2]string]bool)
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+	for _, file := range sorted {
+		from := w.PackageOf(file)
+		if from == nil {
+			continue
+		}
+		for _, imported := range deps[file] {
+			to := w.PackageOf(imported)
+			if to == nil || to.Dir == from.Dir || !touched[to.Dir] {
+				continue
+			}
+			pair := [2]string{min(from.Dir, to.Dir), max(from.Dir, to.Dir)}
+			if seen[pair] {
+				continue
+			}
+			seen[pair] = true
+			couplings = append(couplings, Coupling{From: from.Scope, To: to.Scope, File: file, Import: imported})
+		}
+	}
+	return couplings
+}
+
+// Line renders the workspace for a profile, e.g. "pnpm workspace with 12 packages"
+func (w *Workspace) Line() string {
+	kinds := make([]string, len(w.Kinds))
+	for i, kind := range w.Kinds {
+		kinds[i] = string(kind)
+	}
+	return fmt.Sprintf("%s workspace with %d packages", strings.Join(kinds, " + "), len(w.Packages))
+}
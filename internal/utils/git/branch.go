@@ -0,0 +1,153 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+var (
+	// Placeholders a branch pattern may use
+	branchPlaceholder = regexp.MustCompile(`<([a-z]+)>`)
+	// Runs of characters that do not belong in a slug
+	slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+	// Separators left dangling next to a slash or repeated when a field is empty
+	danglingSeparators = regexp.MustCompile(`[-_.]*/[-_./]*`)
+	repeatedSeparators = regexp.MustCompile(`([-_.])[-_.]+`)
+)
+
+// BranchFields are the values substituted into a branch pattern
+type BranchFields struct {
+	Type   string // Change type, e.g. feat or fix
+	Ticket string // Issue key such as PROJ-123, may be empty
+	Slug   string // Short summary of the task, see Slugify
+}
+
+// FormatBranchName fills a pattern such as "<type>/<ticket>-<slug>" with fields.
+// Separators around empty fields are dropped, so a missing ticket gives
+// "feat/login-form" rather than "feat/-login-form".
+func FormatBranchName(pattern string, fields BranchFields) (string, error) {
+	var unknown []string
+	name := branchPlaceholder.ReplaceAllStringFunc(pattern, func(match string) string {
+		switch match {
+		case "<type>":
+			return fields.Type
+		case "<ticket>":
+			return fields.Ticket
+		case "<slug>":
+			return fields.Slug
+		}
+		unknown = append(unknown, match)
+		return match
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown placeholder %s in branch pattern %q: use <type>, <ticket> and <slug>", strings.Join(unknown, ", "), pattern)
+	}
+
+	name = danglingSeparators.ReplaceAllString(name, "/")
+	name = repeatedSeparators.ReplaceAllString(name, "$1")
+	return strings.Trim(name, "-_./"), nil
+}
+
+// Slugify turns free text into a lowercase, hyphenated branch name segment of at
+// most maxLen characters, cut at a word boundary when possible. Zero means no limit.
+func Slugify(text string, maxLen int) string {
+	slug := strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(text), "-"), "-")
+	if maxLen <= 0 || len(slug) <= maxLen {
+		return slug
+	}
+	slug = slug[:maxLen]
+	if i := strings.LastIndex(slug, "-"); i > maxLen/2 {
+		slug = slug[:i]
+	}
+	return strings.Trim(slug, "-")
+}
+
+// ValidateBranchName checks a branch name against the rules of git check-ref-format
+// --branch, returning an error naming the first rule it breaks
+func ValidateBranchName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("branch name is empty")
+	case name == "HEAD" || name == "@":
+		return fmt.Errorf("%q is not a valid branch name", name)
+	case strings.HasPrefix(name, "-"):
+		return fmt.Errorf("branch name %q starts with a dash", name)
+	case strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.Contains(name, "//"):
+		return fmt.Errorf("branch name %q has an empty path component", name)
+	case strings.HasSuffix(name, "."):
+		return fmt.Errorf("branch name %q ends with a dot", name)
+	case strings.Contains(name, ".."):
+		return fmt.Errorf("branch name %q contains \"..\"", name)
+	case strings.Contains(name, "@{"):
+		return fmt.Errorf("branch name %q contains \"@{\"", name)
+	}
+
+	for _, c := range name {
+		if c < 0x20 || c == 0x7f || strings.ContainsRune(" ~^:?*[\\", c) {
+			return fmt.Errorf("branch name %q contains the invalid character %q", name, c)
+		}
+	}
+	for _, component := range strings.Split(name, "/") {
+		if strings.HasPrefix(component, ".") {
+			return fmt.Errorf("branch name %q has a component starting with a dot", name)
+		}
+		if strings.HasSuffix(component, ".lock") {
+			return fmt.Errorf("branch name %q has a component ending with \".lock\"", name)
+		}
+	}
+	return nil
+}
+
+// BranchCollision returns the existing branch that keeps name from being created,
+// empty when there is none. A local branch collides when it has the same name or
+// when one name is a directory of the other, e.g. "feat" and "feat/login", since
+// git stores branches as files. A remote-tracking branch collides when it has the
+// same name on any remote.
+func (r *Repository) BranchCollision(name string) (string, error) {
+	refs, err := r.repo.References()
+	if err != nil {
+		return "", fmt.Errorf("failed to list branches: %w", err)
+	}
+	defer refs.Close()
+
+	var collision string
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		switch {
+		case ref.Name().IsBranch():
+			existing := ref.Name().Short()
+			if existing == name || strings.HasPrefix(name, existing+"/") || strings.HasPrefix(existing, name+"/") {
+				collision = existing
+			}
+		case ref.Name().IsRemote():
+			// refs/remotes/<remote>/<branch>
+			_, branch, _ := strings.Cut(strings.TrimPrefix(ref.Name().String(), "refs/remotes/"), "/")
+			if branch == name {
+				collision = ref.Name().Short()
+			}
+		}
+		if collision != "" {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list branches: %w", err)
+	}
+	return collision, nil
+}
+
+// CreateBranch creates a branch at HEAD and switches to it, carrying uncommitted
+// changes over as git switch does
+func (r *Repository) CreateBranch(name string) error {
+	if err := ValidateBranchName(name); err != nil {
+		return err
+	}
+	if _, err := r.runGit("switch", "-c", name); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", name, err)
+	}
+	return nil
+}
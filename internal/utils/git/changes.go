@@ -0,0 +1,665 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	utildiff "github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	// Lines of context around each hunk, as in git diff
+	contextLines = 3
+	// Minimum similarity for an added and a deleted file to be reported as a rename
+	renameThreshold = 50
+	// Rename detection by content is skipped when it would compare more pairs than this
+	maxRenamePairs = 1000
+	// Bytes inspected for a NUL byte when deciding whether a file is binary
+	binaryProbeSize = 8000
+	// Hash of the empty tree, the base a root commit is compared against
+	emptyTreeHash = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+)
+
+// fileVersion is one side of a change: a blob at a path with a mode
+type fileVersion struct {
+	path    string
+	hash    plumbing.Hash
+	mode    filemode.FileMode
+	content []byte // Loaded lazily, nil until read
+	loaded  bool
+}
+
+// change pairs the old and new version of a file, either of which may be nil
+type change struct {
+	from, to *fileVersion
+}
+
+// StagedDiff returns the parsed diff between HEAD and the index
+func (r *Repository) StagedDiff() (*Diff, error) {
+	if r.useGitBinary {
+		return r.parsedDiff("--cached")
+	}
+
+	changes, err := r.stagedChanges()
+	if err != nil {
+		return nil, err
+	}
+	changes, err = r.detectRenames(changes)
+	if err != nil {
+		return nil, err
+	}
+	return r.buildDiff(changes)
+}
+
+// UnstagedDiff returns the parsed diff between the index and the worktree
+func (r *Repository) UnstagedDiff() (*Diff, error) {
+	if r.useGitBinary {
+		return r.parsedDiff()
+	}
+
+	changes, err := r.unstagedChanges()
+	if err != nil {
+		return nil, err
+	}
+	return r.buildDiff(changes)
+}
+
+// AmendDiff returns the diff an amended HEAD would contain: HEAD's parent against
+// the index, so both HEAD's own changes and anything staged since are included.
+// A root commit is compared against the empty tree.
+func (r *Repository) AmendDiff() (*Diff, error) {
+	commit, err := r.amendTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.useGitBinary {
+		base := emptyTreeHash
+		if commit.NumParents() > 0 {
+			base = commit.ParentHashes[0].String()
+		}
+		return r.parsedDiff("--cached", base)
+	}
+
+	parent, err := parentEntries(commit)
+	if err != nil {
+		return nil, err
+	}
+	index, err := r.indexEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := r.detectRenames(compareEntries(parent, index))
+	if err != nil {
+		return nil, err
+	}
+	return r.buildDiff(changes)
+}
+
+// amendTarget returns the HEAD commit, which must exist to be amended
+func (r *Repository) amendTarget() (*object.Commit, error) {
+	head, err := r.repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("there is no commit to amend yet")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	commit, err := r.repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD commit: %w", err)
+	}
+	return commit, nil
+}
+
+// parentEntries returns the files in a commit's first parent, empty for a root commit
+func parentEntries(commit *object.Commit) (map[string]*fileVersion, error) {
+	if commit.NumParents() == 0 {
+		return make(map[string]*fileVersion), nil
+	}
+	parent, err := commit.Parent(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parent of %s: %w", commit.Hash, err)
+	}
+	return commitEntries(parent)
+}
+
+// parsedDiff runs git diff with fixed prefixes so user settings such as
+// diff.noprefix cannot change the format the parser relies on
+func (r *Repository) parsedDiff(args ...string) (*Diff, error) {
+	gitArgs := append([]string{
+		"diff", "--no-color", "--no-ext-diff", "--find-renames",
+		"--src-prefix=a/", "--dst-prefix=b/",
+	}, args...)
+
+	output, err := r.runGit(gitArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff: %w", err)
+	}
+	return ParseDiff(output)
+}
+
+// headEntries returns every file in the HEAD commit, empty when HEAD is unborn
+func (r *Repository) headEntries() (map[string]*fileVersion, error) {
+	head, err := r.repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return make(map[string]*fileVersion), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	commit, err := r.repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD commit: %w", err)
+	}
+	return commitEntries(commit)
+}
+
+// commitEntries returns every file in a commit's tree
+func commitEntries(commit *object.Commit) (map[string]*fileVersion, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %w", commit.Hash, err)
+	}
+
+	entries := make(map[string]*fileVersion)
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk tree of %s: %w", commit.Hash, err)
+		}
+		if entry.Mode == filemode.Dir {
+			continue
+		}
+		entries[name] = &fileVersion{path: name, hash: entry.Hash, mode: entry.Mode}
+	}
+	return entries, nil
+}
+
+// indexEntries returns every file in the index. Conflicted paths are reported once.
+func (r *Repository) indexEntries() (map[string]*fileVersion, error) {
+	idx, err := r.repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	entries := make(map[string]*fileVersion, len(idx.Entries))
+	for _, e := range idx.Entries {
+		if _, seen := entries[e.Name]; seen || e.IntentToAdd {
+			continue
+		}
+		entries[e.Name] = &fileVersion{path: e.Name, hash: e.Hash, mode: e.Mode}
+	}
+	return entries, nil
+}
+
+// stagedChanges compares the HEAD tree with the index
+func (r *Repository) stagedChanges() ([]change, error) {
+	head, err := r.headEntries()
+	if err != nil {
+		return nil, err
+	}
+	index, err := r.indexEntries()
+	if err != nil {
+		return nil, err
+	}
+	return compareEntries(head, index), nil
+}
+
+// compareEntries lists the files that differ between two sets of entries
+func compareEntries(old, new map[string]*fileVersion) []change {
+	var changes []change
+	for path, to := range new {
+		from, ok := old[path]
+		switch {
+		case !ok:
+			changes = append(changes, change{to: to})
+		case from.hash != to.hash || from.mode != to.mode:
+			changes = append(changes, change{from: from, to: to})
+		}
+	}
+	for path, from := range old {
+		if _, ok := new[path]; !ok {
+			changes = append(changes, change{from: from})
+		}
+	}
+	return changes
+}
+
+// unstagedChanges compares the index with the worktree. Untracked files are not
+// included, matching git diff.
+func (r *Repository) unstagedChanges() ([]change, error) {
+	status, err := r.status()
+	if err != nil {
+		return nil, err
+	}
+	index, err := r.indexEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []change
+	for path, s := range status {
+		from, ok := index[path]
+		if !ok || s.Worktree == git.Unmodified || s.Worktree == git.Untracked {
+			continue
+		}
+		if s.Worktree == git.Deleted {
+			changes = append(changes, change{from: from})
+			continue
+		}
+
+		to, err := r.worktreeVersion(path)
+		if err != nil {
+			return nil, err
+		}
+		if to.hash != from.hash || to.mode != from.mode {
+			changes = append(changes, change{from: from, to: to})
+		}
+	}
+	return changes, nil
+}
+
+// worktreeVersion reads a file from the worktree, hashing it like git would
+func (r *Repository) worktreeVersion(path string) (*fileVersion, error) {
+	w, err := r.repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	info, err := w.Filesystem.Lstat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	version := &fileVersion{path: path, mode: filemode.Regular, loaded: true}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := w.Filesystem.Readlink(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read link %s: %w", path, err)
+		}
+		version.mode = filemode.Symlink
+		version.content = []byte(target)
+	default:
+		if info.Mode()&0111 != 0 {
+			version.mode = filemode.Executable
+		}
+		if version.content, err = util.ReadFile(w.Filesystem, path); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	version.hash = plumbing.ComputeHash(plumbing.BlobObject, version.content)
+	return version, nil
+}
+
+// load returns the file's content, reading the blob on first use
+func (r *Repository) load(v *fileVersion) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if v.loaded {
+		return v.content, nil
+	}
+
+	if v.mode == filemode.Submodule {
+		v.content = []byte("Subproject commit " + v.hash.String() + "\n")
+		v.loaded = true
+		return v.content, nil
+	}
+
+	blob, err := r.repo.BlobObject(v.hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob for %s: %w", v.path, err)
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob for %s: %w", v.path, err)
+	}
+	defer reader.Close()
+
+	if v.content, err = io.ReadAll(reader); err != nil {
+		return nil, fmt.Errorf("failed to read blob for %s: %w", v.path, err)
+	}
+	v.loaded = true
+	return v.content, nil
+}
+
+// detectRenames pairs deleted and added files into renames, first by identical
+// content and then by line similarity
+func (r *Repository) detectRenames(changes []change) ([]change, error) {
+	var added, deleted []int
+	for i, c := range changes {
+		switch {
+		case c.from == nil:
+			added = append(added, i)
+		case c.to == nil:
+			deleted = append(deleted, i)
+		}
+	}
+	if len(added) == 0 || len(deleted) == 0 {
+		return changes, nil
+	}
+
+	// Sort for deterministic pairing
+	byPath := func(idx []int) {
+		sort.Slice(idx, func(a, b int) bool {
+			return changePath(changes[idx[a]]) < changePath(changes[idx[b]])
+		})
+	}
+	byPath(added)
+	byPath(deleted)
+
+	used := make(map[int]bool)
+	renames := make(map[int]int) // added index -> deleted index
+
+	for _, a := range added {
+		for _, del := range deleted {
+			if !used[del] && changes[del].from.hash == changes[a].to.hash {
+				renames[a] = del
+				used[del] = true
+				break
+			}
+		}
+	}
+
+	similarity := make(map[int]int)
+	if len(added)*len(deleted) <= maxRenamePairs {
+		for _, a := range added {
+			if _, ok := renames[a]; ok {
+				continue
+			}
+			best, bestScore := -1, renameThreshold-1
+			for _, del := range deleted {
+				if used[del] {
+					continue
+				}
+				score, err := r.similarity(changes[del].from, changes[a].to)
+				if err != nil {
+					return nil, err
+				}
+				if score > bestScore {
+					best, bestScore = del, score
+				}
+			}
+			if best >= 0 {
+				renames[a] = best
+				similarity[a] = bestScore
+				used[best] = true
+			}
+		}
+	}
+
+	var result []change
+	for i, c := range changes {
+		if used[i] {
+			continue
+		}
+		if del, ok := renames[i]; ok {
+			c.from = changes[del].from
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// similarity returns the percentage of content shared by two files
+func (r *Repository) similarity(from, to *fileVersion) (int, error) {
+	if from.hash == to.hash {
+		return 100, nil
+	}
+	a, err := r.load(from)
+	if err != nil {
+		return 0, err
+	}
+	b, err := r.load(to)
+	if err != nil {
+		return 0, err
+	}
+	if len(a)+len(b) == 0 || isBinary(a) || isBinary(b) {
+		return 0, nil
+	}
+
+	common := 0
+	for _, d := range utildiff.Do(string(a), string(b)) {
+		if d.Type == diffmatchpatch.DiffEqual {
+			common += len(d.Text)
+		}
+	}
+	return 200 * common / (len(a) + len(b)), nil
+}
+
+// buildDiff turns changes into the diff model, sorted by path like git diff
+func (r *Repository) buildDiff(changes []change) (*Diff, error) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changePath(changes[i]) < changePath(changes[j])
+	})
+
+	diff := &Diff{Files: make([]*FileDiff, 0, len(changes))}
+	for _, c := range changes {
+		f, err := r.fileDiff(c)
+		if err != nil {
+			return nil, err
+		}
+		diff.Files = append(diff.Files, f)
+	}
+	return diff, nil
+}
+
+// fileDiff computes the hunks for a single change
+func (r *Repository) fileDiff(c change) (*FileDiff, error) {
+	f := &FileDiff{Status: StatusModified}
+	oldHash, newHash := plumbing.ZeroHash, plumbing.ZeroHash
+
+	switch {
+	case c.from == nil:
+		f.Status = StatusAdded
+		f.Path = c.to.path
+		f.NewMode = modeString(c.to.mode)
+		newHash = c.to.hash
+	case c.to == nil:
+		f.Status = StatusDeleted
+		f.Path = c.from.path
+		f.OldMode = modeString(c.from.mode)
+		oldHash = c.from.hash
+	default:
+		f.Path = c.to.path
+		f.OldMode, f.NewMode = modeString(c.from.mode), modeString(c.to.mode)
+		oldHash, newHash = c.from.hash, c.to.hash
+		if c.from.path != c.to.path {
+			f.Status = StatusRenamed
+			f.OldPath = c.from.path
+			score, err := r.similarity(c.from, c.to)
+			if err != nil {
+				return nil, err
+			}
+			f.Similarity = score
+		} else if isTypeChange(f.OldMode, f.NewMode) {
+			f.Status = StatusTypeChanged
+		}
+	}
+
+	if oldHash == newHash {
+		// Pure rename or mode change, no content to show
+		return f, nil
+	}
+	f.Index = oldHash.String()[:7] + ".." + newHash.String()[:7]
+
+	oldContent, err := r.load(c.from)
+	if err != nil {
+		return nil, err
+	}
+	newContent, err := r.load(c.to)
+	if err != nil {
+		return nil, err
+	}
+
+	if isBinary(oldContent) || isBinary(newContent) {
+		f.Binary = true
+		return f, nil
+	}
+
+	f.Hunks = buildHunks(string(oldContent), string(newContent))
+	return f, nil
+}
+
+// diffLine is a line of either file, kept with its trailing newline if it has one
+type diffLine struct {
+	kind LineKind
+	text string
+}
+
+// buildHunks computes unified diff hunks with git's default amount of context
+func buildHunks(oldContent, newContent string) []*Hunk {
+	var lines []diffLine
+	for _, d := range utildiff.Do(oldContent, newContent) {
+		kind := LineContext
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			kind = LineDeleted
+		case diffmatchpatch.DiffInsert:
+			kind = LineAdded
+		}
+		for _, text := range splitLinesKeepEOL(d.Text) {
+			lines = append(lines, diffLine{kind: kind, text: text})
+		}
+	}
+
+	oldFileLines := splitLinesKeepEOL(oldContent)
+
+	// Line numbers on each side before every entry
+	oldNo := make([]int, len(lines)+1)
+	newNo := make([]int, len(lines)+1)
+	for i, l := range lines {
+		oldNo[i+1], newNo[i+1] = oldNo[i], newNo[i]
+		if l.kind != LineAdded {
+			oldNo[i+1]++
+		}
+		if l.kind != LineDeleted {
+			newNo[i+1]++
+		}
+	}
+
+	var hunks []*Hunk
+	for i := 0; i < len(lines); {
+		if lines[i].kind == LineContext {
+			i++
+			continue
+		}
+
+		// Extend the hunk while changes are close enough for their context to overlap
+		start := max(i-contextLines, 0)
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].kind != LineContext {
+				end = j
+			} else if j-end > 2*contextLines {
+				break
+			}
+		}
+		stop := min(end+contextLines+1, len(lines))
+
+		h := &Hunk{
+			OldStart: oldNo[start] + 1,
+			OldLines: oldNo[stop] - oldNo[start],
+			NewStart: newNo[start] + 1,
+			NewLines: newNo[stop] - newNo[start],
+		}
+		// An empty side is reported at the line before the hunk
+		if h.OldLines == 0 {
+			h.OldStart--
+		}
+		if h.NewLines == 0 {
+			h.NewStart--
+		}
+		h.Section = sectionHeading(oldFileLines, oldNo[start])
+
+		for _, l := range lines[start:stop] {
+			h.Lines = append(h.Lines, Line{Kind: l.kind, Content: strings.TrimSuffix(l.text, "\n")})
+			if !strings.HasSuffix(l.text, "\n") {
+				h.Lines = append(h.Lines, Line{Kind: LineNoNewline, Content: " No newline at end of file"})
+			}
+		}
+		hunks = append(hunks, h)
+		i = stop
+	}
+	return hunks
+}
+
+// sectionHeading finds the heading git shows after a hunk's range: the closest line
+// above the hunk that starts with a letter, '_' or '$', cut to 80 bytes
+func sectionHeading(lines []string, before int) string {
+	for i := min(before, len(lines)) - 1; i >= 0; i-- {
+		line := lines[i]
+		if line == "" {
+			continue
+		}
+		c := line[0]
+		if c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			if len(line) > 80 {
+				// Cut at a rune boundary so the heading stays valid UTF-8
+				cut := 80
+				for cut > 0 && !utf8.RuneStart(line[cut]) {
+					cut--
+				}
+				line = line[:cut]
+			}
+			return strings.TrimRight(line, " \t\r\n")
+		}
+	}
+	return ""
+}
+
+// splitLinesKeepEOL splits text into lines, keeping each line's newline
+func splitLinesKeepEOL(text string) []string {
+	var lines []string
+	for text != "" {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			lines = append(lines, text)
+			break
+		}
+		lines = append(lines, text[:i+1])
+		text = text[i+1:]
+	}
+	return lines
+}
+
+// isBinary applies git's heuristic of looking for a NUL byte near the start
+func isBinary(content []byte) bool {
+	if len(content) > binaryProbeSize {
+		content = content[:binaryProbeSize]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+func modeString(mode filemode.FileMode) string {
+	return fmt.Sprintf("%06o", uint32(mode))
+}
+
+func changePath(c change) string {
+	if c.to != nil {
+		return c.to.path
+	}
+	return c.from.path
+}
@@ -0,0 +1,145 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Signature formats accepted by git's gpg.format setting
+var signFormats = map[string]bool{"openpgp": true, "x509": true, "ssh": true}
+
+// CommitOptions controls how a commit is created. Settings such as commit.gpgsign,
+// user.signingkey and hooks are honoured because the git binary runs the commit.
+type CommitOptions struct {
+	Amend      bool   // Replace HEAD instead of creating a new commit
+	Fixup      string // Create a fixup! commit for this revision, the message is ignored
+	Sign       bool   // Sign the commit (-S) even when commit.gpgsign is off
+	SigningKey string // Key passed to -S, user.signingkey when empty
+	SignFormat string // gpg.format override: openpgp, x509 or ssh
+	NoVerify   bool   // Skip the pre-commit and commit-msg hooks
+	Author     string // "Name <email>" override for the author
+}
+
+// CommitResult describes a commit that was created
+type CommitResult struct {
+	Hash   string
+	Output string // Hook output and warnings printed during the commit
+}
+
+// ErrCommitFailed is returned when git commit exits with an error. Output holds
+// git's and the hooks' output exactly as printed.
+type ErrCommitFailed struct {
+	Output string
+	Err    error
+}
+
+func (e ErrCommitFailed) Error() string {
+	output := strings.TrimRight(e.Output, "\n")
+	if output == "" {
+		return fmt.Sprintf("git commit: %v", e.Err)
+	}
+	return fmt.Sprintf("git commit: %v\n%s", e.Err, output)
+}
+
+func (e ErrCommitFailed) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks for option combinations git would reject less clearly
+func (o CommitOptions) Validate() error {
+	if o.Amend && o.Fixup != "" {
+		return fmt.Errorf("--amend and --fixup cannot be used together")
+	}
+	if o.SignFormat != "" && !signFormats[o.SignFormat] {
+		return fmt.Errorf("unknown signature format %q, expected openpgp, x509 or ssh", o.SignFormat)
+	}
+	if o.Author != "" && !identityPattern.MatchString(o.Author) {
+		return fmt.Errorf("invalid author %q, expected \"Name <email>\"", o.Author)
+	}
+	return nil
+}
+
+// args builds the git command line for a commit with the given message
+func (o CommitOptions) args(message string) []string {
+	var args []string
+	if o.SignFormat != "" {
+		args = append(args, "-c", "gpg.format="+o.SignFormat)
+	}
+	// Quiet drops git's summary line so the output is what hooks printed
+	args = append(args, "commit", "--quiet")
+
+	switch {
+	case o.Fixup != "":
+		args = append(args, "--fixup="+o.Fixup)
+	case o.Amend && message == "":
+		args = append(args, "--amend", "--no-edit")
+	case o.Amend:
+		args = append(args, "--amend", "-m", message)
+	default:
+		args = append(args, "-m", message)
+	}
+
+	if o.Sign || o.SigningKey != "" {
+		args = append(args, "--gpg-sign="+o.SigningKey)
+	}
+	if o.NoVerify {
+		args = append(args, "--no-verify")
+	}
+	if o.Author != "" {
+		args = append(args, "--author="+o.Author)
+	}
+	return args
+}
+
+// CommitWith creates a commit from the index with the git binary, so signing, hooks
+// and identity follow the user's git configuration. Hook and git output is returned
+// verbatim, both on success and in ErrCommitFailed.
+func (r *Repository) CommitWith(message string, opts CommitOptions) (*CommitResult, error) {
+	return r.commit(message, opts, nil)
+}
+
+// commit runs git commit with extra environment variables, e.g. GIT_INDEX_FILE to
+// commit from another index
+func (r *Repository) commit(message string, opts CommitOptions, env []string) (*CommitResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if message == "" && !opts.Amend && opts.Fixup == "" {
+		return nil, fmt.Errorf("empty commit message")
+	}
+
+	root, err := r.GetRepoRootPath()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("git", opts.args(message)...)
+	cmd.Dir = root
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	// Signing agents and hooks may need to prompt on the terminal
+	cmd.Stdin = os.Stdin
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("failed to run git commit: %w", err)
+		}
+		return nil, ErrCommitFailed{Output: output.String(), Err: err}
+	}
+
+	head, err := r.repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD after commit: %w", err)
+	}
+	return &CommitResult{Hash: head.Hash().String(), Output: output.String()}, nil
+}
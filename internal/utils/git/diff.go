@@ -0,0 +1,447 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ChangeStatus describes how a file changed between two trees
+type ChangeStatus string
+
+const (
+	StatusAdded       ChangeStatus = "added"
+	StatusModified    ChangeStatus = "modified"
+	StatusDeleted     ChangeStatus = "deleted"
+	StatusRenamed     ChangeStatus = "renamed"
+	StatusCopied      ChangeStatus = "copied"
+	StatusTypeChanged ChangeStatus = "type changed"
+)
+
+// LineKind identifies a line inside a hunk
+type LineKind byte
+
+const (
+	LineContext   LineKind = ' '
+	LineAdded     LineKind = '+'
+	LineDeleted   LineKind = '-'
+	LineNoNewline LineKind = '\\' // "\ No newline at end of file" marker
+)
+
+// Diff is a parsed unified diff
+type Diff struct {
+	Files []*FileDiff
+}
+
+// FileDiff is the change to a single file
+type FileDiff struct {
+	Path       string // Path after the change, the old path for deletions
+	OldPath    string // Path before the change, differs from Path for renames and copies
+	Status     ChangeStatus
+	OldMode    string // File modes, only set when they are reported
+	NewMode    string
+	Similarity int    // Similarity percentage for renames and copies
+	Index      string // Abbreviated blob hashes, "old..new"
+	Binary     bool
+	Hunks      []*Hunk
+}
+
+// Hunk is a contiguous block of changes within a file
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Section  string // Enclosing function or section reported after the range, if any
+	Lines    []Line
+}
+
+// Line is a single line of a hunk, without its leading marker
+type Line struct {
+	Kind    LineKind
+	Content string
+}
+
+// ParseDiff parses the output of git diff. Paths are expected with the default
+// a/ and b/ prefixes, which are removed.
+func ParseDiff(raw string) (*Diff, error) {
+	diff := &Diff{}
+	var (
+		file *FileDiff
+		hunk *Hunk
+		// Lines left in the current hunk on each side, used to tell content from headers
+		oldLeft, newLeft int
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNo++
+
+		if hunk != nil && (oldLeft > 0 || newLeft > 0 || strings.HasPrefix(line, `\`)) {
+			kind := LineContext
+			content := line
+			if line != "" {
+				kind = LineKind(line[0])
+				content = line[1:]
+			}
+
+			switch kind {
+			case LineContext:
+				oldLeft--
+				newLeft--
+			case LineDeleted:
+				oldLeft--
+			case LineAdded:
+				newLeft--
+			case LineNoNewline:
+			default:
+				return nil, fmt.Errorf("line %d: unexpected line in hunk: %q", lineNo, line)
+			}
+			hunk.Lines = append(hunk.Lines, Line{Kind: kind, Content: content})
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			file = &FileDiff{Status: StatusModified}
+			hunk = nil
+			file.OldPath, file.Path = parseDiffHeader(strings.TrimPrefix(line, "diff --git "))
+			diff.Files = append(diff.Files, file)
+
+		case file == nil:
+			// Anything before the first file header (e.g. a commit message) is ignored
+
+		case strings.HasPrefix(line, "@@ "):
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			hunk = h
+			oldLeft, newLeft = h.OldLines, h.NewLines
+			file.Hunks = append(file.Hunks, hunk)
+
+		case strings.HasPrefix(line, "new file mode "):
+			file.Status = StatusAdded
+			file.NewMode = strings.TrimPrefix(line, "new file mode ")
+		case strings.HasPrefix(line, "deleted file mode "):
+			file.Status = StatusDeleted
+			file.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+		case strings.HasPrefix(line, "old mode "):
+			file.OldMode = strings.TrimPrefix(line, "old mode ")
+		case strings.HasPrefix(line, "new mode "):
+			file.NewMode = strings.TrimPrefix(line, "new mode ")
+			if isTypeChange(file.OldMode, file.NewMode) {
+				file.Status = StatusTypeChanged
+			}
+		case strings.HasPrefix(line, "similarity index "):
+			file.Similarity, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "similarity index "), "%"))
+		case strings.HasPrefix(line, "rename from "):
+			file.Status = StatusRenamed
+			file.OldPath = unquotePath(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			file.Path = unquotePath(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "copy from "):
+			file.Status = StatusCopied
+			file.OldPath = unquotePath(strings.TrimPrefix(line, "copy from "))
+		case strings.HasPrefix(line, "copy to "):
+			file.Path = unquotePath(strings.TrimPrefix(line, "copy to "))
+		case strings.HasPrefix(line, "index "):
+			fields := strings.Fields(strings.TrimPrefix(line, "index "))
+			if len(fields) > 0 {
+				file.Index = fields[0]
+			}
+			// A trailing mode means it did not change
+			if len(fields) > 1 && file.OldMode == "" && file.NewMode == "" {
+				file.OldMode, file.NewMode = fields[1], fields[1]
+			}
+		case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+			file.Binary = true
+		case strings.HasPrefix(line, "--- "):
+			if p := strings.TrimPrefix(line, "--- "); p != "/dev/null" {
+				file.OldPath = stripPrefix(unquotePath(p), "a/")
+			}
+		case strings.HasPrefix(line, "+++ "):
+			if p := strings.TrimPrefix(line, "+++ "); p != "/dev/null" {
+				file.Path = stripPrefix(unquotePath(p), "b/")
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read diff: %w", err)
+	}
+
+	for _, f := range diff.Files {
+		switch f.Status {
+		case StatusAdded:
+			f.OldPath = ""
+		case StatusDeleted:
+			f.Path = f.OldPath
+		}
+		if f.Status == StatusModified && f.OldPath == f.Path {
+			f.OldPath = ""
+		}
+	}
+
+	return diff, nil
+}
+
+// parseDiffHeader extracts both paths from the "a/<old> b/<new>" part of a diff --git line.
+// Paths containing spaces are ambiguous here, so rename and ---/+++ lines refine them later.
+func parseDiffHeader(header string) (oldPath, newPath string) {
+	if strings.HasPrefix(header, `"`) {
+		if end := closingQuote(header); end > 0 {
+			oldPath = unquotePath(header[:end+1])
+			newPath = unquotePath(strings.TrimSpace(header[end+1:]))
+			return stripPrefix(oldPath, "a/"), stripPrefix(newPath, "b/")
+		}
+	}
+
+	// Without a rename both halves are identical, so split in the middle
+	if n := len(header); n%2 == 1 && header[n/2] == ' ' {
+		a, b := header[:n/2], header[n/2+1:]
+		if strings.TrimPrefix(a, "a/") == strings.TrimPrefix(b, "b/") {
+			return stripPrefix(a, "a/"), stripPrefix(b, "b/")
+		}
+	}
+
+	if i := strings.Index(header, " b/"); i >= 0 {
+		return stripPrefix(header[:i], "a/"), unquotePath(header[i+1:])[2:]
+	}
+	return header, header
+}
+
+// parseHunkHeader parses "@@ -oldStart,oldLines +newStart,newLines @@ section"
+func parseHunkHeader(line string) (*Hunk, error) {
+	rest := strings.TrimPrefix(line, "@@ ")
+	end := strings.Index(rest, " @@")
+	if end < 0 {
+		return nil, fmt.Errorf("invalid hunk header: %q", line)
+	}
+
+	ranges := strings.Fields(rest[:end])
+	if len(ranges) != 2 || !strings.HasPrefix(ranges[0], "-") || !strings.HasPrefix(ranges[1], "+") {
+		return nil, fmt.Errorf("invalid hunk header: %q", line)
+	}
+
+	h := &Hunk{Section: strings.TrimSpace(rest[end+3:])}
+	var err error
+	if h.OldStart, h.OldLines, err = parseRange(ranges[0][1:]); err != nil {
+		return nil, fmt.Errorf("invalid hunk header %q: %w", line, err)
+	}
+	if h.NewStart, h.NewLines, err = parseRange(ranges[1][1:]); err != nil {
+		return nil, fmt.Errorf("invalid hunk header %q: %w", line, err)
+	}
+	return h, nil
+}
+
+// parseRange parses "start,count", where the count defaults to one
+func parseRange(s string) (start, count int, err error) {
+	startStr, countStr, hasCount := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startStr); err != nil {
+		return 0, 0, err
+	}
+	if !hasCount {
+		return start, 1, nil
+	}
+	count, err = strconv.Atoi(countStr)
+	return start, count, err
+}
+
+// closingQuote returns the index of the quote closing a C-style quoted string
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// unquotePath decodes a path that git quoted because of special characters
+func unquotePath(p string) string {
+	if len(p) >= 2 && p[0] == '"' && p[len(p)-1] == '"' {
+		if unquoted, err := strconv.Unquote(p); err == nil {
+			return unquoted
+		}
+	}
+	return p
+}
+
+func stripPrefix(p, prefix string) string {
+	return strings.TrimPrefix(p, prefix)
+}
+
+// isTypeChange reports whether a mode change turns a file into a symlink or submodule or back
+func isTypeChange(oldMode, newMode string) bool {
+	kind := func(mode string) string {
+		if len(mode) < 2 {
+			return mode
+		}
+		return mode[:2]
+	}
+	return oldMode != "" && newMode != "" && kind(oldMode) != kind(newMode)
+}
+
+// Paths returns the path of every changed file
+func (d *Diff) Paths() []string {
+	paths := make([]string, 0, len(d.Files))
+	for _, f := range d.Files {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+// Stats returns the number of added and deleted lines across all files
+func (d *Diff) Stats() (added, deleted int) {
+	for _, f := range d.Files {
+		a, del := f.Stats()
+		added += a
+		deleted += del
+	}
+	return added, deleted
+}
+
+// File returns the diff for a path, matching either side of a rename
+func (d *Diff) File(path string) *FileDiff {
+	for _, f := range d.Files {
+		if f.Path == path || (f.OldPath != "" && f.OldPath == path) {
+			return f
+		}
+	}
+	return nil
+}
+
+// IsEmpty reports whether the diff contains no files
+func (d *Diff) IsEmpty() bool {
+	return d == nil || len(d.Files) == 0
+}
+
+// String renders the diff back into unified diff format
+func (d *Diff) String() string {
+	if d == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, f := range d.Files {
+		f.write(&b)
+	}
+	return b.String()
+}
+
+// Stats returns the number of added and deleted lines in the file
+func (f *FileDiff) Stats() (added, deleted int) {
+	for _, h := range f.Hunks {
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case LineAdded:
+				added++
+			case LineDeleted:
+				deleted++
+			}
+		}
+	}
+	return added, deleted
+}
+
+// String renders the file's diff in unified diff format
+func (f *FileDiff) String() string {
+	var b strings.Builder
+	f.write(&b)
+	return b.String()
+}
+
+func (f *FileDiff) write(b *strings.Builder) {
+	oldPath := f.OldPath
+	if oldPath == "" {
+		oldPath = f.Path
+	}
+	fmt.Fprintf(b, "diff --git %s %s\n", quotePath("a/"+oldPath), quotePath("b/"+f.Path))
+
+	switch {
+	case f.Status == StatusAdded:
+		fmt.Fprintf(b, "new file mode %s\n", f.NewMode)
+	case f.Status == StatusDeleted:
+		fmt.Fprintf(b, "deleted file mode %s\n", f.OldMode)
+	case f.OldMode != f.NewMode && f.OldMode != "" && f.NewMode != "":
+		fmt.Fprintf(b, "old mode %s\nnew mode %s\n", f.OldMode, f.NewMode)
+	}
+
+	switch f.Status {
+	case StatusRenamed:
+		fmt.Fprintf(b, "similarity index %d%%\nrename from %s\nrename to %s\n", f.Similarity, quotePath(f.OldPath), quotePath(f.Path))
+	case StatusCopied:
+		fmt.Fprintf(b, "similarity index %d%%\ncopy from %s\ncopy to %s\n", f.Similarity, quotePath(f.OldPath), quotePath(f.Path))
+	}
+
+	if f.Index != "" {
+		if f.Status != StatusAdded && f.Status != StatusDeleted && f.OldMode == f.NewMode && f.NewMode != "" {
+			fmt.Fprintf(b, "index %s %s\n", f.Index, f.NewMode)
+		} else {
+			fmt.Fprintf(b, "index %s\n", f.Index)
+		}
+	}
+
+	from, to := quotePath("a/"+oldPath), quotePath("b/"+f.Path)
+	if f.Status == StatusAdded {
+		from = "/dev/null"
+	}
+	if f.Status == StatusDeleted {
+		to = "/dev/null"
+	}
+
+	if f.Binary {
+		fmt.Fprintf(b, "Binary files %s and %s differ\n", from, to)
+		return
+	}
+	if len(f.Hunks) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "--- %s\n+++ %s\n", from, to)
+	for _, h := range f.Hunks {
+		h.write(b)
+	}
+}
+
+// Header returns the hunk's "@@ -a,b +c,d @@ section" line
+func (h *Hunk) Header() string {
+	header := fmt.Sprintf("@@ -%s +%s @@", formatRange(h.OldStart, h.OldLines), formatRange(h.NewStart, h.NewLines))
+	if h.Section != "" {
+		header += " " + h.Section
+	}
+	return header
+}
+
+func (h *Hunk) write(b *strings.Builder) {
+	b.WriteString(h.Header())
+	b.WriteByte('\n')
+	for _, l := range h.Lines {
+		b.WriteByte(byte(l.Kind))
+		b.WriteString(l.Content)
+		b.WriteByte('\n')
+	}
+}
+
+func formatRange(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// quotePath quotes a path the way git does when it contains special characters
+func quotePath(p string) string {
+	for _, r := range p {
+		if r < 0x20 || r == '"' || r == '\\' || r >= 0x7f {
+			return strconv.Quote(p)
+		}
+	}
+	return p
+}
@@ -0,0 +1,46 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runGit runs a git command anchored at the repository root and returns its stdout.
+// Stderr is folded into the returned error so git's own message is not lost.
+func (r *Repository) runGit(args ...string) (string, error) {
+	return r.runGitEnv(nil, args...)
+}
+
+// runGitEnv is runGit with extra environment variables, e.g. GIT_INDEX_FILE
+func (r *Repository) runGitEnv(env []string, args ...string) (string, error) {
+	root, err := r.GetRepoRootPath()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = root
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		if msg != "" {
+			return stdout.String(), fmt.Errorf("git %s: %w\n%s", args[0], err, msg)
+		}
+		return stdout.String(), fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return stdout.String(), nil
+}
@@ -1,127 +1,158 @@
 package git
 
 import (
-        "fmt"
-        "os/exec"
-        "path/filepath"
-        "strconv"
-        "strings"
-
-        "github.com/go-git/go-git/v5"
-        d "github.com/jabafett/quill/internal/utils/debug"
-        "github.com/jabafett/quill/internal/utils/helpers"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	d "github.com/jabafett/quill/internal/utils/debug"
+	"github.com/jabafett/quill/internal/utils/helpers"
 )
 
+// gitBinaryEnv opts into running the git binary for diffs and status instead of go-git
+const gitBinaryEnv = "QUILL_GIT_BINARY"
+
 type Repository struct {
-        repo *git.Repository
+	repo         *git.Repository
+	useGitBinary bool
+}
+
+// RepositoryOption configures a Repository
+type RepositoryOption func(*Repository)
+
+// WithGitBinary makes diff and status queries run the git binary at the repository
+// root instead of go-git. It is also enabled by setting QUILL_GIT_BINARY=1.
+func WithGitBinary() RepositoryOption {
+	return func(r *Repository) {
+		r.useGitBinary = true
+	}
+}
+
+// NewRepository opens the repository containing repoPath, which may be any
+// directory inside the worktree
+func NewRepository(repoPath string, opts ...RepositoryOption) (*Repository, error) {
+	r, err := git.PlainOpenWithOptions(repoPath, &git.PlainOpenOptions{
+		DetectDotGit:          true,
+		EnableDotGitCommonDir: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewRepositoryFrom(r, opts...), nil
 }
 
-// NewRepository creates a new Repository instance
-func NewRepository(repoPath string) (*Repository, error) {
-        r, err := git.PlainOpen(repoPath)
-        if err != nil {
-                return nil, err
-        }
-        return &Repository{repo: r}, nil
+// NewRepositoryFrom wraps an already opened go-git repository, e.g. an in-memory one
+func NewRepositoryFrom(repo *git.Repository, opts ...RepositoryOption) *Repository {
+	r := &Repository{repo: repo}
+	if v, err := strconv.ParseBool(os.Getenv(gitBinaryEnv)); err == nil && v {
+		r.useGitBinary = true
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// status returns the worktree status, honouring global and system excludes as git does
+func (r *Repository) status() (git.Status, error) {
+	w, err := r.repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	root := osfs.New("/")
+	if patterns, err := gitignore.LoadGlobalPatterns(root); err == nil {
+		w.Excludes = append(w.Excludes, patterns...)
+	}
+	if patterns, err := gitignore.LoadSystemPatterns(root); err == nil {
+		w.Excludes = append(w.Excludes, patterns...)
+	}
+
+	status, err := w.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+	return status, nil
 }
 
 // GetStagedDiff returns the git diff for staged changes
 func (r *Repository) GetStagedDiff() (string, error) {
-        cmd := exec.Command("git", "diff", "--staged", "--no-color")
-        output, err := cmd.Output()
-        if err != nil {
-                return "", fmt.Errorf("failed to get staged diff: %w", err)
-        }
-        return string(output), nil
+	diff, err := r.StagedDiff()
+	if err != nil {
+		return "", fmt.Errorf("failed to get staged diff: %w", err)
+	}
+	return diff.String(), nil
 }
 
 // GetStagedDiffStats returns more detailed diff stats
 func (r *Repository) GetStagedDiffStats() (added int, deleted int, files []string, err error) {
-        cmd := exec.Command("git", "diff", "--staged", "--numstat")
-        output, err := cmd.Output()
-        if err != nil {
-                return 0, 0, nil, fmt.Errorf("failed to get diff stats: %w", err)
-        }
-
-        // Parse the numstat output
-        lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-        files = make([]string, 0, len(lines))
+	diff, err := r.StagedDiff()
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to get diff stats: %w", err)
+	}
 
-        for _, line := range lines {
-                if line == "" {
-                        continue
-                }
-                parts := strings.Fields(line)
-                if len(parts) >= 3 {
-                        if a, err := strconv.Atoi(parts[0]); err == nil {
-                                added += a
-                        }
-                        if d, err := strconv.Atoi(parts[1]); err == nil {
-                                deleted += d
-                        }
-                        files = append(files, parts[2])
-                }
-        }
-
-        return added, deleted, files, nil
+	added, deleted = diff.Stats()
+	return added, deleted, diff.Paths(), nil
 }
 
-
-
 // HasStagedChanges checks if there are any staged changes
 func (r *Repository) HasStagedChanges() (bool, error) {
-        w, err := r.repo.Worktree()
-        if err != nil {
-                return false, fmt.Errorf("failed to get worktree: %w", err)
-        }
-
-        status, err := w.Status()
-        if err != nil {
-                return false, fmt.Errorf("failed to get status: %w", err)
-        }
-
-        for _, s := range status {
-                if s.Staging != git.Unmodified {
-                        return true, nil
-                }
-        }
-
-        return false, helpers.ErrNoStagedChanges{}
+	hasChanges, err := r.HasStagedChangesOptimized()
+	if err != nil {
+		return false, err
+	}
+	if !hasChanges {
+		return false, helpers.ErrNoStagedChanges{}
+	}
+	return true, nil
 }
 
 // GetRepoRootPath returns the absolute path to the repository's root directory
 func (r *Repository) GetRepoRootPath() (string, error) {
-        w, err := r.repo.Worktree()
-        if err != nil {
-                return "", fmt.Errorf("failed to get worktree: %w", err)
-        }
-        return w.Filesystem.Root(), nil
+	w, err := r.repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+	return w.Filesystem.Root(), nil
 }
 
 // GetRepoName returns the name of the repository
 func (r *Repository) GetRepoName() (string, error) {
-        w, err := r.repo.Worktree()
-        if err != nil {
-                return "", fmt.Errorf("failed to get worktree: %w", err)
-        }
-        return filepath.Base(w.Filesystem.Root()), nil
+	w, err := r.repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+	return filepath.Base(w.Filesystem.Root()), nil
 }
 
 // GetCurrentBranch returns the name of the current branch
 func (r *Repository) GetCurrentBranch() (string, error) {
-    ref, err := r.repo.Head()
-    if err != nil {
-        fmt.Printf("Error getting HEAD: %v\n", err)
-        return "", err
-    }
-
-    branchName := ""
-    if ref.Name().IsBranch() {
-        branchName = ref.Name().Short()
-    } else {
-        branchName = ref.Hash().String()[:7]
-    }
-        return branchName, nil
+	ref, err := r.repo.Head()
+	if err != nil {
+		fmt.Printf("Error getting HEAD: %v\n", err)
+		return "", err
+	}
+
+	branchName := ""
+	if ref.Name().IsBranch() {
+		branchName = ref.Name().Short()
+	} else {
+		branchName = ref.Hash().String()[:7]
+	}
+	return branchName, nil
 }
 
 // Remaining git functions to fill out repository context fields
@@ -134,251 +165,400 @@ func (r *Repository) GetCurrentBranch() (string, error) {
 
 // ListTrackedFiles returns a list of all files tracked by git, respecting .gitignore
 func (r *Repository) ListTrackedFiles() ([]string, error) {
-        w, err := r.repo.Worktree()
-        if err != nil {
-                return nil, fmt.Errorf("failed to get worktree: %w", err)
-        }
+	entries, err := r.indexEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(entries))
+	for file := range entries {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	d.Log("Found %d tracked files", len(files))
+	return files, nil
+}
 
-        status, err := w.Status()
-        if err != nil {
-                return nil, fmt.Errorf("failed to get status: %w", err)
-        }
+// TrackedBlobs returns the blob hash of every regular file in the index, keyed by path.
+// Symlinks and submodules are left out since they have no content to read.
+func (r *Repository) TrackedBlobs() (map[string]string, error) {
+	entries, err := r.indexEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make(map[string]string, len(entries))
+	for path, e := range entries {
+		if e.mode == filemode.Regular || e.mode == filemode.Executable {
+			blobs[path] = e.hash.String()
+		}
+	}
+	return blobs, nil
+}
 
-        var files []string
-        for file, s := range status {
-                // Include files that are tracked
-                if s.Worktree != git.Untracked {
-                        files = append(files, file)
-                }
-        }
-        d.Log("Found %d tracked files", len(files))
-        return files, nil
+// BlobContent reads a blob by its hash
+func (r *Repository) BlobContent(hash string) ([]byte, error) {
+	return r.load(&fileVersion{path: hash, hash: plumbing.NewHash(hash), mode: filemode.Regular})
 }
 
+// BlobSize returns the size of a blob in bytes without reading its content
+func (r *Repository) BlobSize(hash string) (int64, error) {
+	blob, err := r.repo.BlobObject(plumbing.NewHash(hash))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read blob %s: %w", hash, err)
+	}
+	return blob.Size, nil
+}
 
-func (r *Repository) GetNonIgnoredFiles() []string {
-        wt, err := r.repo.Worktree()
-        if err != nil {
-                d.Log("Error getting worktree: %v\n", err)
-                return nil
-        }
-
-        // Use git ls-files command as more reliable way to get tracked files
-        cmd := exec.Command("git", "ls-files")
-        cmd.Dir = wt.Filesystem.Root()
-        output, err := cmd.Output()
-        if err != nil {
-                d.Log("Error running git ls-files: %v\n", err)
-                // Fallback to status-based approach
-                status, err := wt.Status()
-                if err != nil {
-                        d.Log("Error getting worktree status: %v\n", err)
-                        return nil
-                }
-
-                var nonIgnoredFiles []string
-                for filePath := range status {
-                        nonIgnoredFiles = append(nonIgnoredFiles, filePath)
-                }
-                d.Log("Found %d non-ignored files (status fallback)", len(nonIgnoredFiles))
-                return nonIgnoredFiles
-        }
-
-        // Parse ls-files output
-        files := strings.Split(strings.TrimSpace(string(output)), "\n")
-        d.Log("Found %d tracked files (ls-files)", len(files))
-        return files
+// RecentCommits returns the subject lines of up to n commits reachable from HEAD,
+// newest first. An unborn HEAD has no commits.
+func (r *Repository) RecentCommits(n int) ([]string, error) {
+	return r.RecentCommitsIn(".", n)
 }
-// GetChangedFiles returns a list of modified files
-func (r *Repository) GetChangedFiles() ([]string, error) {
-        w, err := r.repo.Worktree()
-        if err != nil {
-                return nil, fmt.Errorf("failed to get worktree: %w", err)
-        }
 
-        status, err := w.Status()
-        if err != nil {
-                return nil, fmt.Errorf("failed to get status: %w", err)
-        }
+// RecentCommitsIn is RecentCommits limited to commits touching files under dir
+func (r *Repository) RecentCommitsIn(dir string, n int) ([]string, error) {
+	head, err := r.HeadCommit()
+	if err != nil || head == "" {
+		return nil, err
+	}
+
+	opts := &git.LogOptions{From: plumbing.NewHash(head)}
+	if dir != "." {
+		opts.PathFilter = func(p string) bool {
+			return strings.HasPrefix(p, dir+"/")
+		}
+	}
+	iter, err := r.repo.Log(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer iter.Close()
+
+	var subjects []string
+	for len(subjects) < n {
+		commit, err := iter.Next()
+		if err != nil {
+			break
+		}
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		subjects = append(subjects, strings.TrimSpace(subject))
+	}
+	return subjects, nil
+}
 
-        var files []string
-        for file, s := range status {
-                if s.Staging != git.Unmodified {
-                        files = append(files, file)
-                }
-        }
+// HeadCommit returns the hash HEAD points to, empty when HEAD is unborn
+func (r *Repository) HeadCommit() (string, error) {
+	head, err := r.repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return head.Hash().String(), nil
+}
 
-        return files, nil
+// ErrNotInHistory is returned when a commit is not an ancestor of HEAD, e.g. after a
+// rebase rewrote it
+var ErrNotInHistory = errors.New("commit is not in the history of HEAD")
+
+// HeadTree returns the hash of the tree HEAD points to, empty when HEAD is unborn
+func (r *Repository) HeadTree() (string, error) {
+	head, err := r.HeadCommit()
+	if err != nil || head == "" {
+		return "", err
+	}
+	commit, err := r.repo.CommitObject(plumbing.NewHash(head))
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD commit: %w", err)
+	}
+	return commit.TreeHash.String(), nil
+}
+
+// CommitsSince counts the commits reachable from HEAD before reaching commit, walking
+// at most limit of them. It returns limit and false when the walk stopped early, and
+// ErrNotInHistory when the history ran out without reaching commit.
+func (r *Repository) CommitsSince(commit string, limit int) (int, bool, error) {
+	head, err := r.HeadCommit()
+	if err != nil {
+		return 0, false, err
+	}
+	if head == commit {
+		return 0, true, nil
+	}
+	if head == "" {
+		return 0, false, ErrNotInHistory
+	}
+
+	iter, err := r.repo.Log(&git.LogOptions{From: plumbing.NewHash(head)})
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer iter.Close()
+
+	target := plumbing.NewHash(commit)
+	for count := 0; count < limit; count++ {
+		c, err := iter.Next()
+		if errors.Is(err, io.EOF) {
+			return count, false, ErrNotInHistory
+		}
+		if err != nil {
+			return count, false, fmt.Errorf("failed to read history: %w", err)
+		}
+		if c.Hash == target {
+			return count, true, nil
+		}
+	}
+	return limit, false, nil
+}
+
+// SubtreeHash returns the hash of directory dir within a tree, empty when the tree
+// has no such directory
+func (r *Repository) SubtreeHash(tree, dir string) (string, error) {
+	root, err := r.repo.TreeObject(plumbing.NewHash(tree))
+	if err != nil {
+		return "", fmt.Errorf("failed to read tree %s: %w", tree, err)
+	}
+	if dir == "." {
+		return tree, nil
+	}
+	sub, err := root.Tree(dir)
+	if errors.Is(err, object.ErrDirectoryNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s in tree %s: %w", dir, tree, err)
+	}
+	return sub.Hash.String(), nil
+}
+
+// TreeChanges counts the files that differ between two trees
+func (r *Repository) TreeChanges(from, to string) (int, error) {
+	if from == to {
+		return 0, nil
+	}
+	trees := make([]*object.Tree, 2)
+	for i, hash := range []string{from, to} {
+		tree, err := r.repo.TreeObject(plumbing.NewHash(hash))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read tree %s: %w", hash, err)
+		}
+		trees[i] = tree
+	}
+
+	changes, err := object.DiffTree(trees[0], trees[1])
+	if err != nil {
+		return 0, fmt.Errorf("failed to compare trees: %w", err)
+	}
+	return len(changes), nil
+}
+
+func (r *Repository) GetNonIgnoredFiles() []string {
+	if r.useGitBinary {
+		output, err := r.runGit("ls-files")
+		if err == nil {
+			files := splitOutput(output)
+			d.Log("Found %d tracked files (ls-files)", len(files))
+			return files
+		}
+		d.Log("Error running git ls-files: %v\n", err)
+	}
+
+	files, err := r.ListTrackedFiles()
+	if err != nil {
+		d.Log("Error reading index: %v\n", err)
+		return nil
+	}
+	return files
+}
+
+// GetChangedFiles returns a list of modified files
+func (r *Repository) GetChangedFiles() ([]string, error) {
+	return r.GetStagedFilesOptimized()
 }
 
 // GetFileType returns the type of changes for a file
 func (r *Repository) GetFileType(path string) (string, error) {
-        w, err := r.repo.Worktree()
-        if err != nil {
-                return "", fmt.Errorf("failed to get worktree: %w", err)
-        }
-
-        status, err := w.Status()
-        if err != nil {
-                return "", fmt.Errorf("failed to get status: %w", err)
-        }
-
-        fileStatus, ok := status[path]
-        if !ok {
-                return "", fmt.Errorf("file not found in status")
-        }
-
-        switch fileStatus.Staging {
-        case git.Added:
-                return "added", nil
-        case git.Modified:
-                return "modified", nil
-        case git.Deleted:
-                return "deleted", nil
-        default:
-                return "unknown", nil
-        }
+	status, err := r.status()
+	if err != nil {
+		return "", err
+	}
+
+	fileStatus, ok := status[path]
+	if !ok {
+		return "", fmt.Errorf("file not found in status")
+	}
+
+	switch fileStatus.Staging {
+	case git.Added:
+		return "added", nil
+	case git.Modified:
+		return "modified", nil
+	case git.Deleted:
+		return "deleted", nil
+	default:
+		return "unknown", nil
+	}
 }
 
 // Commit creates a new git commit with the given message
 func (r *Repository) Commit(message string) error {
-        w, err := r.repo.Worktree()
-        if err != nil {
-                return fmt.Errorf("failed to get worktree: %w", err)
-        }
-
-        // Create commit
-        _, err = w.Commit(message, &git.CommitOptions{})
-        if err != nil {
-                return fmt.Errorf("failed to create commit: %w", err)
-        }
-
-        return nil
+	_, err := r.CommitWith(message, CommitOptions{})
+	return err
 }
 
 // GetStagedDiffOptimized returns an optimized git diff for staged changes
 func (r *Repository) GetStagedDiffOptimized() (string, error) {
-        // Use --no-prefix to avoid directory prefixes
-        // Use --no-color to avoid ANSI codes
-        // Use --cached as an alias for --staged
-        cmd := exec.Command("git", "diff", "--cached", "--no-prefix", "--no-color")
-        output, err := cmd.Output()
-        if err != nil {
-                return "", fmt.Errorf("failed to get staged diff: %w", err)
-        }
-        return string(output), nil
+	return r.GetStagedDiff()
 }
 
 // GetStagedFilesOptimized returns only staged files efficiently
 func (r *Repository) GetStagedFilesOptimized() ([]string, error) {
-        // Use --name-only to get just filenames
-        // Use --cached as an alias for --staged
-        cmd := exec.Command("git", "diff", "--cached", "--name-only")
-        output, err := cmd.Output()
-        if err != nil {
-                return nil, fmt.Errorf("failed to get staged files: %w", err)
-        }
-
-        if len(output) == 0 {
-                return nil, nil
-        }
-
-        files := strings.Split(strings.TrimSpace(string(output)), "\n")
-        return files, nil
+	if r.useGitBinary {
+		output, err := r.runGit("diff", "--cached", "--name-only")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get staged files: %w", err)
+		}
+		return splitOutput(output), nil
+	}
+
+	// Comparing blob hashes is enough, no content needs to be read
+	changes, err := r.stagedChanges()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staged files: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	files := make([]string, 0, len(changes))
+	for _, c := range changes {
+		files = append(files, changePath(c))
+	}
+	sort.Strings(files)
+	return files, nil
 }
 
 // GetFileStatusOptimized returns the status of a specific file efficiently
 func (r *Repository) GetFileStatusOptimized(path string) (string, error) {
-        // Use --porcelain for machine-readable output
-        cmd := exec.Command("git", "status", "--porcelain", path)
-        output, err := cmd.Output()
-        if err != nil {
-                return "", fmt.Errorf("failed to get file status: %w", err)
-        }
-
-        if len(output) < 2 {
-                return "unmodified", nil
-        }
-
-        // First character represents staging status
-        switch output[0] {
-        case 'A':
-                return "added", nil
-        case 'M':
-                return "modified", nil
-        case 'D':
-                return "deleted", nil
-        case 'R':
-                return "renamed", nil
-        case 'C':
-                return "copied", nil
-        default:
-                return "unknown", nil
-        }
+	var code byte
+	if r.useGitBinary {
+		output, err := r.runGit("status", "--porcelain", "--", path)
+		if err != nil {
+			return "", fmt.Errorf("failed to get file status: %w", err)
+		}
+		if len(output) < 2 {
+			return "unmodified", nil
+		}
+		code = output[0]
+	} else {
+		status, err := r.status()
+		if err != nil {
+			return "", fmt.Errorf("failed to get file status: %w", err)
+		}
+		fileStatus, ok := status[filepath.ToSlash(path)]
+		if !ok {
+			return "unmodified", nil
+		}
+		code = byte(fileStatus.Staging)
+	}
+
+	// First character represents staging status
+	switch code {
+	case 'A':
+		return "added", nil
+	case 'M':
+		return "modified", nil
+	case 'D':
+		return "deleted", nil
+	case 'R':
+		return "renamed", nil
+	case 'C':
+		return "copied", nil
+	case ' ':
+		return "unmodified", nil
+	default:
+		return "unknown", nil
+	}
 }
 
 // CommitOptimized creates a new git commit with optimized performance
 func (r *Repository) CommitOptimized(message string) error {
-        // Use -m to avoid opening editor
-        // Use --no-verify to skip hooks for performance
-        cmd := exec.Command("git", "commit", "-m", message)
-        if err := cmd.Run(); err != nil {
-                return fmt.Errorf("failed to create commit: %w", err)
-        }
-        return nil
+	return r.Commit(message)
 }
 
 // HasStagedChangesOptimized checks for staged changes efficiently
 func (r *Repository) HasStagedChangesOptimized() (bool, error) {
-        // Use --quiet to suppress output
-        // Exit status is 1 if there are no changes
-        cmd := exec.Command("git", "diff", "--cached", "--quiet")
-        err := cmd.Run()
-
-        if err != nil {
-                if exitErr, ok := err.(*exec.ExitError); ok {
-                        // Exit status 1 means there are changes
-                        return exitErr.ExitCode() == 1, nil
-                }
-                return false, fmt.Errorf("failed to check staged changes: %w", err)
-        }
-
-        // Exit status 0 means no changes
-        return false, nil
+	if r.useGitBinary {
+		// Exit status is 1 if there are changes
+		_, err := r.runGit("diff", "--cached", "--quiet")
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode() == 1, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to check staged changes: %w", err)
+		}
+		return false, nil
+	}
+
+	changes, err := r.stagedChanges()
+	if err != nil {
+		return false, fmt.Errorf("failed to check staged changes: %w", err)
+	}
+	return len(changes) > 0, nil
 }
 
 // GetUntrackedFiles returns a list of untracked files that are not gitignored
 func (r *Repository) GetUntrackedFiles() ([]string, error) {
-        // Use ls-files with --others to get untracked files
-        // Use --exclude-standard to respect .gitignore
-        cmd := exec.Command("git", "ls-files", "--others", "--exclude-standard")
-        output, err := cmd.Output()
-        if err != nil {
-                return nil, fmt.Errorf("failed to get untracked files: %w", err)
-        }
-
-        if len(output) == 0 {
-                return nil, nil
-        }
-
-        files := strings.Split(strings.TrimSpace(string(output)), "\n")
-        d.Log("Found %d untracked files", len(files))
-        return files, nil
+	var files []string
+	if r.useGitBinary {
+		// Use --exclude-standard to respect .gitignore
+		output, err := r.runGit("ls-files", "--others", "--exclude-standard")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get untracked files: %w", err)
+		}
+		files = splitOutput(output)
+	} else {
+		status, err := r.status()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get untracked files: %w", err)
+		}
+		for file, s := range status {
+			if s.Worktree == git.Untracked {
+				files = append(files, file)
+			}
+		}
+		sort.Strings(files)
+	}
+
+	if len(files) == 0 {
+		return nil, nil
+	}
+	d.Log("Found %d untracked files", len(files))
+	return files, nil
 }
 
-// GetFileContent reads the content of a file
+// GetFileContent reads the content of a file relative to the repository root
 func (r *Repository) GetFileContent(filePath string) (string, error) {
-        rootPath, err := r.GetRepoRootPath()
-        if err != nil {
-                return "", fmt.Errorf("failed to get repo root path: %w", err)
-        }
-
-        fullPath := filepath.Join(rootPath, filePath)
-        cmd := exec.Command("cat", fullPath)
-        output, err := cmd.Output()
-        if err != nil {
-                return "", fmt.Errorf("failed to read file content: %w", err)
-        }
-
-        return string(output), nil
+	w, err := r.repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	content, err := util.ReadFile(w.Filesystem, filepath.ToSlash(filePath))
+	if err != nil {
+		return "", fmt.Errorf("failed to read file content: %w", err)
+	}
+	return string(content), nil
+}
+
+// splitOutput splits command output into non-empty lines
+func splitOutput(output string) []string {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
 }
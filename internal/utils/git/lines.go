@@ -0,0 +1,82 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NumberedLine is a line a diff adds, with its number in the new version of the file
+type NumberedLine struct {
+	Number  int
+	Content string
+}
+
+// AddedLines returns the lines the file's hunks add, numbered as in the new version
+func (f *FileDiff) AddedLines() []NumberedLine {
+	var lines []NumberedLine
+	for _, h := range f.Hunks {
+		n := h.NewStart
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case LineAdded:
+				lines = append(lines, NumberedLine{Number: n, Content: l.Content})
+				n++
+			case LineContext:
+				n++
+			}
+		}
+	}
+	return lines
+}
+
+// NumberedString renders the file's diff with the new line number in front of
+// every added and context line, so that a model can cite locations
+func (f *FileDiff) NumberedString() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== %s (%s)\n", f.Path, f.Status)
+	if f.Binary {
+		b.WriteString("Binary file\n")
+		return b.String()
+	}
+	for _, h := range f.Hunks {
+		b.WriteString(h.Header())
+		b.WriteByte('\n')
+		n := h.NewStart
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case LineAdded, LineContext:
+				fmt.Fprintf(&b, "%5d %c%s\n", n, l.Kind, l.Content)
+				n++
+			case LineDeleted:
+				fmt.Fprintf(&b, "      -%s\n", l.Content)
+			}
+		}
+	}
+	return b.String()
+}
+
+// LocateLine maps a line of the new version of the file onto the diff. A line
+// inside a hunk is kept; any other line moves to the nearest added line. It
+// returns 0 when the diff adds nothing to the file, e.g. for a deletion.
+func (f *FileDiff) LocateLine(line int) int {
+	for _, h := range f.Hunks {
+		if h.NewLines > 0 && line >= h.NewStart && line < h.NewStart+h.NewLines {
+			return line
+		}
+	}
+
+	nearest := 0
+	for _, l := range f.AddedLines() {
+		if nearest == 0 || abs(l.Number-line) < abs(nearest-line) {
+			nearest = l.Number
+		}
+	}
+	return nearest
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,390 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	d "github.com/jabafett/quill/internal/utils/debug"
+)
+
+// quillIgnoreFile lists paths whose changes are summarized instead of sent to the model
+const quillIgnoreFile = ".quillignore"
+
+// NoiseKind explains why a file's diff is left out of prompts
+type NoiseKind string
+
+const (
+	NoiseLockfile  NoiseKind = "lockfile"
+	NoiseGenerated NoiseKind = "generated"
+	NoiseVendored  NoiseKind = "vendored"
+	NoiseBinary    NoiseKind = "binary"
+	NoiseMinified  NoiseKind = "minified"
+	NoiseNoDiff    NoiseKind = "no diff" // Marked -diff in .gitattributes
+	NoiseIgnored   NoiseKind = "ignored" // Listed in .quillignore
+)
+
+// Lockfiles that only record resolved dependency versions
+var lockfiles = map[string]bool{
+	"go.sum":              true,
+	"go.work.sum":         true,
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+	"bun.lockb":           true,
+	"Cargo.lock":          true,
+	"Gemfile.lock":        true,
+	"poetry.lock":         true,
+	"Pipfile.lock":        true,
+	"uv.lock":             true,
+	"composer.lock":       true,
+	"mix.lock":            true,
+	"pubspec.lock":        true,
+	"Podfile.lock":        true,
+	"flake.lock":          true,
+	"packages.lock.json":  true,
+	"gradle.lockfile":     true,
+	"Package.resolved":    true,
+}
+
+// Name patterns of files produced by code generators
+var generatedPatterns = []string{
+	"*.pb.go", "*.pb.gw.go", "*_grpc.pb.go", "*.pb.cc", "*.pb.h", "*_pb2.py", "*_pb2_grpc.py",
+	"*_pb.js", "*_pb.d.ts", "*_generated.go", "*.gen.go", "zz_generated.*.go", "*.generated.*",
+	"*.js.map", "*.css.map", "*.designer.cs",
+}
+
+// Name patterns of minified bundles
+var minifiedPatterns = []string{"*.min.js", "*.min.css", "*.min.mjs"}
+
+// Directory names holding third-party code
+var vendoredDirs = map[string]bool{
+	"vendor":           true,
+	"node_modules":     true,
+	"third_party":      true,
+	"bower_components": true,
+	"Pods":             true,
+}
+
+// Lines longer than this in JavaScript or CSS are taken as minified output
+const minifiedLineLength = 500
+
+// NoisyFile is a changed file whose diff was replaced by a summary
+type NoisyFile struct {
+	Path    string
+	Kind    NoiseKind
+	Summary string
+}
+
+// NoiseFilter classifies changed files that would drown out the real change in a prompt
+type NoiseFilter struct {
+	fs     billy.Filesystem
+	ignore gitignore.Matcher
+	// .gitattributes files read so far, keyed by directory
+	attributes map[string][]gitattributes.MatchAttribute
+	macros     []gitattributes.MatchAttribute // Built-in macros, lowest precedence
+	info       []gitattributes.MatchAttribute // .git/info/attributes, highest precedence
+}
+
+// NoiseFilter returns a filter using the built-in patterns, .gitattributes and .quillignore
+func (r *Repository) NoiseFilter() (*NoiseFilter, error) {
+	w, err := r.repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	f := &NoiseFilter{
+		fs:         w.Filesystem,
+		attributes: make(map[string][]gitattributes.MatchAttribute),
+	}
+
+	// git defines the binary macro itself, go-git only knows macros read from files
+	if macro, err := gitattributes.ParseAttributesLine("[attr]binary -diff -merge -text", nil, true); err == nil {
+		f.macros = append(f.macros, macro)
+	}
+	if info, err := f.fs.Open(".git/info/attributes"); err == nil {
+		if attrs, err := gitattributes.ReadAttributes(info, nil, true); err == nil {
+			f.info = attrs
+		}
+		info.Close()
+	}
+
+	if content, err := util.ReadFile(f.fs, quillIgnoreFile); err == nil {
+		var patterns []gitignore.Pattern
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			patterns = append(patterns, gitignore.ParsePattern(line, nil))
+		}
+		f.ignore = gitignore.NewMatcher(patterns)
+		d.Log("Loaded %d patterns from %s", len(patterns), quillIgnoreFile)
+	}
+
+	return f, nil
+}
+
+// Split separates noisy files from a diff, returning a diff of the remaining files and
+// a summary for each noisy one
+func (f *NoiseFilter) Split(diff *Diff) (*Diff, []NoisyFile) {
+	kept := &Diff{}
+	var noise []NoisyFile
+	for _, file := range diff.Files {
+		kind, ok := f.ClassifyDiff(file)
+		if !ok {
+			kept.Files = append(kept.Files, file)
+			continue
+		}
+		noise = append(noise, NoisyFile{Path: file.Path, Kind: kind, Summary: summarizeDiff(file, kind)})
+	}
+	return kept, noise
+}
+
+// ClassifyDiff reports whether a changed file is noise and of which kind
+func (f *NoiseFilter) ClassifyDiff(file *FileDiff) (NoiseKind, bool) {
+	kind, ok, explicit := f.classifyPath(file.Path)
+	switch {
+	case ok:
+		return kind, true
+	case file.Binary:
+		return NoiseBinary, true
+	case explicit:
+		return "", false
+	}
+
+	// Inspect the start of the new content for generator headers and minified lines. A
+	// hunk further down only shows the middle of the file, where a comment mentioning
+	// generated code is no header, so the path alone decides.
+	if len(file.Hunks) == 0 || file.Hunks[0].NewStart != 1 {
+		return "", false
+	}
+	var head strings.Builder
+	for _, l := range file.Hunks[0].Lines {
+		if l.Kind == LineAdded || l.Kind == LineContext {
+			head.WriteString(l.Content)
+			head.WriteByte('\n')
+		}
+	}
+	return classifyContent(file.Path, head.String())
+}
+
+// ClassifyFile reports whether a file, e.g. an untracked one, is noise based on its
+// path and content
+func (f *NoiseFilter) ClassifyFile(filePath string, content []byte) (NoiseKind, bool) {
+	kind, ok, explicit := f.classifyPath(filePath)
+	switch {
+	case ok:
+		return kind, true
+	case isBinary(content):
+		return NoiseBinary, true
+	case explicit:
+		return "", false
+	}
+	return classifyContent(filePath, string(content))
+}
+
+// classifyPath checks .quillignore, .gitattributes and the built-in patterns. explicit is
+// set when .gitattributes marks the file as not generated or vendored, which overrides
+// the built-in rules.
+func (f *NoiseFilter) classifyPath(filePath string) (kind NoiseKind, ok bool, explicit bool) {
+	parts := strings.Split(filePath, "/")
+
+	if f.ignore != nil && f.ignore.Match(parts, false) {
+		return NoiseIgnored, true, false
+	}
+
+	attrs, _ := gitattributes.NewMatcher(f.attributeStack(parts)).Match(parts,
+		[]string{"linguist-generated", "linguist-vendored", "diff"})
+	if attr, found := attrs["linguist-generated"]; found {
+		if isTrue(attr) {
+			return NoiseGenerated, true, false
+		}
+		explicit = true
+	}
+	if attr, found := attrs["linguist-vendored"]; found {
+		if isTrue(attr) {
+			return NoiseVendored, true, false
+		}
+		explicit = true
+	}
+	if attr, found := attrs["diff"]; found && attr.IsUnset() {
+		return NoiseNoDiff, true, false
+	}
+	if explicit {
+		return "", false, true
+	}
+
+	name := parts[len(parts)-1]
+	switch {
+	case lockfiles[name]:
+		return NoiseLockfile, true, false
+	case matchAny(minifiedPatterns, name):
+		return NoiseMinified, true, false
+	case matchAny(generatedPatterns, name):
+		return NoiseGenerated, true, false
+	}
+	for _, dir := range parts[:len(parts)-1] {
+		if vendoredDirs[dir] {
+			return NoiseVendored, true, false
+		}
+	}
+	return "", false, false
+}
+
+// attributeStack returns the .gitattributes rules that apply to a path, from the root
+// down so deeper files take precedence
+func (f *NoiseFilter) attributeStack(parts []string) []gitattributes.MatchAttribute {
+	stack := append([]gitattributes.MatchAttribute(nil), f.macros...)
+	for i := 0; i < len(parts); i++ {
+		dir := strings.Join(parts[:i], "/")
+		attrs, ok := f.attributes[dir]
+		if !ok {
+			// ReadAttributesFile appends to the slice it is given, so pass a copy
+			domain := append([]string(nil), parts[:i]...)
+			attrs, _ = gitattributes.ReadAttributesFile(f.fs, domain, ".gitattributes", i == 0)
+			f.attributes[dir] = attrs
+		}
+		stack = append(stack, attrs...)
+	}
+	return append(stack, f.info...)
+}
+
+// classifyContent detects generated files by their header and minified JavaScript or CSS
+// by very long lines
+func classifyContent(filePath, content string) (NoiseKind, bool) {
+	// Generated Go code is marked per https://go.dev/s/generatedcode, other generators
+	// use similar banners near the top
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for i := 0; i < 10 && scanner.Scan(); i++ {
+		line := scanner.Text()
+		lower := strings.ToLower(line)
+		if (strings.Contains(line, "Code generated") && strings.Contains(line, "DO NOT EDIT")) ||
+			strings.Contains(lower, "@generated") || strings.Contains(lower, "auto-generated") ||
+			strings.Contains(lower, "autogenerated file") {
+			return NoiseGenerated, true
+		}
+	}
+
+	switch path.Ext(filePath) {
+	case ".js", ".mjs", ".cjs", ".css":
+		for _, line := range strings.SplitN(content, "\n", 20) {
+			if len(line) > minifiedLineLength {
+				return NoiseMinified, true
+			}
+		}
+	}
+	return "", false
+}
+
+// summarizeDiff describes a noisy file's change in one line
+func summarizeDiff(file *FileDiff, kind NoiseKind) string {
+	added, deleted := file.Stats()
+
+	var what string
+	switch {
+	case kind == NoiseLockfile && (path.Base(file.Path) == "go.sum" || path.Base(file.Path) == "go.work.sum"):
+		newVersions, oldVersions := moduleVersions(file)
+		what = fmt.Sprintf("updated dependency checksums (+%d/-%d module versions)", newVersions, oldVersions)
+	case kind == NoiseLockfile:
+		what = fmt.Sprintf("updated resolved dependency versions (+%d/-%d lines)", added, deleted)
+	case kind == NoiseBinary || file.Binary:
+		what = string(file.Status) + " binary file"
+	case kind == NoiseNoDiff:
+		what = fmt.Sprintf("%s (+%d/-%d lines, diff disabled in .gitattributes)", file.Status, added, deleted)
+	case kind == NoiseIgnored:
+		what = fmt.Sprintf("%s (+%d/-%d lines, excluded by %s)", file.Status, added, deleted, quillIgnoreFile)
+	default:
+		what = fmt.Sprintf("%s %s file (+%d/-%d lines)", file.Status, kind, added, deleted)
+	}
+
+	if file.Status == StatusRenamed && file.OldPath != "" {
+		return fmt.Sprintf("%s (renamed from %s): %s", file.Path, file.OldPath, what)
+	}
+	return fmt.Sprintf("%s: %s", file.Path, what)
+}
+
+// moduleVersions counts the distinct module versions whose go.sum checksums a diff
+// adds and removes. Each version has a line for its tree and one for its go.mod.
+func moduleVersions(file *FileDiff) (added, deleted int) {
+	seen := make(map[LineKind]map[string]bool)
+	for _, h := range file.Hunks {
+		for _, l := range h.Lines {
+			fields := strings.Fields(l.Content)
+			if l.Kind == LineContext || len(fields) < 2 {
+				continue
+			}
+			version := fields[0] + " " + strings.TrimSuffix(fields[1], "/go.mod")
+			if seen[l.Kind] == nil {
+				seen[l.Kind] = make(map[string]bool)
+			}
+			seen[l.Kind][version] = true
+		}
+	}
+	return len(seen[LineAdded]), len(seen[LineDeleted])
+}
+
+// SummarizeNoise renders one line per noisy file. Vendored files are collapsed per
+// vendor directory since they tend to change by the hundred.
+func SummarizeNoise(noise []NoisyFile) []string {
+	var lines []string
+	vendored := make(map[string]int)
+	for _, n := range noise {
+		if n.Kind != NoiseVendored {
+			lines = append(lines, n.Summary)
+			continue
+		}
+		vendored[vendorRoot(n.Path)]++
+	}
+
+	roots := make([]string, 0, len(vendored))
+	for root := range vendored {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	for _, root := range roots {
+		lines = append(lines, fmt.Sprintf("%s: %d vendored files changed", root, vendored[root]))
+	}
+	return lines
+}
+
+// vendorRoot returns the vendored directory containing a path, e.g. "web/node_modules/"
+func vendorRoot(filePath string) string {
+	parts := strings.Split(filePath, "/")
+	for i, dir := range parts[:len(parts)-1] {
+		if vendoredDirs[dir] {
+			return strings.Join(parts[:i+1], "/") + "/"
+		}
+	}
+	if dir := path.Dir(filePath); dir != "." {
+		return dir + "/"
+	}
+	return filePath
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrue reports whether an attribute is set, either bare or with a true value
+func isTrue(attr gitattributes.Attribute) bool {
+	if attr.IsSet() {
+		return true
+	}
+	return attr.IsValueSet() && (attr.Value() == "true" || attr.Value() == "1")
+}
@@ -0,0 +1,169 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// CommitInfo is a commit of a revision range as a reader sees it
+type CommitInfo struct {
+	Hash    string
+	Author  string
+	Date    time.Time
+	Message string
+}
+
+// Subject returns the first line of the commit message
+func (c CommitInfo) Subject() string {
+	subject, _, _ := strings.Cut(c.Message, "\n")
+	return strings.TrimSpace(subject)
+}
+
+// RevisionRange is a commit or range of commits resolved for comparison. A single
+// commit is compared against its first parent; a range "a..b" or "a...b" against
+// the merge base of a and b, so it covers what b added since it forked from a.
+type RevisionRange struct {
+	r      *Repository
+	Spec   string
+	base   *object.Commit // Nil for a root commit, compared against the empty tree
+	tip    *object.Commit
+	ignore []plumbing.Hash // Commits where the walk over the range's commits stops
+}
+
+// ResolveRange resolves a revision such as "HEAD~2" or "v1.2" or a range such as
+// "main..feature". An empty side of a range means HEAD.
+func (r *Repository) ResolveRange(spec string) (*RevisionRange, error) {
+	rr := &RevisionRange{r: r, Spec: spec}
+
+	from, to, isRange := strings.Cut(spec, "...")
+	if !isRange {
+		from, to, isRange = strings.Cut(spec, "..")
+	}
+	if !isRange {
+		tip, err := r.resolveCommit(spec)
+		if err != nil {
+			return nil, err
+		}
+		rr.tip = tip
+		if tip.NumParents() > 0 {
+			if rr.base, err = tip.Parent(0); err != nil {
+				return nil, fmt.Errorf("failed to read parent of %s: %w", spec, err)
+			}
+			rr.ignore = tip.ParentHashes
+		}
+		return rr, nil
+	}
+
+	base, err := r.resolveCommit(from)
+	if err != nil {
+		return nil, err
+	}
+	if rr.tip, err = r.resolveCommit(to); err != nil {
+		return nil, err
+	}
+	bases, err := rr.tip.MergeBase(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the merge base of %s: %w", spec, err)
+	}
+	if len(bases) == 0 {
+		return nil, fmt.Errorf("%s has no common history", spec)
+	}
+	rr.base = bases[0]
+	for _, b := range bases {
+		rr.ignore = append(rr.ignore, b.Hash)
+	}
+	return rr, nil
+}
+
+// resolveCommit resolves a revision to a commit, HEAD when rev is empty
+func (r *Repository) resolveCommit(rev string) (*object.Commit, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %q: %w", rev, err)
+	}
+	commit, err := r.repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a commit: %w", rev, err)
+	}
+	return commit, nil
+}
+
+// Diff returns the changes of the range
+func (rr *RevisionRange) Diff() (*Diff, error) {
+	if rr.r.useGitBinary {
+		base := emptyTreeHash
+		if rr.base != nil {
+			base = rr.base.Hash.String()
+		}
+		return rr.r.parsedDiff(base, rr.tip.Hash.String())
+	}
+
+	from, to, err := rr.entries()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := rr.r.detectRenames(compareEntries(from, to))
+	if err != nil {
+		return nil, err
+	}
+	return rr.r.buildDiff(changes)
+}
+
+// Versions reads files as they are at both ends of the range, matching Diff
+func (rr *RevisionRange) Versions() (*FileVersions, error) {
+	from, to, err := rr.entries()
+	if err != nil {
+		return nil, err
+	}
+	return &FileVersions{r: rr.r, from: from, to: to}, nil
+}
+
+// entries returns the files at the base and the tip of the range
+func (rr *RevisionRange) entries() (from, to map[string]*fileVersion, err error) {
+	from = make(map[string]*fileVersion)
+	if rr.base != nil {
+		if from, err = commitEntries(rr.base); err != nil {
+			return nil, nil, err
+		}
+	}
+	if to, err = commitEntries(rr.tip); err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+// Commits returns up to limit commits of the range, newest first, and whether
+// there were more. A single commit's range holds just that commit.
+func (rr *RevisionRange) Commits(limit int) ([]CommitInfo, bool, error) {
+	iter := object.NewCommitPreorderIter(rr.tip, nil, rr.ignore)
+	defer iter.Close()
+
+	var commits []CommitInfo
+	for {
+		c, err := iter.Next()
+		if errors.Is(err, io.EOF) {
+			return commits, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read history: %w", err)
+		}
+		if len(commits) == limit {
+			return commits, true, nil
+		}
+		commits = append(commits, CommitInfo{
+			Hash:    c.Hash.String(),
+			Author:  c.Author.Name,
+			Date:    c.Author.When,
+			Message: strings.TrimSpace(c.Message),
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	d "github.com/jabafett/quill/internal/utils/debug"
+)
+
+// FileStatus is a changed file as git status --porcelain=v2 reports it
+type FileStatus struct {
+	Path     string
+	OrigPath string // Path before a rename, empty otherwise
+	Index    byte   // Staged change: '.', 'M', 'T', 'A', 'D', 'R', 'C' or 'U'
+	Worktree byte   // Unstaged change: the same codes, '?' for untracked files
+}
+
+// Renamed reports whether the file was renamed, in the index or the worktree
+func (s FileStatus) Renamed() bool {
+	return s.OrigPath != ""
+}
+
+// Deleted reports whether nothing is left at Path once the change is staged
+func (s FileStatus) Deleted() bool {
+	return s.Worktree == 'D' || (s.Index == 'D' && s.Worktree == '.')
+}
+
+// PartiallyStaged reports whether the file has staged changes and further
+// unstaged ones
+func (s FileStatus) PartiallyStaged() bool {
+	return s.Index != '.' && s.Index != '?' && s.Worktree != '.'
+}
+
+// Unstaged reports whether the worktree has changes to the file that are not staged
+func (s FileStatus) Unstaged() bool {
+	return s.Worktree != '.'
+}
+
+// Label describes the change in a few words, e.g. "renamed from old.go" or
+// "staged, then modified", empty for a plain modification
+func (s FileStatus) Label() string {
+	var parts []string
+	switch {
+	case s.Renamed():
+		parts = append(parts, "renamed from "+s.OrigPath)
+	case s.Deleted():
+		parts = append(parts, "deleted")
+	case s.Worktree == '?' || s.Index == 'A':
+		parts = append(parts, "new")
+	}
+	if s.PartiallyStaged() {
+		parts = append(parts, "staged, then modified")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseStatusV2 parses the output of git status --porcelain=v2 -z. Ignored files
+// are left out, and unmerged files are reported with 'U' on both sides.
+func ParseStatusV2(output string) ([]FileStatus, error) {
+	var statuses []FileStatus
+	records := strings.Split(output, "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if record == "" {
+			continue
+		}
+
+		switch record[0] {
+		case '1', '2', 'u':
+			// The path is the last field; only renames and copies have a score field before it
+			fields := 9
+			switch record[0] {
+			case '2':
+				fields = 10
+			case 'u':
+				fields = 11
+			}
+			parts := strings.SplitN(record, " ", fields)
+			if len(parts) != fields || len(parts[1]) != 2 {
+				return nil, fmt.Errorf("malformed status record %q", record)
+			}
+			status := FileStatus{
+				Path:     parts[fields-1],
+				Index:    parts[1][0],
+				Worktree: parts[1][1],
+			}
+			if record[0] == '2' {
+				// With -z the original path is the next record
+				i++
+				if i >= len(records) {
+					return nil, fmt.Errorf("rename of %s has no original path", status.Path)
+				}
+				status.OrigPath = records[i]
+			}
+			statuses = append(statuses, status)
+		case '?':
+			statuses = append(statuses, FileStatus{Path: record[2:], Index: '.', Worktree: '?'})
+		case '!', '#':
+			// Ignored files and headers
+		default:
+			return nil, fmt.Errorf("unknown status record %q", record)
+		}
+	}
+	return statuses, nil
+}
+
+// FileStatuses returns the status of every changed and untracked file, sorted by
+// path. Renames in the index come from git; a file deleted in the worktree and an
+// untracked file with the same or similar content are paired into a worktree
+// rename, which git status cannot report since the new file is not yet tracked.
+func (r *Repository) FileStatuses() ([]FileStatus, error) {
+	output, err := r.runGit("status", "--porcelain=v2", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file status: %w", err)
+	}
+	statuses, err := ParseStatusV2(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file status: %w", err)
+	}
+
+	statuses, err = r.pairWorktreeRenames(statuses)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Path < statuses[j].Path })
+	return statuses, nil
+}
+
+// pairWorktreeRenames merges files deleted in the worktree with untracked files
+// they were renamed to, using the same rename detection as the diffs
+func (r *Repository) pairWorktreeRenames(statuses []FileStatus) ([]FileStatus, error) {
+	var deleted, untracked []int
+	for i, s := range statuses {
+		switch {
+		case s.Index == '.' && s.Worktree == 'D':
+			deleted = append(deleted, i)
+		case s.Worktree == '?':
+			untracked = append(untracked, i)
+		}
+	}
+	if len(deleted) == 0 || len(untracked) == 0 {
+		return statuses, nil
+	}
+
+	index, err := r.indexEntries()
+	if err != nil {
+		return nil, err
+	}
+	var changes []change
+	for _, i := range deleted {
+		if from, ok := index[statuses[i].Path]; ok {
+			changes = append(changes, change{from: from})
+		}
+	}
+	for _, i := range untracked {
+		to, err := r.worktreeVersion(statuses[i].Path)
+		if err != nil {
+			d.Log("Warning: Skipping %s for rename detection: %v", statuses[i].Path, err)
+			continue
+		}
+		changes = append(changes, change{to: to})
+	}
+	changes, err = r.detectRenames(changes)
+	if err != nil {
+		return nil, err
+	}
+
+	renamedFrom := make(map[string]string) // New path to old path
+	for _, c := range changes {
+		if c.from != nil && c.to != nil {
+			renamedFrom[c.to.path] = c.from.path
+		}
+	}
+	if len(renamedFrom) == 0 {
+		return statuses, nil
+	}
+
+	paired := make(map[string]bool, len(renamedFrom))
+	for _, old := range renamedFrom {
+		paired[old] = true
+	}
+	result := make([]FileStatus, 0, len(statuses)-len(renamedFrom))
+	for _, s := range statuses {
+		switch {
+		case paired[s.Path] && s.Worktree == 'D':
+			continue
+		case s.Worktree == '?' && renamedFrom[s.Path] != "":
+			s.OrigPath = renamedFrom[s.Path]
+			s.Worktree = 'R'
+		}
+		result = append(result, s)
+	}
+	d.Log("Paired %d worktree renames", len(renamedFrom))
+	return result, nil
+}
+
+// PartiallyStagedFiles returns the files with staged changes and further unstaged
+// ones, whose unstaged parts a commit of the index leaves out
+func (r *Repository) PartiallyStagedFiles() ([]string, error) {
+	statuses, err := r.FileStatuses()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, s := range statuses {
+		if s.PartiallyStaged() {
+			files = append(files, s.Path)
+		}
+	}
+	return files, nil
+}
+
+// StageFiles stages the files' worktree contents, deletions included
+func (r *Repository) StageFiles(files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	args := append([]string{"--literal-pathspecs", "add", "-A", "--"}, files...)
+	if _, err := r.runGit(args...); err != nil {
+		return fmt.Errorf("failed to stage files: %w", err)
+	}
+	return nil
+}
+
+// StatusByPath indexes statuses by path, and renamed files also by their old path
+func StatusByPath(statuses []FileStatus) map[string]FileStatus {
+	byPath := make(map[string]FileStatus, len(statuses))
+	for _, s := range statuses {
+		byPath[s.Path] = s
+	}
+	for _, s := range statuses {
+		if _, ok := byPath[s.OrigPath]; s.Renamed() && !ok {
+			byPath[s.OrigPath] = s
+		}
+	}
+	return byPath
+}
+
+// stagePaths returns the paths git add must be given to stage files: both sides of
+// a rename, deletions, and only files with unstaged changes, since git add fails on
+// a path that is already staged as deleted. Files without a status are skipped.
+func stagePaths(files []string, statuses map[string]FileStatus) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(path string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	for _, file := range files {
+		s, ok := statuses[file]
+		if !ok {
+			d.Log("Warning: %s has no changes to stage", file)
+			continue
+		}
+		if !s.Unstaged() {
+			continue
+		}
+		add(s.Path)
+		if s.Worktree == 'R' {
+			add(s.OrigPath)
+		}
+	}
+	return paths
+}
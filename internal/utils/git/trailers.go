@@ -0,0 +1,351 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/go-git/go-git/v5/config"
+)
+
+// Trailer keys added by quill
+const (
+	TrailerSignedOffBy  = "Signed-off-by"
+	TrailerCoAuthoredBy = "Co-authored-by"
+	DefaultIssueKey     = "Refs"
+)
+
+// Branch name prefixes for a change type, which issue references follow
+const branchTypes = `feat|feature|fix|bugfix|hotfix|chore|docs|refactor|perf|test|build|ci|style|revert|issue`
+
+var (
+	// A trailer line is "Key: value"; conventional commits also allow "BREAKING CHANGE: ..."
+	trailerLine = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*|BREAKING CHANGE)\s*:\s*(.*)$`)
+	// "Name <email>", as used by Co-authored-by
+	identityPattern = regexp.MustCompile(`^[^<>]+ <[^<>\s]+@[^<>\s]+>$`)
+	// Jira style keys such as PROJ-123 leading the segment after a change type, e.g.
+	// feat/PROJ-123-login; keys elsewhere are part of the description
+	defaultIssuePattern = regexp.MustCompile(`(?:^|/)(?i:` + branchTypes + `)/([A-Z][A-Z0-9]+-[0-9]+)(?:[-_/]|$)`)
+	// GitHub style issue numbers leading the segment after a change type, e.g. fix/123-crash;
+	// numbers elsewhere, as in release/2024-01, are versions or dates
+	issueNumberPattern = regexp.MustCompile(`(?:^|/)(?i:` + branchTypes + `)/#?([0-9]+)(?:[-_]|$)`)
+	// A whole word that is an issue key or number, e.g. PROJ-123 or #42
+	leadingIssueKey    = regexp.MustCompile(`^([A-Z][A-Z0-9]+)-[0-9]+$`)
+	leadingIssueNumber = regexp.MustCompile(`^#([0-9]+)$`)
+	// Standards and algorithms named like issue keys, as in feat/UTF-8-paths
+	standardNames = map[string]bool{
+		"UTF": true, "UCS": true, "SHA": true, "MD": true, "ISO": true, "RFC": true, "CVE": true,
+		"IEEE": true, "ECMA": true, "ES": true, "HTTP": true, "TLS": true, "SSL": true, "AES": true, "RSA": true,
+	}
+)
+
+// Trailer is a "Key: value" line at the end of a commit message
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+func (t Trailer) String() string {
+	return t.Key + ": " + t.Value
+}
+
+// TrailerOptions selects which trailers BuildTrailers produces
+type TrailerOptions struct {
+	Signoff      bool     // Add Signed-off-by for the committer
+	CoAuthors    []string // "Name <email>" entries
+	PairingFile  string   // File listing one co-author per line, may start with ~/
+	IssueRefs    bool     // Extract issue references from the branch name
+	IssueKey     string   // Trailer key for issue references, Refs by default
+	IssuePattern string   // Regular expression matching issue references, Jira keys by default
+}
+
+// BuildTrailers resolves the trailers to append to commit messages
+func (r *Repository) BuildTrailers(opts TrailerOptions) ([]Trailer, error) {
+	var trailers []Trailer
+
+	if opts.IssueRefs {
+		refs, err := r.branchIssueRefs(opts.IssuePattern)
+		if err != nil {
+			return nil, err
+		}
+		key := opts.IssueKey
+		if key == "" {
+			key = DefaultIssueKey
+		}
+		for _, ref := range refs {
+			trailers = append(trailers, Trailer{Key: key, Value: ref})
+		}
+	}
+
+	coAuthors := append([]string(nil), opts.CoAuthors...)
+	if opts.PairingFile != "" {
+		paired, err := ReadPairingFile(opts.PairingFile)
+		if err != nil {
+			return nil, err
+		}
+		coAuthors = append(coAuthors, paired...)
+	}
+
+	name, email, err := r.Identity()
+	if err != nil && (opts.Signoff || len(coAuthors) > 0) {
+		return nil, err
+	}
+
+	for _, coAuthor := range coAuthors {
+		coAuthor = strings.TrimSpace(coAuthor)
+		if !identityPattern.MatchString(coAuthor) {
+			return nil, fmt.Errorf("invalid co-author %q, expected \"Name <email>\"", coAuthor)
+		}
+		// Pairing files usually list the whole team, including the committer
+		if email != "" && strings.Contains(coAuthor, "<"+email+">") {
+			continue
+		}
+		trailers = append(trailers, Trailer{Key: TrailerCoAuthoredBy, Value: coAuthor})
+	}
+
+	if opts.Signoff {
+		trailers = append(trailers, Trailer{Key: TrailerSignedOffBy, Value: fmt.Sprintf("%s <%s>", name, email)})
+	}
+
+	return trailers, nil
+}
+
+// Identity returns the committer name and email, honouring GIT_COMMITTER_* overrides
+func (r *Repository) Identity() (name, email string, err error) {
+	cfg, err := r.repo.ConfigScoped(config.SystemScope)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read git config: %w", err)
+	}
+
+	name, email = cfg.User.Name, cfg.User.Email
+	if cfg.Committer.Name != "" {
+		name = cfg.Committer.Name
+	}
+	if cfg.Committer.Email != "" {
+		email = cfg.Committer.Email
+	}
+	if v := os.Getenv("GIT_COMMITTER_NAME"); v != "" {
+		name = v
+	}
+	if v := os.Getenv("GIT_COMMITTER_EMAIL"); v != "" {
+		email = v
+	}
+
+	if name == "" || email == "" {
+		return name, email, fmt.Errorf("git user.name and user.email must be set")
+	}
+	return name, email, nil
+}
+
+// branchIssueRefs extracts issue references from the current branch name
+func (r *Repository) branchIssueRefs(pattern string) ([]string, error) {
+	head, err := r.repo.Head()
+	if err != nil || !head.Name().IsBranch() {
+		// Unborn or detached HEAD, there is no branch name to read
+		return nil, nil
+	}
+	return IssueRefsFromBranch(head.Name().Short(), pattern)
+}
+
+// IssueRefsFromBranch extracts issue references from a branch name, e.g.
+// "feat/PROJ-123-login" gives PROJ-123 and "fix/42-crash" gives #42. Only a
+// reference right after a change type counts, and keys of standards such as UTF-8
+// or SHA-256 never do. A custom pattern replaces the defaults; its first group is
+// used when it has one.
+func IssueRefsFromBranch(branch, pattern string) ([]string, error) {
+	var refs []string
+	seen := make(map[string]bool)
+	add := func(ref string) {
+		if ref != "" && !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid issue pattern %q: %w", pattern, err)
+		}
+		for _, m := range re.FindAllStringSubmatch(branch, -1) {
+			if len(m) > 1 {
+				add(m[1])
+			} else {
+				add(m[0])
+			}
+		}
+		return refs, nil
+	}
+
+	for _, m := range defaultIssuePattern.FindAllStringSubmatch(branch, -1) {
+		if !standardNames[m[1][:strings.Index(m[1], "-")]] {
+			add(m[1])
+		}
+	}
+	if len(refs) == 0 {
+		for _, m := range issueNumberPattern.FindAllStringSubmatch(branch, -1) {
+			add("#" + m[1])
+		}
+	}
+	return refs, nil
+}
+
+// IssueRefFromDescription returns the issue reference leading a task description,
+// e.g. PROJ-123 for "PROJ-123 let users reset their password" or #42 for "#42 fix
+// the crash". Keys further in are part of the prose, as in "support UTF-8 names".
+// A custom pattern is matched against the first word instead of the defaults.
+func IssueRefFromDescription(description, pattern string) (string, error) {
+	fields := strings.Fields(description)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	word := strings.TrimRight(fields[0], ":,")
+
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid issue pattern %q: %w", pattern, err)
+		}
+		m := re.FindStringSubmatch(word)
+		switch {
+		case m == nil:
+			return "", nil
+		case len(m) > 1:
+			return m[1], nil
+		}
+		return m[0], nil
+	}
+
+	if m := leadingIssueKey.FindStringSubmatch(word); m != nil && !standardNames[m[1]] {
+		return m[0], nil
+	}
+	if m := leadingIssueNumber.FindStringSubmatch(word); m != nil {
+		return "#" + m[1], nil
+	}
+	return "", nil
+}
+
+// ReadPairingFile reads co-authors from a pairing file, one "Name <email>" per line.
+// Blank lines and lines starting with # are skipped; a missing file means no co-authors.
+func ReadPairingFile(path string) ([]string, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(home, path[2:])
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pairing file: %w", err)
+	}
+	defer file.Close()
+
+	var coAuthors []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		coAuthors = append(coAuthors, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pairing file: %w", err)
+	}
+	return coAuthors, nil
+}
+
+// ParseTrailers returns the trailers in the last paragraph of a commit message
+func ParseTrailers(message string) []Trailer {
+	_, block := splitTrailerBlock(message)
+	var trailers []Trailer
+	for _, line := range block {
+		if m := trailerLine.FindStringSubmatch(line); m != nil {
+			trailers = append(trailers, Trailer{Key: m[1], Value: strings.TrimSpace(m[2])})
+		}
+	}
+	return trailers
+}
+
+// AppendTrailers adds trailers to a commit message the way git interpret-trailers does:
+// they join an existing trailer block or start a new paragraph. Trailers already present
+// with the same key and value are skipped, and issue references already mentioned in a
+// trailer are not repeated.
+func AppendTrailers(message string, trailers []Trailer) string {
+	body, block := splitTrailerBlock(message)
+	existing := ParseTrailers(message)
+
+	var added []string
+	for _, t := range trailers {
+		if hasTrailer(existing, t) {
+			continue
+		}
+		existing = append(existing, t)
+		added = append(added, t.String())
+	}
+	if len(added) == 0 {
+		return message
+	}
+
+	if len(block) == 0 {
+		return body + "\n\n" + strings.Join(added, "\n")
+	}
+	return body + "\n\n" + strings.Join(append(block, added...), "\n")
+}
+
+// hasTrailer reports whether an equivalent trailer is already present
+func hasTrailer(existing []Trailer, t Trailer) bool {
+	for _, e := range existing {
+		if !strings.EqualFold(e.Key, t.Key) && t.Key != DefaultIssueKey {
+			continue
+		}
+		if strings.EqualFold(e.Key, t.Key) && e.Value == t.Value {
+			return true
+		}
+		// "Closes: PROJ-123, #7" already covers "Refs: PROJ-123", but "Refs: #42" not "#4"
+		if t.Key == DefaultIssueKey {
+			for _, ref := range strings.FieldsFunc(e.Value, isRefSeparator) {
+				if ref == t.Value {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isRefSeparator splits the references listed in a trailer value
+func isRefSeparator(r rune) bool {
+	return r == ',' || r == ';' || unicode.IsSpace(r)
+}
+
+// splitTrailerBlock separates the trailing paragraph of "Key: value" lines from the rest
+// of the message. The subject line is never treated as a trailer.
+func splitTrailerBlock(message string) (body string, block []string) {
+	message = strings.TrimRight(message, " \t\r\n")
+	idx := strings.LastIndex(message, "\n\n")
+	if idx < 0 {
+		return message, nil
+	}
+
+	lines := strings.Split(strings.Trim(message[idx+2:], "\n"), "\n")
+	for i, line := range lines {
+		// Indented lines continue the previous trailer's value
+		if i > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			continue
+		}
+		if !trailerLine.MatchString(line) {
+			return message, nil
+		}
+	}
+	return strings.TrimRight(message[:idx], "\n"), lines
+}
@@ -0,0 +1,442 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	d "github.com/jabafett/quill/internal/utils/debug"
+)
+
+// Refs used to remember the last batch applied by quill so it can be undone
+const (
+	batchBaseRef  = "refs/quill/batch/base"
+	batchHeadRef  = "refs/quill/batch/head"
+	batchIndexRef = "refs/quill/batch/index"
+)
+
+// CommitGroup is a set of files that are staged and committed together
+type CommitGroup struct {
+	Description string
+	Files       []string
+	Message     string // Files are only staged when empty
+}
+
+// Snapshot captures HEAD and the index so they can be restored later
+type Snapshot struct {
+	Head      string // Empty when HEAD is unborn
+	IndexTree string // Tree object written from the index
+}
+
+// BatchRecord describes the last batch of commits applied by quill
+type BatchRecord struct {
+	Base      string // HEAD before the batch, empty when it was unborn
+	Head      string // HEAD after the batch
+	IndexTree string // Index before the batch
+}
+
+// ErrBatchFailed is returned when a group could not be applied
+type ErrBatchFailed struct {
+	Group       int    // Zero-based index of the failing group
+	Description string // Description of the failing group
+	Applied     int    // Number of commits created before the failure
+	Err         error  // Underlying failure
+	RollbackErr error  // Set when restoring the snapshot also failed
+}
+
+func (e ErrBatchFailed) Error() string {
+	msg := fmt.Sprintf("group %d (%s) failed: %v", e.Group+1, e.Description, e.Err)
+	if e.RollbackErr != nil {
+		msg += fmt.Sprintf("; rollback failed: %v", e.RollbackErr)
+	}
+	return msg
+}
+
+func (e ErrBatchFailed) Unwrap() error {
+	return e.Err
+}
+
+// ErrNoBatch is returned by undo when no quill batch has been recorded
+var ErrNoBatch = errors.New("no quill batch to undo")
+
+// TakeSnapshot records the current HEAD and index
+func (r *Repository) TakeSnapshot() (*Snapshot, error) {
+	snap := &Snapshot{}
+
+	head, err := r.repo.Head()
+	switch {
+	case err == nil:
+		snap.Head = head.Hash().String()
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		// Unborn branch, nothing to record
+	default:
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	tree, err := r.runGit("write-tree")
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot index: %w", err)
+	}
+	snap.IndexTree = strings.TrimSpace(tree)
+
+	return snap, nil
+}
+
+// RestoreSnapshot resets HEAD and the index to a snapshot, leaving the worktree untouched
+func (r *Repository) RestoreSnapshot(snap *Snapshot) error {
+	if snap.Head != "" {
+		if _, err := r.runGit("reset", "--soft", snap.Head); err != nil {
+			return fmt.Errorf("failed to reset HEAD: %w", err)
+		}
+	} else if _, err := r.repo.Head(); err == nil {
+		// The branch was born during the batch, so unborn it again
+		if _, err := r.runGit("update-ref", "-d", "HEAD"); err != nil {
+			return fmt.Errorf("failed to reset unborn HEAD: %w", err)
+		}
+	}
+
+	if _, err := r.runGit("read-tree", snap.IndexTree); err != nil {
+		return fmt.Errorf("failed to restore index: %w", err)
+	}
+	return nil
+}
+
+// ApplyGroups stages and commits each group in order. If any group fails, HEAD and
+// the index are restored to their state before the first group and an ErrBatchFailed
+// is returned. On success the batch is recorded so it can be reverted with UndoLastBatch.
+func (r *Repository) ApplyGroups(groups []CommitGroup) ([]string, error) {
+	results, err := r.ApplyGroupsWith(groups, CommitOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make([]string, 0, len(results))
+	for _, result := range results {
+		commits = append(commits, result.Hash)
+	}
+	return commits, nil
+}
+
+// ApplyGroupsWith is ApplyGroups with signing, hook and author options applied to every commit
+func (r *Repository) ApplyGroupsWith(groups []CommitGroup, opts CommitOptions) ([]CommitResult, error) {
+	if opts.Amend || opts.Fixup != "" {
+		return nil, fmt.Errorf("commit groups cannot be applied with --amend or --fixup")
+	}
+
+	snap, err := r.TakeSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	d.Log("Snapshot taken: head=%s index=%s", snap.Head, snap.IndexTree)
+
+	// Deletions and renames are staged from each file's status, read before any group
+	// changes it
+	fileStatuses, err := r.FileStatuses()
+	if err != nil {
+		return nil, err
+	}
+	statuses := StatusByPath(fileStatuses)
+
+	var commits []CommitResult
+	for i, group := range groups {
+		result, err := r.applyGroup(group, statuses, opts)
+		if err != nil {
+			batchErr := ErrBatchFailed{
+				Group:       i,
+				Description: group.Description,
+				Applied:     len(commits),
+				Err:         err,
+			}
+			d.Log("Group %d failed, rolling back: %v", i+1, err)
+			if rbErr := r.RestoreSnapshot(snap); rbErr != nil {
+				batchErr.RollbackErr = rbErr
+			}
+			return nil, batchErr
+		}
+
+		if result != nil {
+			commits = append(commits, *result)
+		}
+	}
+
+	if len(commits) > 0 {
+		if err := r.recordBatch(snap); err != nil {
+			d.Log("Warning: failed to record batch for undo: %v", err)
+		}
+	}
+
+	return commits, nil
+}
+
+// applyGroup stages the group's files and commits them when a message is set. A
+// renamed file stages its old path too, and files already fully staged are left
+// as they are. The commit holds only the group's files, so changes staged for
+// other files stay staged instead of slipping into it.
+func (r *Repository) applyGroup(group CommitGroup, statuses map[string]FileStatus, opts CommitOptions) (*CommitResult, error) {
+	if paths := stagePaths(group.Files, statuses); len(paths) > 0 {
+		// -A stages deletions as well as modifications and new files
+		args := append([]string{"--literal-pathspecs", "add", "-A", "--"}, paths...)
+		if _, err := r.runGit(args...); err != nil {
+			return nil, fmt.Errorf("failed to stage files: %w", err)
+		}
+	}
+
+	if group.Message == "" {
+		return nil, nil
+	}
+
+	result, err := r.commitPaths(group.Message, groupPaths(group.Files, statuses), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return result, nil
+}
+
+// groupPaths returns the paths a group's commit covers: its files and the old
+// paths of renamed ones
+func groupPaths(files []string, statuses map[string]FileStatus) []string {
+	paths := append([]string(nil), files...)
+	for _, file := range files {
+		if s, ok := statuses[file]; ok && s.Renamed() && !slices.Contains(paths, s.OrigPath) {
+			paths = append(paths, s.OrigPath)
+		}
+	}
+	return paths
+}
+
+// commitPaths commits the paths as they are staged, leaving everything else in the
+// index staged. The commit is built in a copy of the real index with every other
+// staged path reset to HEAD; the copy keeps the stat data, so git does not re-hash
+// the worktree. What hooks stage into the copy is carried back for the committed
+// paths afterwards.
+func (r *Repository) commitPaths(message string, paths []string, opts CommitOptions) (*CommitResult, error) {
+	dir, err := os.MkdirTemp("", "quill-index-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	index := filepath.Join(dir, "index")
+	env := []string{"GIT_INDEX_FILE=" + index}
+
+	if err := r.copyIndex(index); err != nil {
+		return nil, fmt.Errorf("failed to prepare temporary index: %w", err)
+	}
+	staged, err := r.runGitEnv(env, "diff", "--cached", "--name-only", "--no-renames", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read staged paths: %w", err)
+	}
+	var others []string
+	for _, path := range strings.Split(staged, "\x00") {
+		if path != "" && !slices.Contains(paths, path) {
+			others = append(others, path)
+		}
+	}
+	if len(others) > 0 {
+		args := append([]string{"--literal-pathspecs", "reset", "-q", "--"}, others...)
+		if _, err := r.runGitEnv(env, args...); err != nil {
+			return nil, fmt.Errorf("failed to prepare temporary index: %w", err)
+		}
+	}
+
+	result, err := r.commit(message, opts, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// A hook such as a formatter may have staged fixes, to these or other files
+	committed, err := r.runGit("diff-tree", "-r", "--root", "--no-commit-id", "--name-only", "--no-renames", "-z", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read committed paths: %w", err)
+	}
+	synced := append([]string(nil), paths...)
+	for _, path := range strings.Split(committed, "\x00") {
+		if path != "" && !slices.Contains(synced, path) {
+			synced = append(synced, path)
+		}
+	}
+	if err := r.syncIndex(env, synced); err != nil {
+		return nil, fmt.Errorf("failed to update the index: %w", err)
+	}
+	return result, nil
+}
+
+// copyIndex copies the repository's index file to path. A repository without an
+// index yet gets an empty one.
+func (r *Repository) copyIndex(path string) error {
+	source, err := r.runGit("rev-parse", "--git-path", "index")
+	if err != nil {
+		return err
+	}
+	source = strings.TrimSpace(source)
+	if !filepath.IsAbs(source) {
+		root, err := r.GetRepoRootPath()
+		if err != nil {
+			return err
+		}
+		source = filepath.Join(root, source)
+	}
+
+	data, err := os.ReadFile(source)
+	if os.IsNotExist(err) {
+		_, err = r.runGitEnv([]string{"GIT_INDEX_FILE=" + path}, "read-tree", "--empty")
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// syncIndex sets the real index entries for paths to those in the index env
+// points at. Paths whose entries already match keep their stat data.
+func (r *Repository) syncIndex(env []string, paths []string) error {
+	from, err := r.stagedEntries(env, paths)
+	if err != nil {
+		return err
+	}
+	to, err := r.stagedEntries(nil, paths)
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	args := []string{"update-index", "--add"}
+	for _, path := range paths {
+		if from[path] == to[path] {
+			continue
+		}
+		changed = append(changed, path)
+		// A path without an entry stays removed
+		if fields := strings.Fields(from[path]); len(fields) == 3 {
+			args = append(args, "--cacheinfo", fields[0]+","+fields[1]+","+path)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	if _, err := r.runGit(append([]string{"--literal-pathspecs", "update-index", "--force-remove", "--"}, changed...)...); err != nil {
+		return err
+	}
+	if len(args) > 2 {
+		if _, err := r.runGit(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stagedEntries reads the index entries for paths as "<mode> <hash> <stage>" by path
+func (r *Repository) stagedEntries(env []string, paths []string) (map[string]string, error) {
+	out, err := r.runGitEnv(env, append([]string{"--literal-pathspecs", "ls-files", "--stage", "-z", "--"}, paths...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read staged entries: %w", err)
+	}
+	entries := make(map[string]string)
+	for _, entry := range strings.Split(out, "\x00") {
+		if info, path, ok := strings.Cut(entry, "\t"); ok {
+			entries[path] = info
+		}
+	}
+	return entries, nil
+}
+
+// recordBatch stores the refs needed to undo the batch that was just applied
+func (r *Repository) recordBatch(snap *Snapshot) error {
+	head, err := r.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	if snap.Head != "" {
+		if _, err := r.runGit("update-ref", batchBaseRef, snap.Head); err != nil {
+			return err
+		}
+	} else {
+		r.deleteRef(batchBaseRef)
+	}
+	if _, err := r.runGit("update-ref", batchHeadRef, head.Hash().String()); err != nil {
+		return err
+	}
+	if _, err := r.runGit("update-ref", batchIndexRef, snap.IndexTree); err != nil {
+		return err
+	}
+	return nil
+}
+
+// LastBatch returns the record of the last batch applied by quill
+func (r *Repository) LastBatch() (*BatchRecord, error) {
+	headRef, err := r.repo.Reference(plumbing.ReferenceName(batchHeadRef), true)
+	if err != nil {
+		return nil, ErrNoBatch
+	}
+	indexRef, err := r.repo.Reference(plumbing.ReferenceName(batchIndexRef), true)
+	if err != nil {
+		return nil, ErrNoBatch
+	}
+
+	record := &BatchRecord{
+		Head:      headRef.Hash().String(),
+		IndexTree: indexRef.Hash().String(),
+	}
+	if baseRef, err := r.repo.Reference(plumbing.ReferenceName(batchBaseRef), true); err == nil {
+		record.Base = baseRef.Hash().String()
+	}
+	return record, nil
+}
+
+// BatchCommits returns one "<short hash> <subject>" line per commit in the batch
+func (r *Repository) BatchCommits(record *BatchRecord) ([]string, error) {
+	rangeSpec := record.Head
+	if record.Base != "" {
+		rangeSpec = record.Base + ".." + record.Head
+	}
+
+	output, err := r.runGit("log", "--format=%h %s", rangeSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batch commits: %w", err)
+	}
+	if strings.TrimSpace(output) == "" {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSpace(output), "\n"), nil
+}
+
+// UndoLastBatch reverts the last batch applied by quill, restoring HEAD and the index to
+// their state before it. Unless force is set, HEAD must still point at the batch's last commit.
+func (r *Repository) UndoLastBatch(force bool) (*BatchRecord, error) {
+	record, err := r.LastBatch()
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := r.repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	if head.Hash().String() != record.Head && !force {
+		return nil, fmt.Errorf("HEAD has moved since the last quill batch (expected %s, found %s); use --force to undo anyway",
+			record.Head[:7], head.Hash().String()[:7])
+	}
+
+	snap := &Snapshot{Head: record.Base, IndexTree: record.IndexTree}
+	if err := r.RestoreSnapshot(snap); err != nil {
+		return nil, err
+	}
+
+	r.deleteRef(batchBaseRef)
+	r.deleteRef(batchHeadRef)
+	r.deleteRef(batchIndexRef)
+
+	return record, nil
+}
+
+// deleteRef removes a ref, ignoring refs that do not exist
+func (r *Repository) deleteRef(name string) {
+	if _, err := r.runGit("update-ref", "-d", name); err != nil {
+		d.Log("Failed to delete ref %s: %v", name, err)
+	}
+}
@@ -0,0 +1,75 @@
+package git
+
+// FileVersions reads the old and new content of the files in a diff
+type FileVersions struct {
+	r        *Repository
+	from, to map[string]*fileVersion
+	worktree bool // The new side is read from the worktree instead of to
+}
+
+// StagedVersions reads files as they are in HEAD and in the index, matching StagedDiff
+func (r *Repository) StagedVersions() (*FileVersions, error) {
+	head, err := r.headEntries()
+	if err != nil {
+		return nil, err
+	}
+	index, err := r.indexEntries()
+	if err != nil {
+		return nil, err
+	}
+	return &FileVersions{r: r, from: head, to: index}, nil
+}
+
+// AmendVersions reads files as they are in HEAD's parent and in the index, matching AmendDiff
+func (r *Repository) AmendVersions() (*FileVersions, error) {
+	commit, err := r.amendTarget()
+	if err != nil {
+		return nil, err
+	}
+	parent, err := parentEntries(commit)
+	if err != nil {
+		return nil, err
+	}
+	index, err := r.indexEntries()
+	if err != nil {
+		return nil, err
+	}
+	return &FileVersions{r: r, from: parent, to: index}, nil
+}
+
+// UnstagedVersions reads files as they are in the index and in the worktree, matching UnstagedDiff
+func (r *Repository) UnstagedVersions() (*FileVersions, error) {
+	index, err := r.indexEntries()
+	if err != nil {
+		return nil, err
+	}
+	return &FileVersions{r: r, from: index, worktree: true}, nil
+}
+
+// Read returns both sides of a file. The old side is nil for added files and the
+// new side is nil for deleted files.
+func (v *FileVersions) Read(file *FileDiff) (before, after []byte, err error) {
+	oldPath := file.Path
+	if file.OldPath != "" {
+		oldPath = file.OldPath
+	}
+
+	if file.Status != StatusAdded {
+		if before, err = v.r.load(v.from[oldPath]); err != nil {
+			return nil, nil, err
+		}
+	}
+	if file.Status == StatusDeleted {
+		return before, nil, nil
+	}
+
+	if v.worktree {
+		version, err := v.r.worktreeVersion(file.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return before, version.content, nil
+	}
+	after, err = v.r.load(v.to[file.Path])
+	return before, after, err
+}
@@ -0,0 +1,85 @@
+package helpers
+
+import (
+	"strings"
+
+	"github.com/jabafett/quill/internal/utils/ai"
+)
+
+// BranchObject is one proposed branch, as its type and slug; the configured
+// pattern turns it into a name
+type BranchObject struct {
+	Type string `json:"type"`
+	Slug string `json:"slug"`
+}
+
+// BranchesObject is a structured branch name response
+type BranchesObject struct {
+	Branches []BranchObject `json:"branches"`
+}
+
+// BranchSchema is the schema of a structured branch name response
+var BranchSchema = &ai.Schema{
+	Name:        "branch_names",
+	Description: "Proposed branch names for the work, each as a change type and a slug",
+	Definition: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"branches": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"type": map[string]any{
+							"type":        "string",
+							"description": "Conventional commit type of the work: feat, fix, docs, style, refactor, perf, test or chore",
+						},
+						"slug": map[string]any{
+							"type":        "string",
+							"description": "Two to five lowercase words joined by hyphens, e.g. add-login-form",
+						},
+					},
+					"required":             []string{"type", "slug"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"branches"},
+		"additionalProperties": false,
+	},
+}
+
+// ParseBranchObject reads a structured branch name response, leaving out proposals
+// without a type or slug
+func ParseBranchObject(response string) ([]BranchObject, error) {
+	var object BranchesObject
+	if err := decodeJSON(response, &object); err != nil {
+		return nil, err
+	}
+
+	var branches []BranchObject
+	for _, b := range object.Branches {
+		if strings.TrimSpace(b.Type) != "" && strings.TrimSpace(b.Slug) != "" {
+			branches = append(branches, b)
+		}
+	}
+	if len(branches) == 0 {
+		return nil, &ValidationError{Problems: []string{"no branch has both a type and a slug"}}
+	}
+	return branches, nil
+}
+
+// ParseBranchResponse reads a text branch name response of one "type/slug" line
+// per proposal. List markers, quotes and lines of any other form are skipped.
+func ParseBranchResponse(response string) []BranchObject {
+	var branches []BranchObject
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.Trim(strings.TrimSpace(line), "-*`'\" ")
+		typ, slug, ok := strings.Cut(line, "/")
+		if !ok || typ == "" || slug == "" || strings.ContainsAny(typ, " :") {
+			continue
+		}
+		branches = append(branches, BranchObject{Type: typ, Slug: slug})
+	}
+	return branches
+}
@@ -0,0 +1,158 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jabafett/quill/internal/utils/ai"
+)
+
+// RiskObject is an area of a change that deserves a careful review
+type RiskObject struct {
+	Area   string `json:"area"`
+	Reason string `json:"reason"`
+}
+
+// ExplanationObject is a structured explanation response
+type ExplanationObject struct {
+	Summary    string       `json:"summary"`
+	Changes    []string     `json:"changes"`
+	Motivation string       `json:"motivation"`
+	Risks      []RiskObject `json:"risks"`
+	Testing    []string     `json:"testing"`
+}
+
+// ExplainedCommit is a commit of an explained range
+type ExplainedCommit struct {
+	Hash    string `json:"hash"`
+	Subject string `json:"subject"`
+	Author  string `json:"author"`
+	Date    string `json:"date"`
+}
+
+// Explanation is a reviewer-oriented explanation of a commit, a range or the
+// staged changes, with what it was built from
+type Explanation struct {
+	Revision    string            `json:"revision"`
+	Commits     []ExplainedCommit `json:"commits,omitempty"`
+	MoreCommits bool              `json:"more_commits,omitempty"` // Commits holds only the newest ones
+	Files       []string          `json:"files"`
+	References  []string          `json:"references,omitempty"`
+	ExplanationObject
+}
+
+// ExplanationSchema is the schema of a structured explanation response
+var ExplanationSchema = &ai.Schema{
+	Name:        "explanation",
+	Description: "A reviewer-oriented explanation of a change",
+	Definition: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"summary": map[string]any{
+				"type":        "string",
+				"description": "Two or three sentences on what the change does overall",
+			},
+			"changes": map[string]any{
+				"type":        "array",
+				"description": "The notable changes, one per item, naming the components and behaviour affected",
+				"items":       map[string]any{"type": "string"},
+			},
+			"motivation": map[string]any{
+				"type":        "string",
+				"description": "Why the change was probably made, based on the commit messages and references; say when it is a guess",
+			},
+			"risks": map[string]any{
+				"type":        "array",
+				"description": "Areas where the change could break something",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"area":   map[string]any{"type": "string", "description": "File, function or component"},
+						"reason": map[string]any{"type": "string", "description": "What could go wrong"},
+					},
+					"required":             []string{"area", "reason"},
+					"additionalProperties": false,
+				},
+			},
+			"testing": map[string]any{
+				"type":        "array",
+				"description": "What to test to verify the change, one scenario per item",
+				"items":       map[string]any{"type": "string"},
+			},
+		},
+		"required":             []string{"summary", "changes", "motivation", "risks", "testing"},
+		"additionalProperties": false,
+	},
+}
+
+// ParseExplanationObject reads a structured explanation response
+func ParseExplanationObject(response string) (ExplanationObject, error) {
+	var explanation ExplanationObject
+	if err := decodeJSON(response, &explanation); err != nil {
+		return ExplanationObject{}, err
+	}
+	if strings.TrimSpace(explanation.Summary) == "" {
+		return ExplanationObject{}, &ValidationError{Problems: []string{"summary is empty"}}
+	}
+	return explanation, nil
+}
+
+// Markdown renders the explanation for reading in a terminal
+func (e *Explanation) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s\n", e.Revision, strings.TrimSpace(e.Summary))
+
+	if len(e.Commits) > 0 {
+		b.WriteString("\n## Commits\n\n")
+		for _, c := range e.Commits {
+			fmt.Fprintf(&b, "- `%s` %s (%s, %s)\n", shortHash(c.Hash), c.Subject, c.Author, c.Date)
+		}
+		if e.MoreCommits {
+			b.WriteString("- ...\n")
+		}
+	}
+
+	writeList(&b, "What changed", e.Changes)
+
+	if motivation := strings.TrimSpace(e.Motivation); motivation != "" || len(e.References) > 0 {
+		b.WriteString("\n## Why\n\n")
+		if motivation != "" {
+			b.WriteString(motivation + "\n")
+		}
+		if len(e.References) > 0 {
+			if motivation != "" {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "References: %s\n", strings.Join(e.References, ", "))
+		}
+	}
+
+	if len(e.Risks) > 0 {
+		b.WriteString("\n## Risky areas\n\n")
+		for _, r := range e.Risks {
+			fmt.Fprintf(&b, "- **%s**: %s\n", r.Area, r.Reason)
+		}
+	}
+
+	writeList(&b, "What to test", e.Testing)
+	return b.String()
+}
+
+// writeList writes a Markdown section of bullet points, nothing when items is empty
+func writeList(b *strings.Builder, heading string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n\n", heading)
+	for _, item := range items {
+		fmt.Fprintf(b, "- %s\n", strings.TrimSpace(item))
+	}
+}
+
+// shortHash abbreviates a commit hash the way git log --oneline does
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,197 @@
+package helpers
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RankedMessage is a commit message candidate with its score
+type RankedMessage struct {
+	Message   string
+	Source    string  // Provider that generated it
+	Score     float64 // 0 to 10, the judge's score when it gave one
+	RuleScore float64 // 0 to 10, from ScoreMessage
+	Rationale string
+}
+
+// Judgement is the judge model's verdict on one candidate
+type Judgement struct {
+	Score     float64
+	Rationale string
+}
+
+// Longest header the commit prompt allows
+const maxHeaderLength = 72
+
+var (
+	conventionalHeader = regexp.MustCompile(`^([a-z]+)(\([^()]+\))?!?: (\S.*)$`)
+	commitTypes        = map[string]bool{
+		"feat": true, "fix": true, "docs": true, "style": true, "refactor": true,
+		"perf": true, "test": true, "chore": true, "build": true, "ci": true, "revert": true,
+	}
+	// "2: 8 - names the changed parser", with optional markdown around the number
+	judgementLine = regexp.MustCompile(`^[\s*#-]*(?i:candidate\s*)?(\d+)[.):\]*]*\s*[:-]?\s*(\d+(?:\.\d+)?)\s*(?:/\s*10)?\s*[-:–—]\s*(.+)$`)
+)
+
+// ScoreMessage rates a commit message from 0 to 10 without AI, on the rules the
+// commit prompt gives: a conventional header of a known type within 72 characters,
+// lowercase and without a trailing period, and a body separated by a blank line
+// for changes to several files. Naming a changed file or symbol counts as specific.
+// The rationale lists what cost points, or what made the message specific.
+func ScoreMessage(message string, files, symbols []string) (float64, string) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return 0, "empty message"
+	}
+	lines := strings.Split(message, "\n")
+	header := strings.TrimSpace(lines[0])
+
+	score := 10.0
+	var issues []string
+	deduct := func(points float64, issue string) {
+		score -= points
+		issues = append(issues, issue)
+	}
+
+	match := conventionalHeader.FindStringSubmatch(header)
+	switch {
+	case match == nil:
+		deduct(4, "header is not type(scope): description")
+	case !commitTypes[match[1]]:
+		deduct(2, fmt.Sprintf("unknown type %q", match[1]))
+	}
+	if match != nil {
+		if first, _ := utf8.DecodeRuneInString(match[3]); first != unicode.ToLower(first) {
+			deduct(0.5, "description is capitalized")
+		}
+	}
+	if len(header) > maxHeaderLength {
+		deduct(1.5, fmt.Sprintf("header is %d characters", len(header)))
+	}
+	if strings.ContainsAny(header[len(header)-1:], ".!?;:,") {
+		deduct(0.5, "header ends with punctuation")
+	}
+
+	switch {
+	case len(lines) > 1 && strings.TrimSpace(lines[1]) != "":
+		deduct(1, "no blank line after the header")
+	case len(lines) == 1 && len(files) > 1:
+		deduct(1, fmt.Sprintf("no body for a %d-file change", len(files)))
+	}
+
+	named := specificTerms(message, files, symbols)
+	if len(named) == 0 && (len(files) > 0 || len(symbols) > 0) {
+		deduct(1.5, "names none of the changed files or symbols")
+	}
+
+	score = max(score, 0)
+	if len(issues) > 0 {
+		return score, strings.Join(issues, "; ")
+	}
+	if len(named) > 0 {
+		return score, "follows the format, names " + strings.Join(named[:min(len(named), 3)], ", ")
+	}
+	return score, "follows the format"
+}
+
+// specificTerms returns the changed file and symbol names the message mentions
+func specificTerms(message string, files, symbols []string) []string {
+	text := strings.ToLower(message)
+	seen := make(map[string]bool)
+	var named []string
+	check := func(term string) {
+		if len(term) < 3 || seen[term] {
+			return
+		}
+		seen[term] = true
+		if strings.Contains(text, strings.ToLower(term)) {
+			named = append(named, term)
+		}
+	}
+
+	for _, name := range symbolNames(symbols) {
+		check(name)
+	}
+	for _, file := range files {
+		base := path.Base(file)
+		check(strings.TrimSuffix(base, path.Ext(base)))
+	}
+	return named
+}
+
+// symbolNames reads the symbol names from changed symbol lines such as
+// "internal/git/diff.go: func ParseDiff (signature changed), method Diff.Paths (added)"
+func symbolNames(lines []string) []string {
+	var names []string
+	for _, line := range lines {
+		_, changes, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		for _, change := range strings.Split(changes, ", ") {
+			fields := strings.Fields(change)
+			if len(fields) < 2 {
+				continue
+			}
+			name := fields[1]
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				name = name[i+1:]
+			}
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ParseJudgeResponse reads "<candidate>: <score> - <rationale>" lines from a judge
+// response, keyed by the 1-based candidate number. Lines for other numbers and
+// scores outside 0 to 10 are ignored.
+func ParseJudgeResponse(response string, candidates int) map[int]Judgement {
+	judgements := make(map[int]Judgement)
+	for _, line := range strings.Split(response, "\n") {
+		match := judgementLine.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n < 1 || n > candidates {
+			continue
+		}
+		score, err := strconv.ParseFloat(match[2], 64)
+		if err != nil || score < 0 || score > 10 {
+			continue
+		}
+		judgements[n] = Judgement{Score: score, Rationale: strings.TrimSpace(match[3])}
+	}
+	return judgements
+}
+
+// RankMessages scores the candidates with ScoreMessage and the judgements, keyed by
+// 1-based position, and sorts them best first. A judged candidate's score is the
+// judge's, with the rule score breaking ties; the others keep their rule score.
+func RankMessages(candidates []RankedMessage, judgements map[int]Judgement, files, symbols []string) []RankedMessage {
+	ranked := make([]RankedMessage, len(candidates))
+	for i, candidate := range candidates {
+		candidate.RuleScore, candidate.Rationale = ScoreMessage(candidate.Message, files, symbols)
+		candidate.Score = candidate.RuleScore
+		if judgement, ok := judgements[i+1]; ok {
+			candidate.Score = judgement.Score
+			candidate.Rationale = judgement.Rationale
+		}
+		ranked[i] = candidate
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].RuleScore > ranked[j].RuleScore
+	})
+	return ranked
+}
@@ -0,0 +1,226 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RemainingDescription is the description of the group ReconcileGroups collects
+// the changed files no suggested group listed into
+const RemainingDescription = "Remaining changes"
+
+// Reconciliation reports how ReconcileGroups fitted suggested groups to the change set
+type Reconciliation struct {
+	Matched   map[string]string // Suggested path to the changed file it was taken for
+	Dropped   []string          // Suggested paths matching no changed file, or one an earlier group has
+	Remaining []string          // Changed files no group listed
+}
+
+// Changed reports whether reconciling altered any group
+func (r Reconciliation) Changed() bool {
+	return len(r.Matched) > 0 || len(r.Dropped) > 0 || len(r.Remaining) > 0
+}
+
+// Lines describes the reconciliation in one line per change, for logs
+func (r Reconciliation) Lines() []string {
+	var lines []string
+	suggested := make([]string, 0, len(r.Matched))
+	for path := range r.Matched {
+		suggested = append(suggested, path)
+	}
+	sort.Strings(suggested)
+	for _, path := range suggested {
+		lines = append(lines, fmt.Sprintf("matched %s to %s", path, r.Matched[path]))
+	}
+	for _, path := range r.Dropped {
+		lines = append(lines, "dropped "+path)
+	}
+	if len(r.Remaining) > 0 {
+		lines = append(lines, fmt.Sprintf("%d file(s) in %q: %s", len(r.Remaining), RemainingDescription, strings.Join(r.Remaining, ", ")))
+	}
+	return lines
+}
+
+// ReconcileGroups fits suggested groups to the files that actually changed. Paths
+// are normalized and near-misses matched to a changed file (see fileMatcher), paths
+// matching none or already placed in an earlier group are dropped, and groups left
+// without files are removed. Changed files no group lists are collected into a
+// final group that is not marked for staging, so they are never committed under a
+// generic message by accident. Each group's Notes say what changed about it.
+func ReconcileGroups(groups []SuggestionGroup, stagedFiles, unstagedFiles []string) ([]SuggestionGroup, Reconciliation) {
+	changed := changedFiles(stagedFiles, unstagedFiles)
+	matcher := newFileMatcher(changed)
+	rec := Reconciliation{Matched: make(map[string]string)}
+	placed := make(map[string]bool)
+
+	reconciled := make([]SuggestionGroup, 0, len(groups)+1)
+	for _, group := range groups {
+		files := make([]string, 0, len(group.Files))
+		for _, suggested := range group.Files {
+			file, ok := matcher.resolve(suggested)
+			switch {
+			case !ok:
+				rec.Dropped = append(rec.Dropped, suggested)
+				group.Notes = append(group.Notes, fmt.Sprintf("dropped %s: not a changed file", suggested))
+			case placed[file]:
+				rec.Dropped = append(rec.Dropped, suggested)
+				group.Notes = append(group.Notes, fmt.Sprintf("dropped %s: already in an earlier group", suggested))
+			default:
+				if file != suggested {
+					rec.Matched[suggested] = file
+					group.Notes = append(group.Notes, fmt.Sprintf("%s matched to %s", suggested, file))
+				}
+				placed[file] = true
+				files = append(files, file)
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+		group.Files = files
+		group.ShouldStage = false
+		for _, file := range files {
+			group.ShouldStage = group.ShouldStage || contains(unstagedFiles, file)
+		}
+		reconciled = append(reconciled, group)
+	}
+
+	for _, file := range changed {
+		if !placed[file] {
+			rec.Remaining = append(rec.Remaining, file)
+		}
+	}
+	if len(rec.Remaining) > 0 {
+		reconciled = append(reconciled, SuggestionGroup{
+			Description: RemainingDescription,
+			Files:       rec.Remaining,
+			Message:     "chore: update remaining files\n\n- " + strings.Join(rec.Remaining, "\n- "),
+			Notes:       []string{fmt.Sprintf("%d changed file(s) no suggested group listed; press s to stage them", len(rec.Remaining))},
+		})
+	}
+	return reconciled, rec
+}
+
+// changedFiles returns the staged and unstaged files once each, in order
+func changedFiles(stagedFiles, unstagedFiles []string) []string {
+	seen := make(map[string]bool, len(stagedFiles)+len(unstagedFiles))
+	var files []string
+	for _, file := range append(append([]string(nil), stagedFiles...), unstagedFiles...) {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// fileMatcher resolves paths a model wrote to the changed files they mean
+type fileMatcher struct {
+	files []string
+	exact map[string]string // Changed file by its path
+	lower map[string]string // Changed file by its lowercase path, when unambiguous
+}
+
+func newFileMatcher(files []string) *fileMatcher {
+	m := &fileMatcher{
+		files: files,
+		exact: make(map[string]string, len(files)),
+		lower: make(map[string]string, len(files)),
+	}
+	ambiguous := make(map[string]bool)
+	for _, file := range files {
+		m.exact[file] = file
+		key := strings.ToLower(file)
+		if _, ok := m.lower[key]; ok {
+			ambiguous[key] = true
+		}
+		m.lower[key] = file
+	}
+	for key := range ambiguous {
+		delete(m.lower, key)
+	}
+	return m
+}
+
+// resolve returns the changed file a suggested path refers to. It tries, in
+// order: the path as written, the path without quotes and diff or ./ prefixes,
+// a case-insensitive match, a unique changed file the path is a suffix of (or
+// that is a suffix of the path), and a unique changed file within two edits.
+func (m *fileMatcher) resolve(path string) (string, bool) {
+	if file, ok := m.exact[path]; ok {
+		return file, true
+	}
+	path = normalizePath(path)
+	if path == "" {
+		return "", false
+	}
+	if file, ok := m.exact[path]; ok {
+		return file, true
+	}
+	if file, ok := m.lower[strings.ToLower(path)]; ok {
+		return file, true
+	}
+
+	var suffixed []string
+	for _, file := range m.files {
+		if strings.HasSuffix(file, "/"+path) || strings.HasSuffix(path, "/"+file) {
+			suffixed = append(suffixed, file)
+		}
+	}
+	if len(suffixed) == 1 {
+		return suffixed[0], true
+	}
+
+	// Short paths are too easily a couple of edits from an unrelated file
+	if len(path) < 8 {
+		return "", false
+	}
+	best, bestDistance, ties := "", 3, 0
+	for _, file := range m.files {
+		switch d := editDistance(path, file); {
+		case d < bestDistance:
+			best, bestDistance, ties = file, d, 1
+		case d == bestDistance:
+			ties++
+		}
+	}
+	if best != "" && ties == 1 {
+		return best, true
+	}
+	return "", false
+}
+
+// normalizePath strips what models add around paths: quotes, backticks, the a/
+// and b/ prefixes of diff headers, ./ and a leading slash
+func normalizePath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), "\"'`")
+	for _, prefix := range []string{"a/", "b/", "./", "/"} {
+		if rest := strings.TrimPrefix(path, prefix); rest != path && rest != "" {
+			path = rest
+			break
+		}
+	}
+	return path
+}
+
+// editDistance is the Levenshtein distance between two paths
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,295 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/jabafett/quill/internal/utils/ai"
+)
+
+// Severity ranks how much a review finding matters
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// Severities lists every severity, least severe first
+var Severities = []Severity{SeverityInfo, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// Rank orders severities from 1 for info to 5 for critical, 0 when unknown
+func (s Severity) Rank() int {
+	for i, severity := range Severities {
+		if s == severity {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// ParseSeverity reads a severity name, case-insensitively
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if severity.Rank() == 0 {
+		return "", fmt.Errorf("unknown severity '%s', expected info, low, medium, high or critical", s)
+	}
+	return severity, nil
+}
+
+// ParseFailThreshold reads the lowest severity that fails a review. "none" turns
+// failing off and returns an empty severity.
+func ParseFailThreshold(s string) (Severity, error) {
+	if strings.EqualFold(strings.TrimSpace(s), "none") {
+		return "", nil
+	}
+	return ParseSeverity(s)
+}
+
+// Category is the kind of problem a finding reports
+type Category string
+
+const (
+	CategoryBug         Category = "bug"
+	CategoryDebug       Category = "debug"
+	CategoryTodo        Category = "todo"
+	CategoryMissingTest Category = "missing-test"
+	CategorySecret      Category = "secret"
+)
+
+// categoryDescriptions describe each category, in the order they are listed
+var categoryDescriptions = []struct {
+	category    Category
+	description string
+}{
+	{CategoryBug, "Likely bug"},
+	{CategoryDebug, "Leftover debug statement"},
+	{CategoryTodo, "TODO or FIXME comment"},
+	{CategoryMissingTest, "Changed code without tests"},
+	{CategorySecret, "Possible secret"},
+}
+
+func (c Category) known() bool {
+	for _, d := range categoryDescriptions {
+		if d.category == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Finding is a problem found in a change
+type Finding struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"` // Line in the new version of the file, 0 for the whole file
+	Severity Severity `json:"severity"`
+	Category Category `json:"category"`
+	Message  string   `json:"message"`
+	Source   string   `json:"source,omitempty"` // "rules" for local checks, "model" for the AI review
+}
+
+// Location returns "file:line", or just the file for a finding about the whole file
+func (f Finding) Location() string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return f.File
+}
+
+// findingsObject is a structured review response
+type findingsObject struct {
+	Findings []Finding `json:"findings"`
+}
+
+// ReviewSchema is the schema of a structured review response
+var ReviewSchema = &ai.Schema{
+	Name:        "review",
+	Description: "Problems found reviewing a change",
+	Definition: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"findings": map[string]any{
+				"type":        "array",
+				"description": "The problems found, empty when there are none",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"file": map[string]any{"type": "string", "description": "Path of the file, as in the diff"},
+						"line": map[string]any{"type": "integer", "description": "Line number in the new version of the file, as numbered in the diff"},
+						"severity": map[string]any{
+							"type": "string",
+							"enum": []string{"info", "low", "medium", "high", "critical"},
+						},
+						"category": map[string]any{
+							"type": "string",
+							"enum": []string{"bug", "debug", "todo", "missing-test", "secret"},
+						},
+						"message": map[string]any{"type": "string", "description": "What is wrong and how to fix it, in one or two sentences"},
+					},
+					"required":             []string{"file", "line", "severity", "category", "message"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"findings"},
+		"additionalProperties": false,
+	},
+}
+
+// ParseReviewObject reads a structured review response
+func ParseReviewObject(response string) ([]Finding, error) {
+	var object findingsObject
+	if err := decodeJSON(response, &object); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for i, f := range object.Findings {
+		if strings.TrimSpace(f.File) == "" || strings.TrimSpace(f.Message) == "" {
+			problems = append(problems, fmt.Sprintf("finding %d needs a file and a message", i+1))
+		}
+		severity, err := ParseSeverity(string(f.Severity))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("finding %d: %v", i+1, err))
+		}
+		category := Category(strings.ToLower(strings.TrimSpace(string(f.Category))))
+		if !category.known() {
+			problems = append(problems, fmt.Sprintf("finding %d: unknown category '%s'", i+1, f.Category))
+		}
+		object.Findings[i].Severity = severity
+		object.Findings[i].Category = category
+		object.Findings[i].Message = strings.TrimSpace(f.Message)
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return object.Findings, nil
+}
+
+// Review is the result of reviewing a change
+type Review struct {
+	Files      []string  `json:"files"`
+	Findings   []Finding `json:"findings"`
+	Omitted    []string  `json:"omitted,omitempty"`     // Files left out of the AI review for size
+	ModelError string    `json:"model_error,omitempty"` // Why the AI review failed, leaving only the local checks
+}
+
+// Sort orders the findings by file and line, the most severe first on a line
+func (r *Review) Sort() {
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		a, b := r.Findings[i], r.Findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Severity.Rank() > b.Severity.Rank()
+	})
+}
+
+// AtOrAbove returns the findings of threshold severity or worse, none when the
+// threshold is empty
+func (r *Review) AtOrAbove(threshold Severity) []Finding {
+	if threshold == "" {
+		return nil
+	}
+	var found []Finding
+	for _, f := range r.Findings {
+		if f.Severity.Rank() >= threshold.Rank() {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+// Text renders the findings one per line, "file:line: severity [category] message",
+// followed by a count per severity
+func (r *Review) Text() string {
+	if len(r.Findings) == 0 {
+		return "No findings\n"
+	}
+
+	var b strings.Builder
+	counts := make(map[Severity]int)
+	for _, f := range r.Findings {
+		fmt.Fprintf(&b, "%s: %s [%s] %s\n", f.Location(), f.Severity, f.Category, f.Message)
+		counts[f.Severity]++
+	}
+
+	var parts []string
+	for i := len(Severities) - 1; i >= 0; i-- {
+		if n := counts[Severities[i]]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, Severities[i]))
+		}
+	}
+	noun := "findings"
+	if len(r.Findings) == 1 {
+		noun = "finding"
+	}
+	fmt.Fprintf(&b, "\n%d %s (%s)\n", len(r.Findings), noun, strings.Join(parts, ", "))
+	return b.String()
+}
+
+// SARIF renders the findings as a SARIF 2.1.0 log for editors and code scanning.
+// Paths are relative to the repository root, which consumers know as %SRCROOT%.
+func (r *Review) SARIF() ([]byte, error) {
+	rules := make([]map[string]any, 0, len(categoryDescriptions))
+	for _, c := range categoryDescriptions {
+		rules = append(rules, map[string]any{
+			"id":               string(c.category),
+			"shortDescription": map[string]any{"text": c.description},
+		})
+	}
+
+	results := make([]map[string]any, 0, len(r.Findings))
+	for _, f := range r.Findings {
+		location := map[string]any{
+			"artifactLocation": map[string]any{
+				"uri":       (&url.URL{Path: f.File}).String(),
+				"uriBaseId": "%SRCROOT%",
+			},
+		}
+		if f.Line > 0 {
+			location["region"] = map[string]any{"startLine": f.Line}
+		}
+		results = append(results, map[string]any{
+			"ruleId":     string(f.Category),
+			"level":      sarifLevel(f.Severity),
+			"message":    map[string]any{"text": f.Message},
+			"locations":  []any{map[string]any{"physicalLocation": location}},
+			"properties": map[string]any{"severity": string(f.Severity), "source": f.Source},
+		})
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []any{map[string]any{
+			"tool": map[string]any{"driver": map[string]any{
+				"name":           "quill",
+				"informationUri": "https://github.com/jabafett/quill",
+				"rules":          rules,
+			}},
+			"results": results,
+		}},
+	}, "", "  ")
+}
+
+// sarifLevel maps a severity onto the three SARIF result levels
+func sarifLevel(s Severity) string {
+	switch s {
+	case SeverityHigh, SeverityCritical:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
@@ -0,0 +1,202 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jabafett/quill/internal/utils/ai"
+)
+
+// CommitObject is a commit message as a structured response holds it
+type CommitObject struct {
+	Header string `json:"header"`
+	Body   string `json:"body"`
+	Footer string `json:"footer"`
+}
+
+// String joins the parts into a commit message, leaving out empty ones
+func (c CommitObject) String() string {
+	message := strings.TrimSpace(c.Header)
+	for _, part := range []string{c.Body, c.Footer} {
+		if part = strings.TrimSpace(part); part != "" {
+			message += "\n\n" + part
+		}
+	}
+	return message
+}
+
+// GroupObject is one commit grouping of a structured suggestion response
+type GroupObject struct {
+	Description string   `json:"description"`
+	Files       []string `json:"files"`
+	CommitObject
+}
+
+// SuggestionObject is a structured suggestion response
+type SuggestionObject struct {
+	Groups []GroupObject `json:"groups"`
+}
+
+// ValidationError lists what is wrong with a structured response, in words the
+// model can act on when asked to repair it
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid response: " + strings.Join(e.Problems, "; ")
+}
+
+// commitProperties are the parts of a commit message, shared by both schemas
+var commitProperties = map[string]any{
+	"header": map[string]any{
+		"type":        "string",
+		"description": "Conventional commit header: type(scope): description",
+	},
+	"body": map[string]any{
+		"type":        "string",
+		"description": "What changed and why, empty for trivial changes",
+	},
+	"footer": map[string]any{
+		"type":        "string",
+		"description": "Footers such as BREAKING CHANGE, or empty",
+	},
+}
+
+// CommitMessageSchema is the schema of a structured commit message response
+var CommitMessageSchema = &ai.Schema{
+	Name:        "commit_message",
+	Description: "A conventional commit message",
+	Definition: map[string]any{
+		"type":                 "object",
+		"properties":           commitProperties,
+		"required":             []string{"header", "body", "footer"},
+		"additionalProperties": false,
+	},
+}
+
+// SuggestionSchema is the schema of a structured suggestion response
+var SuggestionSchema = &ai.Schema{
+	Name:        "commit_groups",
+	Description: "The changes grouped into commits, each with its files and commit message",
+	Definition: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"groups": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"description": map[string]any{
+							"type":        "string",
+							"description": "Brief description of the grouping",
+						},
+						"files": map[string]any{
+							"type":        "array",
+							"description": "Paths of the changed files in the group, exactly as listed in the changes",
+							"items":       map[string]any{"type": "string"},
+						},
+						"header": commitProperties["header"],
+						"body":   commitProperties["body"],
+						"footer": commitProperties["footer"],
+					},
+					"required":             []string{"description", "files", "header", "body", "footer"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"groups"},
+		"additionalProperties": false,
+	},
+}
+
+// decodeJSON unmarshals a structured response. Providers without constrained
+// output may wrap the object in a code fence or surrounding text.
+func decodeJSON(response string, v any) error {
+	response = strings.TrimSpace(response)
+	if start, end := strings.Index(response, "{"), strings.LastIndex(response, "}"); start >= 0 && end > start {
+		response = response[start : end+1]
+	}
+	if err := json.Unmarshal([]byte(response), v); err != nil {
+		return &ValidationError{Problems: []string{"the response is not a JSON object matching the schema: " + err.Error()}}
+	}
+	return nil
+}
+
+// ParseCommitObject reads a structured commit message response
+func ParseCommitObject(response string) (string, error) {
+	var commit CommitObject
+	if err := decodeJSON(response, &commit); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(commit.Header) == "" {
+		return "", &ValidationError{Problems: []string{"header is empty"}}
+	}
+	return commit.String(), nil
+}
+
+// ParseSuggestionObject reads a structured suggestion response and checks it
+// against the changed files. Paths that resolve to no changed file or to one an
+// earlier group has, groups without files, empty headers and changed files no
+// group lists are reported in a ValidationError, alongside the groups as the
+// model wrote them. Pass the groups through ReconcileGroups before staging.
+func ParseSuggestionObject(response string, stagedFiles, unstagedFiles []string) ([]SuggestionGroup, error) {
+	var suggestion SuggestionObject
+	if err := decodeJSON(response, &suggestion); err != nil {
+		return nil, err
+	}
+
+	changed := changedFiles(stagedFiles, unstagedFiles)
+	matcher := newFileMatcher(changed)
+
+	var problems []string
+	groups := make([]SuggestionGroup, 0, len(suggestion.Groups))
+	placed := make(map[string]int) // Changed file to the 1-based group it was placed in
+	for i, object := range suggestion.Groups {
+		n := i + 1
+		if strings.TrimSpace(object.Header) == "" {
+			problems = append(problems, fmt.Sprintf("group %d has an empty header", n))
+		}
+
+		group := SuggestionGroup{
+			Description: strings.TrimSpace(object.Description),
+			Message:     object.CommitObject.String(),
+		}
+		for _, path := range object.Files {
+			file, ok := matcher.resolve(path)
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("group %d lists %q, which is not a changed file", n, path))
+			case placed[file] != 0:
+				problems = append(problems, fmt.Sprintf("group %d repeats %q from group %d", n, file, placed[file]))
+			default:
+				placed[file] = n
+				group.ShouldStage = group.ShouldStage || contains(unstagedFiles, file)
+			}
+			group.Files = append(group.Files, path)
+		}
+		if len(object.Files) == 0 {
+			problems = append(problems, fmt.Sprintf("group %d has no files", n))
+		}
+		groups = append(groups, group)
+	}
+	if len(suggestion.Groups) == 0 {
+		problems = append(problems, "there are no groups")
+	}
+
+	var uncovered []string
+	for _, file := range changed {
+		if placed[file] == 0 {
+			uncovered = append(uncovered, file)
+		}
+	}
+	if len(uncovered) > 0 {
+		problems = append(problems, "these changed files are in no group: "+strings.Join(uncovered, ", "))
+	}
+
+	if len(problems) > 0 {
+		return groups, &ValidationError{Problems: problems}
+	}
+	return groups, nil
+}
@@ -13,11 +13,13 @@ import (
 
 // SuggestionGroup represents a group of files that should be committed together
 type SuggestionGroup struct {
-	ID          string   // Unique identifier for the group
-	Description string   // Description of the group
-	Files       []string // Files in the group
-	Message     string   // Suggested commit message
-	ShouldStage bool     // Whether the files should be staged
+	ID          string            // Unique identifier for the group
+	Description string            // Description of the group
+	Files       []string          // Files in the group
+	Message     string            // Suggested commit message
+	ShouldStage bool              // Whether the files should be staged
+	Notes       []string          // How ReconcileGroups changed the group's files
+	Labels      map[string]string // File to how it changed when not a plain modification, e.g. "deleted"
 }
 
 // ErrNoChanges is returned when there are no changes to suggest groupings for
@@ -27,7 +29,9 @@ func (e ErrNoChanges) Error() string {
 	return "no changes found to suggest groupings for"
 }
 
-// ParseSuggestionResponse parses the AI response into structured suggestions
+// ParseSuggestionResponse parses the AI response into structured suggestions. Files
+// are kept as the model wrote them when they resolve to a changed file, so pass the
+// groups through ReconcileGroups before staging anything.
 func ParseSuggestionResponse(response string, stagedFiles, unstagedFiles []string) []SuggestionGroup {
 	// First, try to parse as XML
 	xmlGroups := parseXMLResponse(response, stagedFiles, unstagedFiles)
@@ -84,16 +88,9 @@ func parseXMLResponse(response string, stagedFiles, unstagedFiles []string) []Su
 	}
 	debug.Log("parseXMLResponse: Successfully unmarshalled %d groups from XML.", len(suggestions.Groups))
 
-	// Combine staged and unstaged for easier validation checking later
-	// This avoids creating it repeatedly inside the loop
-	allKnownFiles := make(map[string]struct{}, len(stagedFiles)+len(unstagedFiles))
-	for _, f := range stagedFiles {
-		allKnownFiles[f] = struct{}{}
-	}
-	for _, f := range unstagedFiles {
-		allKnownFiles[f] = struct{}{}
-	}
-	debug.Log("  All known files map for validation created with %d entries.", len(allKnownFiles))
+	// Paths are checked against the changed files, allowing for the near-misses
+	// ReconcileGroups corrects
+	matcher := newFileMatcher(changedFiles(stagedFiles, unstagedFiles))
 
 	// Convert to SuggestionGroup objects
 	for i, xmlGroup := range suggestions.Groups {
@@ -111,7 +108,7 @@ func parseXMLResponse(response string, stagedFiles, unstagedFiles []string) []Su
 		// Validate files against the combined known files map
 		validatedFiles := make([]string, 0, len(filesFromXML))
 		for _, file := range filesFromXML {
-			if _, exists := allKnownFiles[file]; exists {
+			if _, exists := matcher.resolve(file); exists {
 				validatedFiles = append(validatedFiles, file)
 			} else {
 				debug.Log("  File '%s' from XML group not found in known staged/unstaged files.", file)
@@ -191,14 +188,8 @@ func parseRegexResponse(response string, stagedFiles, unstagedFiles []string) []
 	groupMatches := groupPattern.FindAllStringSubmatchIndex(response, -1)
 	debug.Log("parseRegexResponse: Found %d potential group matches via regex.", len(groupMatches))
 
-	// Combine staged and unstaged for easier validation checking later
-	allKnownFiles := make(map[string]struct{}, len(stagedFiles)+len(unstagedFiles))
-	for _, f := range stagedFiles {
-		allKnownFiles[f] = struct{}{}
-	}
-	for _, f := range unstagedFiles {
-		allKnownFiles[f] = struct{}{}
-	}
+	// Paths are checked against the changed files, allowing for near-misses
+	matcher := newFileMatcher(changedFiles(stagedFiles, unstagedFiles))
 
 	// Process standard groupings
 	for i, groupMatch := range groupMatches {
@@ -262,7 +253,7 @@ func parseRegexResponse(response string, stagedFiles, unstagedFiles []string) []
 		// Validate files against the repository
 		validatedFiles := make([]string, 0, len(filesFromRegex))
 		for _, file := range filesFromRegex {
-			if _, exists := allKnownFiles[file]; exists {
+			if _, exists := matcher.resolve(file); exists {
 				validatedFiles = append(validatedFiles, file)
 			} else {
 				debug.Log("  File '%s' from regex group not found in known staged/unstaged files.", file)
@@ -313,13 +304,7 @@ func fallbackParsing(response string, stagedFiles, unstagedFiles []string) []Sug
 	var groups []SuggestionGroup
 
 	// Combine for validation
-	allKnownFiles := make(map[string]struct{}, len(stagedFiles)+len(unstagedFiles))
-	for _, f := range stagedFiles {
-		allKnownFiles[f] = struct{}{}
-	}
-	for _, f := range unstagedFiles {
-		allKnownFiles[f] = struct{}{}
-	}
+	matcher := newFileMatcher(changedFiles(stagedFiles, unstagedFiles))
 
 	// Look for file paths with common extensions more broadly
 	// This regex is simplified to find potential paths, validation is key
@@ -331,8 +316,8 @@ func fallbackParsing(response string, stagedFiles, unstagedFiles []string) []Sug
 	for _, match := range extMatches {
 		if len(match) > 1 {
 			potentialFile := strings.Trim(strings.TrimSpace(match[1]), `"'`)
-			if _, exists := allKnownFiles[potentialFile]; exists && !contains(files, potentialFile) {
-				files = append(files, potentialFile)
+			if file, exists := matcher.resolve(potentialFile); exists && !contains(files, file) {
+				files = append(files, file)
 			}
 		}
 	}
@@ -21,6 +21,7 @@ var (
 	Gemini    = Provider{Name: "gemini", KeyName: "GEMINI_API_KEY"}
 	Anthropic = Provider{Name: "anthropic", KeyName: "ANTHROPIC_API_KEY"}
 	OpenAI    = Provider{Name: "openai", KeyName: "OPENAI_API_KEY"}
+	Ollama    = Provider{Name: "ollama", KeyName: "OLLAMA_API_KEY"}
 )
 
 // StoreAPIKey stores an API key in the system keyring
@@ -0,0 +1,45 @@
+package templates
+
+// branchInstructions are the naming rules both response formats share
+const branchInstructions = `Your task is to propose names for a git branch holding the described work. Please do not hallucinate.
+Each proposal is a change type and a slug; quill adds the ticket and applies the team's naming pattern.
+- The type is the conventional commit type the work mostly is: feat, fix, docs, style, refactor, perf, test or chore
+- The slug is two to five lowercase words joined by hyphens, naming what the work does, e.g. add-login-form or fix-cache-expiry
+- Do not put issue keys, the author, dates or the type in the slug
+- Prefer the task description over the changes when both are given; the changes may be only a start
+- <current_branch> is where the work starts from; do not propose it again
+- Make the proposals differ in wording or emphasis, best first`
+
+const (
+	// BranchSystemPrompt holds the static instructions for proposing branch names
+	BranchSystemPrompt = branchInstructions + `
+
+Respond with exactly one "<type>/<slug>" line per proposal, e.g.:
+feat/add-login-form
+
+Do not add any other text.`
+
+	// BranchJSONSystemPrompt holds the static instructions for proposing branch names
+	// when the provider is asked for a JSON object matching a schema
+	BranchJSONSystemPrompt = branchInstructions + `
+
+Respond with a JSON object with one entry in "branches" per proposal, each with its "type" and "slug".`
+
+	// BranchTemplate holds the task and the uncommitted changes to name a branch for
+	BranchTemplate = `{{- if .Description}}<task>
+{{.Description}}
+</task>
+{{end}}
+{{- if .Branch}}<current_branch>{{.Branch}}</current_branch>
+{{end}}
+{{- if .Files}}<files_changed>
+{{join .Files "\n"}}
+</files_changed>
+{{end}}
+{{- if .Diff}}<diff>
+{{.Diff}}
+</diff>
+{{end}}
+Propose {{.Count}} branch names{{if .Structured}} as a JSON object{{end}}.
+`
+)
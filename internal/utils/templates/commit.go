@@ -2,7 +2,9 @@ package templates
 
 // Template definitions
 const (
-	CommitMessageTemplate = `Your task is to generate a commit message for the given information. Please do not hallucinate.
+	// CommitMessageSystemPrompt holds the static instructions, sent as the system prompt
+	// so providers that support it can cache them between requests
+	CommitMessageSystemPrompt = `Your task is to generate a commit message for the given information. Please do not hallucinate.
 The commit message should:
 - Keep the first line under 72 characters
 - No periods or other punctuation at the end of any lines
@@ -18,6 +20,13 @@ The commit message should:
 - If breaking change, add BREAKING CHANGE: in footer
 - Please refrain from discussing formatting changes nor inferences about the scope of the change through code that has only been reformatted (e.g., indentation, line length, etc.)
 - Sift through the noise in the diff and information provided to zero in on what was modified, added, or removed
+- Lockfiles, generated, vendored and binary files are listed in <summarized_changes> instead of the diff; mention them only as supporting changes (e.g. updated dependencies)
+- <changed_symbols> lists the functions, methods and types each file's hunks touch; use these names to say precisely what changed
+- <file_context> summarizes the changed files and the files that import them; use it to understand their purpose, not as a list of changes
+- <packages> lists the monorepo packages the change touches with their scope; use that scope in the header, and when the change spans packages use the one it is mainly about
+- <related_code> holds existing code that resembles the change, found by similarity search; use it to recognize conventions and related features, never describe it as changed
+- <partially_staged> lists staged files that also have unstaged edits; those edits are not part of this commit, so describe only what the diff shows for them
+- <impact> lists the code outside the diff that depends on the change and any dependency updates; mention affected callers in the body when the change alters behaviour they rely on
 
 Types:
 feat: New features that add functionality (e.g., "feat(auth): add password reset flow")
@@ -43,13 +52,39 @@ feat(auth): add password reset flow
 - Include a new route for handling password reset requests.
 - Update the login page to display a message indicating that a password reset is required.
 
-BREAKING CHANGE: The password reset flow now requires a confirmation step.
+BREAKING CHANGE: The password reset flow now requires a confirmation step.`
 
-______________________________________________________________________________________________________________________
+	// CommitMessageJSONSystemPrompt holds the static instructions when the provider is
+	// asked for a JSON object matching a schema instead of the message text
+	CommitMessageJSONSystemPrompt = CommitMessageSystemPrompt + `
 
-<repo_description>
+Respond with a JSON object holding the parts of the message: "header" for the first line, "body" for the body, and "footer" for the footers or an empty string.`
+
+	// CommitMessageTemplate holds the per-commit data
+	CommitMessageTemplate = `<repo_description>
 {{.RepoDescription}}
 </repo_description>
+{{- if .Packages}}
+<packages>
+{{- range .Packages}}
+- {{.}}
+{{- end}}
+</packages>
+{{- end}}
+{{- if .FileContext}}
+<file_context>
+{{- range .FileContext}}
+- {{.}}
+{{- end}}
+</file_context>
+{{- end}}
+{{- if .RelatedCode}}
+<related_code>
+{{- range .RelatedCode}}
+=== {{.}}
+{{- end}}
+</related_code>
+{{- end}}
 
 <files_changed>
 {{.Files}}
@@ -57,6 +92,34 @@ ________________________________________________________________________________
 <diff>
 {{.Diff}}
 </diff>
-Generate only the commit message without any explanation or additional text.
+{{- if .Noise}}
+<summarized_changes>
+{{- range .Noise}}
+- {{.}}
+{{- end}}
+</summarized_changes>
+{{- end}}
+{{- if .Symbols}}
+<changed_symbols>
+{{- range .Symbols}}
+- {{.}}
+{{- end}}
+</changed_symbols>
+{{- end}}
+{{- if .PartiallyStaged}}
+<partially_staged>
+{{- range .PartiallyStaged}}
+- {{.}}
+{{- end}}
+</partially_staged>
+{{- end}}
+{{- if .Impact}}
+<impact>
+{{- range .Impact}}
+- {{.}}
+{{- end}}
+</impact>
+{{- end}}
+{{if .Structured}}Generate only the JSON object of the commit message.{{else}}Generate only the commit message without any explanation or additional text.{{end}}
 `
 )
@@ -1,25 +1,44 @@
-// use this file as inspiration to create a context summary prompt to fill in the file data field in the RepositoryContext's Description field!
 package templates
 
-// ContextExtractionTemplate defines the template for extracting context from repository
-const ContextExtractionTemplate = `Analyze the following repository context:
+const (
+	// ContextExtractionSystemPrompt holds the static instructions for describing a repository
+	ContextExtractionSystemPrompt = `Your task is to describe a repository so a commit message writer understands what it is for. Please do not hallucinate.
+The repository profile was measured from the files and is accurate; do not restate it.
+Use the key files and recent history to explain:
+1. The repository's purpose and who uses it
+2. Its architecture: the main modules and how they fit together
+3. Conventions worth knowing when describing changes to it
+
+Respond with at most two short paragraphs of plain text, without headings or lists.`
+
+	// ContextExtractionTemplate holds the profile, key files and history of a repository,
+	// or of one package of a monorepo
+	ContextExtractionTemplate = `{{- if .Package -}}
+This is the {{.Package}} package of a monorepo. Describe this package only; the rest of the repository is described separately.
+
+{{end -}}
+Repository profile:
+{{- range .Profile}}
+{{.}}
+{{- end}}
+
 Files:
-{{range .Files}}
+{{- range .Files}}
 - {{.}}
-{{end}}
+{{- end}}
+{{.AllTheFilesClarification}}
 
 File Contents:
 {{range $file, $content := .FilesContent}}
 === {{$file}} ===
 {{$content}}
 {{end}}
-{{.AllTheFilesClarification}}
-
 Git history:
-{{.GitHistory}}
+{{- range .GitHistory}}
+- {{.}}
+{{- end}}
 {{.GitHistoryClarification}}
 
-Provide a concise summary of (but not limited to):
-1. The repository's purpose and/or goal
-2. The repository's architecture and design patterns
-3. Any other relevant context about what the repository is for or does` 
\ No newline at end of file
+Describe the repository above.
+`
+)
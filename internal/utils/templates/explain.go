@@ -0,0 +1,79 @@
+package templates
+
+const (
+	// ExplainSystemPrompt holds the static instructions for explaining a change to a reviewer
+	ExplainSystemPrompt = `Your task is to explain a change to a reviewer or a new team member who has not seen it. Please do not hallucinate.
+- <commits> holds the commit messages; with <references> they are the best evidence of why the change was made
+- When the motivation is not stated anywhere, give the most likely reason and say that it is inferred
+- Describe behaviour and components, not line-by-line edits; skip formatting-only changes
+- Lockfiles, generated, vendored and binary files are listed in <summarized_changes> instead of the diff; mention them only as supporting changes
+- <changed_symbols> lists the functions, methods and types each file's hunks touch; use these names to be precise
+- <file_context> and <repository_context> describe the codebase as it is today; use them to explain what the changed code is for
+- <omitted_files> changed too, but their diff was left out for size; do not guess at their contents
+- Risky areas are the places a reviewer should read closely: changed behaviour callers rely on, error handling, concurrency, security, data formats and migrations
+- Testing items are concrete scenarios to check, not generic advice
+
+Respond with a JSON object with:
+- "summary": two or three sentences on what the change does overall
+- "changes": the notable changes, one per item
+- "motivation": why the change was probably made
+- "risks": objects with the "area" and the "reason" it is risky, empty when there are none
+- "testing": what to test, one scenario per item`
+
+	// ExplainTemplate holds the change to explain and its context
+	ExplainTemplate = `<revision>{{.Revision}}</revision>
+{{- if .Context}}
+<repository_context>
+{{.Context}}
+</repository_context>
+{{- end}}
+{{- if .Commits}}
+<commits>
+{{- range .Commits}}
+=== {{.}}
+{{- end}}
+{{- if .MoreCommits}}
+(older commits left out)
+{{- end}}
+</commits>
+{{- end}}
+{{- if .References}}
+<references>
+{{join .References "\n"}}
+</references>
+{{- end}}
+<files_changed>
+{{join .Files "\n"}}
+</files_changed>
+{{- if .FileContext}}
+<file_context>
+{{- range .FileContext}}
+- {{.}}
+{{- end}}
+</file_context>
+{{- end}}
+{{- if .Symbols}}
+<changed_symbols>
+{{- range .Symbols}}
+- {{.}}
+{{- end}}
+</changed_symbols>
+{{- end}}
+{{- if .Noise}}
+<summarized_changes>
+{{- range .Noise}}
+- {{.}}
+{{- end}}
+</summarized_changes>
+{{- end}}
+{{- if .Omitted}}
+<omitted_files>
+{{join .Omitted "\n"}}
+</omitted_files>
+{{- end}}
+<diff>
+{{.Diff}}
+</diff>
+Explain this change as a JSON object.
+`
+)
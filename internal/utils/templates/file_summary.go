@@ -0,0 +1,29 @@
+package templates
+
+const (
+	// FileSummarySystemPrompt holds the static instructions for summarizing files for the index
+	FileSummarySystemPrompt = `Your task is to summarize source files so a commit message writer knows what each file is for. Please do not hallucinate.
+For every file:
+- Write one sentence of at most 25 words describing the file's responsibility
+- Name the main types or functions it provides when that helps
+- Do not describe the implementation line by line
+
+Respond with exactly one line per file in the form:
+<path>: <summary>
+
+Do not add any other text.`
+
+	// FileSummaryTemplate holds the files of one summary batch
+	FileSummaryTemplate = `{{- range .Files}}
+=== {{.Path}} ===
+{{- if .Symbols}}
+Exported: {{join .Symbols ", "}}
+{{- end}}
+{{- if .Imports}}
+Imports: {{join .Imports ", "}}
+{{- end}}
+{{.Excerpt}}
+{{end}}
+Summarize each file above, one "<path>: <summary>" line per file.
+`
+)
@@ -0,0 +1,36 @@
+package templates
+
+const (
+	// JudgeSystemPrompt holds the static instructions for ranking commit message candidates
+	JudgeSystemPrompt = `Your task is to judge candidate commit messages for a change. Please do not hallucinate.
+Score each candidate from 0 to 10 on:
+1. Accuracy: it describes what the diff actually changes, and nothing it does not
+2. Format: a conventional commit header "<type>(<scope>): <description>" in imperative mood, lowercase, at most 72 characters, without a trailing period, and a body separated by a blank line
+3. Specificity: it names the changed components, functions or behaviour instead of generic wording
+
+Respond with exactly one line per candidate in the form:
+<candidate number>: <score> - <rationale of at most 12 words>
+
+Do not add any other text.`
+
+	// JudgeTemplate holds the change and the candidates to judge
+	JudgeTemplate = `<files_changed>
+{{join .Files "\n"}}
+</files_changed>
+<diff>
+{{.Diff}}
+</diff>
+{{- if .Symbols}}
+<changed_symbols>
+{{- range .Symbols}}
+- {{.}}
+{{- end}}
+</changed_symbols>
+{{- end}}
+{{range $i, $candidate := .Candidates}}
+=== Candidate {{inc $i}} ===
+{{$candidate}}
+{{end}}
+Score each candidate above, one "<candidate number>: <score> - <rationale>" line per candidate.
+`
+)
@@ -0,0 +1,23 @@
+package templates
+
+const (
+	// RepairSystemPrompt holds the static instructions for fixing an invalid structured response
+	RepairSystemPrompt = `Your previous response did not pass validation. Please do not hallucinate.
+Correct every problem listed and respond with the complete corrected JSON object only, in the same schema.
+Keep everything that was not part of a problem unchanged.`
+
+	// RepairTemplate holds the original request, the invalid response and its problems
+	RepairTemplate = `<request>
+{{.Prompt}}
+</request>
+<response>
+{{.Response}}
+</response>
+<problems>
+{{- range .Problems}}
+- {{.}}
+{{- end}}
+</problems>
+Respond with the corrected JSON object only.
+`
+)
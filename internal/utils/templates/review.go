@@ -0,0 +1,73 @@
+package templates
+
+const (
+	// ReviewSystemPrompt holds the static instructions for reviewing staged changes before they are committed
+	ReviewSystemPrompt = `Your task is to review a change before it is committed and report real problems in it. Please do not hallucinate.
+- Every line of <diff> that exists in the new version of a file is prefixed with its line number; cite these numbers, never count lines yourself
+- Only report problems in added lines or caused by the change; leave unchanged code alone
+- "bug": likely defects such as wrong conditions, off-by-one errors, nil or null dereferences, ignored errors, resource leaks, races and broken edge cases
+- "debug": print statements, breakpoints and temporary logging left in by mistake
+- "todo": TODO, FIXME and similar notes the change adds
+- "missing-test": changed functions or behaviour in <changed_symbols> with no test among <changed_tests>, when the repository has tests for such code
+- "secret": credentials, tokens or keys; values shown as [REDACTED] were already removed and reported
+- <local_findings> were already reported by local checks; do not repeat them
+- Severity: "critical" would break production or leak a credential, "high" is a likely bug, "medium" a probable problem, "low" a minor issue, "info" a note
+- Do not report style, naming or formatting preferences, and do not praise the change
+- <file_context> and <repository_context> describe the codebase as it is today; use them to judge how the changed code is used
+- <omitted_files> changed too, but their diff was left out for size; do not report anything about them
+
+Respond with a JSON object with:
+- "findings": objects with the "file" path as in the diff, the "line", the "severity", the "category" and a one or two sentence "message" saying what is wrong and how to fix it; empty when the change looks fine`
+
+	// ReviewTemplate holds the staged changes to review and their context
+	ReviewTemplate = `{{- if .Context}}<repository_context>
+{{.Context}}
+</repository_context>
+{{end -}}
+<files_changed>
+{{join .Files "\n"}}
+</files_changed>
+{{- if .Tests}}
+<changed_tests>
+{{join .Tests "\n"}}
+</changed_tests>
+{{- end}}
+{{- if .FileContext}}
+<file_context>
+{{- range .FileContext}}
+- {{.}}
+{{- end}}
+</file_context>
+{{- end}}
+{{- if .Symbols}}
+<changed_symbols>
+{{- range .Symbols}}
+- {{.}}
+{{- end}}
+</changed_symbols>
+{{- end}}
+{{- if .Findings}}
+<local_findings>
+{{- range .Findings}}
+- {{.}}
+{{- end}}
+</local_findings>
+{{- end}}
+{{- if .Noise}}
+<summarized_changes>
+{{- range .Noise}}
+- {{.}}
+{{- end}}
+</summarized_changes>
+{{- end}}
+{{- if .Omitted}}
+<omitted_files>
+{{join .Omitted "\n"}}
+</omitted_files>
+{{- end}}
+<diff>
+{{.Diff}}
+</diff>
+Review this change and respond with a JSON object.
+`
+)
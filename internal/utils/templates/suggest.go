@@ -1,8 +1,8 @@
 // Staging template for generating a suite of commit groupings along with commit messages
 package templates
 
-// SuggestTemplate defines the template for generating a suite of commit groupings along with descriptions
-const SuggestTemplate = `# TASK
+// suggestInstructions are the grouping and commit message rules both response formats share
+const suggestInstructions = `# TASK
 Analyze the repository changes and suggest logical commit groupings. Group related changes together and create appropriate conventional commit messages for each group.
 
 ## COMMIT MESSAGE GUIDELINES
@@ -25,6 +25,12 @@ Analyze the repository changes and suggest logical commit groupings. Group relat
 - Be specific about what was changed and how
 - Please refrain from discussing formatting changes nor inferences about the scope of the change through code that has only been reformatted (e.g., indentation, line length, etc.) look for functional changes, sometimes autoformatters will change many lines and this is not relevant but code might have still changed within the reformatted code
 - Sift through the noise in the diff and information provided to zero in on what was modified, added, or removed
+- Lockfiles, generated, vendored and binary files are summarized in one line each instead of shown as diffs; still place each of them in the group it belongs to (e.g. go.sum with the go.mod change)
+- "Changed symbols" lists the functions, methods and types each file's hunks touch; use them to keep related code in one group and to name what changed
+- "Packages" lists the monorepo packages the changes touch with their scope; use that scope in each group's header
+- "File context" summarizes the changed files and the files that import them; use it to understand what each file is for, not as a list of changes
+- "Renamed files" pairs each file moved in the worktree with its new path, whose content is under the untracked files; treat it as one rename and list only the new path
+- "Related code" holds existing code that resembles the changes, found by similarity search; use it to recognize which changes belong to the same feature, never as changes to group
 
 ### Types
 - feat: New features that add functionality
@@ -41,22 +47,12 @@ Analyze the repository changes and suggest logical commit groupings. Group relat
 - Keep related changes together in a single commit
 - Include tests with the implementation they test
 - Include documentation with the code it documents
+- In a monorepo, keep each group within one package unless "Packages" lists the packages as coupled; a change to a shared package and its callers in another belongs together only then
 - If all changes are related to a single feature or fix, use just one grouping
+`
 
-## REPOSITORY CONTEXT
-{{.Context}}
-
-## CHANGES TO ANALYZE
-
-### Staged Changes
-{{.Staged}}
-
-### Unstaged Changes
-{{.Unstaged}}
-
-### Untracked Files
-{{.Untracked}}
-
+// SuggestSystemPrompt holds the static instructions for commit grouping, sent as the system prompt
+const SuggestSystemPrompt = suggestInstructions + `
 ## RESPONSE FORMAT
 
 Your response must be formatted in XML as follows:
@@ -125,3 +121,87 @@ Your response must be formatted in XML as follows:
 
 IMPORTANT: Your response must be valid XML and follow the exact format shown.
 `
+
+// SuggestJSONSystemPrompt holds the static instructions for commit grouping when
+// the provider is asked for a JSON object matching a schema
+const SuggestJSONSystemPrompt = suggestInstructions + `
+## RESPONSE FORMAT
+
+Respond with a JSON object with one entry in "groups" per commit. Each group has:
+- "description": brief description of the grouping
+- "files": the paths of its changed files, exactly as they appear in the changes
+- "header": type(scope): short description
+- "body": what was changed and why
+- "footer": footer notes like BREAKING CHANGE, or an empty string
+
+Place every changed file in exactly one group and list no other files.
+`
+
+// SuggestTemplate defines the template for the repository changes to group
+const SuggestTemplate = `## REPOSITORY CONTEXT
+{{.Context}}
+{{- if .Packages}}
+
+### Packages
+{{- range .Packages}}
+- {{.}}
+{{- end}}
+{{- end}}
+{{- if .FileContext}}
+
+### File Context
+{{- range .FileContext}}
+- {{.}}
+{{- end}}
+{{- end}}
+{{- if .RelatedCode}}
+
+### Related Code
+{{- range .RelatedCode}}
+=== {{.}}
+{{- end}}
+{{- end}}
+
+## CHANGES TO ANALYZE
+
+### Staged Changes
+{{.Staged}}
+{{- range .StagedNoise}}
+- {{.}}
+{{- end}}
+{{- if .StagedSymbols}}
+
+Changed symbols:
+{{- range .StagedSymbols}}
+- {{.}}
+{{- end}}
+{{- end}}
+
+### Unstaged Changes
+{{.Unstaged}}
+{{- range .UnstagedNoise}}
+- {{.}}
+{{- end}}
+{{- if .UnstagedSymbols}}
+
+Changed symbols:
+{{- range .UnstagedSymbols}}
+- {{.}}
+{{- end}}
+{{- end}}
+
+### Untracked Files
+{{.Untracked}}
+{{- range .UntrackedNoise}}
+- {{.}}
+{{- end}}
+{{- if .Renames}}
+
+### Renamed Files
+{{- range .Renames}}
+- {{.}}
+{{- end}}
+{{- end}}
+
+{{if .Structured}}Respond with the JSON object only.{{else}}Respond with the XML suggestions only.{{end}}
+`
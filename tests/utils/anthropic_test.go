@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jabafett/quill/internal/utils/ai"
+)
+
+func TestAnthropicSystemPromptCaching(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			System []struct {
+				Text         string `json:"text"`
+				CacheControl struct {
+					Type string `json:"type"`
+				} `json:"cache_control"`
+			} `json:"system"`
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+		if len(body.System) != 1 || body.System[0].Text != "instructions" || body.System[0].CacheControl.Type != "ephemeral" {
+			t.Errorf("Expected cached system block, got %+v", body.System)
+		}
+		if len(body.Messages) != 1 || body.Messages[0].Content != "diff" {
+			t.Errorf("Expected only the prompt in messages, got %+v", body.Messages)
+		}
+		w.Write([]byte(`{"content":[{"type":"text","text":"feat: add thing"}]}`))
+	}))
+	defer server.Close()
+
+	provider, _ := ai.NewAnthropicProvider(ai.Options{Model: "claude", MaxTokens: 100, Host: server.URL})
+	responses, err := provider.Generate(context.Background(), "diff", ai.GenerateOptions{
+		MaxCandidates: 3,
+		System:        "instructions",
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(responses) != 3 {
+		t.Errorf("Expected 3 candidates, got %d", len(responses))
+	}
+}
+
+func TestAnthropicErrorParsing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("retry-after", "12")
+		w.WriteHeader(529)
+		w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+	}))
+	defer server.Close()
+
+	provider, _ := ai.NewAnthropicProvider(ai.Options{Model: "claude", MaxTokens: 100, Host: server.URL})
+	_, err := provider.Generate(context.Background(), "diff", ai.GenerateOptions{MaxCandidates: 1})
+
+	var apiErr *ai.AnthropicError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *ai.AnthropicError, got %v", err)
+	}
+	if !apiErr.IsOverloaded() || apiErr.Message != "Overloaded" {
+		t.Errorf("Expected overloaded error, got %+v", apiErr)
+	}
+	if apiErr.RetryDelay() != 12*time.Second {
+		t.Errorf("Expected retry delay of 12s, got %v", apiErr.RetryDelay())
+	}
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/jabafett/quill/internal/utils/helpers"
+)
+
+func TestFormatBranchName(t *testing.T) {
+	tests := []struct {
+		pattern string
+		fields  git.BranchFields
+		want    string
+	}{
+		{"<type>/<ticket>-<slug>", git.BranchFields{Type: "feat", Ticket: "PROJ-123", Slug: "add-login"}, "feat/PROJ-123-add-login"},
+		{"<type>/<ticket>-<slug>", git.BranchFields{Type: "feat", Slug: "add-login"}, "feat/add-login"},
+		{"<ticket>/<slug>", git.BranchFields{Type: "fix", Slug: "crash"}, "crash"},
+		{"<slug>_<ticket>", git.BranchFields{Slug: "crash"}, "crash"},
+		{"users/me/<type>--<slug>", git.BranchFields{Type: "fix", Slug: "crash"}, "users/me/fix-crash"},
+	}
+	for _, tt := range tests {
+		got, err := git.FormatBranchName(tt.pattern, tt.fields)
+		if err != nil {
+			t.Errorf("FormatBranchName(%q) failed: %v", tt.pattern, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("FormatBranchName(%q, %+v) = %q, want %q", tt.pattern, tt.fields, got, tt.want)
+		}
+	}
+
+	if _, err := git.FormatBranchName("<type>/<author>-<slug>", git.BranchFields{}); err == nil {
+		t.Error("Expected an error for an unknown placeholder")
+	}
+
+	if slug := git.Slugify("Fix the cache: expire entries after TTL!", 0); slug != "fix-the-cache-expire-entries-after-ttl" {
+		t.Errorf("Unexpected slug %q", slug)
+	}
+	if slug := git.Slugify("reset password by email link", 20); slug != "reset-password-by" {
+		t.Errorf("Expected the slug cut at a word boundary, got %q", slug)
+	}
+}
+
+func TestValidateBranchName(t *testing.T) {
+	names := []string{
+		"feat/login", "fix/PROJ-12-crash", "v1.2", "a/b.c/d",
+		"", "-flag", "feat/", "/feat", "feat//login", "feat..login", "feat/.hidden",
+		"feat/login.lock", "feat.", "with space", "tilde~1", "caret^", "colon:x",
+		"what?", "star*", "bracket[", "back\\slash", "at@{1}", "@", "HEAD",
+	}
+	for _, name := range names {
+		err := git.ValidateBranchName(name)
+		// git itself is the reference for which names are valid
+		gitErr := exec.Command("git", "check-ref-format", "--branch", name).Run()
+		if name == "HEAD" || name == "@" {
+			gitErr = err // --branch resolves these to the current branch, but neither can be created
+		}
+		if (err == nil) != (gitErr == nil) {
+			t.Errorf("ValidateBranchName(%q) = %v, git check-ref-format says %v", name, err, gitErr)
+		}
+	}
+}
+
+func TestBranchCollisionAndCreate(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "main.go", "package main\n")
+	runGitCmd(t, dir, "add", ".")
+	runGitCmd(t, dir, "commit", "-q", "-m", "initial")
+	runGitCmd(t, dir, "branch", "feat/login")
+	runGitCmd(t, dir, "branch", "fix")
+	head := runGitCmd(t, dir, "rev-parse", "HEAD")
+	runGitCmd(t, dir, "update-ref", "refs/remotes/origin/docs/readme", head)
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	tests := map[string]string{
+		"feat/login":  "feat/login",
+		"feat":        "feat/login",
+		"fix/crash":   "fix",
+		"docs/readme": "origin/docs/readme",
+		"feat/signup": "",
+	}
+	for name, want := range tests {
+		got, err := repo.BranchCollision(name)
+		if err != nil {
+			t.Fatalf("BranchCollision(%q) failed: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("BranchCollision(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	// Uncommitted changes come along to the new branch
+	writeTestFile(t, dir, "main.go", "package main\n\n// work in progress\n")
+	if err := repo.CreateBranch("feat/signup"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	if branch := runGitCmd(t, dir, "branch", "--show-current"); branch != "feat/signup" {
+		t.Errorf("Expected to be on feat/signup, got %q", branch)
+	}
+	if status := runGitCmd(t, dir, "status", "--porcelain"); status != "M main.go" {
+		t.Errorf("Expected the change to be kept, got %q", status)
+	}
+	if err := repo.CreateBranch("bad name"); err == nil {
+		t.Error("Expected an error for an invalid branch name")
+	}
+}
+
+func TestParseBranchResponses(t *testing.T) {
+	branches, err := helpers.ParseBranchObject(`{"branches":[{"type":"feat","slug":"add-login"},{"type":"","slug":"x"}]}`)
+	if err != nil {
+		t.Fatalf("ParseBranchObject failed: %v", err)
+	}
+	if len(branches) != 1 || branches[0].Slug != "add-login" {
+		t.Errorf("Expected the incomplete proposal dropped, got %+v", branches)
+	}
+	if _, err := helpers.ParseBranchObject(`{"branches":[]}`); err == nil {
+		t.Error("Expected an error for no branches")
+	}
+
+	branches = helpers.ParseBranchResponse("Here are some names:\n- feat/add-login\n`fix/expire-cache`\n")
+	if len(branches) != 2 || branches[0].Type != "feat" || branches[1].Slug != "expire-cache" {
+		t.Errorf("Unexpected branches from text: %+v", branches)
+	}
+}
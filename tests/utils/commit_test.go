@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+func TestCommitWith(t *testing.T) {
+	dir := initTestRepo(t)
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	hook := filepath.Join(dir, ".git", "hooks", "pre-commit")
+	writeTestFile(t, dir, ".git/hooks/pre-commit", "#!/bin/sh\necho 'lint: 2 problems found' >&2\nexit 1\n")
+	if err := os.Chmod(hook, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, dir, "a.txt", "a\n")
+	runGitCmd(t, dir, "add", "a.txt")
+
+	// Hook failures surface the hook's own output
+	_, err = repo.CommitWith("feat: add a", git.CommitOptions{})
+	var commitErr git.ErrCommitFailed
+	if !errors.As(err, &commitErr) {
+		t.Fatalf("Expected ErrCommitFailed, got %v", err)
+	}
+	if !strings.Contains(commitErr.Output, "lint: 2 problems found") {
+		t.Errorf("Expected hook output in error, got %q", commitErr.Output)
+	}
+
+	result, err := repo.CommitWith("feat: add a", git.CommitOptions{
+		NoVerify: true,
+		Author:   "Jane Doe <jane@example.com>",
+	})
+	if err != nil {
+		t.Fatalf("CommitWith failed: %v", err)
+	}
+	if head := runGitCmd(t, dir, "rev-parse", "HEAD"); head != result.Hash {
+		t.Errorf("Expected hash %s, got %s", head, result.Hash)
+	}
+	if author := runGitCmd(t, dir, "log", "-1", "--format=%an <%ae>"); author != "Jane Doe <jane@example.com>" {
+		t.Errorf("Expected author override, got %q", author)
+	}
+
+	// Amend keeps a single commit and replaces its message
+	writeTestFile(t, dir, "b.txt", "b\n")
+	runGitCmd(t, dir, "add", "b.txt")
+	if _, err := repo.CommitWith("feat: add a and b", git.CommitOptions{Amend: true, NoVerify: true}); err != nil {
+		t.Fatalf("Amend failed: %v", err)
+	}
+	if count := runGitCmd(t, dir, "rev-list", "--count", "HEAD"); count != "2" {
+		t.Errorf("Expected 2 commits after amend, got %s", count)
+	}
+	if subject := runGitCmd(t, dir, "log", "-1", "--format=%s"); subject != "feat: add a and b" {
+		t.Errorf("Unexpected subject after amend: %q", subject)
+	}
+
+	writeTestFile(t, dir, "a.txt", "a2\n")
+	runGitCmd(t, dir, "add", "a.txt")
+	if _, err := repo.CommitWith("", git.CommitOptions{Fixup: "HEAD", NoVerify: true}); err != nil {
+		t.Fatalf("Fixup failed: %v", err)
+	}
+	if subject := runGitCmd(t, dir, "log", "-1", "--format=%s"); subject != "fixup! feat: add a and b" {
+		t.Errorf("Unexpected fixup subject: %q", subject)
+	}
+
+	if _, err := repo.CommitWith("x", git.CommitOptions{Amend: true, Fixup: "HEAD"}); err == nil {
+		t.Error("Expected --amend with --fixup to be rejected")
+	}
+	if _, err := repo.CommitWith("x", git.CommitOptions{Author: "jane"}); err == nil {
+		t.Error("Expected a malformed author to be rejected")
+	}
+}
+
+func TestCommitWithSSHSigning(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+
+	dir := initTestRepo(t)
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen failed: %v\n%s", err, out)
+	}
+	runGitCmd(t, dir, "config", "user.signingkey", key+".pub")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	writeTestFile(t, dir, "a.txt", "a\n")
+	runGitCmd(t, dir, "add", "a.txt")
+	if _, err := repo.CommitWith("feat: add a", git.CommitOptions{Sign: true, SignFormat: "ssh"}); err != nil {
+		t.Fatalf("Signed commit failed: %v", err)
+	}
+
+	if raw := runGitCmd(t, dir, "cat-file", "commit", "HEAD"); !strings.Contains(raw, "BEGIN SSH SIGNATURE") {
+		t.Errorf("Expected an SSH signature, got:\n%s", raw)
+	}
+}
+
+func TestAmendDiff(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "a.txt", "one\n")
+	runGitCmd(t, dir, "add", "a.txt")
+	runGitCmd(t, dir, "commit", "-q", "-m", "add a")
+	writeTestFile(t, dir, "b.txt", "two\n")
+	runGitCmd(t, dir, "add", "b.txt")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	diff, err := repo.AmendDiff()
+	if err != nil {
+		t.Fatalf("AmendDiff failed: %v", err)
+	}
+	if paths := strings.Join(diff.Paths(), ","); paths != "a.txt,b.txt" {
+		t.Errorf("Expected HEAD's and staged files, got %s", paths)
+	}
+
+	expected := runGitCmd(t, dir, "diff", "--cached", "--no-color", "HEAD^")
+	if got := strings.TrimSpace(diff.String()); got != expected {
+		t.Errorf("AmendDiff does not match git:\n%s\nwant\n%s", got, expected)
+	}
+}
@@ -0,0 +1,291 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+const sampleDiff = `diff --git a/main.go b/main.go
+index 83db48f..bf269f4 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@ package main
+ import "fmt"
+-func old() {}
++func renamed() {}
++-- not a header
+ 
+ func main() {}
+@@ -10 +11 @@ func main() {}
+-x
++y
+\ No newline at end of file
+diff --git a/old name.txt b/new name.txt
+similarity index 90%
+rename from old name.txt
+rename to new name.txt
+index 1111111..2222222 100644
+--- a/old name.txt
++++ b/new name.txt
+@@ -1 +1 @@
+-a
++b
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000..3333333
+Binary files /dev/null and b/logo.png differ
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+index 4444444..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-one
+-two
+diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+`
+
+func TestParseDiff(t *testing.T) {
+	diff, err := git.ParseDiff(sampleDiff)
+	if err != nil {
+		t.Fatalf("ParseDiff failed: %v", err)
+	}
+
+	if len(diff.Files) != 5 {
+		t.Fatalf("Expected 5 files, got %d", len(diff.Files))
+	}
+
+	main := diff.Files[0]
+	if main.Path != "main.go" || main.Status != git.StatusModified || main.OldPath != "" {
+		t.Errorf("Unexpected main.go diff: %+v", main)
+	}
+	if len(main.Hunks) != 2 {
+		t.Fatalf("Expected 2 hunks in main.go, got %d", len(main.Hunks))
+	}
+	if h := main.Hunks[0]; h.OldStart != 1 || h.OldLines != 4 || h.NewStart != 1 || h.NewLines != 5 || h.Section != "package main" {
+		t.Errorf("Unexpected first hunk: %+v", h)
+	}
+	if h := main.Hunks[1]; h.OldLines != 1 || h.NewStart != 11 || h.Lines[len(h.Lines)-1].Kind != git.LineNoNewline {
+		t.Errorf("Unexpected second hunk: %+v", h)
+	}
+	if added, deleted := main.Stats(); added != 3 || deleted != 2 {
+		t.Errorf("Expected +3/-2 in main.go, got +%d/-%d", added, deleted)
+	}
+
+	rename := diff.Files[1]
+	if rename.Status != git.StatusRenamed || rename.OldPath != "old name.txt" || rename.Path != "new name.txt" || rename.Similarity != 90 {
+		t.Errorf("Unexpected rename: %+v", rename)
+	}
+
+	if logo := diff.Files[2]; logo.Status != git.StatusAdded || !logo.Binary || logo.Path != "logo.png" {
+		t.Errorf("Unexpected binary file: %+v", logo)
+	}
+
+	if gone := diff.Files[3]; gone.Status != git.StatusDeleted || gone.Path != "gone.txt" {
+		t.Errorf("Unexpected deletion: %+v", gone)
+	}
+
+	if run := diff.Files[4]; run.OldMode != "100644" || run.NewMode != "100755" || len(run.Hunks) != 0 {
+		t.Errorf("Unexpected mode change: %+v", run)
+	}
+
+	if got := diff.String(); got != sampleDiff {
+		t.Errorf("Round trip mismatch:\n%s", got)
+	}
+}
+
+func TestStagedDiff(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "notes.txt", strings.Repeat("line\n", 20))
+	runGitCmd(t, dir, "add", "notes.txt")
+	runGitCmd(t, dir, "commit", "-q", "-m", "add notes")
+	// Users with diff.noprefix set must not break parsing
+	runGitCmd(t, dir, "config", "diff.noprefix", "true")
+
+	writeTestFile(t, dir, "docs/.keep", "")
+	runGitCmd(t, dir, "mv", "notes.txt", "docs/notes.txt")
+	writeTestFile(t, dir, "README.md", "changed\n")
+	runGitCmd(t, dir, "add", "README.md")
+	writeTestFile(t, dir, "unstaged.txt", "x\n")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	diff, err := repo.StagedDiff()
+	if err != nil {
+		t.Fatalf("StagedDiff failed: %v", err)
+	}
+
+	if got := strings.Join(diff.Paths(), ","); got != "README.md,docs/notes.txt" {
+		t.Errorf("Unexpected staged paths: %s", got)
+	}
+	if f := diff.File("notes.txt"); f == nil || f.Status != git.StatusRenamed || f.Path != "docs/notes.txt" {
+		t.Errorf("Expected rename of notes.txt, got %+v", f)
+	}
+	if added, deleted := diff.Stats(); added != 1 || deleted != 1 {
+		t.Errorf("Expected +1/-1, got +%d/-%d", added, deleted)
+	}
+}
+
+func TestDiffMatchesGitBinary(t *testing.T) {
+	dir := initTestRepo(t)
+	var long strings.Builder
+	for i := 0; i < 40; i++ {
+		long.WriteString("line " + strings.Repeat("x", i) + "\n")
+	}
+	writeTestFile(t, dir, "long.txt", long.String())
+	writeTestFile(t, dir, "moved.txt", long.String())
+	writeTestFile(t, dir, "removed.txt", "bye\n")
+	writeTestFile(t, dir, "script.sh", "echo hi\n")
+	runGitCmd(t, dir, "add", ".")
+	runGitCmd(t, dir, "commit", "-q", "-m", "add files")
+
+	// Two separate hunks, a new file without a trailing newline, a deletion,
+	// a rename with an edit and a mode change
+	content := strings.Replace(long.String(), "line x\n", "changed\n", 1)
+	content = strings.Replace(content, "line "+strings.Repeat("x", 35)+"\n", "changed too\n", 1)
+	writeTestFile(t, dir, "long.txt", content)
+	writeTestFile(t, dir, "new.txt", "no newline")
+	runGitCmd(t, dir, "rm", "-q", "removed.txt")
+	writeTestFile(t, dir, "docs/.keep", "")
+	runGitCmd(t, dir, "mv", "moved.txt", "docs/moved.txt")
+	writeTestFile(t, dir, "docs/moved.txt", long.String()+"extra\n")
+	if err := os.Chmod(filepath.Join(dir, "script.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGitCmd(t, dir, "add", "long.txt", "new.txt", "docs/moved.txt", "script.sh")
+	// Unstaged edit on top of the staged one
+	writeTestFile(t, dir, "long.txt", content+"unstaged\n")
+
+	native, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	binary, err := git.NewRepository(dir, git.WithGitBinary())
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	for name, get := range map[string]func(*git.Repository) (*git.Diff, error){
+		"staged":   (*git.Repository).StagedDiff,
+		"unstaged": (*git.Repository).UnstagedDiff,
+	} {
+		want, err := get(binary)
+		if err != nil {
+			t.Fatalf("%s diff via git failed: %v", name, err)
+		}
+		got, err := get(native)
+		if err != nil {
+			t.Fatalf("%s diff via go-git failed: %v", name, err)
+		}
+
+		if got.String() != want.String() {
+			t.Errorf("%s diff differs from git\ngo-git:\n%s\ngit:\n%s", name, got, want)
+		}
+	}
+}
+
+func TestRepositoryFromSubdirectory(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "pkg/file.go", "package pkg\n")
+	runGitCmd(t, dir, "add", "pkg/file.go")
+
+	repo, err := git.NewRepository(filepath.Join(dir, "pkg"))
+	if err != nil {
+		t.Fatalf("Failed to open repository from subdirectory: %v", err)
+	}
+
+	files, err := repo.GetStagedFilesOptimized()
+	if err != nil {
+		t.Fatalf("GetStagedFilesOptimized failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != "pkg/file.go" {
+		t.Errorf("Expected [pkg/file.go], got %v", files)
+	}
+
+	content, err := repo.GetFileContent("README.md")
+	if err != nil || content != "init\n" {
+		t.Errorf("Expected README.md to be read from the root, got %q (%v)", content, err)
+	}
+}
+
+func TestInMemoryRepository(t *testing.T) {
+	fs := memfs.New()
+	r, err := gogit.Init(memory.NewStorage(), fs)
+	if err != nil {
+		t.Fatalf("Failed to init in-memory repository: %v", err)
+	}
+	if err := util.WriteFile(fs, "staged.txt", []byte("staged\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := util.WriteFile(fs, "untracked.txt", []byte("untracked\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Add("staged.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := git.NewRepositoryFrom(r)
+
+	hasChanges, err := repo.HasStagedChangesOptimized()
+	if err != nil || !hasChanges {
+		t.Fatalf("Expected staged changes, got %v (%v)", hasChanges, err)
+	}
+
+	diff, err := repo.StagedDiff()
+	if err != nil {
+		t.Fatalf("StagedDiff failed: %v", err)
+	}
+	if len(diff.Files) != 1 || diff.Files[0].Status != git.StatusAdded || diff.Files[0].Path != "staged.txt" {
+		t.Errorf("Unexpected staged diff:\n%s", diff)
+	}
+
+	untracked, err := repo.GetUntrackedFiles()
+	if err != nil || len(untracked) != 1 || untracked[0] != "untracked.txt" {
+		t.Errorf("Expected [untracked.txt], got %v (%v)", untracked, err)
+	}
+}
+
+func TestHunkSectionKeepsRunes(t *testing.T) {
+	dir := initTestRepo(t)
+	// The heading is cut at 80 bytes, which falls inside an é
+	heading := "a" + strings.Repeat("é", 60)
+	writeTestFile(t, dir, "accents.txt", heading+"\n"+strings.Repeat("\tbody\n", 10))
+	runGitCmd(t, dir, "add", "accents.txt")
+	runGitCmd(t, dir, "commit", "-q", "-m", "add accents")
+	writeTestFile(t, dir, "accents.txt", heading+"\n"+strings.Repeat("\tbody\n", 9)+"\tchanged\n")
+	runGitCmd(t, dir, "add", "accents.txt")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	diff, err := repo.StagedDiff()
+	if err != nil {
+		t.Fatalf("StagedDiff failed: %v", err)
+	}
+	f := diff.File("accents.txt")
+	if f == nil || len(f.Hunks) != 1 {
+		t.Fatalf("Expected one hunk, got %+v", f)
+	}
+	if section := f.Hunks[0].Section; !utf8.ValidString(section) || !strings.HasPrefix(heading, section) || len(section) != 79 {
+		t.Errorf("Expected the heading cut before the split rune, got %q", section)
+	}
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	c "context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/context"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+// keywordEmbed embeds a text as the counts of a few keywords, so similarity
+// follows the words the texts share
+func keywordEmbed(calls *int) context.Embed {
+	keywords := []string{"parse", "token", "socket", "retry", "render"}
+	return func(ctx c.Context, texts []string) ([][]float32, error) {
+		*calls += len(texts)
+		vectors := make([][]float32, len(texts))
+		for i, text := range texts {
+			vectors[i] = make([]float32, len(keywords))
+			for j, word := range keywords {
+				vectors[i][j] = float32(strings.Count(strings.ToLower(text), word))
+			}
+		}
+		return vectors, nil
+	}
+}
+
+func TestChunkFile(t *testing.T) {
+	src := "package a\n\ntype Parser struct{}\n\n// Parse reads input\nfunc (p *Parser) Parse() {\n\treturn\n}\n\nfunc helper() {}\n"
+	var spans [][2]int
+	for _, chunk := range context.ChunkFile("a.go", []byte(src)) {
+		spans = append(spans, [2]int{chunk.Start, chunk.End})
+	}
+	if want := [][2]int{{3, 3}, {5, 8}, {10, 10}}; !reflect.DeepEqual(spans, want) {
+		t.Errorf("Go chunks: got %v, want %v", spans, want)
+	}
+
+	text := strings.Repeat("line\n", 90)
+	spans = nil
+	for _, chunk := range context.ChunkFile("notes.txt", []byte(text)) {
+		spans = append(spans, [2]int{chunk.Start, chunk.End})
+	}
+	if want := [][2]int{{1, 40}, {41, 80}, {81, 90}}; !reflect.DeepEqual(spans, want) {
+		t.Errorf("Text chunks: got %v, want %v", spans, want)
+	}
+}
+
+func TestEmbeddingsIndex(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "lexer.go", "package m\n\n// Tokenize splits input into tokens for parse\nfunc Tokenize() {}\n")
+	writeTestFile(t, dir, "net.go", "package m\n\n// Dial opens a socket with retry\nfunc Dial() {}\n")
+	writeTestFile(t, dir, "view.go", "package m\n\n// Render draws the page\nfunc Render() {}\n")
+	runGitCmd(t, dir, "add", ".")
+	runGitCmd(t, dir, "commit", "-q", "-m", "init")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	index, err := context.OpenFileIndex(dir)
+	if err != nil {
+		t.Fatalf("OpenFileIndex failed: %v", err)
+	}
+	defer index.Close()
+	if _, err := index.Update(c.Background(), repo, nil, false); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	calls := 0
+	embed := keywordEmbed(&calls)
+	stats, err := index.UpdateEmbeddings(c.Background(), repo, "test/keywords", embed)
+	if err != nil {
+		t.Fatalf("UpdateEmbeddings failed: %v", err)
+	}
+	if stats.Indexed != 4 {
+		t.Errorf("First run: got %+v, want 4 files embedded", stats)
+	}
+
+	calls = 0
+	stats, err = index.UpdateEmbeddings(c.Background(), repo, "test/keywords", embed)
+	if err != nil {
+		t.Fatalf("UpdateEmbeddings failed: %v", err)
+	}
+	if stats.Indexed != 0 || stats.Unchanged != 4 || calls != 0 {
+		t.Errorf("Second run re-embedded files: %+v, %d texts", stats, calls)
+	}
+
+	queries := []context.RelatedQuery{
+		{Path: "parser.go", Text: "func Parse() { for token := range Tokenize() {} }"},
+		{Path: "client.go", Text: "conn := Dial() // retry the socket"},
+	}
+	blocks, err := index.Related(c.Background(), "test/keywords", embed, queries, []string{"view.go"}, 1, 1000)
+	if err != nil {
+		t.Fatalf("Related failed: %v", err)
+	}
+	if len(blocks) != 2 || !strings.HasPrefix(blocks[0], "net.go:3-4\n") || !strings.HasPrefix(blocks[1], "lexer.go:3-4\n") {
+		t.Errorf("Unexpected related code: %q", blocks)
+	}
+
+	blocks, err = index.Related(c.Background(), "test/keywords", embed, queries, nil, 1, 5)
+	if err != nil || len(blocks) != 0 {
+		t.Errorf("Budget not enforced: %q, %v", blocks, err)
+	}
+
+	blocks, err = index.Related(c.Background(), "other/model", embed, queries, nil, 1, 1000)
+	if err != nil || blocks != nil {
+		t.Errorf("Vectors of another model were used: %q, %v", blocks, err)
+	}
+
+	stats, err = index.UpdateEmbeddings(c.Background(), repo, "other/model", embed)
+	if err != nil {
+		t.Fatalf("UpdateEmbeddings failed: %v", err)
+	}
+	if stats.Indexed != 4 {
+		t.Errorf("New model: got %+v, want everything re-embedded", stats)
+	}
+}
+
+func TestHunkQueries(t *testing.T) {
+	diff, err := git.ParseDiff("diff --git a/x.go b/x.go\n--- a/x.go\n+++ b/x.go\n@@ -1,2 +1,2 @@ func X()\n keep\n-old\n+new\n")
+	if err != nil {
+		t.Fatalf("ParseDiff failed: %v", err)
+	}
+	want := []context.RelatedQuery{{Path: "x.go", Text: "x.go func X()\nold\nnew"}}
+	if got := context.HunkQueries(diff); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %+v, want %+v", got, want)
+	}
+}
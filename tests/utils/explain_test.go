@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/jabafett/quill/internal/utils/helpers"
+)
+
+func TestResolveRange(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "main.go", "package main\n")
+	runGitCmd(t, dir, "add", ".")
+	runGitCmd(t, dir, "commit", "-q", "-m", "initial")
+	runGitCmd(t, dir, "branch", "-M", "main")
+
+	runGitCmd(t, dir, "switch", "-q", "-c", "feature")
+	writeTestFile(t, dir, "login.go", "package main\n\nfunc login() {}\n")
+	runGitCmd(t, dir, "add", ".")
+	runGitCmd(t, dir, "commit", "-q", "-m", "feat: add login\n\nRefs: PROJ-7")
+	writeTestFile(t, dir, "main.go", "package main\n\nfunc main() { login() }\n")
+	runGitCmd(t, dir, "commit", "-q", "-am", "feat: call login")
+
+	// main moves on after feature forked, which a..b must leave out
+	runGitCmd(t, dir, "switch", "-q", "main")
+	writeTestFile(t, dir, "README.md", "# app\n")
+	runGitCmd(t, dir, "add", ".")
+	runGitCmd(t, dir, "commit", "-q", "-m", "docs: add readme")
+	runGitCmd(t, dir, "switch", "-q", "feature")
+
+	for _, opts := range [][]git.RepositoryOption{nil, {git.WithGitBinary()}} {
+		repo, err := git.NewRepository(dir, opts...)
+		if err != nil {
+			t.Fatalf("Failed to open repository: %v", err)
+		}
+
+		rr, err := repo.ResolveRange("main..feature")
+		if err != nil {
+			t.Fatalf("ResolveRange failed: %v", err)
+		}
+		diff, err := rr.Diff()
+		if err != nil {
+			t.Fatalf("Diff failed: %v", err)
+		}
+		if paths := strings.Join(diff.Paths(), ","); paths != "login.go,main.go" {
+			t.Errorf("Expected the feature's files only, got %s", paths)
+		}
+		commits, more, err := rr.Commits(10)
+		if err != nil {
+			t.Fatalf("Commits failed: %v", err)
+		}
+		if len(commits) != 2 || more || commits[0].Subject() != "feat: call login" {
+			t.Errorf("Expected the two feature commits, newest first, got %+v", commits)
+		}
+		if commits, more, _ := rr.Commits(1); len(commits) != 1 || !more {
+			t.Errorf("Expected one commit and more, got %d and %v", len(commits), more)
+		}
+
+		rr, err = repo.ResolveRange("HEAD~1")
+		if err != nil {
+			t.Fatalf("ResolveRange failed: %v", err)
+		}
+		if diff, _ := rr.Diff(); strings.Join(diff.Paths(), ",") != "login.go" {
+			t.Errorf("Expected HEAD~1 to add login.go, got %v", diff.Paths())
+		}
+		if commits, _, _ := rr.Commits(10); len(commits) != 1 || !strings.Contains(commits[0].Message, "Refs: PROJ-7") {
+			t.Errorf("Expected only HEAD~1 with its full message, got %+v", commits)
+		}
+
+		// A root commit is compared against the empty tree
+		rr, err = repo.ResolveRange("main~1")
+		if err != nil {
+			t.Fatalf("ResolveRange failed: %v", err)
+		}
+		if diff, _ := rr.Diff(); strings.Join(diff.Paths(), ",") != "main.go" {
+			t.Errorf("Expected the root commit to add main.go, got %v", diff.Paths())
+		}
+	}
+
+	repo, _ := git.NewRepository(dir)
+	if _, err := repo.ResolveRange("no-such-branch"); err == nil {
+		t.Error("Expected an error for an unknown revision")
+	}
+}
+
+func TestExplanationMarkdown(t *testing.T) {
+	explanation := &helpers.Explanation{
+		Revision:   "main..feature",
+		Commits:    []helpers.ExplainedCommit{{Hash: "0123456789abcdef", Subject: "feat: add login", Author: "Dev", Date: "2024-05-01"}},
+		Files:      []string{"login.go"},
+		References: []string{"PROJ-7"},
+	}
+	var err error
+	explanation.ExplanationObject, err = helpers.ParseExplanationObject(`{"summary":"Adds a login flow.",` +
+		`"changes":["Add login()"],"motivation":"Users need accounts.",` +
+		`"risks":[{"area":"main","reason":"login runs at startup"}],"testing":["Start the app"]}`)
+	if err != nil {
+		t.Fatalf("ParseExplanationObject failed: %v", err)
+	}
+
+	markdown := explanation.Markdown()
+	for _, want := range []string{
+		"# main..feature", "- `0123456` feat: add login (Dev, 2024-05-01)", "## What changed\n\n- Add login()",
+		"References: PROJ-7", "- **main**: login runs at startup", "## What to test\n\n- Start the app",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected %q in:\n%s", want, markdown)
+		}
+	}
+
+	if _, err := helpers.ParseExplanationObject(`{"summary":""}`); err == nil {
+		t.Error("Expected an error for an empty summary")
+	}
+}
@@ -0,0 +1,218 @@
+package tests
+
+import (
+	c "context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/context"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+func TestAnalyzeFile(t *testing.T) {
+	tests := []struct {
+		path     string
+		content  string
+		imports  []string
+		exported []string
+	}{
+		{
+			path:     "main.go",
+			content:  "package main\n\nimport (\n\t\"fmt\"\n\tg \"github.com/x/y\"\n)\n\nfunc Run() {}\nfunc helper() {}\n",
+			imports:  []string{"fmt", "github.com/x/y"},
+			exported: []string{"Run"},
+		},
+		{
+			path:     "app.ts",
+			content:  "import { a } from './a'\nexport * from \"../b\"\nconst c = require('./c')\nexport function load() {}\nfunction local() {}\n",
+			imports:  []string{"./a", "../b", "./c"},
+			exported: []string{"load"},
+		},
+		{
+			path:     "pkg/mod.py",
+			content:  "import os, pkg.util as u\nfrom . import sibling\nfrom ..core import thing\n\ndef public(): pass\ndef _private(): pass\n",
+			imports:  []string{"os", "pkg.util", ".", "..core"},
+			exported: []string{"public"},
+		},
+		{
+			path:     "src/lib.rs",
+			content:  "mod parser;\nuse crate::parser::Parser;\npub fn parse() {}\nfn internal() {}\n",
+			imports:  []string{"mod parser", "crate::parser::Parser"},
+			exported: []string{"parse"},
+		},
+		{
+			path:     "App.java",
+			content:  "import java.util.List;\nimport static org.x.Util.helper;\npublic class App {\n  public void run() {}\n  private void hide() {}\n}\n",
+			imports:  []string{"java.util.List", "org.x.Util.helper"},
+			exported: []string{"App", "App.run"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			analysis, err := context.AnalyzeFile(tt.path, []byte(tt.content))
+			if err != nil {
+				t.Fatalf("AnalyzeFile failed: %v", err)
+			}
+			if !reflect.DeepEqual(analysis.Imports, tt.imports) {
+				t.Errorf("Imports: got %v, want %v", analysis.Imports, tt.imports)
+			}
+			var exported []string
+			for _, sym := range analysis.Symbols {
+				if sym.Exported {
+					exported = append(exported, sym.Name)
+				}
+			}
+			if !reflect.DeepEqual(exported, tt.exported) {
+				t.Errorf("Exported: got %v, want %v", exported, tt.exported)
+			}
+		})
+	}
+}
+
+func TestResolver(t *testing.T) {
+	files := []string{
+		"go.mod", "cmd/main.go", "internal/git/git.go", "internal/git/git_test.go",
+		"web/app.ts", "web/util/index.ts", "web/api.js",
+		"pkg/__init__.py", "pkg/core.py", "pkg/sub/mod.py",
+		"src/lib.rs", "src/parser.rs", "src/parser/lexer.rs",
+		"java/src/com/x/App.java", "java/src/com/x/Util.java",
+	}
+	resolver := context.NewResolver(files, func(path string) ([]byte, error) {
+		return []byte("module github.com/acme/tool\n\ngo 1.23\n"), nil
+	})
+
+	tests := []struct {
+		from, spec string
+		want       []string
+	}{
+		{"cmd/main.go", "github.com/acme/tool/internal/git", []string{"internal/git/git.go"}},
+		{"cmd/main.go", "fmt", nil},
+		{"web/app.ts", "./util", []string{"web/util/index.ts"}},
+		{"web/app.ts", "./api", []string{"web/api.js"}},
+		{"web/app.ts", "react", nil},
+		{"pkg/sub/mod.py", "..core", []string{"pkg/core.py"}},
+		{"pkg/sub/mod.py", "pkg.core.thing", []string{"pkg/core.py"}},
+		{"pkg/core.py", ".", []string{"pkg/__init__.py"}},
+		{"src/lib.rs", "mod parser", []string{"src/parser.rs"}},
+		{"src/parser.rs", "mod lexer", []string{"src/parser/lexer.rs"}},
+		{"src/parser/lexer.rs", "crate::parser::Parser", []string{"src/parser.rs"}},
+		{"java/src/com/x/App.java", "com.x.Util", []string{"java/src/com/x/Util.java"}},
+		{"java/src/com/x/App.java", "com.x.Util.helper", []string{"java/src/com/x/Util.java"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+" "+tt.spec, func(t *testing.T) {
+			got := resolver.Resolve(tt.from, tt.spec)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileIndexUpdate(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "go.mod", "module example.com/m\n\ngo 1.23\n")
+	writeTestFile(t, dir, "a/a.go", "// Package a parses things\npackage a\n\nfunc Parse() {}\n")
+	writeTestFile(t, dir, "b/b.go", "package b\n\nimport \"example.com/m/a\"\n\nfunc Use() { a.Parse() }\n")
+	writeTestFile(t, dir, "c/c.go", "package c\n\nfunc Other() {}\n")
+	writeTestFile(t, dir, "go.sum", "example.com/x v1.0.0 h1:abc=\n")
+	runGitCmd(t, dir, "add", ".")
+	runGitCmd(t, dir, "commit", "-q", "-m", "init")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	var summarized []string
+	summarize := func(ctx c.Context, files []context.SummaryRequest) (map[string]string, error) {
+		summaries := make(map[string]string)
+		for _, file := range files {
+			summarized = append(summarized, file.Path)
+			if file.Path != "a/a.go" {
+				summaries[file.Path] = "Summary of " + file.Path
+			}
+		}
+		return summaries, nil
+	}
+
+	update := func() *context.IndexStats {
+		t.Helper()
+		index, err := context.OpenFileIndex(dir)
+		if err != nil {
+			t.Fatalf("OpenFileIndex failed: %v", err)
+		}
+		defer index.Close()
+		stats, err := index.Update(c.Background(), repo, summarize, false)
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		return stats
+	}
+
+	stats := update()
+	if stats.Indexed != 5 || stats.Skipped != 1 {
+		t.Errorf("First run: got %+v, want 5 indexed and go.sum skipped", stats)
+	}
+
+	summarized = nil
+	stats = update()
+	if stats.Indexed != 0 || stats.Unchanged != 5 || len(summarized) != 0 {
+		t.Errorf("Second run re-indexed files: %+v, summarized %v", stats, summarized)
+	}
+
+	writeTestFile(t, dir, "a/a.go", "// Package a parses things\npackage a\n\nfunc Parse() {}\n\nfunc Format() {}\n")
+	runGitCmd(t, dir, "add", "a/a.go")
+	stats = update()
+	if stats.Indexed != 1 || stats.Removed != 1 || !reflect.DeepEqual(summarized, []string{"a/a.go"}) {
+		t.Errorf("Changed file run: got %+v, summarized %v", stats, summarized)
+	}
+
+	index, err := context.OpenFileIndex(dir)
+	if err != nil {
+		t.Fatalf("OpenFileIndex failed: %v", err)
+	}
+	defer index.Close()
+
+	manifest, err := index.Manifest()
+	if err != nil || manifest == nil {
+		t.Fatalf("Manifest missing: %v", err)
+	}
+	entry, err := index.Entry(manifest.Files["a/a.go"])
+	if err != nil || entry == nil {
+		t.Fatalf("Entry for a/a.go missing: %v", err)
+	}
+	if !reflect.DeepEqual(entry.Symbols, []string{"func Parse", "func Format"}) {
+		t.Errorf("Unexpected symbols: %v", entry.Symbols)
+	}
+
+	lines, err := index.Context([]string{"a/a.go"}, 10)
+	if err != nil {
+		t.Fatalf("Context failed: %v", err)
+	}
+	want := []string{
+		"a/a.go: Package a parses things",
+		"b/b.go (imports a/a.go): Summary of b/b.go",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Got context %q, want %q", lines, want)
+	}
+}
+
+func TestParseFileSummaries(t *testing.T) {
+	requested := []context.SummaryRequest{{Path: "a.go"}, {Path: "dir/b.py"}}
+	response := "Here you go:\n- `a.go`: Parses diffs\ndir/b.py: Loads config: from disk\nother.go: Not requested\n"
+
+	got := context.ParseFileSummaries(response, requested)
+	want := map[string]string{"a.go": "Parses diffs", "dir/b.py": "Loads config: from disk"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+
+	if summary := context.FallbackSummary([]byte("package x\n\nfunc A() {}\n"), []string{"func A"}); !strings.HasPrefix(summary, "Defines func A") {
+		t.Errorf("Unexpected fallback summary: %q", summary)
+	}
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/context"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		name    string
+		deps    map[string]string
+	}{
+		{
+			path:    "go.mod",
+			content: "module github.com/acme/tool\n\ngo 1.23\n\nrequire (\n\tgithub.com/spf13/cobra v1.8.0\n\tgolang.org/x/mod v0.12.0 // indirect\n)\n",
+			name:    "github.com/acme/tool",
+			deps:    map[string]string{"github.com/spf13/cobra": "v1.8.0", "golang.org/x/mod": "v0.12.0"},
+		},
+		{
+			path:    "web/package.json",
+			content: `{"name": "@acme/web", "dependencies": {"react": "^18.2.0"}, "devDependencies": {"vitest": "^1.0.0"}}`,
+			name:    "@acme/web",
+			deps:    map[string]string{"react": "^18.2.0", "vitest": "^1.0.0"},
+		},
+		{
+			path:    "Cargo.toml",
+			content: "[package]\nname = \"acme-core\"\n\n[dependencies]\nserde = { version = \"1.0\", features = [\"derive\"] }\nanyhow = \"1\"\nlocal = { path = \"../local\" }\n",
+			name:    "acme-core",
+			deps:    map[string]string{"serde": "1.0", "anyhow": "1", "local": "path:../local"},
+		},
+		{
+			path:    "requirements.txt",
+			content: "# runtime\nrequests>=2.31 # http\n-r base.txt\nPyYAML==6.0\nnumpy\n",
+			deps:    map[string]string{"requests": ">=2.31", "PyYAML": "==6.0", "numpy": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			m, err := context.ParseManifest(tt.path, []byte(tt.content))
+			if err != nil {
+				t.Fatalf("ParseManifest failed: %v", err)
+			}
+			if m.Name != tt.name {
+				t.Errorf("Name: got %q, want %q", m.Name, tt.name)
+			}
+			if !reflect.DeepEqual(m.Dependencies, tt.deps) {
+				t.Errorf("Dependencies: got %v, want %v", m.Dependencies, tt.deps)
+			}
+		})
+	}
+}
+
+func TestDiffManifests(t *testing.T) {
+	before := `{"dependencies": {"react": "^18.0.0", "lodash": "^4.0.0"}}`
+	after := `{"dependencies": {"react": "^18.2.0", "zod": "^3.0.0"}}`
+
+	changes, err := context.DiffManifests("package.json", []byte(before), []byte(after))
+	if err != nil {
+		t.Fatalf("DiffManifests failed: %v", err)
+	}
+
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.String())
+	}
+	want := []string{
+		"package.json: lodash removed",
+		"package.json: react updated ^18.0.0 -> ^18.2.0",
+		"package.json: zod added ^3.0.0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestImpact(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "go.mod", "module example.com/m\n\ngo 1.23\n")
+	writeTestFile(t, dir, "core/core.go", "package core\n\nfunc Parse() {}\n")
+	writeTestFile(t, dir, "api/api.go", "package api\n\nimport \"example.com/m/core\"\n\nfunc Serve() { core.Parse() }\n")
+	writeTestFile(t, dir, "cmd/main.go", "package main\n\nimport (\n\t\"example.com/m/api\"\n\t\"github.com/spf13/cobra\"\n)\n\nfunc main() { api.Serve(); _ = cobra.Command{} }\n")
+	writeTestFile(t, dir, "other/other.go", "package other\n\nfunc Other() {}\n")
+	runGitCmd(t, dir, "add", ".")
+	runGitCmd(t, dir, "commit", "-q", "-m", "init")
+
+	writeTestFile(t, dir, "core/core.go", "package core\n\nfunc Parse() int { return 0 }\n")
+	writeTestFile(t, dir, "go.mod", "module example.com/m\n\ngo 1.23\n\nrequire github.com/spf13/cobra v1.8.0\n")
+	runGitCmd(t, dir, "add", ".")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	diff, err := repo.StagedDiff()
+	if err != nil {
+		t.Fatalf("StagedDiff failed: %v", err)
+	}
+	versions, err := repo.StagedVersions()
+	if err != nil {
+		t.Fatalf("StagedVersions failed: %v", err)
+	}
+	dependencies := context.ManifestChanges(diff, versions)
+
+	graph, err := context.BuildImportGraph(repo, nil)
+	if err != nil {
+		t.Fatalf("BuildImportGraph failed: %v", err)
+	}
+
+	direct := graph.Impact([]string{"core/core.go"}, dependencies, 1)
+	if want := []context.Dependent{{Path: "api/api.go", Via: "core/core.go", Depth: 1}}; !reflect.DeepEqual(direct.Dependents, want) {
+		t.Errorf("Direct dependents: got %+v, want %+v", direct.Dependents, want)
+	}
+	if want := []string{"example.com/m/api"}; !reflect.DeepEqual(direct.Packages, want) {
+		t.Errorf("Packages: got %v, want %v", direct.Packages, want)
+	}
+
+	all := graph.Impact([]string{"core/core.go"}, nil, 0)
+	if len(all.Dependents) != 2 || all.Dependents[1].Path != "cmd/main.go" || all.Dependents[1].Depth != 2 {
+		t.Errorf("Transitive dependents: got %+v", all.Dependents)
+	}
+
+	if len(direct.Dependencies) != 1 || direct.Dependencies[0].Change.Name != "github.com/spf13/cobra" {
+		t.Fatalf("Dependency changes: got %+v", direct.Dependencies)
+	}
+	if users := direct.Dependencies[0].Users; !reflect.DeepEqual(users, []string{"cmd/main.go"}) {
+		t.Errorf("Dependency users: got %v", users)
+	}
+
+	want := []string{
+		"Callers in example.com/m/api depend on the changed files",
+		"go.mod: github.com/spf13/cobra added v1.8.0, imported by cmd/main.go",
+	}
+	if got := direct.Summarize(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Summarize: got %q, want %q", got, want)
+	}
+}
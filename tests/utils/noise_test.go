@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+func TestNoiseFilter(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, ".gitattributes", "api/*.ts linguist-generated\nkeep.pb.go linguist-generated=false\n*.dat -diff\n")
+	writeTestFile(t, dir, ".quillignore", "# test data\nfixtures/\n")
+	writeTestFile(t, dir, "main.go", "package main\n")
+	writeTestFile(t, dir, "go.sum", "a v1 h1:x\na v1/go.mod h1:y\nb v2 h1:z\n")
+	writeTestFile(t, dir, "api.pb.go", "package api\n")
+	writeTestFile(t, dir, "keep.pb.go", "package api\n")
+	writeTestFile(t, dir, "api/client.ts", "export {}\n")
+	writeTestFile(t, dir, "vendor/lib/a.go", "package lib\n")
+	writeTestFile(t, dir, "vendor/lib/b.go", "package lib\n")
+	writeTestFile(t, dir, "table.dat", "1,2,3\n")
+	writeTestFile(t, dir, "logo.png", "\x89PNG\x00\x00")
+	writeTestFile(t, dir, "mocks.go", "// Code generated by mockgen. DO NOT EDIT.\npackage mocks\n")
+	writeTestFile(t, dir, "fixtures/data.json", "{}\n")
+	runGitCmd(t, dir, "add", ".")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	diff, err := repo.StagedDiff()
+	if err != nil {
+		t.Fatalf("StagedDiff failed: %v", err)
+	}
+	filter, err := repo.NoiseFilter()
+	if err != nil {
+		t.Fatalf("NoiseFilter failed: %v", err)
+	}
+
+	kept, noise := filter.Split(diff)
+
+	if got := strings.Join(kept.Paths(), ","); got != ".gitattributes,.quillignore,keep.pb.go,main.go" {
+		t.Errorf("Unexpected kept files: %s", got)
+	}
+
+	want := map[string]git.NoiseKind{
+		"go.sum":             git.NoiseLockfile,
+		"api.pb.go":          git.NoiseGenerated,
+		"api/client.ts":      git.NoiseGenerated,
+		"vendor/lib/a.go":    git.NoiseVendored,
+		"vendor/lib/b.go":    git.NoiseVendored,
+		"table.dat":          git.NoiseNoDiff,
+		"logo.png":           git.NoiseBinary,
+		"mocks.go":           git.NoiseGenerated,
+		"fixtures/data.json": git.NoiseIgnored,
+	}
+	if len(noise) != len(want) {
+		t.Errorf("Expected %d noisy files, got %d: %+v", len(want), len(noise), noise)
+	}
+	for _, n := range noise {
+		if want[n.Path] != n.Kind {
+			t.Errorf("Expected %s to be %q, got %q", n.Path, want[n.Path], n.Kind)
+		}
+	}
+
+	summary := strings.Join(git.SummarizeNoise(noise), "\n")
+	for _, line := range []string{"go.sum: updated dependency checksums (+2/-0 module versions)", "vendor/: 2 vendored files changed"} {
+		if !strings.Contains(summary, line) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", line, summary)
+		}
+	}
+}
+
+func TestNoiseGoSumVersions(t *testing.T) {
+	diff, err := git.ParseDiff(`diff --git a/go.sum b/go.sum
+index 1111111..2222222 100644
+--- a/go.sum
++++ b/go.sum
+@@ -1,3 +1,3 @@
+-example.com/a v1.0.0 h1:old=
+-example.com/a v1.0.0/go.mod h1:oldmod=
++example.com/a v1.1.0 h1:new=
++example.com/a v1.1.0/go.mod h1:newmod=
+ example.com/b v2.0.0 h1:same=
+`)
+	if err != nil {
+		t.Fatalf("ParseDiff failed: %v", err)
+	}
+	dir := initTestRepo(t)
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	filter, err := repo.NoiseFilter()
+	if err != nil {
+		t.Fatalf("NoiseFilter failed: %v", err)
+	}
+
+	// One version bump, not four changed checksums
+	_, noise := filter.Split(diff)
+	if summary := git.SummarizeNoise(noise); len(summary) != 1 || summary[0] != "go.sum: updated dependency checksums (+1/-1 module versions)" {
+		t.Errorf("Unexpected summary: %v", summary)
+	}
+}
+
+func TestNoiseBannerOnlyAtFileStart(t *testing.T) {
+	diff, err := git.ParseDiff(`diff --git a/gen.go b/gen.go
+index 1111111..2222222 100644
+--- a/gen.go
++++ b/gen.go
+@@ -1,2 +1,3 @@
+ // Code generated by stringer. DO NOT EDIT.
++
+ package gen
+diff --git a/handler.go b/handler.go
+index 3333333..4444444 100644
+--- a/handler.go
++++ b/handler.go
+@@ -40,2 +40,3 @@ func handle() {
+ 	// The response type is auto-generated from the schema
++	validate(resp)
+ 	return resp
+`)
+	if err != nil {
+		t.Fatalf("ParseDiff failed: %v", err)
+	}
+
+	repo, err := git.NewRepository(initTestRepo(t))
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	filter, err := repo.NoiseFilter()
+	if err != nil {
+		t.Fatalf("NoiseFilter failed: %v", err)
+	}
+	if kind, ok := filter.ClassifyDiff(diff.File("gen.go")); !ok || kind != git.NoiseGenerated {
+		t.Errorf("Expected a banner at the top to mark gen.go generated, got %q", kind)
+	}
+	if kind, ok := filter.ClassifyDiff(diff.File("handler.go")); ok {
+		t.Errorf("Expected a comment in the middle of handler.go not to make it noise, got %q", kind)
+	}
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/ai"
+)
+
+func TestResolveOllamaHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        string
+		configHost string
+		want       string
+	}{
+		{name: "default", want: "http://localhost:11434"},
+		{name: "config host", configHost: "http://gpu-box:8080", want: "http://gpu-box:8080"},
+		{name: "bare host gets port", configHost: "gpu-box", want: "http://gpu-box:11434"},
+		{name: "env overrides config", env: "remote:1234", configHost: "gpu-box", want: "http://remote:1234"},
+		{name: "bind address", env: "0.0.0.0", want: "http://127.0.0.1:11434"},
+		{name: "ipv6 host", configHost: "http://[::1]", want: "http://[::1]:11434"},
+		{name: "ipv6 bind address", env: "[::]:8080", want: "http://[::1]:8080"},
+		{name: "https keeps its default port", configHost: "https://ollama.example.com", want: "https://ollama.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OLLAMA_HOST", tt.env)
+			got, err := ai.ResolveOllamaHost(tt.configHost)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveOllamaHost(%q) = %q, want %q", tt.configHost, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOllamaChatRequest(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Expected /api/chat, got %s", r.URL.Path)
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+		if stream, ok := body["stream"].(bool); !ok || stream {
+			t.Errorf("Expected stream=false, got %v", body["stream"])
+		}
+		options := body["options"].(map[string]any)
+		if options["num_predict"] != float64(256) || options["num_ctx"] != float64(4096) {
+			t.Errorf("Unexpected options: %v", options)
+		}
+		messages := body["messages"].([]any)
+		if len(messages) != 2 || messages[0].(map[string]any)["role"] != "system" {
+			t.Errorf("Expected system and user messages, got %v", messages)
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": map[string]string{"role": "assistant", "content": "feat: add thing"},
+			"done":    true,
+		})
+	}))
+	defer server.Close()
+
+	t.Setenv("OLLAMA_HOST", "")
+	provider, err := ai.NewOllamaProvider(ai.Options{
+		Model:     "test-model",
+		Host:      server.URL,
+		MaxTokens: 256,
+		NumCtx:    4096,
+		Seed:      7,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	responses, err := provider.Generate(context.Background(), "prompt", ai.GenerateOptions{
+		MaxCandidates: 2,
+		System:        "system prompt",
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(responses) != 2 || calls != 2 {
+		t.Errorf("Expected 2 responses from 2 calls, got %d from %d", len(responses), calls)
+	}
+}
+
+func TestOllamaEmbed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("Expected /api/embed, got %s", r.URL.Path)
+		}
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+		if body.Model != "nomic-embed-text" || len(body.Input) != 2 {
+			t.Errorf("Unexpected request: %+v", body)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": [][]float32{{1, 0}, {0, 1}}})
+	}))
+	defer server.Close()
+
+	t.Setenv("OLLAMA_HOST", "")
+	provider, err := ai.NewOllamaProvider(ai.Options{Model: "test-model", Host: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	vectors, err := provider.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(vectors) != 2 || vectors[1][1] != 1 {
+		t.Errorf("Unexpected vectors: %v", vectors)
+	}
+}
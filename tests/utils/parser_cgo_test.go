@@ -0,0 +1,6 @@
+//go:build cgo
+
+package tests
+
+// treeSitter reports whether symbols come from the tree-sitter parser
+const treeSitter = true
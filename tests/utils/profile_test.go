@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/context"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+func TestProfileRepository(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "go.mod", "module example.com/tool\n\ngo 1.23\n\nrequire github.com/spf13/cobra v1.8.0\n")
+	writeTestFile(t, dir, "main.go", "package main\n\nimport \"github.com/spf13/cobra\"\n\nfunc main() { _ = cobra.Command{} }\n")
+	writeTestFile(t, dir, "internal/parser/parser.go", "// Package parser reads tool configuration files.\npackage parser\n\nfunc Parse() {}\n")
+	writeTestFile(t, dir, "internal/parser/parser_test.go", "package parser\n\nimport \"testing\"\n\nfunc TestParse(t *testing.T) { Parse() }\n")
+	writeTestFile(t, dir, "scripts/release.sh", "#!/bin/sh\necho release\n")
+	writeTestFile(t, dir, "docs/guide.md", "# Guide\n")
+	writeTestFile(t, dir, ".github/workflows/ci.yml", "on: push\n")
+	writeTestFile(t, dir, "LICENSE", "MIT License\n\nPermission is hereby granted, free of charge, to any person obtaining a copy\n")
+	runGitCmd(t, dir, "add", ".")
+	runGitCmd(t, dir, "commit", "-q", "-m", "init")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	profile, err := context.ProfileRepository(repo)
+	if err != nil {
+		t.Fatalf("ProfileRepository failed: %v", err)
+	}
+
+	if profile.Files != 9 {
+		t.Errorf("Files: got %d, want 9", profile.Files)
+	}
+	// internal, internal/parser, scripts, docs, .github, .github/workflows
+	if profile.Directories != 6 {
+		t.Errorf("Directories: got %d, want 6", profile.Directories)
+	}
+
+	var languages []string
+	for _, lang := range profile.Languages {
+		languages = append(languages, lang.Name)
+	}
+	if want := []string{"Go", "Shell"}; !reflect.DeepEqual(languages, want) {
+		t.Errorf("Languages: got %v, want %v", languages, want)
+	}
+
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"BuildSystems", profile.BuildSystems, []string{"Go modules"}},
+		{"Frameworks", profile.Frameworks, []string{"Cobra"}},
+		{"EntryPoints", profile.EntryPoints, []string{"main.go"}},
+		{"CI", profile.CI, []string{"GitHub Actions (.github/workflows/ci.yml)"}},
+		{"License", profile.License, "MIT"},
+		{"Tests", profile.Tests, context.TestLayout{Files: 1, Dirs: []string{"internal/parser"}, Colocated: true}},
+	}
+	for _, check := range checks {
+		if !reflect.DeepEqual(check.got, check.want) {
+			t.Errorf("%s: got %v, want %v", check.name, check.got, check.want)
+		}
+	}
+
+	if len(profile.Modules) != 2 {
+		t.Fatalf("Modules: got %+v", profile.Modules)
+	}
+	parser := profile.Modules[0]
+	if parser.Path != "internal/parser" || parser.Files != 2 || parser.Language != "Go" || parser.Description != "Package parser reads tool configuration files" {
+		t.Errorf("Module: got %+v", parser)
+	}
+}
+
+func TestDirectoryTree(t *testing.T) {
+	files := []string{"README.md", "cmd/quill/main.go", "internal/utils/git/git.go", ".github/workflows/ci.yml"}
+	want := "- cmd\n  - quill\n- internal\n  - utils\n"
+	if got := context.DirectoryTree(files, 2); got != want {
+		t.Errorf("DirectoryTree: got %q, want %q", got, want)
+	}
+}
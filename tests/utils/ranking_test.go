@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/helpers"
+)
+
+func TestScoreMessage(t *testing.T) {
+	files := []string{"internal/git/diff.go", "internal/git/diff_test.go"}
+	symbols := []string{"internal/git/diff.go: func ParseDiff (signature changed), method Diff.Paths (added)"}
+
+	tests := []struct {
+		name      string
+		message   string
+		score     float64
+		rationale string
+	}{
+		{
+			name:      "specific conventional message",
+			message:   "feat(git): add Paths to parsed diffs\n\n- Let ParseDiff callers list changed files",
+			score:     10,
+			rationale: "follows the format, names ParseDiff, Paths, diff",
+		},
+		{
+			name:      "format problems",
+			message:   "Updated the diff code.",
+			score:     4.5,
+			rationale: "header is not type(scope): description; header ends with punctuation; no body for a 2-file change",
+		},
+		{
+			name:      "generic wording",
+			message:   "fix(core): Improve things\n\n- Various fixes",
+			score:     8,
+			rationale: "description is capitalized; names none of the changed files or symbols",
+		},
+		{
+			name:      "unknown type and missing blank line",
+			message:   "update(diff): rework parsing\n- details",
+			score:     7,
+			rationale: "unknown type \"update\"; no blank line after the header",
+		},
+		{
+			name:      "description starting with an accented letter",
+			message:   "fix(git): élargir ParseDiff\n\n- Accept more headers",
+			score:     10,
+			rationale: "follows the format, names ParseDiff, diff",
+		},
+		{name: "empty", message: "  ", score: 0, rationale: "empty message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, rationale := helpers.ScoreMessage(tt.message, files, symbols)
+			if score != tt.score || rationale != tt.rationale {
+				t.Errorf("Got %.1f %q, want %.1f %q", score, rationale, tt.score, tt.rationale)
+			}
+		})
+	}
+}
+
+func TestParseJudgeResponse(t *testing.T) {
+	response := "Here are the scores:\n1: 7 - accurate but vague\n**2.** 9/10 - names the parser change\n3: 12 - out of range\n5: 8 - no such candidate\n"
+	got := helpers.ParseJudgeResponse(response, 3)
+	want := map[int]helpers.Judgement{
+		1: {Score: 7, Rationale: "accurate but vague"},
+		2: {Score: 9, Rationale: "names the parser change"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %+v, want %+v", got, want)
+	}
+}
+
+func TestRankMessages(t *testing.T) {
+	candidates := []helpers.RankedMessage{
+		{Message: "Fixed stuff", Source: "ollama"},
+		{Message: "fix(diff): handle renames in ParseDiff", Source: "gemini"},
+		{Message: "fix: handle renames", Source: "openai"},
+	}
+	files := []string{"diff.go"}
+	symbols := []string{"diff.go: func ParseDiff (modified)"}
+
+	var order []string
+	for _, r := range helpers.RankMessages(candidates, nil, files, symbols) {
+		order = append(order, r.Source)
+	}
+	if want := []string{"gemini", "openai", "ollama"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Rule ranking: got %v, want %v", order, want)
+	}
+
+	// The judge's scores win over the rules, and rule scores break its ties
+	judgements := map[int]helpers.Judgement{
+		1: {Score: 6, Rationale: "vague"},
+		2: {Score: 6, Rationale: "accurate"},
+		3: {Score: 9, Rationale: "best"},
+	}
+	ranked := helpers.RankMessages(candidates, judgements, files, symbols)
+	order = nil
+	for _, r := range ranked {
+		order = append(order, r.Source)
+	}
+	if want := []string{"openai", "gemini", "ollama"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Judged ranking: got %v, want %v", order, want)
+	}
+	if ranked[0].Rationale != "best" || ranked[0].Score != 9 {
+		t.Errorf("Unexpected top candidate: %+v", ranked[0])
+	}
+}
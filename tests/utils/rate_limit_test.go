@@ -3,6 +3,7 @@ package tests
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -18,7 +19,7 @@ const (
 	testPrompt           = "test prompt"
 )
 
-var errTemporary = errors.New("temporary error")
+var errTemporary = &ai.ProviderError{Kind: ai.KindOverloaded, Provider: "gemini", StatusCode: 503, Message: "temporary error"}
 
 func TestRateLimiting(t *testing.T) {
 	mock := &mocks.MockGeminiProvider{
@@ -99,3 +100,79 @@ func TestRetryDisabled(t *testing.T) {
 		t.Errorf("Expected single attempt, got %d", retryCount)
 	}
 }
+
+func TestRetrySkipsPermanentErrors(t *testing.T) {
+	var retryCount int32
+	mock := &mocks.MockGeminiProvider{
+		GenerateFunc: func(ctx context.Context, prompt string, opts ai.GenerateOptions) ([]string, error) {
+			atomic.AddInt32(&retryCount, 1)
+			return nil, &ai.ProviderError{Kind: ai.KindAuth, Provider: "gemini", StatusCode: 401, Message: "invalid api key"}
+		},
+	}
+
+	provider := factories.GetRateLimitedProvider(mock, true)
+
+	_, err := provider.Generate(context.Background(), testPrompt, ai.GenerateOptions{})
+	if ai.Classify(err) != ai.KindAuth {
+		t.Errorf("Expected authentication error, got %v", err)
+	}
+
+	if retryCount != 1 {
+		t.Errorf("Expected single attempt for a permanent error, got %d", retryCount)
+	}
+
+	// Unclassified errors are not retried either
+	retryCount = 0
+	mock.GenerateFunc = func(ctx context.Context, prompt string, opts ai.GenerateOptions) ([]string, error) {
+		atomic.AddInt32(&retryCount, 1)
+		return nil, errors.New("unexpected response")
+	}
+	if _, err := provider.Generate(context.Background(), testPrompt, ai.GenerateOptions{}); err == nil || retryCount != 1 {
+		t.Errorf("Expected a single failed attempt for an unclassified error, got %d attempts and %v", retryCount, err)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	prompt := strings.Repeat("a", 400)
+
+	if got := factories.EstimateTokens(prompt, ai.GenerateOptions{}); got != 100 {
+		t.Errorf("Expected 100 tokens, got %d", got)
+	}
+
+	// The estimate is per request; candidates sent in one request cost the prompt once
+	opts := ai.GenerateOptions{MaxCandidates: 3, System: strings.Repeat("b", 400)}
+	if got := factories.EstimateTokens(prompt, opts); got != 200 {
+		t.Errorf("Expected 200 tokens for one request, got %d", got)
+	}
+}
+
+// fanOutProvider sends one request per candidate, like the Anthropic and Ollama providers
+type fanOutProvider struct {
+	mocks.MockGeminiProvider
+}
+
+func (p *fanOutProvider) Requests(opts ai.GenerateOptions) int {
+	return opts.MaxCandidates
+}
+
+func TestRequestsPerCall(t *testing.T) {
+	opts := ai.GenerateOptions{MaxCandidates: 3}
+	if got := factories.RequestsPerCall(&mocks.MockGeminiProvider{}, opts); got != 1 {
+		t.Errorf("Expected one request for a provider sending all candidates at once, got %d", got)
+	}
+	if got := factories.RequestsPerCall(&fanOutProvider{}, opts); got != 3 {
+		t.Errorf("Expected one request per candidate, got %d", got)
+	}
+	if got := factories.RequestsPerCall(&fanOutProvider{}, ai.GenerateOptions{}); got != 1 {
+		t.Errorf("Expected at least one request, got %d", got)
+	}
+}
+
+func TestDefaultRateLimits(t *testing.T) {
+	if limits := factories.DefaultRateLimits("ollama"); limits != (factories.RateLimits{}) {
+		t.Errorf("Expected Ollama to be unlimited, got %+v", limits)
+	}
+	if limits := factories.DefaultRateLimits("openai"); limits.RequestsPerMinute != 60 {
+		t.Errorf("Expected 60 requests per minute for hosted providers, got %+v", limits)
+	}
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/helpers"
+)
+
+func TestReconcileGroups(t *testing.T) {
+	staged := []string{"internal/git/diff.go"}
+	unstaged := []string{"internal/git/diff.go", "internal/git/status.go", "docs/README.md", "cmd/main.go"}
+
+	groups, rec := helpers.ReconcileGroups([]helpers.SuggestionGroup{
+		{
+			Description: "diff parsing",
+			Files:       []string{"b/internal/git/diff.go", "git/status.go", "internal/git/missing.go"},
+			Message:     "feat(git): parse renames",
+		},
+		{
+			Description: "docs",
+			Files:       []string{"`docs/README.md`", "internal/git/diff.go"},
+			Message:     "docs: explain renames",
+		},
+		{
+			Description: "hallucinated",
+			Files:       []string{"nowhere.go"},
+			Message:     "fix: nothing",
+		},
+	}, staged, unstaged)
+
+	if len(groups) != 3 {
+		t.Fatalf("Expected 2 groups and the remaining group, got %+v", groups)
+	}
+	if want := []string{"internal/git/diff.go", "internal/git/status.go"}; !slices.Equal(groups[0].Files, want) {
+		t.Errorf("Expected %v, got %v", want, groups[0].Files)
+	}
+	if want := []string{"docs/README.md"}; !slices.Equal(groups[1].Files, want) {
+		t.Errorf("Expected the repeated file dropped, got %v", groups[1].Files)
+	}
+	if len(groups[0].Notes) != 3 || len(groups[1].Notes) != 2 {
+		t.Errorf("Expected notes for every fix, got %q and %q", groups[0].Notes, groups[1].Notes)
+	}
+
+	remaining := groups[2]
+	if remaining.Description != helpers.RemainingDescription || !slices.Equal(remaining.Files, []string{"cmd/main.go"}) {
+		t.Errorf("Expected cmd/main.go in the remaining group, got %+v", remaining)
+	}
+	if remaining.ShouldStage {
+		t.Error("Expected the remaining group not to be marked for staging")
+	}
+
+	if rec.Matched["git/status.go"] != "internal/git/status.go" || len(rec.Matched) != 3 {
+		t.Errorf("Unexpected matches: %v", rec.Matched)
+	}
+	if want := []string{"internal/git/missing.go", "internal/git/diff.go", "nowhere.go"}; !slices.Equal(rec.Dropped, want) {
+		t.Errorf("Expected dropped %v, got %v", want, rec.Dropped)
+	}
+}
+
+func TestReconcileGroupsFuzzyMatch(t *testing.T) {
+	files := []string{"internal/providers/suggest_provider.go", "internal/providers/generate_provider.go", "go.mod", "go.sum"}
+
+	tests := []struct {
+		name      string
+		suggested string
+		want      string // Empty when the path should be dropped
+	}{
+		{name: "exact", suggested: "go.mod", want: "go.mod"},
+		{name: "diff prefix", suggested: "a/go.sum", want: "go.sum"},
+		{name: "dot slash", suggested: "./go.mod", want: "go.mod"},
+		{name: "case", suggested: "Internal/Providers/Suggest_Provider.go", want: "internal/providers/suggest_provider.go"},
+		{name: "suffix", suggested: "generate_provider.go", want: "internal/providers/generate_provider.go"},
+		{name: "typo", suggested: "internal/provider/suggest_provider.go", want: "internal/providers/suggest_provider.go"},
+		{name: "ambiguous suffix", suggested: "provider.go"},
+		{name: "short typo", suggested: "go.mad"},
+		{name: "unknown", suggested: "internal/cmd/root.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, _ := helpers.ReconcileGroups([]helpers.SuggestionGroup{{
+				Description: "group",
+				Files:       []string{tt.suggested},
+			}}, nil, files)
+
+			var got string
+			if groups[0].Description == "group" {
+				got = groups[0].Files[0]
+			}
+			if got != tt.want {
+				t.Errorf("%q resolved to %q, want %q", tt.suggested, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package tests
+
+import (
+	c "context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jabafett/quill/internal/providers"
+	"github.com/jabafett/quill/internal/utils/ai"
+	"github.com/jabafett/quill/internal/utils/config"
+	"github.com/jabafett/quill/internal/utils/context"
+	"github.com/jabafett/quill/internal/utils/git"
+	"github.com/jabafett/quill/internal/utils/helpers"
+	"github.com/jabafett/quill/tests/mocks"
+)
+
+const reviewDiff = `diff --git a/app.js b/app.js
+index 1111111..2222222 100644
+--- a/app.js
++++ b/app.js
+@@ -10,4 +10,6 @@ function start() {
+ const port = 8080;
+-listen(port);
++console.log("starting", port);
++listen(port); // TODO: read the port from the environment
+ done();
++const apiKey = "sk-live0123456789abcdefghij";
+ }
+@@ -40,2 +42,3 @@ function stop() {
+ close();
++const password = "${DB_PASSWORD}";
+ }
+diff --git a/app.test.js b/app.test.js
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/app.test.js
+@@ -0,0 +1,2 @@
++console.log("debugging the test");
++test("starts", () => start());
+`
+
+func TestLocateLine(t *testing.T) {
+	diff, err := git.ParseDiff(reviewDiff)
+	if err != nil {
+		t.Fatalf("ParseDiff failed: %v", err)
+	}
+	file := diff.File("app.js")
+
+	added := file.AddedLines()
+	if len(added) != 4 || added[0].Number != 11 || added[2].Number != 14 || added[3].Number != 43 {
+		t.Fatalf("Unexpected added lines: %+v", added)
+	}
+
+	for _, tc := range []struct{ line, want int }{
+		{12, 12}, // Added line
+		{10, 10}, // Context line inside a hunk
+		{30, 43}, // Between hunks, nearest added line below
+		{20, 14}, // Nearest added line above
+		{1, 11},
+	} {
+		if got := file.LocateLine(tc.line); got != tc.want {
+			t.Errorf("LocateLine(%d) = %d, want %d", tc.line, got, tc.want)
+		}
+	}
+
+	numbered := file.NumberedString()
+	for _, want := range []string{"=== app.js (modified)", "   11 +console.log", "      -listen(port);", "   13  done();"} {
+		if !strings.Contains(numbered, want) {
+			t.Errorf("Expected %q in:\n%s", want, numbered)
+		}
+	}
+}
+
+func TestScanDiff(t *testing.T) {
+	diff, err := git.ParseDiff(reviewDiff)
+	if err != nil {
+		t.Fatalf("ParseDiff failed: %v", err)
+	}
+
+	var got []string
+	for _, f := range context.ScanDiff(diff) {
+		got = append(got, f.Location()+" "+string(f.Severity)+" "+string(f.Category))
+		if f.Category == helpers.CategorySecret && strings.Contains(f.Message, "sk-live") {
+			t.Errorf("Expected the secret to stay out of the message, got %q", f.Message)
+		}
+		if f.Category == helpers.CategoryTodo && f.Message != "Unresolved TODO: read the port from the environment" {
+			t.Errorf("Unexpected TODO message %q", f.Message)
+		}
+	}
+	// The environment lookup is no secret and test files may log
+	want := []string{"app.js:11 low debug", "app.js:12 info todo", "app.js:14 high secret"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected findings %v, got %v", want, got)
+	}
+
+	redacted := context.RedactSecrets(diff).String()
+	if strings.Contains(redacted, "sk-live") || !strings.Contains(redacted, `+const apiKey = "[REDACTED]";`) {
+		t.Errorf("Expected the API key to be redacted, got:\n%s", redacted)
+	}
+	if !strings.Contains(diff.String(), "sk-live") {
+		t.Error("Expected RedactSecrets to leave the original diff alone")
+	}
+
+	// Secrets in lines the change keeps or removes were committed all the same
+	removal, err := git.ParseDiff("diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n" +
+		"@@ -1,2 +1,3 @@\n const key = \"sk-kept0123456789abcdefghij\"\n-const token = \"sk-gone0123456789abcdefghij\"\n" +
+		"+const token = os.Getenv(\"TOKEN\")\n+fmt.Println(token)\n")
+	if err != nil {
+		t.Fatalf("ParseDiff failed: %v", err)
+	}
+	if redacted := context.RedactSecrets(removal).String(); strings.Contains(redacted, "sk-kept") || strings.Contains(redacted, "sk-gone") {
+		t.Errorf("Expected context and deleted lines to be redacted, got:\n%s", redacted)
+	}
+	// Go commands print with fmt, so only the print builtins count as debug output
+	if findings := context.ScanDiff(removal); len(findings) != 0 {
+		t.Errorf("Expected no findings, got %+v", findings)
+	}
+}
+
+func TestReviewReport(t *testing.T) {
+	findings, err := helpers.ParseReviewObject(`{"findings":[` +
+		`{"file":"app.js","line":14,"severity":"Medium","category":"bug","message":"port is unused"},` +
+		`{"file":"my file.js","line":0,"severity":"critical","category":"missing-test","message":"start() has no test"}]}`)
+	if err != nil {
+		t.Fatalf("ParseReviewObject failed: %v", err)
+	}
+	review := &helpers.Review{Files: []string{"app.js", "my file.js"}, Findings: append(findings,
+		helpers.Finding{File: "app.js", Line: 11, Severity: helpers.SeverityLow, Category: helpers.CategoryDebug, Message: "console call left in"})}
+	review.Sort()
+
+	if review.Findings[0].Line != 11 || review.Findings[1].Severity != helpers.SeverityMedium {
+		t.Errorf("Expected findings sorted by file and line, got %+v", review.Findings)
+	}
+	for _, tc := range []struct {
+		threshold string
+		want      int
+	}{{"info", 3}, {"medium", 2}, {"high", 1}, {"none", 0}} {
+		threshold, err := helpers.ParseFailThreshold(tc.threshold)
+		if err != nil {
+			t.Fatalf("ParseFailThreshold(%q) failed: %v", tc.threshold, err)
+		}
+		if got := len(review.AtOrAbove(threshold)); got != tc.want {
+			t.Errorf("Expected %d findings at or above %q, got %d", tc.want, tc.threshold, got)
+		}
+	}
+	if _, err := helpers.ParseFailThreshold("blocker"); err == nil {
+		t.Error("Expected an error for an unknown severity")
+	}
+	if text := review.Text(); !strings.Contains(text, "app.js:14: medium [bug] port is unused") ||
+		!strings.Contains(text, "3 findings (1 critical, 1 medium, 1 low)") {
+		t.Errorf("Unexpected text report:\n%s", text)
+	}
+
+	raw, err := review.SARIF()
+	if err != nil {
+		t.Fatalf("SARIF failed: %v", err)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region *struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(raw, &log); err != nil {
+		t.Fatalf("Invalid SARIF JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 3 {
+		t.Fatalf("Unexpected SARIF log:\n%s", raw)
+	}
+	results := log.Runs[0].Results
+	if results[1].Level != "warning" || results[1].Locations[0].PhysicalLocation.Region.StartLine != 14 {
+		t.Errorf("Expected a warning on line 14, got %+v", results[1])
+	}
+	if loc := results[2].Locations[0].PhysicalLocation; results[2].Level != "error" || loc.ArtifactLocation.URI != "my%20file.js" || loc.Region != nil {
+		t.Errorf("Expected a file-level error with an escaped URI, got %+v", results[2])
+	}
+
+	if _, err := helpers.ParseReviewObject(`{"findings":[{"file":"a.go","line":1,"severity":"urgent","category":"style","message":"x"}]}`); err == nil {
+		t.Error("Expected an error for an unknown severity and category")
+	}
+}
+
+func TestReviewKeepsLocalFindingsOnProviderFailure(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "app.js", "const apiKey = \"sk-live0123456789abcdefghij\";\n")
+	runGitCmd(t, dir, "add", "app.js")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	cfg := &config.Config{
+		Core:   config.CoreConfig{DefaultProvider: "gemini"},
+		Review: config.ReviewConfig{FailOn: "high"},
+	}
+	mock := &mocks.MockGeminiProvider{
+		GenerateFunc: func(ctx c.Context, prompt string, opts ai.GenerateOptions) ([]string, error) {
+			return nil, errors.New("service unavailable")
+		},
+	}
+	reviewer, err := providers.NewReviewFactoryWithProvider(cfg, repo, mock)
+	if err != nil {
+		t.Fatalf("Failed to create review factory: %v", err)
+	}
+
+	review, err := reviewer.Review(c.Background())
+	if err != nil {
+		t.Fatalf("Review failed: %v", err)
+	}
+	if !strings.Contains(review.ModelError, "service unavailable") {
+		t.Errorf("Expected the provider failure to be recorded, got %q", review.ModelError)
+	}
+	if len(review.Findings) == 0 || review.Findings[0].Category != helpers.CategorySecret {
+		t.Fatalf("Expected the local secret finding to be kept, got %+v", review.Findings)
+	}
+	threshold, _ := helpers.ParseFailThreshold(reviewer.FailOn())
+	if failing := review.AtOrAbove(threshold); len(failing) == 0 {
+		t.Error("Expected the local findings to reach the fail threshold")
+	}
+
+	// A response that cannot be read, even after a repair, is a failure too
+	mock.GenerateFunc = func(ctx c.Context, prompt string, opts ai.GenerateOptions) ([]string, error) {
+		return []string{"not a review"}, nil
+	}
+	review, err = reviewer.Review(c.Background())
+	if err != nil {
+		t.Fatalf("Review failed: %v", err)
+	}
+	if review.ModelError == "" || len(review.Findings) == 0 {
+		t.Errorf("Expected the local findings and a model error, got %+v", review)
+	}
+}
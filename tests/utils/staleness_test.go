@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jabafett/quill/internal/utils/context"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+func TestCheckStaleness(t *testing.T) {
+	dir := initTestRepo(t)
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	head, err := repo.HeadCommit()
+	if err != nil {
+		t.Fatalf("HeadCommit failed: %v", err)
+	}
+	tree, err := repo.HeadTree()
+	if err != nil {
+		t.Fatalf("HeadTree failed: %v", err)
+	}
+	summary := &context.RepoSummary{Files: 1, Head: head, TreeHash: tree, UpdatedAt: time.Now()}
+	thresholds := context.StalenessThresholds{Commits: 3, ChangedFiles: 3, Age: 24 * time.Hour}
+
+	fresh, err := context.CheckStaleness(repo, summary, thresholds)
+	if err != nil {
+		t.Fatalf("CheckStaleness failed: %v", err)
+	}
+	if fresh.Stale() || fresh.Commits != 0 || fresh.ChangedFiles != 0 {
+		t.Errorf("Fresh summary: got %+v", fresh)
+	}
+
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		writeTestFile(t, dir, name, "package main\n")
+		runGitCmd(t, dir, "add", name)
+		runGitCmd(t, dir, "commit", "-q", "-m", "add "+name)
+	}
+
+	stale, err := context.CheckStaleness(repo, summary, thresholds)
+	if err != nil {
+		t.Fatalf("CheckStaleness failed: %v", err)
+	}
+	want := []string{"at least 3 commits since it was written", "3 files changed since it was written"}
+	if !reflect.DeepEqual(stale.Reasons, want) {
+		t.Errorf("Reasons: got %q, want %q", stale.Reasons, want)
+	}
+	if stale.Files != 4 {
+		t.Errorf("Files: got %d, want 4", stale.Files)
+	}
+
+	relaxed, err := context.CheckStaleness(repo, summary, context.StalenessThresholds{Commits: 10, ChangedFiles: 10})
+	if err != nil {
+		t.Fatalf("CheckStaleness failed: %v", err)
+	}
+	if relaxed.Stale() || relaxed.Commits != 3 || !relaxed.CommitsExact {
+		t.Errorf("Relaxed thresholds: got %+v", relaxed)
+	}
+
+	old := *summary
+	old.UpdatedAt = time.Now().Add(-72 * time.Hour)
+	aged, err := context.CheckStaleness(repo, &old, context.StalenessThresholds{Age: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("CheckStaleness failed: %v", err)
+	}
+	if want := []string{"written 3 days ago"}; !reflect.DeepEqual(aged.Reasons, want) {
+		t.Errorf("Aged reasons: got %q, want %q", aged.Reasons, want)
+	}
+
+	// Rewriting the indexed commit takes it out of the history of HEAD
+	runGitCmd(t, dir, "reset", "-q", "--hard", head)
+	writeTestFile(t, dir, "d.go", "package main\n")
+	runGitCmd(t, dir, "add", "d.go")
+	runGitCmd(t, dir, "commit", "-q", "--amend", "-m", "rewritten")
+	rewritten, err := context.CheckStaleness(repo, summary, context.StalenessThresholds{})
+	if err != nil {
+		t.Fatalf("CheckStaleness failed: %v", err)
+	}
+	if !rewritten.Rewritten || !rewritten.Stale() {
+		t.Errorf("Rewritten history: got %+v", rewritten)
+	}
+
+	untracked, err := context.CheckStaleness(repo, &context.RepoSummary{UpdatedAt: time.Now()}, thresholds)
+	if err != nil {
+		t.Fatalf("CheckStaleness failed: %v", err)
+	}
+	if !untracked.Untracked || !untracked.Stale() {
+		t.Errorf("Summary without HEAD: got %+v", untracked)
+	}
+}
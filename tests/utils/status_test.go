@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+func TestParseStatusV2(t *testing.T) {
+	output := strings.Join([]string{
+		"1 .M N... 100644 100644 100644 abc abc src/main.go",
+		"1 MM N... 100644 100644 100644 abc def with space.go",
+		"1 .D N... 100644 100644 000000 abc abc gone.go",
+		"2 R. N... 100644 100644 100644 abc abc R100 new/name.go",
+		"old/name.go",
+		"u UU N... 100644 100644 100644 100644 a b c conflict.go",
+		"? untracked.txt",
+		"! ignored.log",
+		"",
+	}, "\x00")
+
+	statuses, err := git.ParseStatusV2(output)
+	if err != nil {
+		t.Fatalf("ParseStatusV2 failed: %v", err)
+	}
+	if len(statuses) != 6 {
+		t.Fatalf("Expected 6 statuses, got %+v", statuses)
+	}
+
+	tests := []struct {
+		status          git.FileStatus
+		path, origPath  string
+		deleted, staged bool // staged: partially staged
+	}{
+		{status: statuses[0], path: "src/main.go"},
+		{status: statuses[1], path: "with space.go", staged: true},
+		{status: statuses[2], path: "gone.go", deleted: true},
+		{status: statuses[3], path: "new/name.go", origPath: "old/name.go"},
+		{status: statuses[4], path: "conflict.go", staged: true},
+		{status: statuses[5], path: "untracked.txt"},
+	}
+	for _, tt := range tests {
+		s := tt.status
+		if s.Path != tt.path || s.OrigPath != tt.origPath {
+			t.Errorf("Expected %s (from %q), got %+v", tt.path, tt.origPath, s)
+		}
+		if s.Deleted() != tt.deleted || s.PartiallyStaged() != tt.staged {
+			t.Errorf("%s: deleted=%v partially staged=%v, want %v and %v",
+				s.Path, s.Deleted(), s.PartiallyStaged(), tt.deleted, tt.staged)
+		}
+	}
+	if label := statuses[3].Label(); label != "renamed from old/name.go" {
+		t.Errorf("Unexpected rename label %q", label)
+	}
+
+	if _, err := git.ParseStatusV2("1 .M short"); err == nil {
+		t.Error("Expected an error for a malformed record")
+	}
+}
+
+func TestApplyGroupsDeletedAndRenamed(t *testing.T) {
+	dir := initTestRepo(t)
+	content := "package util\n\nfunc Helper() string {\n\treturn \"help\"\n}\n"
+	writeTestFile(t, dir, "util.go", content)
+	writeTestFile(t, dir, "obsolete.go", "package obsolete\n")
+	writeTestFile(t, dir, "main.go", "package main\n")
+	runGitCmd(t, dir, "add", ".")
+	runGitCmd(t, dir, "commit", "-q", "-m", "add files")
+
+	// A rename and a deletion in the worktree, and a file staged and then modified again
+	if err := os.Rename(filepath.Join(dir, "util.go"), filepath.Join(dir, "helpers.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "obsolete.go")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, "main.go", "package main\n\n// staged\n")
+	runGitCmd(t, dir, "add", "main.go")
+	writeTestFile(t, dir, "main.go", "package main\n\n// staged\n// unstaged\n")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	statuses, err := repo.FileStatuses()
+	if err != nil {
+		t.Fatalf("FileStatuses failed: %v", err)
+	}
+	byPath := git.StatusByPath(statuses)
+	if s := byPath["helpers.go"]; s.OrigPath != "util.go" || s.Worktree != 'R' {
+		t.Errorf("Expected a worktree rename from util.go, got %+v", s)
+	}
+	if len(statuses) != 3 {
+		t.Errorf("Expected the rename as one entry, got %+v", statuses)
+	}
+	if !byPath["main.go"].PartiallyStaged() {
+		t.Errorf("Expected main.go to be partially staged, got %+v", byPath["main.go"])
+	}
+
+	_, err = repo.ApplyGroups([]git.CommitGroup{
+		{Description: "rename", Files: []string{"helpers.go"}, Message: "refactor: rename util to helpers"},
+		{Description: "cleanup", Files: []string{"obsolete.go", "main.go"}, Message: "chore: remove obsolete package"},
+	})
+	if err != nil {
+		t.Fatalf("ApplyGroups failed: %v", err)
+	}
+
+	if changes := runGitCmd(t, dir, "show", "--name-status", "--format=", "-M", "HEAD~1"); !strings.HasPrefix(changes, "R100") || strings.Count(changes, "\n") != 0 {
+		t.Errorf("Expected a single rename in the first commit, got:\n%s", changes)
+	}
+	if changes := runGitCmd(t, dir, "show", "--name-status", "--format=", "HEAD"); changes != "M\tmain.go\nD\tobsolete.go" {
+		t.Errorf("Unexpected second commit:\n%s", changes)
+	}
+	if status := runGitCmd(t, dir, "status", "--porcelain"); status != "" {
+		t.Errorf("Expected a clean worktree, got:\n%s", status)
+	}
+}
+
+func TestPartiallyStagedFiles(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "a.go", "package a\n")
+	writeTestFile(t, dir, "b.go", "package b\n")
+	runGitCmd(t, dir, "add", ".")
+	runGitCmd(t, dir, "commit", "-q", "-m", "add files")
+
+	writeTestFile(t, dir, "a.go", "package a\n\n// staged\n")
+	writeTestFile(t, dir, "b.go", "package b\n\n// staged\n")
+	runGitCmd(t, dir, "add", ".")
+	writeTestFile(t, dir, "a.go", "package a\n\n// staged\n// unstaged\n")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	files, err := repo.PartiallyStagedFiles()
+	if err != nil {
+		t.Fatalf("PartiallyStagedFiles failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != "a.go" {
+		t.Fatalf("Expected only a.go to be partially staged, got %v", files)
+	}
+
+	if err := repo.StageFiles(files); err != nil {
+		t.Fatalf("StageFiles failed: %v", err)
+	}
+	if files, _ := repo.PartiallyStagedFiles(); len(files) != 0 {
+		t.Errorf("Expected no partially staged files after staging, got %v", files)
+	}
+	if status := runGitCmd(t, dir, "status", "--porcelain"); status != "M  a.go\nM  b.go" {
+		t.Errorf("Expected both files fully staged, got:\n%s", status)
+	}
+}
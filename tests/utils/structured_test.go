@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/ai"
+	"github.com/jabafett/quill/internal/utils/helpers"
+)
+
+func TestParseCommitObject(t *testing.T) {
+	message, err := helpers.ParseCommitObject("```json\n" +
+		`{"header":"feat(git): add rename detection","body":"- Read porcelain v2 output","footer":""}` + "\n```")
+	if err != nil {
+		t.Fatalf("ParseCommitObject failed: %v", err)
+	}
+	if want := "feat(git): add rename detection\n\n- Read porcelain v2 output"; message != want {
+		t.Errorf("Expected %q, got %q", want, message)
+	}
+
+	var validation *helpers.ValidationError
+	if _, err := helpers.ParseCommitObject(`{"header":" ","body":"text","footer":""}`); !errors.As(err, &validation) {
+		t.Errorf("Expected a ValidationError for an empty header, got %v", err)
+	}
+	if _, err := helpers.ParseCommitObject("feat: plain text"); !errors.As(err, &validation) {
+		t.Errorf("Expected a ValidationError for text, got %v", err)
+	}
+}
+
+func TestParseSuggestionObject(t *testing.T) {
+	staged := []string{"a.go"}
+	unstaged := []string{"a.go", "b.go", "c.go"}
+
+	t.Run("valid", func(t *testing.T) {
+		groups, err := helpers.ParseSuggestionObject(`{"groups":[
+			{"description":"parser","files":["a.go","b.go"],"header":"feat: add parser","body":"","footer":""},
+			{"description":"docs","files":["c.go"],"header":"docs: explain parser","body":"- usage","footer":""}]}`,
+			staged, unstaged)
+		if err != nil {
+			t.Fatalf("ParseSuggestionObject failed: %v", err)
+		}
+		if len(groups) != 2 || len(groups[0].Files) != 2 || !groups[0].ShouldStage {
+			t.Fatalf("Unexpected groups: %+v", groups)
+		}
+		if groups[1].Message != "docs: explain parser\n\n- usage" {
+			t.Errorf("Unexpected message: %q", groups[1].Message)
+		}
+	})
+
+	t.Run("invalid files", func(t *testing.T) {
+		groups, err := helpers.ParseSuggestionObject(`{"groups":[
+			{"description":"parser","files":["a.go","missing.go"],"header":"feat: add parser","body":"","footer":""},
+			{"description":"again","files":["a.go"],"header":"","body":"","footer":""}]}`,
+			staged, unstaged)
+
+		var validation *helpers.ValidationError
+		if !errors.As(err, &validation) {
+			t.Fatalf("Expected a ValidationError, got %v", err)
+		}
+		// missing.go, the repeated a.go, the empty header and the uncovered b.go and c.go
+		if len(validation.Problems) != 4 {
+			t.Errorf("Expected 4 problems, got %q", validation.Problems)
+		}
+		if len(groups) != 2 || len(groups[0].Files) != 2 {
+			t.Errorf("Expected the groups as written for ReconcileGroups, got %+v", groups)
+		}
+	})
+}
+
+func TestAnthropicStructuredOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Tools []struct {
+				Name        string         `json:"name"`
+				InputSchema map[string]any `json:"input_schema"`
+			} `json:"tools"`
+			ToolChoice struct {
+				Type string `json:"type"`
+				Name string `json:"name"`
+			} `json:"tool_choice"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+		if len(body.Tools) != 1 || body.Tools[0].Name != "commit_message" || body.Tools[0].InputSchema["type"] != "object" {
+			t.Errorf("Expected the schema as a tool, got %+v", body.Tools)
+		}
+		if body.ToolChoice.Type != "tool" || body.ToolChoice.Name != "commit_message" {
+			t.Errorf("Expected the tool to be forced, got %+v", body.ToolChoice)
+		}
+		w.Write([]byte(`{"content":[{"type":"text","text":"Here it is"},` +
+			`{"type":"tool_use","name":"commit_message","input":{"header":"feat: add thing","body":"","footer":""}}]}`))
+	}))
+	defer server.Close()
+
+	provider, _ := ai.NewAnthropicProvider(ai.Options{Model: "claude", MaxTokens: 100, Host: server.URL})
+	responses, err := provider.Generate(context.Background(), "diff", ai.GenerateOptions{
+		MaxCandidates: 1,
+		Schema:        helpers.CommitMessageSchema,
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if message, err := helpers.ParseCommitObject(responses[0]); err != nil || message != "feat: add thing" {
+		t.Errorf("Expected the tool input as the response, got %q (%v)", responses[0], err)
+	}
+}
+
+func TestOllamaStructuredOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+		format, ok := body["format"].(map[string]any)
+		if !ok || !strings.Contains(strings.Join(toStrings(format["required"]), ","), "groups") {
+			t.Errorf("Expected the schema as format, got %v", body["format"])
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": map[string]string{"role": "assistant", "content": `{"groups":[]}`},
+			"done":    true,
+		})
+	}))
+	defer server.Close()
+
+	t.Setenv("OLLAMA_HOST", "")
+	provider, err := ai.NewOllamaProvider(ai.Options{Model: "test-model", Host: server.URL})
+	if err != nil {
+		t.Fatalf("NewOllamaProvider failed: %v", err)
+	}
+	if _, err := provider.Generate(context.Background(), "diff", ai.GenerateOptions{
+		MaxCandidates: 1,
+		Schema:        helpers.SuggestionSchema,
+	}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+}
+
+// toStrings converts a decoded JSON array of strings
+func toStrings(v any) []string {
+	items, _ := v.([]any)
+	strs := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/context"
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+func TestExtractSymbols(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    []string
+		oneLine bool // Nests declarations on a single line, which only the parser sees
+	}{
+		{
+			path:    "repo.go",
+			content: "package git\n\ntype Repo[T any] struct{ x int }\n\nfunc (r *Repo[T]) Commit(msg string) error {\n\treturn nil\n}\n\nfunc Parse(s string) int {\n\treturn 0\n}\n",
+			want:    []string{"struct Repo", "method Repo.Commit", "func Parse"},
+		},
+		{
+			path:    "app.ts",
+			content: "export class Store extends Base {\n  load(id: string): void {}\n}\nexport interface Item { id(): string }\nexport const handler = async (req: Request) => {\n  return 1\n}\n",
+			want:    []string{"class Store", "method Store.load", "interface Item", "method Item.id", "func handler"},
+			oneLine: true,
+		},
+		{
+			path:    "app.js",
+			content: "class A { m() {} }\nconst f = function (x) { return x }\nconst n = 1\n",
+			want:    []string{"class A", "method A.m", "func f"},
+			oneLine: true,
+		},
+		{
+			path:    "app.py",
+			content: "class Cache(Base):\n    @property\n    def size(self):\n        def inner(): pass\n        return 1\n\ndef load(path):\n    return path\n",
+			want:    []string{"class Cache", "method Cache.size", "func load"},
+		},
+		{
+			path:    "lib.rs",
+			content: "struct S { a: i32 }\ntrait T { fn t(&self); }\nimpl T for S {\n    fn t(&self) {}\n}\npub fn top(x: u8) -> u8 { x }\n",
+			want:    []string{"struct S", "trait T", "method T.t", "method S.t", "func top"},
+			oneLine: true,
+		},
+		{
+			path:    "App.java",
+			content: "public class App {\n  public App(int x) {}\n  private int run(String s) { return 1; }\n}\n",
+			want:    []string{"class App", "constructor App.App", "method App.run"},
+		},
+		{
+			path:    "README.md",
+			content: "# Title\n",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if tt.oneLine && !treeSitter {
+				t.Skip("Needs the tree-sitter parser, which needs cgo")
+			}
+			symbols, err := context.ExtractSymbols(tt.path, []byte(tt.content))
+			if err != nil {
+				t.Fatalf("ExtractSymbols failed: %v", err)
+			}
+			var got []string
+			for _, sym := range symbols {
+				got = append(got, string(sym.Kind)+" "+sym.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangedSymbols(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "calc.go", `package calc
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func Sub(a, b int) int {
+	return a - b
+}
+
+func Old() {}
+
+type Calc struct{}
+
+func (c *Calc) Reset() {
+	c = nil
+}
+`)
+	runGitCmd(t, dir, "add", "calc.go")
+	runGitCmd(t, dir, "commit", "-q", "-m", "add calc")
+
+	writeTestFile(t, dir, "calc.go", `package calc
+
+func Add(a, b int) int {
+	return b + a
+}
+
+func Sub(a, b, c int) int {
+	return a - b - c
+}
+
+type Calc struct{}
+
+func (c *Calc) Reset() {
+	c = nil
+}
+
+func Mul(a, b int) int {
+	return a * b
+}
+`)
+	runGitCmd(t, dir, "add", "calc.go")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	diff, err := repo.StagedDiff()
+	if err != nil {
+		t.Fatalf("StagedDiff failed: %v", err)
+	}
+	versions, err := repo.StagedVersions()
+	if err != nil {
+		t.Fatalf("StagedVersions failed: %v", err)
+	}
+
+	files := context.ChangedSymbols(diff, versions)
+	if len(files) != 1 || files[0].Path != "calc.go" {
+		t.Fatalf("Expected changes in calc.go, got %+v", files)
+	}
+
+	var got []string
+	for _, change := range files[0].Changes {
+		got = append(got, change.String())
+	}
+	want := []string{
+		"func Add (modified)",
+		"func Sub (signature changed)",
+		"func Mul (added)",
+		"func Old (removed)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+
+	summary := context.SummarizeSymbols(files)
+	if len(summary) != 1 || !strings.HasPrefix(summary[0], "calc.go: func Add (modified), ") {
+		t.Errorf("Unexpected summary: %v", summary)
+	}
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+func TestAppendTrailers(t *testing.T) {
+	signoff := git.Trailer{Key: git.TrailerSignedOffBy, Value: "Test User <test@example.com>"}
+	refs := git.Trailer{Key: git.DefaultIssueKey, Value: "PROJ-123"}
+
+	tests := []struct {
+		name     string
+		message  string
+		trailers []git.Trailer
+		want     string
+	}{
+		{
+			name:     "subject only",
+			message:  "feat: add login",
+			trailers: []git.Trailer{refs, signoff},
+			want:     "feat: add login\n\nRefs: PROJ-123\nSigned-off-by: Test User <test@example.com>",
+		},
+		{
+			name:     "body without trailers",
+			message:  "feat: add login\n\nUsers can now sign in.\n",
+			trailers: []git.Trailer{signoff},
+			want:     "feat: add login\n\nUsers can now sign in.\n\nSigned-off-by: Test User <test@example.com>",
+		},
+		{
+			name:     "joins existing block",
+			message:  "feat!: drop v1 API\n\nBREAKING CHANGE: v1 endpoints are gone",
+			trailers: []git.Trailer{signoff},
+			want:     "feat!: drop v1 API\n\nBREAKING CHANGE: v1 endpoints are gone\nSigned-off-by: Test User <test@example.com>",
+		},
+		{
+			name:     "skips duplicates",
+			message:  "fix: crash\n\nSigned-off-by: Test User <test@example.com>",
+			trailers: []git.Trailer{signoff, signoff},
+			want:     "fix: crash\n\nSigned-off-by: Test User <test@example.com>",
+		},
+		{
+			name:     "issue already closed",
+			message:  "fix: crash\n\nCloses: PROJ-123",
+			trailers: []git.Trailer{refs},
+			want:     "fix: crash\n\nCloses: PROJ-123",
+		},
+		{
+			name:     "issue listed with others",
+			message:  "fix: crash\n\nCloses: #7, PROJ-123",
+			trailers: []git.Trailer{refs},
+			want:     "fix: crash\n\nCloses: #7, PROJ-123",
+		},
+		{
+			name:     "longer issue number is another issue",
+			message:  "fix: crash\n\nRefs: #42",
+			trailers: []git.Trailer{{Key: git.DefaultIssueKey, Value: "#4"}},
+			want:     "fix: crash\n\nRefs: #42\nRefs: #4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := git.AppendTrailers(tt.message, tt.trailers); got != tt.want {
+				t.Errorf("AppendTrailers() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIssueRefsFromBranch(t *testing.T) {
+	tests := []struct {
+		branch  string
+		pattern string
+		want    []string
+	}{
+		{branch: "feat/PROJ-123-login", want: []string{"PROJ-123"}},
+		{branch: "fix/42-crash", want: []string{"#42"}},
+		{branch: "feat/ABC-1-and-ABC-2", want: []string{"ABC-1"}},
+		{branch: "users/jane/FIX/ABC-9", want: []string{"ABC-9"}},
+		{branch: "ABC-1-login", want: nil},
+		{branch: "feat/UTF-8-paths", want: nil},
+		{branch: "fix/SHA-256-check", want: nil},
+		{branch: "chore/ISO-8601", want: nil},
+		{branch: "main", want: nil},
+		{branch: "fix/#7", want: []string{"#7"}},
+		{branch: "release/2024-01", want: nil},
+		{branch: "2024/42-crash", want: nil},
+		{branch: "feat/gh-77-search", pattern: `gh-([0-9]+)`, want: []string{"77"}},
+	}
+
+	for _, tt := range tests {
+		got, err := git.IssueRefsFromBranch(tt.branch, tt.pattern)
+		if err != nil {
+			t.Fatalf("IssueRefsFromBranch(%q) failed: %v", tt.branch, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("IssueRefsFromBranch(%q) = %v, want %v", tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestIssueRefFromDescription(t *testing.T) {
+	tests := []struct {
+		description string
+		pattern     string
+		want        string
+	}{
+		{description: "PROJ-123 let users reset their password", want: "PROJ-123"},
+		{description: "PROJ-123: reset passwords", want: "PROJ-123"},
+		{description: "#42 fix the crash", want: "#42"},
+		{description: "support UTF-8 filenames", want: ""},
+		{description: "UTF-8 filenames", want: ""},
+		{description: "fix the crash in #42", want: ""},
+		{description: "gh-77 search", pattern: `gh-([0-9]+)`, want: "77"},
+		{description: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		got, err := git.IssueRefFromDescription(tt.description, tt.pattern)
+		if err != nil {
+			t.Fatalf("IssueRefFromDescription(%q) failed: %v", tt.description, err)
+		}
+		if got != tt.want {
+			t.Errorf("IssueRefFromDescription(%q) = %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
+
+func TestBuildTrailers(t *testing.T) {
+	// Identity must come from the repository config
+	for _, key := range []string{"GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	dir := initTestRepo(t)
+	runGitCmd(t, dir, "checkout", "-q", "-b", "feat/PROJ-123-login")
+
+	pairing := filepath.Join(t.TempDir(), "pair")
+	content := "# current pair\nJane Doe <jane@example.com>\nTest User <test@example.com>\n"
+	if err := os.WriteFile(pairing, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	trailers, err := repo.BuildTrailers(git.TrailerOptions{
+		Signoff:     true,
+		CoAuthors:   []string{"Jane Doe <jane@example.com>"},
+		PairingFile: pairing,
+		IssueRefs:   true,
+	})
+	if err != nil {
+		t.Fatalf("BuildTrailers failed: %v", err)
+	}
+
+	// The committer is dropped from the pairing file; duplicates collapse when appended
+	msg := git.AppendTrailers("feat: add login", trailers)
+	want := "feat: add login\n\nRefs: PROJ-123\nCo-authored-by: Jane Doe <jane@example.com>\nSigned-off-by: Test User <test@example.com>"
+	if msg != want {
+		t.Errorf("Unexpected message:\n%q\nwant\n%q", msg, want)
+	}
+
+	// git must see the same trailer block
+	writeTestFile(t, dir, "MSG", msg+"\n")
+	parsed := runGitCmd(t, dir, "interpret-trailers", "--parse", "MSG")
+	var ours []string
+	for _, tr := range git.ParseTrailers(msg) {
+		ours = append(ours, tr.String())
+	}
+	if joined := strings.Join(ours, "\n"); joined != parsed {
+		t.Errorf("git parsed trailers\n%s\nwe parsed\n%s", parsed, joined)
+	}
+
+	if _, err := repo.BuildTrailers(git.TrailerOptions{CoAuthors: []string{"jane"}}); err == nil {
+		t.Error("Expected an error for a malformed co-author")
+	}
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/git"
+)
+
+// initTestRepo creates a repository with a single initial commit
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	runGitCmd(t, dir, "init", "-q")
+	runGitCmd(t, dir, "config", "user.name", "Test User")
+	runGitCmd(t, dir, "config", "user.email", "test@example.com")
+	writeTestFile(t, dir, "README.md", "init\n")
+	runGitCmd(t, dir, "add", "README.md")
+	runGitCmd(t, dir, "commit", "-q", "-m", "initial commit")
+	return dir
+}
+
+func runGitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyGroupsRollback(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "a.txt", "a\n")
+	writeTestFile(t, dir, "b.txt", "b\n")
+	runGitCmd(t, dir, "add", "b.txt")
+
+	startHead := runGitCmd(t, dir, "rev-parse", "HEAD")
+	startIndex := runGitCmd(t, dir, "write-tree")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	_, err = repo.ApplyGroups([]git.CommitGroup{
+		{Description: "first", Files: []string{"a.txt"}, Message: "feat: add a"},
+		{Description: "second", Files: []string{"missing.txt"}, Message: "feat: add missing"},
+	})
+
+	var batchErr git.ErrBatchFailed
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected ErrBatchFailed, got %v", err)
+	}
+	if batchErr.Group != 1 || batchErr.Applied != 1 {
+		t.Errorf("Expected failure at group 1 after 1 commit, got group %d after %d", batchErr.Group, batchErr.Applied)
+	}
+	if batchErr.RollbackErr != nil {
+		t.Fatalf("Rollback failed: %v", batchErr.RollbackErr)
+	}
+
+	if head := runGitCmd(t, dir, "rev-parse", "HEAD"); head != startHead {
+		t.Errorf("Expected HEAD %s after rollback, got %s", startHead, head)
+	}
+	if index := runGitCmd(t, dir, "write-tree"); index != startIndex {
+		t.Errorf("Expected index tree %s after rollback, got %s", startIndex, index)
+	}
+}
+
+func TestApplyGroupsUndo(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "a.txt", "a\n")
+	writeTestFile(t, dir, "b.txt", "b\n")
+
+	startHead := runGitCmd(t, dir, "rev-parse", "HEAD")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	commits, err := repo.ApplyGroups([]git.CommitGroup{
+		{Description: "first", Files: []string{"a.txt"}, Message: "feat: add a"},
+		{Description: "second", Files: []string{"b.txt"}, Message: "feat: add b"},
+	})
+	if err != nil {
+		t.Fatalf("ApplyGroups failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits, got %d", len(commits))
+	}
+
+	if _, err := repo.UndoLastBatch(false); err != nil {
+		t.Fatalf("UndoLastBatch failed: %v", err)
+	}
+	if head := runGitCmd(t, dir, "rev-parse", "HEAD"); head != startHead {
+		t.Errorf("Expected HEAD %s after undo, got %s", startHead, head)
+	}
+	if _, err := repo.LastBatch(); !errors.Is(err, git.ErrNoBatch) {
+		t.Errorf("Expected ErrNoBatch after undo, got %v", err)
+	}
+}
+
+func TestApplyGroupsKeepsHookStagedFixes(t *testing.T) {
+	dir := initTestRepo(t)
+	// A formatter hook that fixes the staged files and stages the fixes
+	writeTestFile(t, dir, ".git/hooks/pre-commit", "#!/bin/sh\n"+
+		"for f in $(git diff --cached --name-only --diff-filter=AM -- '*.txt'); do\n"+
+		"  echo formatted >> \"$f\"\n  git add \"$f\"\ndone\n")
+	if err := os.Chmod(filepath.Join(dir, ".git", "hooks", "pre-commit"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, "a.txt", "a\n")
+	writeTestFile(t, dir, "b.txt", "b\n")
+	writeTestFile(t, dir, "c.txt", "c\n")
+	runGitCmd(t, dir, "add", "c.txt")
+
+	repo, err := git.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	if _, err := repo.ApplyGroups([]git.CommitGroup{
+		{Description: "first", Files: []string{"a.txt"}, Message: "feat: add a"},
+		{Description: "second", Files: []string{"b.txt"}, Message: "feat: add b"},
+	}); err != nil {
+		t.Fatalf("ApplyGroups failed: %v", err)
+	}
+
+	if got := runGitCmd(t, dir, "show", "HEAD~1:a.txt"); got != "a\nformatted" {
+		t.Errorf("Expected the hook's fix in the first commit, got %q", got)
+	}
+	if got := runGitCmd(t, dir, "show", "--name-only", "--format=", "HEAD"); got != "b.txt" {
+		t.Errorf("Expected only b.txt in the second commit, got %q", got)
+	}
+	// The fixes reach the real index, and other staged files stay staged
+	if got := runGitCmd(t, dir, "diff", "--cached", "--name-only"); got != "c.txt" {
+		t.Errorf("Expected only c.txt staged, got %q", got)
+	}
+	if got := runGitCmd(t, dir, "status", "--porcelain", "--", "a.txt", "b.txt"); got != "" {
+		t.Errorf("Expected a.txt and b.txt clean, got %q", got)
+	}
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jabafett/quill/internal/utils/context"
+)
+
+func detectWorkspace(contents map[string]string) *context.Workspace {
+	files := make([]string, 0, len(contents))
+	for p := range contents {
+		files = append(files, p)
+	}
+	sort.Strings(files)
+	return context.DetectWorkspace(files, func(p string) ([]byte, error) {
+		content, ok := contents[p]
+		if !ok {
+			return nil, fmt.Errorf("%s not found", p)
+		}
+		return []byte(content), nil
+	})
+}
+
+func TestDetectWorkspace(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents map[string]string
+		kinds    []context.WorkspaceKind
+		packages []context.WorkspacePackage
+	}{
+		{
+			name: "pnpm with turbo",
+			contents: map[string]string{
+				"package.json":                 `{"name": "root", "private": true}`,
+				"pnpm-workspace.yaml":          "packages:\n  - 'apps/*'\n  - 'packages/**'\n  - '!packages/legacy'\n",
+				"turbo.json":                   "{}",
+				"apps/web/package.json":        `{"name": "@acme/web"}`,
+				"packages/ui/package.json":     `{"name": "@acme/ui"}`,
+				"packages/legacy/package.json": `{"name": "legacy"}`,
+				"tools/package.json":           `{"name": "tools"}`,
+			},
+			kinds: []context.WorkspaceKind{context.WorkspacePnpm, context.WorkspaceTurbo},
+			packages: []context.WorkspacePackage{
+				{Name: "@acme/web", Dir: "apps/web", Scope: "web", Kind: context.WorkspacePnpm},
+				{Name: "@acme/ui", Dir: "packages/ui", Scope: "ui", Kind: context.WorkspacePnpm},
+			},
+		},
+		{
+			name: "yarn workspaces",
+			contents: map[string]string{
+				"package.json":           `{"workspaces": {"packages": ["libs/*"]}}`,
+				"libs/core/package.json": `{}`,
+			},
+			kinds:    []context.WorkspaceKind{context.WorkspaceNpm},
+			packages: []context.WorkspacePackage{{Name: "libs/core", Dir: "libs/core", Scope: "core", Kind: context.WorkspaceNpm}},
+		},
+		{
+			name: "go.work",
+			contents: map[string]string{
+				"go.work":        "go 1.23\n\nuse (\n\t./api\n\t./cli\n)\n",
+				"api/go.mod":     "module example.com/api/v2\n",
+				"cli/go.mod":     "module example.com/cli\n",
+				"scratch/go.mod": "module example.com/scratch\n",
+			},
+			kinds: []context.WorkspaceKind{context.WorkspaceGo},
+			packages: []context.WorkspacePackage{
+				{Name: "example.com/api/v2", Dir: "api", Scope: "api", Kind: context.WorkspaceGo},
+				{Name: "example.com/cli", Dir: "cli", Scope: "cli", Kind: context.WorkspaceGo},
+			},
+		},
+		{
+			name: "cargo workspace",
+			contents: map[string]string{
+				"Cargo.toml":               "[workspace]\nmembers = [\"crates/*\"]\nexclude = [\"crates/old\"]\n",
+				"crates/parser/Cargo.toml": "[package]\nname = \"acme-parser\"\n",
+				"crates/old/Cargo.toml":    "[package]\nname = \"old\"\n",
+			},
+			kinds:    []context.WorkspaceKind{context.WorkspaceCargo},
+			packages: []context.WorkspacePackage{{Name: "acme-parser", Dir: "crates/parser", Scope: "acme-parser", Kind: context.WorkspaceCargo}},
+		},
+		{
+			name: "bazel",
+			contents: map[string]string{
+				"MODULE.bazel":          "",
+				"BUILD.bazel":           "",
+				"server/BUILD":          "",
+				"server/handlers/BUILD": "",
+				"client/BUILD.bazel":    "",
+			},
+			kinds: []context.WorkspaceKind{context.WorkspaceBazel},
+			packages: []context.WorkspacePackage{
+				{Name: "//client", Dir: "client", Scope: "client", Kind: context.WorkspaceBazel},
+				{Name: "//server", Dir: "server", Scope: "server", Kind: context.WorkspaceBazel},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := detectWorkspace(tt.contents)
+			if w == nil {
+				t.Fatal("No workspace detected")
+			}
+			if !reflect.DeepEqual(w.Kinds, tt.kinds) {
+				t.Errorf("Kinds: got %v, want %v", w.Kinds, tt.kinds)
+			}
+			if !reflect.DeepEqual(w.Packages, tt.packages) {
+				t.Errorf("Packages: got %+v, want %+v", w.Packages, tt.packages)
+			}
+		})
+	}
+
+	if w := detectWorkspace(map[string]string{"go.mod": "module example.com/m\n", "main.go": ""}); w != nil {
+		t.Errorf("Single module: got %+v, want nil", w)
+	}
+}
+
+func TestWorkspaceCouplings(t *testing.T) {
+	w := detectWorkspace(map[string]string{
+		"package.json":              `{"workspaces": ["packages/*"]}`,
+		"packages/web/package.json": `{"name": "web"}`,
+		"packages/ui/package.json":  `{"name": "ui"}`,
+		"packages/db/package.json":  `{"name": "db"}`,
+	})
+
+	changed := []string{"packages/web/page.ts", "packages/ui/button.ts", "packages/db/schema.ts", "README.md"}
+	var scopes []string
+	for _, pkg := range w.Touched(changed) {
+		scopes = append(scopes, pkg.Scope)
+	}
+	if want := []string{"db", "ui", "web"}; !reflect.DeepEqual(scopes, want) {
+		t.Errorf("Touched: got %v, want %v", scopes, want)
+	}
+
+	deps := map[string][]string{
+		"packages/web/page.ts": {"packages/ui/button.ts", "packages/ui/theme.ts", "packages/web/layout.ts"},
+	}
+	got := w.Couplings(changed, deps)
+	want := []context.Coupling{{From: "web", To: "ui", File: "packages/web/page.ts", Import: "packages/ui/button.ts"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Couplings: got %+v, want %+v", got, want)
+	}
+	if line := got[0].String(); line != "web and ui are coupled: packages/web/page.ts imports packages/ui/button.ts" {
+		t.Errorf("String: got %q", line)
+	}
+}